@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lioreshai/duplicaci/internal/config"
+	"github.com/lioreshai/duplicaci/internal/executor"
+	"github.com/lioreshai/duplicaci/internal/stats"
+)
+
+// checkOptions controls optional flags appended to a duplicacy check
+type checkOptions struct {
+	Chunks  bool   // Verify chunk contents with -chunks
+	Persist bool   // Continue past missing/corrupt chunks with -persist
+	ID      string // Limit the check to a single snapshot ID
+	Threads int    // Download threads (-threads is only passed when > 1)
+
+	// Revisions limits the check to these revisions of ID (all when empty)
+	Revisions []int
+}
+
+// checkArgs builds the duplicacy check arguments for a storage
+// -tabular is always included so the output can be parsed for stats
+func checkArgs(storage string, opts checkOptions) []string {
+	args := []string{"check", "-tabular", "-storage", storage}
+	if opts.ID != "" {
+		args = append(args, "-id", opts.ID)
+	}
+	for _, rev := range opts.Revisions {
+		args = append(args, "-r", strconv.Itoa(rev))
+	}
+	if opts.Chunks {
+		args = append(args, "-chunks")
+	}
+	if opts.Persist {
+		args = append(args, "-persist")
+	}
+	return append(args, threadsArgs(opts.Threads)...)
+}
+
+// threadsArgs returns the -threads option for n threads, or nothing when n <= 1
+func threadsArgs(n int) []string {
+	if n > 1 {
+		return []string{"-threads", strconv.Itoa(n)}
+	}
+	return nil
+}
+
+// backupArgs builds the duplicacy backup arguments for one destination of a
+// backup, limiting the upload to limitRate KB/s when it is positive and ending
+// with the backup's configured backup_options
+func backupArgs(storage string, backup config.BackupConfig, limitRate int) []string {
+	args := []string{"backup", "-storage", storage}
+	if backup.Repository != "" {
+		args = append(args, "-repository", backup.Repository)
+	}
+	args = append(args, threadsArgs(backup.Threads)...)
+	if backup.VSS {
+		args = append(args, "-vss")
+	}
+	if limitRate > 0 {
+		args = append(args, "-limit-rate", strconv.Itoa(limitRate))
+	}
+	return append(args, backup.BackupOptions...)
+}
+
+// hostKeyTypes are the host key types in the order duplicacy's SSH client
+// asks for them; the server answers with the first it has, so that is the key
+// duplicacy compares against
+var hostKeyTypes = []string{"ecdsa-sha2-nistp256", "ecdsa-sha2-nistp384", "ecdsa-sha2-nistp521", "ssh-rsa", "ssh-ed25519"}
+
+// knownHostCommand returns a shell command that adds the key of an SFTP storage
+// host (host or host:port) to the known_hosts file in dir's duplicacy
+// preferences directory (.duplicacy, or the directory a .duplicacy file points
+// to), unless an entry for the host is already there. duplicacy does not read
+// ~/.ssh/known_hosts: it trusts the first key it sees and records it there as
+// "<ip>:<port> <type> <key>", so the entry is written in that form and a
+// recorded key is never replaced. An empty dir means the current directory.
+func knownHostCommand(hostPort, dir string) string {
+	host, port := hostPort, "22"
+	if i := strings.LastIndex(hostPort, ":"); i >= 0 {
+		host, port = hostPort[:i], hostPort[i+1:]
+	}
+
+	var cd string
+	if dir != "" {
+		cd = "cd " + executor.ShellQuote(dir) + " && "
+	}
+	return cd + `pref=.duplicacy && { [ ! -f .duplicacy ] || pref=$(cat .duplicacy); } && ` +
+		`mkdir -p "$pref" && touch "$pref/known_hosts" && ` +
+		fmt.Sprintf(`addr=$(getent hosts %s | awk '{print $1; exit}') && [ -n "$addr" ] && `, host) +
+		fmt.Sprintf(`case $addr in *:*) addr="[$addr]";; esac && addr="$addr:%s" && `, port) +
+		`{ grep -q "^$addr " "$pref/known_hosts" || { ` +
+		fmt.Sprintf(`keys=$(ssh-keyscan -p %s %s 2>/dev/null) && `, port, host) +
+		fmt.Sprintf(`for t in %s; do key=$(printf '%%s\n' "$keys" | awk -v t="$t" '$2 == t {print $2 " " $3; exit}'); [ -z "$key" ] || break; done && `, strings.Join(hostKeyTypes, " ")) +
+		`[ -n "$key" ] && echo "$addr $key" >> "$pref/known_hosts"; }; }`
+}
+
+// acceptExitCode returns nil if err is a duplicacy exit code listed in okCodes,
+// which the caller then treats as success; any other error is returned as is.
+// A storage locked by another operation is never accepted, whatever its code.
+func acceptExitCode(err error, okCodes []int) error {
+	var exitErr *executor.ExitError
+	if !errors.As(err, &exitErr) || errors.Is(err, executor.ErrStorageLocked) {
+		return err
+	}
+	for _, code := range okCodes {
+		if exitErr.Code == code {
+			return nil
+		}
+	}
+	return err
+}
+
+// exitNothingToBackup is the exit code of a duplicacy backup that found no
+// changed files, so created no revision
+const exitNothingToBackup = 100
+
+// nothingToBackup reports whether a backup's err is exit code 100 (and not a
+// locked storage that happened to exit 100)
+func nothingToBackup(err error) bool {
+	var exitErr *executor.ExitError
+	return errors.As(err, &exitErr) && exitErr.Code == exitNothingToBackup && !errors.Is(err, executor.ErrStorageLocked)
+}
+
+// backupRevisionPattern matches the line duplicacy prints when a backup
+// finishes, e.g. "Backup for /mnt/appdata at revision 12 completed"
+var backupRevisionPattern = regexp.MustCompile(`Backup for .*\brevision (\d+)\b.*completed|Backup for .*completed.*\brevision (\d+)\b`)
+
+// backupRevision returns the revision a backup created, from the completion
+// line in its output. ok is false when there is none, e.g. when there was
+// nothing to back up.
+func backupRevision(output string) (revision int, ok bool) {
+	for _, line := range strings.Split(output, "\n") {
+		m := backupRevisionPattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		digits := m[1]
+		if digits == "" {
+			digits = m[2]
+		}
+		if rev, err := strconv.Atoi(digits); err == nil {
+			revision, ok = rev, true
+		}
+	}
+	return revision, ok
+}
+
+// snapshotRevisionPattern matches a revision line of duplicacy list output, e.g.
+// "Snapshot appdata revision 12 created at 2025-01-15 06:00 -hash", once any
+// -log prefix is stripped
+var snapshotRevisionPattern = regexp.MustCompile(`^Snapshot (\S+) revision (\d+) created at (\d{4}-\d{2}-\d{2} \d{2}:\d{2})`)
+
+// snapshotTimeLayout is the creation time format in duplicacy list output
+const snapshotTimeLayout = "2006-01-02 15:04"
+
+// lastRevisions returns the n most recent revisions of a snapshot ID listed
+// in duplicacy list output, in ascending order. Revisions are passed to check
+// individually rather than as a range, since pruning leaves gaps.
+func lastRevisions(listOutput, id string, n int) []int {
+	var revisions []int
+	for _, line := range strings.Split(listOutput, "\n") {
+		m := snapshotRevisionPattern.FindStringSubmatch(strings.TrimSpace(stats.StripLogPrefix(line)))
+		if m == nil || m[1] != id {
+			continue
+		}
+		rev, err := strconv.Atoi(m[2])
+		if err != nil {
+			continue
+		}
+		revisions = append(revisions, rev)
+	}
+
+	sort.Ints(revisions)
+	if len(revisions) > n {
+		revisions = revisions[len(revisions)-n:]
+	}
+	return revisions
+}
+
+// revisionTimes returns the creation time of every revision in duplicacy list
+// output, grouped by snapshot ID. Times are read as local time, as printed.
+func revisionTimes(listOutput string) map[string][]time.Time {
+	times := make(map[string][]time.Time)
+	for _, line := range strings.Split(listOutput, "\n") {
+		m := snapshotRevisionPattern.FindStringSubmatch(strings.TrimSpace(stats.StripLogPrefix(line)))
+		if m == nil {
+			continue
+		}
+		created, err := time.ParseInLocation(snapshotTimeLayout, m[3], time.Local)
+		if err != nil {
+			continue
+		}
+		times[m[1]] = append(times[m[1]], created)
+	}
+	return times
+}
@@ -0,0 +1,334 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/lioreshai/duplicaci/internal/config"
+	"github.com/lioreshai/duplicaci/internal/executor"
+)
+
+func TestCheckArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     checkOptions
+		expected []string
+	}{
+		{
+			name:     "default",
+			opts:     checkOptions{},
+			expected: []string{"check", "-tabular", "-storage", "NAS"},
+		},
+		{
+			name:     "verify chunks",
+			opts:     checkOptions{Chunks: true},
+			expected: []string{"check", "-tabular", "-storage", "NAS", "-chunks"},
+		},
+		{
+			name:     "single snapshot id",
+			opts:     checkOptions{ID: "appdata"},
+			expected: []string{"check", "-tabular", "-storage", "NAS", "-id", "appdata"},
+		},
+		{
+			name:     "persist",
+			opts:     checkOptions{Persist: true},
+			expected: []string{"check", "-tabular", "-storage", "NAS", "-persist"},
+		},
+		{
+			name:     "id with chunks",
+			opts:     checkOptions{ID: "appdata", Chunks: true},
+			expected: []string{"check", "-tabular", "-storage", "NAS", "-id", "appdata", "-chunks"},
+		},
+		{
+			name:     "single thread omitted",
+			opts:     checkOptions{Threads: 1},
+			expected: []string{"check", "-tabular", "-storage", "NAS"},
+		},
+		{
+			name:     "threads",
+			opts:     checkOptions{Chunks: true, Threads: 8},
+			expected: []string{"check", "-tabular", "-storage", "NAS", "-chunks", "-threads", "8"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checkArgs("NAS", tt.opts)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("checkArgs() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBackupArgs(t *testing.T) {
+	tests := []struct {
+		name      string
+		backup    config.BackupConfig
+		limitRate int
+		expected  []string
+	}{
+		{
+			name:     "default",
+			backup:   config.BackupConfig{Name: "appdata"},
+			expected: []string{"backup", "-storage", "NAS"},
+		},
+		{
+			name:     "threads",
+			backup:   config.BackupConfig{Name: "appdata", Threads: 4},
+			expected: []string{"backup", "-storage", "NAS", "-threads", "4"},
+		},
+		{
+			name:     "shared repository",
+			backup:   config.BackupConfig{Name: "photos", Repository: "/mnt/user", Threads: 2},
+			expected: []string{"backup", "-storage", "NAS", "-repository", "/mnt/user", "-threads", "2"},
+		},
+		{
+			name:     "vss",
+			backup:   config.BackupConfig{Name: "documents", VSS: true},
+			expected: []string{"backup", "-storage", "NAS", "-vss"},
+		},
+		{
+			name:      "vss with threads and limit rate",
+			backup:    config.BackupConfig{Name: "documents", Threads: 2, VSS: true},
+			limitRate: 512,
+			expected:  []string{"backup", "-storage", "NAS", "-threads", "2", "-vss", "-limit-rate", "512"},
+		},
+		{
+			name:      "limit rate",
+			backup:    config.BackupConfig{Name: "appdata", Threads: 2},
+			limitRate: 512,
+			expected:  []string{"backup", "-storage", "NAS", "-threads", "2", "-limit-rate", "512"},
+		},
+		{
+			name:      "backup options last",
+			backup:    config.BackupConfig{Name: "appdata", Threads: 2, BackupOptions: []string{"-hash", "-max-in-memory-entries=1024"}},
+			limitRate: 512,
+			expected:  []string{"backup", "-storage", "NAS", "-threads", "2", "-limit-rate", "512", "-hash", "-max-in-memory-entries=1024"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := backupArgs("NAS", tt.backup, tt.limitRate)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("backupArgs() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLastRevisions(t *testing.T) {
+	listOutput := `Storage set to /mnt/backups
+Snapshot appdata revision 1 created at 2025-01-01 06:00 -hash
+Snapshot appdata revision 2 created at 2025-01-02 06:00
+Snapshot other revision 9 created at 2025-01-02 06:00
+Snapshot appdata revision 5 created at 2025-01-05 06:00
+Snapshot appdata revision 7 created at 2025-01-07 06:00
+`
+
+	tests := []struct {
+		name     string
+		id       string
+		n        int
+		expected []int
+	}{
+		{"last two skips pruned gaps", "appdata", 2, []int{5, 7}},
+		{"more than available", "appdata", 10, []int{1, 2, 5, 7}},
+		{"other id", "other", 3, []int{9}},
+		{"unknown id", "missing", 3, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := lastRevisions(listOutput, tt.id, tt.n)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("lastRevisions() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBackupRevision(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		revision int
+		ok       bool
+	}{
+		{
+			name: "tabular output",
+			output: `Storage set to sftp://backup@nas/duplicacy
+Last backup at revision 11 found
+Indexing /mnt/appdata
+Parsing filter file /mnt/appdata/.duplicacy/filters
+Loaded 3 include/exclude pattern(s)
+Uploaded chunk 1 size 4194304, 4.00MB/s 00:00:03 50.0%
+Backup for /mnt/appdata at revision 12 completed
+`,
+			revision: 12,
+			ok:       true,
+		},
+		{
+			name:     "log format",
+			output:   "2025-01-15 06:00:42.123 INFO BACKUP_END Backup for /mnt/appdata at revision 3 completed\n",
+			revision: 3,
+			ok:       true,
+		},
+		{
+			name:     "completed before revision",
+			output:   "Backup for /mnt/appdata completed as revision 7\n",
+			revision: 7,
+			ok:       true,
+		},
+		{
+			name:   "nothing to back up",
+			output: "Last backup at revision 11 found\nNo files under the repository to be backed up\n",
+		},
+		{
+			name:   "failed backup",
+			output: "Last backup at revision 11 found\nFailed to upload the chunk: connection reset\n",
+		},
+		{
+			name: "empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revision, ok := backupRevision(tt.output)
+			if revision != tt.revision || ok != tt.ok {
+				t.Errorf("backupRevision() = %d, %v, want %d, %v", revision, ok, tt.revision, tt.ok)
+			}
+		})
+	}
+}
+
+func TestCheckArgs_Revisions(t *testing.T) {
+	got := checkArgs("NAS", checkOptions{ID: "appdata", Revisions: []int{5, 7}})
+	expected := []string{"check", "-tabular", "-storage", "NAS", "-id", "appdata", "-r", "5", "-r", "7"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("checkArgs() = %v, want %v", got, expected)
+	}
+}
+
+func TestAcceptExitCode(t *testing.T) {
+	other := errors.New("connection refused")
+	tests := []struct {
+		name     string
+		err      error
+		okCodes  []int
+		expected error
+	}{
+		{"success", nil, []int{100}, nil},
+		{"code in set", &executor.ExitError{Code: 100}, []int{100}, nil},
+		{"wrapped code in set", fmt.Errorf("backup: %w", &executor.ExitError{Code: 3}), []int{100, 3}, nil},
+		{"code outside set", &executor.ExitError{Code: 1}, []int{100}, &executor.ExitError{Code: 1}},
+		{"empty set", &executor.ExitError{Code: 100}, []int{}, &executor.ExitError{Code: 100}},
+		{"not an exit error", other, []int{100}, other},
+		{"locked storage with code in set", &lockedExitError{&executor.ExitError{Code: 100}}, []int{100}, &lockedExitError{&executor.ExitError{Code: 100}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := acceptExitCode(tt.err, tt.okCodes)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("acceptExitCode() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+// knownHostStubs stand in for getent and ssh-keyscan: the host resolves to
+// 192.0.2.10 and offers an ed25519 and an ecdsa key, in that order
+const knownHostStubs = `getent() { echo "192.0.2.10      $2"; }; ` +
+	`ssh-keyscan() { echo "# banner"; echo "$3 ssh-ed25519 AAAAED"; echo "$3 ecdsa-sha2-nistp256 AAAAEC"; }; `
+
+func TestKnownHostCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		host     string
+		existing string
+		expected string
+	}{
+		{
+			name:     "default port",
+			host:     "sftp.example.com",
+			expected: "192.0.2.10:22 ecdsa-sha2-nistp256 AAAAEC\n",
+		},
+		{
+			name:     "custom port",
+			host:     "sftp.example.com:2222",
+			expected: "192.0.2.10:2222 ecdsa-sha2-nistp256 AAAAEC\n",
+		},
+		{
+			name:     "recorded key kept",
+			host:     "sftp.example.com:2222",
+			existing: "192.0.2.10:2222 ssh-rsa AAAARSA\n",
+			expected: "192.0.2.10:2222 ssh-rsa AAAARSA\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "cache's dir")
+			pref := filepath.Join(dir, ".duplicacy")
+			if err := os.MkdirAll(pref, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if tt.existing != "" {
+				if err := os.WriteFile(filepath.Join(pref, "known_hosts"), []byte(tt.existing), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// Running twice must not add a second entry
+			for i := 0; i < 2; i++ {
+				if out, err := exec.Command("bash", "-c", knownHostStubs+knownHostCommand(tt.host, dir)).CombinedOutput(); err != nil {
+					t.Fatalf("command failed: %v\n%s", err, out)
+				}
+			}
+
+			data, err := os.ReadFile(filepath.Join(pref, "known_hosts"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.expected {
+				t.Errorf("expected known_hosts %q, got %q", tt.expected, data)
+			}
+		})
+	}
+}
+
+func TestKnownHostCommand_PreferenceFile(t *testing.T) {
+	// A .duplicacy file points to the preferences directory elsewhere
+	dir := t.TempDir()
+	pref := filepath.Join(t.TempDir(), "prefs")
+	if err := os.WriteFile(filepath.Join(dir, ".duplicacy"), []byte(pref), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if out, err := exec.Command("bash", "-c", knownHostStubs+knownHostCommand("sftp.example.com", dir)).CombinedOutput(); err != nil {
+		t.Fatalf("command failed: %v\n%s", err, out)
+	}
+
+	data, err := os.ReadFile(filepath.Join(pref, "known_hosts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "192.0.2.10:22 ecdsa-sha2-nistp256 AAAAEC\n" {
+		t.Errorf("expected the entry in the referenced preferences dir, got %q", data)
+	}
+}
+
+func TestKnownHostCommand_Unresolved(t *testing.T) {
+	cmd := `getent() { return 2; }; ` + knownHostCommand("sftp.example.com", t.TempDir())
+	if err := exec.Command("bash", "-c", cmd).Run(); err == nil {
+		t.Error("expected an unresolvable host to fail")
+	}
+}
@@ -21,9 +21,11 @@ var (
 	runPrune        bool
 	pruneOptions    string
 	runCheck        bool
+	okExitCodes     []int
 	dockerContainer string
 	sshHost         string
 	sshPassword     string
+	sshPasswordMode string
 	storagePassword string
 	gcdToken        string
 
@@ -32,7 +34,12 @@ var (
 	forgejoURL   string
 	forgejoRepo  string
 	forgejoToken string
-	assignee     string
+	assignees    []string
+
+	titleTemplate string
+
+	// configGlobalOptions is connection.global_options from the config, if loaded
+	configGlobalOptions []string
 )
 
 var backupCmd = &cobra.Command{
@@ -40,16 +47,21 @@ var backupCmd = &cobra.Command{
 	Short: "Run a Duplicacy backup",
 	Long: `Run a Duplicacy backup for the specified repository to one or more storage backends.
 
-Optionally run prune and/or check operations after the backup completes.`,
+Optionally run prune and/or check operations after the backup completes.
+
+With --config and no --repository, back up every backup in the config to its
+destinations, as "duplicaci run" does without the prune and check phases
+(which --prune and --check add back).`,
 	RunE: runBackup,
 }
 
 func init() {
-	backupCmd.Flags().StringVarP(&repository, "repository", "r", "", "Repository ID to backup")
+	backupCmd.Flags().StringVarP(&repository, "repository", "r", "", "Repository ID to backup (omit with --config to back up every configured backup)")
 	backupCmd.Flags().StringVarP(&repoPath, "repo-path", "p", "", "Path to repository (cd here before running duplicacy)")
 	backupCmd.Flags().StringVar(&cacheDir, "cache-dir", "", "Duplicacy Web GUI cache directory (e.g., /cache/localhost/0)")
 	backupCmd.Flags().StringSliceVarP(&storages, "storage", "s", []string{}, "Storage backend(s) to backup to")
 	backupCmd.Flags().StringVar(&backupOptions, "backup-options", "", "Additional backup options (e.g., '-threads 4')")
+	backupCmd.Flags().IntSliceVar(&okExitCodes, "ok-exit-codes", config.DefaultOKExitCodes, "duplicacy backup exit codes treated as success")
 	backupCmd.Flags().BoolVar(&runPrune, "prune", false, "Run prune after backup")
 	backupCmd.Flags().StringVar(&pruneOptions, "prune-options", "-keep 0:180 -keep 7:14 -keep 1:1 -a", "Prune retention options")
 	backupCmd.Flags().BoolVar(&runCheck, "check", false, "Run check after backup")
@@ -57,6 +69,7 @@ func init() {
 	backupCmd.Flags().StringVar(&dockerContainer, "docker-container", "", "Run inside Docker container")
 	backupCmd.Flags().StringVar(&sshHost, "ssh-host", "", "SSH to host before running (user@host)")
 	backupCmd.Flags().StringVar(&sshPassword, "ssh-password", "", "SSH password (or SSH_PASSWORD env)")
+	backupCmd.Flags().StringVar(&sshPasswordMode, "ssh-password-mode", "", `How sshpass gets the SSH password: "arg" (-p, default) or "file" (a pipe, kept out of the process list)`)
 	backupCmd.Flags().StringVar(&storagePassword, "storage-password", "", "Duplicacy storage encryption password (or DUPLICACY_PASSWORD env)")
 	backupCmd.Flags().StringVar(&gcdToken, "gcd-token", "", "Google Drive token file path (for gcd:// storages)")
 
@@ -64,16 +77,21 @@ func init() {
 	backupCmd.Flags().StringVar(&forgejoURL, "forgejo-url", "", "Forgejo server URL")
 	backupCmd.Flags().StringVar(&forgejoRepo, "forgejo-repo", "", "Repository for issues (owner/repo)")
 	backupCmd.Flags().StringVar(&forgejoToken, "forgejo-token", "", "Forgejo API token (or FORGEJO_TOKEN env)")
-	backupCmd.Flags().StringVar(&assignee, "assignee", "", "Assign issues to this user")
+	backupCmd.Flags().StringSliceVar(&assignees, "assignee", nil, "Assign issues to this user (repeat or comma-separate for several)")
+	backupCmd.Flags().StringVar(&titleTemplate, "title-template", "", "Go template for the issue title (.FailedBackups, .Host, .Timestamp)")
 }
 
 func runBackup(cmd *cobra.Command, args []string) error {
+	if configSpecified() && repository == "" {
+		return backupFromConfig(cmd)
+	}
+
 	// Load config file if specified
 	var cfg *config.Config
 	var err error
 
-	if configFile != "" {
-		cfg, err = config.Load(configFile)
+	if configSpecified() {
+		cfg, err = loadConfig()
 		if err != nil {
 			return fmt.Errorf("failed to load config: %w", err)
 		}
@@ -106,11 +124,15 @@ func runBackup(cmd *cobra.Command, args []string) error {
 
 	// Create executor
 	exec := executor.New(executor.Options{
+		Context:         cmd.Context(),
 		DryRun:          dryRun,
 		Verbose:         verbose,
+		SkipRepoCheck:   noRepoCheck,
+		GlobalOptions:   duplicacyGlobalOptions(configGlobalOptions...),
 		DockerContainer: dockerContainer,
 		SSHHost:         sshHost,
 		SSHPassword:     sshPassword,
+		SSHPasswordMode: sshPasswordMode,
 		RepoPath:        repoPath,
 		CacheDir:        cacheDir,
 		StoragePassword: storagePassword,
@@ -128,11 +150,11 @@ func runBackup(cmd *cobra.Command, args []string) error {
 			backupArgs = append(backupArgs, strings.Fields(backupOptions)...)
 		}
 
-		err := exec.RunDuplicacyWithStorage(storage, backupArgs...)
+		err := acceptExitCode(exec.RunDuplicacyWithStorage(storage, backupArgs...), okExitCodes)
 		if err != nil {
 			errMsg := fmt.Sprintf("backup to %s failed: %v", storage, err)
 			allErrors = append(allErrors, errMsg)
-			fmt.Fprintf(os.Stderr, "ERROR: %s\n", errMsg)
+			printError("", "%s", errMsg)
 			continue
 		}
 		fmt.Printf("    Backup to '%s' completed successfully\n", storage)
@@ -148,7 +170,7 @@ func runBackup(cmd *cobra.Command, args []string) error {
 			if err != nil {
 				errMsg := fmt.Sprintf("check on %s failed: %v", storage, err)
 				allErrors = append(allErrors, errMsg)
-				fmt.Fprintf(os.Stderr, "ERROR: %s\n", errMsg)
+				printError("", "%s", errMsg)
 			}
 		}
 	}
@@ -165,7 +187,7 @@ func runBackup(cmd *cobra.Command, args []string) error {
 			if err != nil {
 				errMsg := fmt.Sprintf("prune on %s failed: %v", storage, err)
 				allErrors = append(allErrors, errMsg)
-				fmt.Fprintf(os.Stderr, "ERROR: %s\n", errMsg)
+				printError("", "%s", errMsg)
 			}
 		}
 	}
@@ -173,7 +195,7 @@ func runBackup(cmd *cobra.Command, args []string) error {
 	// Handle notifications
 	if len(allErrors) > 0 && createIssues {
 		if err := sendFailureNotification(allErrors); err != nil {
-			fmt.Fprintf(os.Stderr, "WARNING: Failed to create issue: %v\n", err)
+			printWarning("", "Failed to create issue: %v", err)
 		}
 		return fmt.Errorf("backup completed with %d error(s)", len(allErrors))
 	}
@@ -186,6 +208,27 @@ func runBackup(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// backupFromConfig backs up every configured backup through the run command's
+// backup phase, adding its check and prune phases for --check and --prune
+func backupFromConfig(cmd *cobra.Command) error {
+	if len(storages) > 0 {
+		return fmt.Errorf("--storage requires --repository; without it each backup uses its configured destinations")
+	}
+
+	phases := []string{"backup"}
+	if runPrune {
+		phases = append(phases, "prune")
+	}
+	if runCheck {
+		phases = append(phases, "check")
+	}
+
+	prevPhases := onlyPhases
+	onlyPhases = phases
+	defer func() { onlyPhases = prevPhases }()
+	return runAllBackups(cmd, nil)
+}
+
 func applyConfig(cfg *config.Config) {
 	if sshHost == "" && cfg.SSH.Host != "" {
 		sshHost = cfg.SSH.Host
@@ -202,12 +245,25 @@ func applyConfig(cfg *config.Config) {
 	if forgejoRepo == "" && cfg.Notifications.Forgejo.Repo != "" {
 		forgejoRepo = cfg.Notifications.Forgejo.Repo
 	}
-	if forgejoToken == "" && cfg.Notifications.Forgejo.TokenEnv != "" {
-		forgejoToken = os.Getenv(cfg.Notifications.Forgejo.TokenEnv)
+	if sshPassword == "" {
+		sshPassword = cfg.Connection.SSHPassword()
+	}
+	if sshPasswordMode == "" {
+		sshPasswordMode = cfg.Connection.SSHPasswordMode
+	}
+	if storagePassword == "" {
+		storagePassword = cfg.Connection.StoragePassword()
+	}
+	if forgejoToken == "" {
+		forgejoToken = cfg.Notifications.Forgejo.GetToken()
+	}
+	if len(assignees) == 0 {
+		assignees = cfg.Notifications.Forgejo.AllAssignees()
 	}
-	if assignee == "" && cfg.Notifications.Forgejo.Assignee != "" {
-		assignee = cfg.Notifications.Forgejo.Assignee
+	if titleTemplate == "" && cfg.Notifications.TitleTemplate != "" {
+		titleTemplate = cfg.Notifications.TitleTemplate
 	}
+	configGlobalOptions = cfg.Connection.GlobalOptions
 }
 
 func sendFailureNotification(errors []string) error {
@@ -215,12 +271,12 @@ func sendFailureNotification(errors []string) error {
 		return fmt.Errorf("forgejo notification requires --forgejo-url, --forgejo-repo, and --forgejo-token")
 	}
 
-	n := notifier.NewForgejo(forgejoURL, forgejoRepo, forgejoToken)
-	if assignee != "" {
-		n.SetAssignee(assignee)
+	var n notifier.Notifier = &notifier.NullNotifier{}
+	if !noNotify {
+		n = notifier.NewForgejo(forgejoURL, forgejoRepo, forgejoToken, notifier.WithAssignees(assignees...))
 	}
 
-	title := fmt.Sprintf("[duplicaci] %s: backup failed", repository)
+	title := failureTitle(titleTemplate, []string{repository}, sshHost)
 	body := fmt.Sprintf("## Backup Failure\n\n**Repository:** %s\n**Storages:** %s\n\n### Errors\n\n",
 		repository, strings.Join(storages, ", "))
 
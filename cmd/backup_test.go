@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lioreshai/duplicaci/internal/summary"
+)
+
+func TestRunBackup_FromConfig(t *testing.T) {
+	defer func() {
+		configFile = ""
+		dryRun = false
+		summaryFile = ""
+		runCheck = false
+	}()
+	t.Setenv("DUPLICACY_PASSWORD", "secret")
+	dir := t.TempDir()
+	configFile = filepath.Join(dir, "duplicaci.yaml")
+	content := "backups:\n" +
+		"  - name: appdata\n    path: /mnt/appdata\n    destinations: [NAS, B2]\n" +
+		"  - name: photos\n    path: /mnt/photos\n    destinations: [NAS]\n"
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	summaryFile = filepath.Join(dir, "summary.json")
+	dryRun = true
+
+	tests := []struct {
+		name   string
+		check  bool
+		phases string
+	}{
+		{"backup only", false, "backup"},
+		{"with --check", true, "backup,check"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runCheck = tt.check
+
+			var err error
+			out := captureStdout(t, func() { err = runBackup(backupCmd, nil) })
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// Dry run prints each duplicacy command instead of running it
+			for _, cmd := range []string{"cd /mnt/appdata && duplicacy backup", "cd /mnt/photos && duplicacy backup"} {
+				if !strings.Contains(out, cmd) {
+					t.Errorf("expected dry-run command with %q in:\n%s", cmd, out)
+				}
+			}
+
+			got, err := summary.ReadFile(summaryFile)
+			if err != nil {
+				t.Fatalf("failed to read summary: %v", err)
+			}
+			var phases []string
+			for _, p := range got.Phases {
+				phases = append(phases, p.Name)
+			}
+			if strings.Join(phases, ",") != tt.phases {
+				t.Errorf("expected phases %s, got %v", tt.phases, phases)
+			}
+			if n := len(got.Phases[0].Operations); n != 3 {
+				t.Errorf("expected 3 backup operations, got %d", n)
+			}
+		})
+	}
+}
+
+func TestRunBackup_FromConfigRejectsStorage(t *testing.T) {
+	defer func() {
+		configFile = ""
+		storages = nil
+	}()
+	configFile = "duplicaci.yaml"
+	storages = []string{"NAS"}
+
+	err := runBackup(backupCmd, nil)
+	if err == nil || !strings.Contains(err.Error(), "--storage requires --repository") {
+		t.Errorf("expected --storage error, got %v", err)
+	}
+}
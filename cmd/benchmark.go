@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/lioreshai/duplicaci/internal/executor"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Benchmark flags
+	benchmarkOpts benchmarkOptions
+	benchmarkJSON bool
+)
+
+var benchmarkCmd = &cobra.Command{
+	Use:   "benchmark",
+	Short: "Measure disk and storage throughput",
+	Long: `Run duplicacy benchmark against one or more storages to measure local disk
+speed, chunking speed, and upload/download throughput. Useful for diagnosing
+slow backups.
+
+The benchmark uploads and then deletes temporary chunks on the storage.
+
+Example:
+  duplicaci benchmark --storage NAS --docker-container Duplicacy --ssh-host root@host
+  duplicaci benchmark --config duplicaci.yaml --storage NAS --file-size 64 --json`,
+	RunE: runBenchmarkCmd,
+}
+
+func init() {
+	benchmarkCmd.Flags().StringVarP(&repoPath, "repo-path", "p", "", "Path to repository (cd here before running duplicacy)")
+	benchmarkCmd.Flags().StringVar(&cacheDir, "cache-dir", "", "Duplicacy Web GUI cache directory (e.g., /cache/localhost/0)")
+	benchmarkCmd.Flags().StringSliceVarP(&storages, "storage", "s", []string{}, "Storage backend(s) to benchmark")
+	benchmarkCmd.Flags().StringVar(&dockerContainer, "docker-container", "", "Run inside Docker container")
+	benchmarkCmd.Flags().StringVar(&sshHost, "ssh-host", "", "SSH to host before running (user@host)")
+	benchmarkCmd.Flags().StringVar(&sshPassword, "ssh-password", "", "SSH password (or SSH_PASSWORD env)")
+	benchmarkCmd.Flags().StringVar(&sshPasswordMode, "ssh-password-mode", "", `How sshpass gets the SSH password: "arg" (-p, default) or "file" (a pipe, kept out of the process list)`)
+	benchmarkCmd.Flags().StringVar(&storagePassword, "storage-password", "", "Duplicacy storage encryption password (or DUPLICACY_PASSWORD env)")
+	benchmarkCmd.Flags().StringVar(&gcdToken, "gcd-token", "", "Google Drive token file path (for gcd:// storages)")
+	benchmarkCmd.Flags().IntVar(&benchmarkOpts.FileSize, "file-size", 0, "Size in MB of the local file to write and split (duplicacy default: 256)")
+	benchmarkCmd.Flags().IntVar(&benchmarkOpts.ChunkCount, "chunk-count", 0, "Number of chunks to upload and download (duplicacy default: 64)")
+	benchmarkCmd.Flags().IntVar(&benchmarkOpts.ChunkSize, "chunk-size", 0, "Size in MB of each chunk (duplicacy default: 4)")
+	benchmarkCmd.Flags().IntVar(&benchmarkOpts.UploadThreads, "upload-threads", 0, "Number of upload threads (duplicacy default: 1)")
+	benchmarkCmd.Flags().IntVar(&benchmarkOpts.DownloadThreads, "download-threads", 0, "Number of download threads (duplicacy default: 1)")
+	benchmarkCmd.Flags().BoolVar(&benchmarkJSON, "json", false, "Print the parsed throughput of each storage as JSON on stdout (duplicacy output goes to stderr)")
+
+	rootCmd.AddCommand(benchmarkCmd)
+}
+
+// benchmarkOptions controls the optional duplicacy benchmark flags; zero
+// values leave duplicacy's defaults
+type benchmarkOptions struct {
+	FileSize        int // -file-size, in MB
+	ChunkCount      int // -chunk-count
+	ChunkSize       int // -chunk-size, in MB
+	UploadThreads   int // -upload-threads
+	DownloadThreads int // -download-threads
+}
+
+// benchmarkArgs builds the duplicacy benchmark arguments for a storage
+func benchmarkArgs(storage string, opts benchmarkOptions) []string {
+	args := []string{"benchmark", "-storage", storage}
+	for _, opt := range []struct {
+		flag  string
+		value int
+	}{
+		{"-file-size", opts.FileSize},
+		{"-chunk-count", opts.ChunkCount},
+		{"-chunk-size", opts.ChunkSize},
+		{"-upload-threads", opts.UploadThreads},
+		{"-download-threads", opts.DownloadThreads},
+	} {
+		if opt.value > 0 {
+			args = append(args, opt.flag, strconv.Itoa(opt.value))
+		}
+	}
+	return args
+}
+
+// benchmarkResult is the throughput parsed from duplicacy benchmark output, in
+// MB/s; a measurement missing from the output is 0
+type benchmarkResult struct {
+	Storage   string  `json:"storage"`
+	DiskWrite float64 `json:"disk_write_mbps"`
+	DiskRead  float64 `json:"disk_read_mbps"`
+	Upload    float64 `json:"upload_mbps"`
+	Download  float64 `json:"download_mbps"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// benchmarkLinePattern matches the throughput lines of duplicacy benchmark, e.g.
+// "Uploaded 256.00M bytes in 62.88s: 4.07M/s"
+var benchmarkLinePattern = regexp.MustCompile(`^(Wrote|Read|Uploaded|Downloaded) \S+ bytes in [\d.]+s: ([\d.]+)([KMG])/s`)
+
+// parseBenchmarkOutput extracts the disk and storage throughput from duplicacy
+// benchmark output. ok is false when no throughput line was found.
+func parseBenchmarkOutput(output string) (result benchmarkResult, ok bool) {
+	for _, line := range strings.Split(output, "\n") {
+		m := benchmarkLinePattern.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		rate, err := strconv.ParseFloat(m[2], 64)
+		if err != nil {
+			continue
+		}
+		switch m[3] {
+		case "K":
+			rate /= 1024
+		case "G":
+			rate *= 1024
+		}
+
+		switch m[1] {
+		case "Wrote":
+			result.DiskWrite = rate
+		case "Read":
+			result.DiskRead = rate
+		case "Uploaded":
+			result.Upload = rate
+		case "Downloaded":
+			result.Download = rate
+		}
+		ok = true
+	}
+	return result, ok
+}
+
+func runBenchmarkCmd(cmd *cobra.Command, args []string) error {
+	if configSpecified() {
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		applyConfig(cfg)
+	}
+
+	if len(storages) == 0 {
+		return fmt.Errorf("at least one --storage is required")
+	}
+
+	if sshPassword == "" {
+		sshPassword = os.Getenv("SSH_PASSWORD")
+	}
+
+	if storagePassword == "" {
+		storagePassword = os.Getenv("DUPLICACY_PASSWORD")
+	}
+
+	exec := executor.New(executor.Options{
+		Context:         cmd.Context(),
+		DryRun:          dryRun,
+		Verbose:         verbose,
+		SkipRepoCheck:   noRepoCheck,
+		GlobalOptions:   duplicacyGlobalOptions(configGlobalOptions...),
+		DockerContainer: dockerContainer,
+		SSHHost:         sshHost,
+		SSHPassword:     sshPassword,
+		SSHPasswordMode: sshPasswordMode,
+		RepoPath:        repoPath,
+		CacheDir:        cacheDir,
+		StoragePassword: storagePassword,
+		GCDToken:        gcdToken,
+	})
+
+	// With --json, stdout carries only the results: everything else printed
+	// (progress, duplicacy output) goes to stderr instead
+	stdout := os.Stdout
+	if benchmarkJSON {
+		os.Stdout = os.Stderr
+		defer func() { os.Stdout = stdout }()
+	}
+
+	var results []benchmarkResult
+	var hasErrors bool
+	for _, storage := range storages {
+		fmt.Printf("==> Benchmarking storage '%s'\n", storage)
+
+		output, err := exec.RunDuplicacyCaptureWithStorage(storage, benchmarkArgs(storage, benchmarkOpts)...)
+		if output != "" {
+			fmt.Print(output)
+		}
+
+		result, ok := parseBenchmarkOutput(output)
+		result.Storage = storage
+		switch {
+		case err != nil:
+			result.Error = err.Error()
+			printError("", "benchmark on %s failed: %v", storage, err)
+			hasErrors = true
+		case !ok && !dryRun:
+			printWarning("    ", "no throughput found in the benchmark output of %s", storage)
+		}
+		results = append(results, result)
+	}
+
+	if benchmarkJSON {
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, string(data))
+	}
+
+	if hasErrors {
+		return fmt.Errorf("benchmark completed with errors")
+	}
+	return nil
+}
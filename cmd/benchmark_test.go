@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestBenchmarkArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     benchmarkOptions
+		expected []string
+	}{
+		{
+			name:     "defaults",
+			opts:     benchmarkOptions{},
+			expected: []string{"benchmark", "-storage", "NAS"},
+		},
+		{
+			name:     "sizes",
+			opts:     benchmarkOptions{FileSize: 64, ChunkCount: 16, ChunkSize: 8},
+			expected: []string{"benchmark", "-storage", "NAS", "-file-size", "64", "-chunk-count", "16", "-chunk-size", "8"},
+		},
+		{
+			name:     "threads",
+			opts:     benchmarkOptions{UploadThreads: 4, DownloadThreads: 2},
+			expected: []string{"benchmark", "-storage", "NAS", "-upload-threads", "4", "-download-threads", "2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := benchmarkArgs("NAS", tt.opts); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+const sampleBenchmarkOutput = `Storage set to sftp://backup@nas/duplicacy
+Generating 244.14M byte random data in memory
+Writing random data to local disk
+Wrote 244.14M bytes in 3.05s: 80.00M/s
+Reading the random data from local disk
+Read 244.14M bytes in 0.18s: 1388.05M/s
+Split 244.14M bytes into 53 chunks without compression/encryption in 1.69s: 144.25M/s
+Split 244.14M bytes into 53 chunks with compression but without encryption in 2.32s: 105.02M/s
+Split 244.14M bytes into 53 chunks with compression and encryption in 2.44s: 99.90M/s
+Generating 64 chunks
+Uploaded 256.00M bytes in 62.88s: 4.07M/s
+Downloaded 256.00M bytes in 63.00s: 4.06M/s
+Deleted 64 temporary files from the storage
+`
+
+func TestParseBenchmarkOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected benchmarkResult
+		ok       bool
+	}{
+		{
+			name:     "full run",
+			output:   sampleBenchmarkOutput,
+			expected: benchmarkResult{DiskWrite: 80, DiskRead: 1388.05, Upload: 4.07, Download: 4.06},
+			ok:       true,
+		},
+		{
+			name:     "K and G rates",
+			output:   "Uploaded 16.00M bytes in 32.00s: 512.00K/s\nDownloaded 16.00M bytes in 0.01s: 1.50G/s\n",
+			expected: benchmarkResult{Upload: 0.5, Download: 1536},
+			ok:       true,
+		},
+		{
+			name:   "no throughput lines",
+			output: "Failed to load the storage: not found\n",
+			ok:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseBenchmarkOutput(tt.output)
+			if ok != tt.ok {
+				t.Errorf("expected ok %v, got %v", tt.ok, ok)
+			}
+			if got != tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRunBenchmarkCmd_JSONDryRun(t *testing.T) {
+	defer func() {
+		dryRun = false
+		benchmarkJSON = false
+		storages = nil
+	}()
+	dryRun = true
+	benchmarkJSON = true
+	storages = []string{"NAS", "Cloud"}
+
+	// Progress goes to stderr; keep it out of the test output
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	origStderr := os.Stderr
+	os.Stderr = devNull
+	defer func() { os.Stderr = origStderr }()
+
+	out := captureStdout(t, func() {
+		if err := runBenchmarkCmd(benchmarkCmd, nil); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	var results []benchmarkResult
+	if err := json.Unmarshal([]byte(out), &results); err != nil {
+		t.Fatalf("expected stdout to be one JSON document, got %v:\n%s", err, out)
+	}
+	if len(results) != 2 || results[0].Storage != "NAS" || results[1].Storage != "Cloud" {
+		t.Errorf("expected results for NAS and Cloud, got %+v", results)
+	}
+}
@@ -3,6 +3,8 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/lioreshai/duplicaci/internal/executor"
 	"github.com/lioreshai/duplicaci/internal/stats"
@@ -10,7 +12,22 @@ import (
 )
 
 var (
-	updateStats bool
+	updateStats  bool
+	noStats      bool // run check but never write Web UI stats (shared by check and run)
+	verifyChunks bool
+	persist      bool
+	checkID      string
+	checkLast    int
+
+	// jsonLogs asks duplicacy for log-style output and parses stats from its JSON
+	// event stream, falling back to the tabular text (shared by check and run)
+	jsonLogs bool
+
+	// threads is passed as -threads to prune and check (shared by both commands)
+	threads int
+
+	// checkOutputDir archives captured check output (shared by check and run)
+	checkOutputDir string
 )
 
 var checkCmd = &cobra.Command{
@@ -28,16 +45,119 @@ func init() {
 	checkCmd.Flags().StringVar(&dockerContainer, "docker-container", "", "Run inside Docker container")
 	checkCmd.Flags().StringVar(&sshHost, "ssh-host", "", "SSH to host before running (user@host)")
 	checkCmd.Flags().StringVar(&sshPassword, "ssh-password", "", "SSH password (or SSH_PASSWORD env)")
+	checkCmd.Flags().StringVar(&sshPasswordMode, "ssh-password-mode", "", `How sshpass gets the SSH password: "arg" (-p, default) or "file" (a pipe, kept out of the process list)`)
 	checkCmd.Flags().StringVar(&storagePassword, "storage-password", "", "Duplicacy storage encryption password (or DUPLICACY_PASSWORD env)")
 	checkCmd.Flags().StringVar(&gcdToken, "gcd-token", "", "Google Drive token file path (for gcd:// storages)")
 	checkCmd.Flags().BoolVar(&updateStats, "update-stats", false, "Update Duplicacy Web UI stats after check")
+	checkCmd.Flags().BoolVar(&noStats, "no-stats", false, "Never write Web UI stats, even with --update-stats")
+	checkCmd.Flags().BoolVar(&verifyChunks, "chunks", false, "Download and verify every chunk (slow: reads the entire storage)")
+	checkCmd.Flags().BoolVar(&persist, "persist", false, "Keep checking past missing/corrupt chunks and report them all")
+	checkCmd.Flags().StringVar(&checkID, "id", "", "Only check this snapshot ID")
+	checkCmd.Flags().IntVar(&checkLast, "last", 0, "Only check the N most recent revisions of --id")
+	checkCmd.Flags().BoolVar(&jsonLogs, "json-logs", false, "Run duplicacy with -log: stats come from JSON log events when present, else from the timestamp-prefixed log-style check output")
+	checkCmd.Flags().IntVar(&threads, "threads", 1, "Number of download threads")
+	checkCmd.Flags().StringVar(&checkOutputDir, "output-dir", "", "Save each storage's raw check output to <dir>/<storage>-<date>.txt")
+}
+
+// recentRevisions lists a snapshot ID's revisions and returns the n most recent.
+// If listing fails or finds nothing, it warns and returns nil so the whole ID is checked.
+func recentRevisions(exec duplicacyCapturer, storage, id string, n int) []int {
+	output, err := exec.RunDuplicacyCaptureWithStorage(storage, "list", "-storage", storage, "-id", id)
+	if err != nil {
+		printWarning("    ", "failed to list revisions (checking all of %s): %v", id, err)
+		return nil
+	}
+	revisions := lastRevisions(output, id, n)
+	if len(revisions) == 0 {
+		printWarning("    ", "no revisions listed for %s (checking all revisions)", id)
+		return nil
+	}
+	return revisions
+}
+
+// parseCheckStats parses captured check output into stats. With --json-logs the
+// JSON event stream is tried first and the tabular text is the fallback. A
+// non-empty id keeps only that snapshot ID's statistics.
+func parseCheckStats(output, id string) (*stats.DayStats, error) {
+	var dayStats *stats.DayStats
+	var err error
+	if jsonLogs {
+		dayStats, err = stats.ParseJSONCheckLog(strings.NewReader(output))
+	}
+	if dayStats == nil {
+		dayStats, err = stats.ParseCheckOutput(output)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if id != "" {
+		if err := dayStats.KeepRepository(id); err != nil {
+			return nil, err
+		}
+	}
+	return dayStats, nil
+}
+
+// duplicacyCapturer runs a duplicacy command and captures its output
+type duplicacyCapturer interface {
+	RunDuplicacyCaptureWithStorage(storageName string, args ...string) (string, error)
+}
+
+// archiveCheckOutput writes a storage's captured check output to
+// <dir>/<storage>-<date>.txt, creating dir if needed, and returns the file path
+func archiveCheckOutput(dir, storage, date, output string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create output dir: %w", err)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s.txt", storage, date))
+	if err := os.WriteFile(path, []byte(output), 0644); err != nil {
+		return "", fmt.Errorf("failed to save check output: %w", err)
+	}
+	return path, nil
+}
+
+// saveCheckOutput archives check output under date when --output-dir is set, warning on failure
+func saveCheckOutput(storage, date, output string) {
+	if checkOutputDir == "" || output == "" {
+		return
+	}
+	path, err := archiveCheckOutput(checkOutputDir, storage, date, output)
+	if err != nil {
+		printWarning("    ", "%v", err)
+		return
+	}
+	fmt.Printf("    Saved check output to %s\n", path)
 }
 
 func runCheckCmd(cmd *cobra.Command, args []string) error {
+	if configSpecified() {
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		applyConfig(cfg)
+
+		// With a config loaded, --id must name a configured backup
+		if checkID != "" {
+			if err := cfg.ValidateBackupName(checkID); err != nil {
+				return fmt.Errorf("invalid --id: %w", err)
+			}
+		}
+	}
+
 	if len(storages) == 0 {
 		return fmt.Errorf("at least one --storage is required")
 	}
 
+	// duplicacy selects revisions per snapshot ID, so "last N" needs one
+	if checkLast < 0 {
+		return fmt.Errorf("--last must be positive")
+	}
+	if checkLast > 0 && checkID == "" {
+		return fmt.Errorf("--last requires --id")
+	}
+
 	if sshPassword == "" {
 		sshPassword = os.Getenv("SSH_PASSWORD")
 	}
@@ -47,11 +167,15 @@ func runCheckCmd(cmd *cobra.Command, args []string) error {
 	}
 
 	exec := executor.New(executor.Options{
+		Context:         cmd.Context(),
 		DryRun:          dryRun,
 		Verbose:         verbose,
+		SkipRepoCheck:   noRepoCheck,
+		GlobalOptions:   duplicacyGlobalOptions(configGlobalOptions...),
 		DockerContainer: dockerContainer,
 		SSHHost:         sshHost,
 		SSHPassword:     sshPassword,
+		SSHPasswordMode: sshPasswordMode,
 		RepoPath:        repoPath,
 		CacheDir:        cacheDir,
 		StoragePassword: storagePassword,
@@ -60,8 +184,10 @@ func runCheckCmd(cmd *cobra.Command, args []string) error {
 
 	// Create stats writer if updating stats
 	var statsWriter *stats.Writer
-	if updateStats && dockerContainer != "" {
+	if updateStats && !noStats && dockerContainer != "" {
 		statsWriter = stats.NewWriter(sshHost, sshPassword, dockerContainer)
+		statsWriter.SSHPasswordMode = sshPasswordMode
+		statsWriter.GeneratedBy = generatedBy()
 		statsWriter.DryRun = dryRun
 		statsWriter.Verbose = verbose
 	}
@@ -71,39 +197,51 @@ func runCheckCmd(cmd *cobra.Command, args []string) error {
 	for _, storage := range storages {
 		fmt.Printf("==> Checking storage '%s'\n", storage)
 
+		opts := checkOptions{Chunks: verifyChunks, Persist: persist, ID: checkID, Threads: threads}
+		if checkLast > 0 {
+			opts.Revisions = recentRevisions(exec, storage, checkID, checkLast)
+		}
+
 		// Run check with -tabular to get stats output
-		output, err := exec.RunDuplicacyCaptureWithStorage(storage, "check", "-tabular", "-storage", storage)
+		output, err := exec.RunDuplicacyCaptureWithStorage(storage, checkArgs(storage, opts)...)
 
 		// Print the output (since we captured it)
 		if output != "" {
 			fmt.Print(output)
 		}
+		saveCheckOutput(storage, stats.TodayDate(), output)
 
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "ERROR: check on %s failed: %v\n", storage, err)
+			printError("", "check on %s failed: %v", storage, err)
 			hasErrors = true
-			continue
+		} else {
+			fmt.Printf("    Check on '%s' completed successfully\n", storage)
 		}
 
-		fmt.Printf("    Check on '%s' completed successfully\n", storage)
-
-		// Update stats if enabled
+		// Update stats if enabled; failed checks are recorded too so they show red
 		if statsWriter != nil && output != "" {
-			dayStats, parseErr := stats.ParseCheckOutput(output)
+			dayStats, parseErr := parseCheckStats(output, checkID)
 			if parseErr != nil {
-				fmt.Fprintf(os.Stderr, "    WARNING: failed to parse check output for stats: %v\n", parseErr)
+				if err == nil {
+					printWarning("    ", "failed to parse check output for stats: %v", parseErr)
+				}
 			} else {
+				dayStats.SetCheckResult(err)
+
 				// Print parsed stats summary
 				fmt.Printf("\n    Storage Stats Summary:\n")
 				fmt.Printf("      Total size: %s\n", stats.FormatBytes(dayStats.TotalSize))
 				fmt.Printf("      Total chunks: %d\n", dayStats.TotalChunks)
+				if dayStats.MissingChunks > 0 || dayStats.CorruptChunks > 0 {
+					fmt.Printf("      Missing chunks: %d, corrupt chunks: %d\n", dayStats.MissingChunks, dayStats.CorruptChunks)
+				}
 				fmt.Printf("      Repositories: %d\n", len(dayStats.Repositories))
 				for repoName, repoStats := range dayStats.Repositories {
 					fmt.Printf("        - %s: %d revisions, %s\n", repoName, repoStats.Revisions, stats.FormatBytes(repoStats.TotalSize))
 				}
 
 				if writeErr := statsWriter.UpdateStorageStats(storage, dayStats); writeErr != nil {
-					fmt.Fprintf(os.Stderr, "    WARNING: failed to update stats: %v\n", writeErr)
+					printWarning("    ", "failed to update stats: %v", writeErr)
 				} else {
 					fmt.Printf("    Updated Duplicacy Web UI stats for '%s'\n", storage)
 				}
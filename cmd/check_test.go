@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestArchiveCheckOutput(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "audit", "checks")
+
+	path, err := archiveCheckOutput(dir, "NAS", "2025-01-15", sampleCheckOutput)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := filepath.Join(dir, "NAS-2025-01-15.txt")
+	if path != expected {
+		t.Errorf("expected %q, got %q", expected, path)
+	}
+	data, err := os.ReadFile(expected)
+	if err != nil {
+		t.Fatalf("failed to read archived output: %v", err)
+	}
+	if string(data) != sampleCheckOutput {
+		t.Errorf("expected archived output %q, got %q", sampleCheckOutput, string(data))
+	}
+}
+
+func TestArchiveCheckOutput_Unwritable(t *testing.T) {
+	// A file where the directory should be makes MkdirAll fail
+	blocker := filepath.Join(t.TempDir(), "blocker")
+	if err := os.WriteFile(blocker, nil, 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	if _, err := archiveCheckOutput(blocker, "NAS", "2025-01-15", "output"); err == nil {
+		t.Error("expected error when the output dir cannot be created")
+	}
+}
+
+func TestRecentRevisions(t *testing.T) {
+	tests := []struct {
+		name     string
+		runner   *fakeRunner
+		expected []int
+	}{
+		{
+			name:     "listed",
+			runner:   &fakeRunner{output: "Snapshot appdata revision 3 created at 2025-01-03 06:00\nSnapshot appdata revision 4 created at 2025-01-04 06:00\n"},
+			expected: []int{4},
+		},
+		{
+			name:     "nothing listed falls back to all",
+			runner:   &fakeRunner{},
+			expected: nil,
+		},
+		{
+			name:     "list failure falls back to all",
+			runner:   &fakeRunner{errs: map[string]error{"list NAS": errors.New("command exited with code 1")}},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := recentRevisions(tt.runner, "NAS", "appdata", 1)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("recentRevisions() = %v, want %v", got, tt.expected)
+			}
+			cmds := tt.runner.commands()
+			if len(cmds) != 1 || cmds[0] != "list -storage NAS -id appdata" {
+				t.Errorf("expected a single list call, got %v", cmds)
+			}
+		})
+	}
+}
+
+func TestParseCheckStats(t *testing.T) {
+	defer func() { jsonLogs = false }()
+
+	jsonOutput := `{"time":"2025-12-29 01:02:45.064","level":"INFO","id":"SNAPSHOT_CHECK","message":"Total chunk size is 8,853K in 92 chunks"}
+{"time":"2025-12-29 01:02:45.065","level":"INFO","id":"SNAPSHOT_CHECK","message":" appdata | all |  |  |  |     92 | 8,853K |   92 | 8,853K |     |       |"}
+`
+
+	tests := []struct {
+		name     string
+		jsonLogs bool
+		output   string
+		id       string
+		wantErr  bool
+	}{
+		{"tabular", false, sampleCheckOutput, "", false},
+		{"json", true, jsonOutput, "", false},
+		{"json mode falls back to tabular", true, sampleCheckOutput, "", false},
+		{"json without json mode", false, jsonOutput, "", true},
+		{"id filter", true, jsonOutput, "appdata", false},
+		{"unknown id", false, sampleCheckOutput, "photos", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jsonLogs = tt.jsonLogs
+			got, err := parseCheckStats(tt.output, tt.id)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.TotalChunks != 92 || len(got.Repositories) != 1 {
+				t.Errorf("expected 92 chunks in 1 repository, got %+v", got)
+			}
+		})
+	}
+}
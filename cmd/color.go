@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+var (
+	// noColor is the --no-color flag
+	noColor bool
+
+	// colorEnabled makes the status helpers below color their labels; set
+	// before each command runs from --no-color, NO_COLOR and whether stdout
+	// is a terminal
+	colorEnabled bool
+)
+
+// ANSI color codes for status labels
+const (
+	ansiReset  = "\033[0m"
+	ansiRed    = "\033[31m"
+	ansiGreen  = "\033[32m"
+	ansiYellow = "\033[33m"
+)
+
+// isTerminal reports whether f is a terminal rather than a file or pipe
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// useColor reports whether status labels should be colored: never with
+// --no-color or NO_COLOR (https://no-color.org) set, else only on a terminal
+func useColor(noColorFlag bool, stdout *os.File) bool {
+	if noColorFlag || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	return isTerminal(stdout)
+}
+
+// colorize wraps s in the given color when color output is enabled
+func colorize(color, s string) string {
+	if !colorEnabled {
+		return s
+	}
+	return color + s + ansiReset
+}
+
+// printOK prints prefix and a green OK on stdout, followed by detail if set
+func printOK(prefix, detail string) {
+	if detail != "" {
+		detail = " " + detail
+	}
+	fmt.Printf("%s%s%s\n", prefix, colorize(ansiGreen, "OK"), detail)
+}
+
+// printError prints prefix and a red "ERROR: " message on stderr
+func printError(prefix, format string, args ...interface{}) {
+	fprintError(os.Stderr, prefix, format, args...)
+}
+
+// fprintError is printError writing to w
+func fprintError(w io.Writer, prefix, format string, args ...interface{}) {
+	fmt.Fprintf(w, "%s%s: %s\n", prefix, colorize(ansiRed, "ERROR"), fmt.Sprintf(format, args...))
+}
+
+// printWarning prints prefix and a yellow "WARNING: " message on stderr
+func printWarning(prefix, format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "%s%s: %s\n", prefix, colorize(ansiYellow, "WARNING"), fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// withColor sets colorEnabled for the duration of a test
+func withColor(t *testing.T, enabled bool) {
+	t.Helper()
+	prev := colorEnabled
+	colorEnabled = enabled
+	t.Cleanup(func() { colorEnabled = prev })
+}
+
+func TestPrintOK_Color(t *testing.T) {
+	withColor(t, true)
+
+	out := captureStdout(t, func() { printOK("    ", "(revision 3)") })
+	expected := "    " + ansiGreen + "OK" + ansiReset + " (revision 3)\n"
+	if out != expected {
+		t.Errorf("expected %q, got %q", expected, out)
+	}
+}
+
+func TestPrintOK_NoColor(t *testing.T) {
+	withColor(t, false)
+
+	out := captureStdout(t, func() { printOK("    ", "") })
+	if out != "    OK\n" {
+		t.Errorf("expected %q, got %q", "    OK\n", out)
+	}
+}
+
+func TestFprintError_Color(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		withColor(t, enabled)
+
+		var buf strings.Builder
+		fprintError(&buf, "  ", "check %s failed", "NAS")
+		hasCodes := strings.Contains(buf.String(), ansiRed) && strings.Contains(buf.String(), ansiReset)
+		if hasCodes != enabled {
+			t.Errorf("color %v: unexpected output %q", enabled, buf.String())
+		}
+		if plain := strings.NewReplacer(ansiRed, "", ansiReset, "").Replace(buf.String()); plain != "  ERROR: check NAS failed\n" {
+			t.Errorf("color %v: expected plain text %q, got %q", enabled, "  ERROR: check NAS failed\n", plain)
+		}
+	}
+}
+
+func TestPrintChecklist_Color(t *testing.T) {
+	withColor(t, true)
+
+	var buf strings.Builder
+	printChecklist(&buf, []doctorResult{{name: "ssh"}})
+	if !strings.Contains(buf.String(), "["+ansiGreen+" OK "+ansiReset+"] ssh") {
+		t.Errorf("expected a green OK label, got %q", buf.String())
+	}
+}
+
+func TestUseColor(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+
+	// A regular file stands in for redirected stdout
+	file, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	if useColor(false, file) {
+		t.Error("expected no color when stdout is not a terminal")
+	}
+	if useColor(true, file) {
+		t.Error("expected no color with --no-color")
+	}
+	if isTerminal(file) {
+		t.Error("expected a regular file not to be a terminal")
+	}
+}
+
+func TestUseColor_CharDevice(t *testing.T) {
+	// /dev/null is a character device, so it passes the terminal check
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Skip(err)
+	}
+	defer devNull.Close()
+
+	t.Setenv("NO_COLOR", "")
+	if !useColor(false, devNull) {
+		t.Error("expected color on a character device")
+	}
+	if useColor(true, devNull) {
+		t.Error("expected no color with --no-color")
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	if useColor(false, devNull) {
+		t.Error("expected no color with NO_COLOR set")
+	}
+}
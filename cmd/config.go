@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// configJSON prints the effective config as JSON instead of YAML
+var configJSON bool
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the configuration",
+}
+
+var configPrintCmd = &cobra.Command{
+	Use:   "print",
+	Short: "Print the effective config with secrets redacted",
+	Long: `Print the config as duplicaci sees it after merging --config-dir files,
+applying defaults, migrating legacy fields and running *_command secret
+sources. Tokens and storage env values are redacted.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runConfigPrint(os.Stdout)
+	},
+}
+
+func init() {
+	configPrintCmd.Flags().BoolVar(&configJSON, "json", false, "Print JSON instead of YAML")
+	configCmd.AddCommand(configPrintCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+// runConfigPrint loads the config and writes its redacted effective form.
+// The config is not validated so a broken one can still be inspected.
+func runConfigPrint(w io.Writer) error {
+	if !configSpecified() {
+		return fmt.Errorf("--config or --config-dir is required for the config command")
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	data, err := yaml.Marshal(cfg.Redacted())
+	if err != nil {
+		return err
+	}
+	if configJSON {
+		// Round-trip through YAML so JSON keys match the config file's names
+		var doc interface{}
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return err
+		}
+		if data, err = json.MarshalIndent(doc, "", "  "); err != nil {
+			return err
+		}
+		data = append(data, '\n')
+	}
+	_, err = w.Write(data)
+	return err
+}
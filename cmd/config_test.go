@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const configPrintSample = `ssh:
+  host: root@nas
+backups:
+  - name: appdata
+    path: /mnt/appdata
+    destinations: [B2]
+storages:
+  B2:
+    env:
+      DUPLICACY_B2_KEY: b2-secret
+notifications:
+  forgejo:
+    url: https://git.example.com
+    repo: ops/backups
+    token: forgejo-secret
+`
+
+func TestRunConfigPrint(t *testing.T) {
+	defer func() {
+		configFile = ""
+		configJSON = false
+	}()
+	configFile = filepath.Join(t.TempDir(), "duplicaci.yaml")
+	if err := os.WriteFile(configFile, []byte(configPrintSample), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := runConfigPrint(&buf); err != nil {
+		t.Fatalf("runConfigPrint() error: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"gcd_token: /config/gcd-token.json", // default
+		"threads: 1",                        // backup default
+		"daily: 7",                          // retention default
+		"host: root@nas",                    // migrated into connection
+		"token: '********'",
+		"DUPLICACY_B2_KEY: '********'",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+	for _, secret := range []string{"forgejo-secret", "b2-secret"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("expected %q to be redacted, got:\n%s", secret, out)
+		}
+	}
+
+	configJSON = true
+	buf.Reset()
+	if err := runConfigPrint(&buf); err != nil {
+		t.Fatalf("runConfigPrint() json error: %v", err)
+	}
+	var doc struct {
+		Connection struct {
+			Host string `json:"host"`
+		} `json:"connection"`
+		Notifications struct {
+			Forgejo struct {
+				Token string `json:"token"`
+			} `json:"forgejo"`
+		} `json:"notifications"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid JSON output: %v\n%s", err, buf.String())
+	}
+	if doc.Connection.Host != "root@nas" {
+		t.Errorf("expected migrated host %q, got %q", "root@nas", doc.Connection.Host)
+	}
+	if doc.Notifications.Forgejo.Token != "********" {
+		t.Errorf("expected redacted token, got %q", doc.Notifications.Forgejo.Token)
+	}
+}
+
+func TestRunConfigPrint_NoConfig(t *testing.T) {
+	var buf bytes.Buffer
+	err := runConfigPrint(&buf)
+	if err == nil || !strings.Contains(err.Error(), "--config or --config-dir is required") {
+		t.Errorf("expected missing config error, got %v", err)
+	}
+}
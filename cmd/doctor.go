@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/lioreshai/duplicaci/internal/config"
+	"github.com/lioreshai/duplicaci/internal/executor"
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose the environment before a first run",
+	Long: `Check that everything a run needs is in place: the config is valid, the
+local tools (bash, ssh, sshpass, docker) are on PATH, the container can be
+reached, duplicacy can be found in it, and the notification token is set.
+
+Prints a pass/fail checklist and exits non-zero if a critical check fails.`,
+	RunE: runDoctor,
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
+
+// lookPath finds an executable on PATH (tests substitute a fake)
+var lookPath = exec.LookPath
+
+// doctorResult is one line of the doctor checklist
+type doctorResult struct {
+	name     string
+	detail   string // shown after a passing check, e.g. the resolved path
+	err      error  // nil when the check passed
+	critical bool   // a failure makes doctor exit non-zero; otherwise it is a warning
+}
+
+// environmentProbe is the part of the executor doctor uses to reach the container
+type environmentProbe interface {
+	Ping() error
+	DuplicacyPath() (string, error)
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	if !configSpecified() {
+		return fmt.Errorf("--config or --config-dir is required for the doctor command")
+	}
+
+	cfg, results := checkConfig()
+
+	if cfg != nil {
+		sshPassword := cfg.Connection.SSHPassword()
+		results = append(results, checkTools(cfg, sshPassword, lookPath)...)
+		if cfg.Connection.ComposeService != "" {
+			err := resolveContainer(cfg, hostResolver(cmd.Context(), cfg, sshPassword))
+			results = append(results, doctorResult{
+				name:     "resolve compose service " + cfg.Connection.ComposeService,
+				detail:   cfg.Connection.Container,
+				err:      err,
+				critical: true,
+			})
+		}
+		probe := executor.New(executor.Options{
+			Context:          cmd.Context(),
+			DockerContainer:  cfg.Connection.Container,
+			ContainerUser:    cfg.Connection.ContainerUser,
+			DuplicacyVersion: cfg.Connection.DuplicacyVersion,
+			SSHHost:          cfg.Connection.Host,
+			SSHPassword:      sshPassword,
+			SSHPasswordMode:  cfg.Connection.SSHPasswordMode,
+		})
+		results = append(results, checkConnection(cfg, probe)...)
+		results = append(results, checkNotificationToken(cfg)...)
+	}
+
+	if failed := printChecklist(os.Stdout, results); failed > 0 {
+		return fmt.Errorf("doctor found %d critical problem(s)", failed)
+	}
+	return nil
+}
+
+// checkConfig loads and validates the config; cfg is nil if either fails
+func checkConfig() (*config.Config, []doctorResult) {
+	cfg, err := loadConfig()
+	if err == nil {
+		err = cfg.Validate()
+	}
+	result := doctorResult{name: "config is valid", err: err, critical: true}
+	if err != nil {
+		return nil, []doctorResult{result}
+	}
+	return cfg, []doctorResult{result}
+}
+
+// checkTools checks that the local commands a run shells out to are on PATH.
+// ssh and sshpass are only needed with connection.host (sshpass only when
+// SSH_PASSWORD is set), and docker only for a local container.
+func checkTools(cfg *config.Config, sshPassword string, look func(string) (string, error)) []doctorResult {
+	tools := []string{"bash"}
+	if cfg.Connection.Host != "" {
+		tools = append(tools, "ssh")
+		if sshPassword != "" {
+			tools = append(tools, "sshpass")
+		}
+	} else if cfg.Connection.HasContainer() {
+		tools = append(tools, "docker")
+	}
+
+	var results []doctorResult
+	for _, tool := range tools {
+		path, err := look(tool)
+		results = append(results, doctorResult{
+			name:     tool + " on PATH",
+			detail:   path,
+			err:      err,
+			critical: true,
+		})
+	}
+	return results
+}
+
+// checkConnection checks that the container can be reached and duplicacy found
+// in it. Duplicacy discovery is skipped when the container is unreachable.
+func checkConnection(cfg *config.Config, probe environmentProbe) []doctorResult {
+	var results []doctorResult
+	if cfg.Connection.Host != "" || cfg.Connection.Container != "" {
+		target := cfg.Connection.Container
+		if target == "" {
+			target = cfg.Connection.Host
+		}
+		err := probe.Ping()
+		results = append(results, doctorResult{name: "reach " + target, err: err, critical: true})
+		if err != nil {
+			return results
+		}
+	}
+
+	path, err := probe.DuplicacyPath()
+	return append(results, doctorResult{name: "duplicacy found", detail: path, err: err, critical: true})
+}
+
+// checkNotificationToken warns when Forgejo notifications are configured but
+// no token is set, since failures would then go unreported
+func checkNotificationToken(cfg *config.Config) []doctorResult {
+	f := cfg.Notifications.Forgejo
+	if f.URL == "" && f.Repo == "" {
+		return nil
+	}
+
+	var err error
+	if f.GetToken() == "" {
+		source := "FORGEJO_TOKEN"
+		if f.TokenEnv != "" {
+			source = f.TokenEnv
+		}
+		err = fmt.Errorf("no token set (notifications.forgejo.token or %s)", source)
+	}
+	return []doctorResult{{name: "notification token set", err: err}}
+}
+
+// printChecklist writes one line per result and returns the number of failed
+// critical checks
+func printChecklist(w io.Writer, results []doctorResult) int {
+	failed := 0
+	for _, r := range results {
+		switch {
+		case r.err == nil && r.detail != "":
+			fmt.Fprintf(w, "[%s] %s (%s)\n", colorize(ansiGreen, " OK "), r.name, r.detail)
+		case r.err == nil:
+			fmt.Fprintf(w, "[%s] %s\n", colorize(ansiGreen, " OK "), r.name)
+		case r.critical:
+			failed++
+			fmt.Fprintf(w, "[%s] %s: %v\n", colorize(ansiRed, "FAIL"), r.name, r.err)
+		default:
+			fmt.Fprintf(w, "[%s] %s: %v\n", colorize(ansiYellow, "WARN"), r.name, r.err)
+		}
+	}
+	return failed
+}
@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lioreshai/duplicaci/internal/config"
+)
+
+// fakeProbe reports fixed connection results
+type fakeProbe struct {
+	pingErr  error
+	path     string
+	pathErr  error
+	discover int // DuplicacyPath calls
+}
+
+func (f *fakeProbe) Ping() error {
+	return f.pingErr
+}
+
+func (f *fakeProbe) DuplicacyPath() (string, error) {
+	f.discover++
+	return f.path, f.pathErr
+}
+
+// fakeLookPath finds only the listed tools
+func fakeLookPath(available ...string) func(string) (string, error) {
+	return func(name string) (string, error) {
+		for _, a := range available {
+			if a == name {
+				return "/usr/bin/" + name, nil
+			}
+		}
+		return "", errors.New("executable file not found in $PATH")
+	}
+}
+
+// failures returns the names of the failed results
+func failures(results []doctorResult) []string {
+	var names []string
+	for _, r := range results {
+		if r.err != nil {
+			names = append(names, r.name)
+		}
+	}
+	return names
+}
+
+func TestCheckTools(t *testing.T) {
+	tests := []struct {
+		name        string
+		connection  config.ConnectionConfig
+		sshPassword string
+		available   []string
+		checked     []string
+		failed      []string
+	}{
+		{"remote with password", config.ConnectionConfig{Host: "root@nas", Container: "Duplicacy"}, "pw", []string{"bash", "ssh"}, []string{"bash", "ssh", "sshpass"}, []string{"sshpass on PATH"}},
+		{"remote with keys", config.ConnectionConfig{Host: "root@nas", Container: "Duplicacy"}, "", []string{"bash", "ssh"}, []string{"bash", "ssh"}, nil},
+		{"local container", config.ConnectionConfig{Container: "Duplicacy"}, "", []string{"bash"}, []string{"bash", "docker"}, []string{"docker on PATH"}},
+		{"local duplicacy", config.ConnectionConfig{}, "", []string{"bash"}, []string{"bash"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Connection: tt.connection}
+			results := checkTools(cfg, tt.sshPassword, fakeLookPath(tt.available...))
+
+			var checked []string
+			for _, r := range results {
+				checked = append(checked, strings.TrimSuffix(r.name, " on PATH"))
+				if !r.critical {
+					t.Errorf("expected %s to be critical", r.name)
+				}
+			}
+			if strings.Join(checked, ",") != strings.Join(tt.checked, ",") {
+				t.Errorf("expected checks %v, got %v", tt.checked, checked)
+			}
+			if got := failures(results); strings.Join(got, ",") != strings.Join(tt.failed, ",") {
+				t.Errorf("expected failures %v, got %v", tt.failed, got)
+			}
+		})
+	}
+}
+
+func TestCheckConnection(t *testing.T) {
+	cfg := &config.Config{Connection: config.ConnectionConfig{Host: "root@nas", Container: "Duplicacy"}}
+
+	t.Run("reachable", func(t *testing.T) {
+		probe := &fakeProbe{path: "/config/bin/duplicacy_linux_x64_3.2.3"}
+		results := checkConnection(cfg, probe)
+		if len(results) != 2 || len(failures(results)) != 0 {
+			t.Fatalf("expected 2 passing checks, got %+v", results)
+		}
+		if results[1].detail != probe.path {
+			t.Errorf("expected duplicacy path detail, got %q", results[1].detail)
+		}
+	})
+
+	t.Run("unreachable skips discovery", func(t *testing.T) {
+		probe := &fakeProbe{pingErr: errors.New("No such container")}
+		results := checkConnection(cfg, probe)
+		if got := failures(results); len(got) != 1 || got[0] != "reach Duplicacy" {
+			t.Errorf("expected only the reach check to fail, got %v", got)
+		}
+		if probe.discover != 0 {
+			t.Error("expected duplicacy discovery to be skipped")
+		}
+	})
+
+	t.Run("duplicacy missing", func(t *testing.T) {
+		probe := &fakeProbe{pathErr: errors.New("cannot find duplicacy")}
+		if got := failures(checkConnection(cfg, probe)); len(got) != 1 || got[0] != "duplicacy found" {
+			t.Errorf("expected the duplicacy check to fail, got %v", got)
+		}
+	})
+}
+
+func TestCheckNotificationToken(t *testing.T) {
+	t.Setenv("FORGEJO_TOKEN", "")
+
+	if results := checkNotificationToken(&config.Config{}); len(results) != 0 {
+		t.Errorf("expected no check without notifications, got %+v", results)
+	}
+
+	cfg := &config.Config{}
+	cfg.Notifications.Forgejo = config.ForgejoNotificationConfig{URL: "https://git.example.com", Repo: "user/infra"}
+	results := checkNotificationToken(cfg)
+	if len(results) != 1 || results[0].err == nil || results[0].critical {
+		t.Fatalf("expected a non-critical token warning, got %+v", results)
+	}
+	if !strings.Contains(results[0].err.Error(), "FORGEJO_TOKEN") {
+		t.Errorf("expected the warning to name FORGEJO_TOKEN, got %v", results[0].err)
+	}
+
+	t.Setenv("FORGEJO_TOKEN", "secret")
+	if got := failures(checkNotificationToken(cfg)); len(got) != 0 {
+		t.Errorf("expected token check to pass, got %v", got)
+	}
+}
+
+func TestCheckConfig(t *testing.T) {
+	defer func() { configFile = "" }()
+	dir := t.TempDir()
+
+	configFile = filepath.Join(dir, "valid.yaml")
+	valid := "version: 2\nbackups:\n  - name: appdata\n    path: /mnt/appdata\n    destinations: [NAS]\n"
+	if err := os.WriteFile(configFile, []byte(valid), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	if cfg, results := checkConfig(); cfg == nil || results[0].err != nil {
+		t.Errorf("expected valid config, got %+v", results)
+	}
+
+	configFile = filepath.Join(dir, "invalid.yaml")
+	if err := os.WriteFile(configFile, []byte("version: 2\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	if cfg, results := checkConfig(); cfg != nil || results[0].err == nil || !results[0].critical {
+		t.Errorf("expected a critical config failure, got %+v", results)
+	}
+}
+
+func TestPrintChecklist(t *testing.T) {
+	results := []doctorResult{
+		{name: "bash on PATH", detail: "/usr/bin/bash", critical: true},
+		{name: "config is valid", critical: true},
+		{name: "sshpass on PATH", err: errors.New("not found"), critical: true},
+		{name: "notification token set", err: errors.New("no token set")},
+	}
+
+	var buf bytes.Buffer
+	if failed := printChecklist(&buf, results); failed != 1 {
+		t.Errorf("expected 1 critical failure, got %d", failed)
+	}
+
+	expected := `[ OK ] bash on PATH (/usr/bin/bash)
+[ OK ] config is valid
+[FAIL] sshpass on PATH: not found
+[WARN] notification token set: no token set
+`
+	if buf.String() != expected {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", buf.String(), expected)
+	}
+}
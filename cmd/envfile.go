@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// envKeyPattern matches valid environment variable names
+var envKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// envVar is a single KEY=VALUE assignment from an env file
+type envVar struct {
+	Key   string
+	Value string
+}
+
+// parseEnvFile parses dotenv-style KEY=VALUE lines. Blank lines and lines
+// starting with # are skipped, an optional "export " prefix is allowed, and
+// values may be wrapped in single quotes (literal) or double quotes (with
+// \", \\ and \n escapes). Unquoted values end at a " #" comment.
+func parseEnvFile(r io.Reader) ([]envVar, error) {
+	var vars []envVar
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		eq := strings.Index(line, "=")
+		if eq < 0 {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", lineNum)
+		}
+		key := strings.TrimSpace(line[:eq])
+		if !envKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("line %d: invalid variable name %q", lineNum, key)
+		}
+
+		value, err := parseEnvValue(strings.TrimSpace(line[eq+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		vars = append(vars, envVar{Key: key, Value: value})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return vars, nil
+}
+
+// parseEnvValue unquotes a raw env file value
+func parseEnvValue(raw string) (string, error) {
+	if raw == "" {
+		return "", nil
+	}
+
+	switch raw[0] {
+	case '\'':
+		end := strings.Index(raw[1:], "'")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated single-quoted value")
+		}
+		return raw[1 : end+1], nil
+	case '"':
+		var b strings.Builder
+		for i := 1; i < len(raw); i++ {
+			switch c := raw[i]; {
+			case c == '"':
+				return b.String(), nil
+			case c == '\\' && i+1 < len(raw):
+				i++
+				if raw[i] == 'n' {
+					b.WriteByte('\n')
+				} else {
+					b.WriteByte(raw[i])
+				}
+			default:
+				b.WriteByte(c)
+			}
+		}
+		return "", fmt.Errorf("unterminated double-quoted value")
+	}
+
+	if i := strings.Index(raw, " #"); i >= 0 {
+		raw = strings.TrimSpace(raw[:i])
+	}
+	return raw, nil
+}
+
+// loadEnvFile sets the variables from an env file in the process environment.
+// Variables that are already set are kept unless override is true.
+func loadEnvFile(path string, override bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read env file: %w", err)
+	}
+	defer f.Close()
+
+	vars, err := parseEnvFile(f)
+	if err != nil {
+		return fmt.Errorf("failed to parse env file %s: %w", path, err)
+	}
+
+	for _, v := range vars {
+		if _, set := os.LookupEnv(v.Key); set && !override {
+			continue
+		}
+		if err := os.Setenv(v.Key, v.Value); err != nil {
+			return fmt.Errorf("failed to set %s from env file: %w", v.Key, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseEnvFile(t *testing.T) {
+	input := `# secrets for the nightly run
+
+SSH_PASSWORD=plain
+export DUPLICACY_PASSWORD="double \"quoted\" $NOT_EXPANDED"
+FORGEJO_TOKEN='single # not a comment'
+TRAILING=value # comment
+EMPTY=
+MULTI="line1\nline2"
+`
+
+	got, err := parseEnvFile(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []envVar{
+		{"SSH_PASSWORD", "plain"},
+		{"DUPLICACY_PASSWORD", `double "quoted" $NOT_EXPANDED`},
+		{"FORGEJO_TOKEN", "single # not a comment"},
+		{"TRAILING", "value"},
+		{"EMPTY", ""},
+		{"MULTI", "line1\nline2"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestParseEnvFile_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		errMsg string
+	}{
+		{"missing equals", "# ok\nJUSTAKEY\n", "line 2: expected KEY=VALUE"},
+		{"bad name", "BAD-NAME=x", "invalid variable name"},
+		{"unterminated double", `KEY="open`, "unterminated double-quoted value"},
+		{"unterminated single", `KEY='open`, "unterminated single-quoted value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseEnvFile(strings.NewReader(tt.input))
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %q", tt.errMsg, err.Error())
+			}
+		})
+	}
+}
+
+func TestLoadEnvFile_Precedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ci.env")
+	if err := os.WriteFile(path, []byte("DUPLICACI_TEST_SET=from-file\nDUPLICACI_TEST_UNSET=from-file\n"), 0600); err != nil {
+		t.Fatalf("failed to write env file: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		override bool
+		expected string
+	}{
+		{"existing env wins", false, "from-env"},
+		{"override", true, "from-file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DUPLICACI_TEST_SET", "from-env")
+			t.Setenv("DUPLICACI_TEST_UNSET", "")
+			os.Unsetenv("DUPLICACI_TEST_UNSET")
+
+			if err := loadEnvFile(path, tt.override); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := os.Getenv("DUPLICACI_TEST_SET"); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+			if got := os.Getenv("DUPLICACI_TEST_UNSET"); got != "from-file" {
+				t.Errorf("expected unset variable to be loaded, got %q", got)
+			}
+		})
+	}
+}
+
+func TestLoadEnvFile_Missing(t *testing.T) {
+	if err := loadEnvFile(filepath.Join(t.TempDir(), "missing.env"), false); err == nil {
+		t.Error("expected error for missing env file")
+	}
+}
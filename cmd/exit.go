@@ -0,0 +1,36 @@
+package cmd
+
+import "errors"
+
+// Process exit codes
+const (
+	exitFailure           = 1   // Any failure not listed below
+	exitDuplicacyNotFound = 3   // The duplicacy binary could not be found for any storage
+	exitInterrupted       = 130 // Stopped by SIGINT/SIGTERM (128 + SIGINT, as shells report it)
+)
+
+// exitError attaches a specific process exit code to an error
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// ExitCode returns the process exit code for an error returned by Execute
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var ee *exitError
+	if errors.As(err, &ee) {
+		return ee.code
+	}
+	return exitFailure
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{"nil", nil, 0},
+		{"plain error", errors.New("boom"), exitFailure},
+		{"exit error", &exitError{code: exitDuplicacyNotFound, err: errors.New("missing")}, exitDuplicacyNotFound},
+		{"wrapped exit error", fmt.Errorf("run: %w", &exitError{code: 7, err: errors.New("x")}), 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExitCode(tt.err); got != tt.expected {
+				t.Errorf("ExitCode() = %d, want %d", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestInterruptedError(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		err      error
+		expected int
+	}{
+		{"no error", cancelled, nil, 0},
+		{"not interrupted", context.Background(), errors.New("boom"), exitFailure},
+		{"interrupted", cancelled, errors.New("completed with 2 error(s)"), exitInterrupted},
+		{"specific code kept", cancelled, &exitError{code: exitDuplicacyNotFound, err: errors.New("missing")}, exitDuplicacyNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExitCode(interruptedError(tt.ctx, tt.err)); got != tt.expected {
+				t.Errorf("ExitCode() = %d, want %d", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/lioreshai/duplicaci/internal/config"
+)
+
+// acquireLock creates the lock file at path, failing if it already exists, so a
+// second run stops instead of overlapping the first. The file records the PID
+// of the holder; release removes it.
+func acquireLock(path string) (release func(), err error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, fs.ErrExist) {
+		owner, _ := os.ReadFile(path)
+		return nil, fmt.Errorf("another run holds the lock %s (pid %s); remove it if no run is active",
+			path, strings.TrimSpace(string(owner)))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create lock file: %w", err)
+	}
+
+	_, err = fmt.Fprintf(f, "%d\n", os.Getpid())
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return nil, fmt.Errorf("failed to write lock file: %w", err)
+	}
+
+	return func() { os.Remove(path) }, nil
+}
+
+// exclusivePruneAllowed returns an error when a storage asks for -exclusive
+// prune but the run does not hold the lock that rules out overlapping runs.
+// The lock is local to this host: it cannot see the Web UI scheduler or other
+// duplicacy clients, so holding it is necessary but not sufficient.
+func exclusivePruneAllowed(storage config.StorageConfig, lockHeld bool) error {
+	if storage.ExclusivePrune && !lockHeld {
+		return errors.New("refusing -exclusive prune without holding the run lock (set lock_file); " +
+			"even with it, -exclusive is only safe when no other client, including the Web UI scheduler, writes to this storage")
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/lioreshai/duplicaci/internal/config"
+)
+
+func TestAcquireLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "duplicaci.lock")
+
+	release, err := acquireLock(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || strings.TrimSpace(string(data)) != strconv.Itoa(os.Getpid()) {
+		t.Errorf("expected the lock file to hold our pid, got %q, %v", data, err)
+	}
+
+	if _, err := acquireLock(path); err == nil || !strings.Contains(err.Error(), "another run holds the lock") {
+		t.Errorf("expected a held lock error, got %v", err)
+	}
+
+	release()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected release to remove the lock file, got %v", err)
+	}
+
+	release, err = acquireLock(path)
+	if err != nil {
+		t.Fatalf("expected the lock to be free after release: %v", err)
+	}
+	release()
+}
+
+func TestExclusivePruneAllowed(t *testing.T) {
+	tests := []struct {
+		name      string
+		exclusive bool
+		lockHeld  bool
+		wantErr   bool
+	}{
+		{"not exclusive", false, false, false},
+		{"not exclusive with lock", false, true, false},
+		{"exclusive with lock", true, true, false},
+		{"exclusive without lock", true, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := exclusivePruneAllowed(config.StorageConfig{ExclusivePrune: tt.exclusive}, tt.lockHeld)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error=%v, got %v", tt.wantErr, err)
+			}
+			// The lock only covers duplicaci runs, which the error must not hide
+			if err != nil && !strings.Contains(err.Error(), "including the Web UI scheduler") {
+				t.Errorf("expected the error to warn about other clients, got %v", err)
+			}
+		})
+	}
+}
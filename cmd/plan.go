@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/lioreshai/duplicaci/internal/config"
+)
+
+// plannedOperation is one step of a run as listed by --list-operations
+type plannedOperation struct {
+	Label   string // what the step is, e.g. "backup appdata -> NAS"
+	Command string // shell command, or the duplicacy invocation with its working dir
+}
+
+// planOperations lists, in run order, every hook and duplicacy command a run
+// of the given phases (nil for all) would execute. It uses only the config:
+// duplicacy is the default binary name, each backup runs in its cache_dir
+// or path (the Web UI cache dir is not discovered), and no SSH or Docker
+// connection is made.
+func planOperations(cfg *config.Config, phases map[string]bool) []plannedOperation {
+	runs := func(phase string) bool { return phases == nil || phases[phase] }
+	globalOpts := duplicacyGlobalOptions(cfg.Connection.GlobalOptions...)
+	duplicacy := func(dir string, args []string) string {
+		cmd := strings.Join(append(append([]string{"duplicacy"}, globalOpts...), args...), " ")
+		if dir == "" {
+			return cmd
+		}
+		return fmt.Sprintf("cd %s && %s", dir, cmd)
+	}
+
+	var ops []plannedOperation
+	if cfg.Hooks.Pre != "" {
+		ops = append(ops, plannedOperation{"pre-run hook", cfg.Hooks.Pre})
+	}
+
+	if runs("backup") {
+		for _, backup := range cfg.Backups {
+			if backup.PreHook != "" {
+				ops = append(ops, plannedOperation{backup.Name + " pre-hook", backup.PreHook})
+			}
+			for _, dest := range cfg.Destinations(backup) {
+				storageName := cfg.StorageName(dest)
+				ops = append(ops, plannedOperation{
+					Label:   fmt.Sprintf("backup %s -> %s", backup.Name, dest),
+					Command: duplicacy(plannedDir(backup), backupArgs(storageName, backup, cfg.LimitRate(backup, dest))),
+				})
+			}
+			if backup.PostHook != "" {
+				ops = append(ops, plannedOperation{backup.Name + " post-hook", backup.PostHook})
+			}
+		}
+	}
+
+	// Check and prune run in maintenance_dir, else the first backup's directory
+	maintenanceDir := cfg.MaintenanceDir
+	if maintenanceDir == "" && len(cfg.Backups) > 0 {
+		maintenanceDir = plannedDir(cfg.Backups[0])
+	}
+
+	if runs("check") {
+		for _, storage := range cfg.AllStorages() {
+			checkOpts, ids := planCheck(cfg, storage)
+			for _, id := range ids {
+				label := "check " + storage
+				if id != "" {
+					label += "/" + id
+				}
+				checkOpts.ID = id
+				ops = append(ops, plannedOperation{
+					Label:   label,
+					Command: duplicacy(maintenanceDir, checkArgs(cfg.StorageName(storage), checkOpts)),
+				})
+			}
+		}
+	}
+
+	if runs("prune") {
+		for _, storage := range cfg.AllStorages() {
+			if !cfg.GetStorageConfig(storage).IsPrunable() {
+				continue
+			}
+			for _, target := range planPrune(cfg, storage) {
+				ops = append(ops, plannedOperation{
+					Label:   fmt.Sprintf("prune %s (%s)", storage, target.source),
+					Command: duplicacy(maintenanceDir, target.args),
+				})
+			}
+		}
+	}
+
+	if cfg.Hooks.Post != "" {
+		ops = append(ops, plannedOperation{"post-run hook", cfg.Hooks.Post})
+	}
+	return ops
+}
+
+// plannedDir is the directory a backup's commands run in without cache dir discovery
+func plannedDir(backup config.BackupConfig) string {
+	if backup.CacheDir != "" {
+		return backup.CacheDir
+	}
+	return backup.Path
+}
+
+// printOperations prints a numbered operation list
+func printOperations(w io.Writer, ops []plannedOperation) {
+	fmt.Fprintf(w, "Planned operations (%d)\n", len(ops))
+	for i, op := range ops {
+		fmt.Fprintf(w, "\n%3d. %s\n     %s\n", i+1, op.Label, op.Command)
+	}
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/lioreshai/duplicaci/internal/config"
+)
+
+// planConfig is a representative config: hooks, two backups, storage-level and
+// per-backup retention, an alias, check_per_id and a maintenance-only storage
+func planConfig() *config.Config {
+	return &config.Config{
+		Connection: config.ConnectionConfig{GlobalOptions: []string{"-background"}},
+		Hooks:      config.HooksConfig{Pre: "echo start", Post: "echo done"},
+		Backups: []config.BackupConfig{
+			{Name: "appdata", Path: "/mnt/appdata", Destinations: []string{"NAS", "Cloud"}, Threads: 4, PostHook: "sync"},
+			{Name: "photos", Path: "/mnt/photos", CacheDir: "/cache/localhost/1", Destinations: []string{"NAS"},
+				Retention: config.RetentionConfig{Daily: 3, Weekly: 1}},
+		},
+		Storages: map[string]config.StorageConfig{
+			"Cloud": {Name: "gdrive", Retention: config.RetentionConfig{Daily: 7, Weekly: 4}},
+			"NAS":   {CheckPerID: true},
+		},
+		Maintenance: []string{"Archive"},
+	}
+}
+
+func TestPlanOperations(t *testing.T) {
+	expected := []plannedOperation{
+		{"pre-run hook", "echo start"},
+		{"backup appdata -> NAS", "cd /mnt/appdata && duplicacy -background backup -storage NAS -threads 4"},
+		{"backup appdata -> Cloud", "cd /mnt/appdata && duplicacy -background backup -storage gdrive -threads 4"},
+		{"appdata post-hook", "sync"},
+		{"backup photos -> NAS", "cd /cache/localhost/1 && duplicacy -background backup -storage NAS"},
+		{"check NAS/appdata", "cd /mnt/appdata && duplicacy -background check -tabular -storage NAS -id appdata"},
+		{"check NAS/photos", "cd /mnt/appdata && duplicacy -background check -tabular -storage NAS -id photos"},
+		{"check Cloud", "cd /mnt/appdata && duplicacy -background check -tabular -storage gdrive"},
+		{"check Archive", "cd /mnt/appdata && duplicacy -background check -tabular -storage Archive"},
+		{"prune NAS (repository: appdata)", "cd /mnt/appdata && duplicacy -background prune -storage NAS -id appdata -keep 0:35 -keep 7:7 -keep 1:1"},
+		{"prune NAS (repository: photos)", "cd /mnt/appdata && duplicacy -background prune -storage NAS -id photos -keep 0:10 -keep 7:3 -keep 1:1"},
+		{"prune Cloud (all repositories)", "cd /mnt/appdata && duplicacy -background prune -storage gdrive -keep 0:35 -keep 7:7 -keep 1:1 -a"},
+		{"prune Archive (maintenance, default retention)", "cd /mnt/appdata && duplicacy -background prune -storage Archive -keep 0:35 -keep 7:7 -keep 1:1 -a"},
+		{"post-run hook", "echo done"},
+	}
+
+	got := planOperations(planConfig(), nil)
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d operations, got %d: %+v", len(expected), len(got), got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("operation %d: expected %+v, got %+v", i+1, expected[i], got[i])
+		}
+	}
+}
+
+func TestPlanOperations_Phases(t *testing.T) {
+	got := planOperations(planConfig(), map[string]bool{"check": true})
+
+	var labels []string
+	for _, op := range got {
+		labels = append(labels, op.Label)
+	}
+	expected := "pre-run hook,check NAS/appdata,check NAS/photos,check Cloud,check Archive,post-run hook"
+	if strings.Join(labels, ",") != expected {
+		t.Errorf("expected %s, got %v", expected, labels)
+	}
+}
+
+func TestPrintOperations(t *testing.T) {
+	var buf bytes.Buffer
+	printOperations(&buf, []plannedOperation{{"check NAS", "duplicacy check -tabular -storage NAS"}})
+
+	expected := "Planned operations (1)\n\n  1. check NAS\n     duplicacy check -tabular -storage NAS\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
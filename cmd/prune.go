@@ -3,12 +3,18 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/lioreshai/duplicaci/internal/config"
 	"github.com/lioreshai/duplicaci/internal/executor"
 	"github.com/spf13/cobra"
 )
 
+// minRevisions is the --revisions-to-keep-minimum safety floor (0 = off)
+var minRevisions int
+
 var pruneCmd = &cobra.Command{
 	Use:   "prune",
 	Short: "Prune old backup revisions",
@@ -25,8 +31,11 @@ func init() {
 	pruneCmd.Flags().StringVar(&dockerContainer, "docker-container", "", "Run inside Docker container")
 	pruneCmd.Flags().StringVar(&sshHost, "ssh-host", "", "SSH to host before running (user@host)")
 	pruneCmd.Flags().StringVar(&sshPassword, "ssh-password", "", "SSH password (or SSH_PASSWORD env)")
+	pruneCmd.Flags().StringVar(&sshPasswordMode, "ssh-password-mode", "", `How sshpass gets the SSH password: "arg" (-p, default) or "file" (a pipe, kept out of the process list)`)
 	pruneCmd.Flags().StringVar(&storagePassword, "storage-password", "", "Duplicacy storage encryption password (or DUPLICACY_PASSWORD env)")
 	pruneCmd.Flags().StringVar(&gcdToken, "gcd-token", "", "Google Drive token file path (for gcd:// storages)")
+	pruneCmd.Flags().IntVar(&threads, "threads", 1, "Number of threads for deleting chunks")
+	pruneCmd.Flags().IntVar(&minRevisions, "revisions-to-keep-minimum", 0, "Refuse to prune a repository below this many revisions (0 = no floor)")
 }
 
 func runPruneCmd(cmd *cobra.Command, args []string) error {
@@ -34,6 +43,13 @@ func runPruneCmd(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("at least one --storage is required")
 	}
 
+	if err := config.CheckPruneOptions(pruneOptions); err != nil {
+		return fmt.Errorf("refusing to prune: %w", err)
+	}
+	if minRevisions < 0 {
+		return fmt.Errorf("--revisions-to-keep-minimum must not be negative")
+	}
+
 	if sshPassword == "" {
 		sshPassword = os.Getenv("SSH_PASSWORD")
 	}
@@ -43,11 +59,15 @@ func runPruneCmd(cmd *cobra.Command, args []string) error {
 	}
 
 	exec := executor.New(executor.Options{
+		Context:         cmd.Context(),
 		DryRun:          dryRun,
 		Verbose:         verbose,
+		SkipRepoCheck:   noRepoCheck,
+		GlobalOptions:   duplicacyGlobalOptions(configGlobalOptions...),
 		DockerContainer: dockerContainer,
 		SSHHost:         sshHost,
 		SSHPassword:     sshPassword,
+		SSHPasswordMode: sshPasswordMode,
 		RepoPath:        repoPath,
 		CacheDir:        cacheDir,
 		StoragePassword: storagePassword,
@@ -59,12 +79,17 @@ func runPruneCmd(cmd *cobra.Command, args []string) error {
 	for _, storage := range storages {
 		fmt.Printf("==> Pruning storage '%s'\n", storage)
 
-		pruneArgs := []string{"prune", "-storage", storage}
-		pruneArgs = append(pruneArgs, strings.Fields(pruneOptions)...)
+		pruneArgs := pruneCmdArgs(storage, pruneOptions, threads)
+
+		if err := checkRevisionFloor(exec, storage, pruneArgs, minRevisions, time.Now()); err != nil {
+			printError("", "refusing to prune %s: %v", storage, err)
+			hasErrors = true
+			continue
+		}
 
 		err := exec.RunDuplicacyWithStorage(storage, pruneArgs...)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "ERROR: prune on %s failed: %v\n", storage, err)
+			printError("", "prune on %s failed: %v", storage, err)
 			hasErrors = true
 			continue
 		}
@@ -78,3 +103,78 @@ func runPruneCmd(cmd *cobra.Command, args []string) error {
 	fmt.Println("==> All prune operations completed successfully")
 	return nil
 }
+
+// checkRevisionFloor lists the revisions a prune would act on and returns an
+// error if its -keep rules would leave any snapshot ID with fewer than
+// minRevisions revisions. duplicacy has no such floor, so the retention is
+// replayed against the listed creation times. A floor of 0 skips the check.
+func checkRevisionFloor(exec duplicacyCapturer, storageName string, pruneArgs []string, minRevisions int, now time.Time) error {
+	if minRevisions <= 0 {
+		return nil
+	}
+
+	listArgs := []string{"list", "-storage", storageName}
+	if id := argValue(pruneArgs, "-id"); id != "" {
+		listArgs = append(listArgs, "-id", id)
+	} else {
+		listArgs = append(listArgs, "-a")
+	}
+	output, err := exec.RunDuplicacyCaptureWithStorage(storageName, listArgs...)
+	if err != nil {
+		return fmt.Errorf("failed to list revisions for the min revisions check: %w", err)
+	}
+
+	violations, err := revisionFloorViolations(output, pruneArgs, minRevisions, now)
+	if err != nil {
+		return err
+	}
+	if len(violations) > 0 {
+		return fmt.Errorf("prune would go below %d revisions: %s", minRevisions, strings.Join(violations, ", "))
+	}
+	return nil
+}
+
+// revisionFloorViolations replays the -keep rules in pruneArgs against the
+// revisions in duplicacy list output and describes each snapshot ID that the
+// prune would leave with fewer than minRevisions revisions. IDs already below
+// the floor are only reported if the prune would delete more of them. Output
+// in which no revision can be read is an error, so an unexpected list format
+// never lets a prune past the floor.
+func revisionFloorViolations(listOutput string, pruneArgs []string, minRevisions int, now time.Time) ([]string, error) {
+	rules, err := config.ParseKeepRules(strings.Join(pruneArgs, " "))
+	if err != nil {
+		return nil, err
+	}
+
+	times := revisionTimes(listOutput)
+	if len(times) == 0 && strings.TrimSpace(listOutput) != "" {
+		return nil, fmt.Errorf("no revisions found in list output; refusing to prune without the min revisions check")
+	}
+
+	var violations []string
+	for id, created := range times {
+		kept := config.KeptRevisions(rules, created, now)
+		if kept < minRevisions && kept < len(created) {
+			violations = append(violations, fmt.Sprintf("%s would keep %d of %d", id, kept, len(created)))
+		}
+	}
+	sort.Strings(violations)
+	return violations, nil
+}
+
+// argValue returns the value following flag in args, or "" if it is absent
+func argValue(args []string, flag string) string {
+	for i := 0; i+1 < len(args); i++ {
+		if args[i] == flag {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// pruneCmdArgs builds the duplicacy prune arguments for the prune command
+func pruneCmdArgs(storage, options string, threads int) []string {
+	args := []string{"prune", "-storage", storage}
+	args = append(args, strings.Fields(options)...)
+	return append(args, threadsArgs(threads)...)
+}
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// dailyListOutput returns duplicacy list output with one revision per day for
+// each ID, the newest created at now
+func dailyListOutput(now time.Time, days int, ids ...string) string {
+	var b strings.Builder
+	for _, id := range ids {
+		for age := days - 1; age >= 0; age-- {
+			created := now.AddDate(0, 0, -age).Format(snapshotTimeLayout)
+			fmt.Fprintf(&b, "Snapshot %s revision %d created at %s -hash\n", id, days-age, created)
+		}
+	}
+	return b.String()
+}
+
+func TestRevisionFloorViolations(t *testing.T) {
+	now := time.Date(2025, 3, 1, 6, 0, 0, 0, time.Local)
+	listOutput := dailyListOutput(now, 10, "appdata", "photos")
+
+	tests := []struct {
+		name     string
+		args     string
+		min      int
+		expected []string
+	}{
+		{"floor met", "prune -storage NAS -keep 0:7 -a", 7, nil},
+		{"floor violated", "prune -storage NAS -keep 0:7 -a", 8, []string{"appdata would keep 7 of 10", "photos would keep 7 of 10"}},
+		{"nothing deleted", "prune -storage NAS -keep 0:30 -a", 20, nil},
+		{"keeps everything daily", "prune -storage NAS -keep 1:1 -a", 10, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := revisionFloorViolations(listOutput, strings.Fields(tt.args), tt.min, now)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRevisionFloorViolations_LogPrefixed(t *testing.T) {
+	now := time.Date(2025, 3, 1, 6, 0, 0, 0, time.Local)
+	var b strings.Builder
+	for _, line := range strings.SplitAfter(strings.TrimSuffix(dailyListOutput(now, 3, "app"), "\n"), "\n") {
+		b.WriteString("2025-03-01 06:00:00.123 INFO SNAPSHOT_INFO " + line)
+	}
+
+	got, err := revisionFloorViolations(b.String(), strings.Fields("prune -storage NAS -keep 0:1 -a"), 2, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, []string{"app would keep 1 of 3"}) {
+		t.Errorf("expected the -log prefixed revisions to be read, got %v", got)
+	}
+}
+
+func TestRevisionFloorViolations_Unparseable(t *testing.T) {
+	now := time.Date(2025, 3, 1, 6, 0, 0, 0, time.Local)
+	args := strings.Fields("prune -storage NAS -keep 0:7 -a")
+
+	if _, err := revisionFloorViolations("Storage set to /nas\nsomething unexpected\n", args, 2, now); err == nil {
+		t.Error("expected an error when no revision can be read")
+	}
+	if got, err := revisionFloorViolations("", args, 2, now); err != nil || got != nil {
+		t.Errorf("expected no violations for empty output, got %v, %v", got, err)
+	}
+}
+
+func TestCheckRevisionFloor(t *testing.T) {
+	now := time.Date(2025, 3, 1, 6, 0, 0, 0, time.Local)
+	pruneAll := []string{"prune", "-storage", "NAS", "-keep", "0:7", "-a"}
+	pruneID := []string{"prune", "-storage", "NAS", "-id", "appdata", "-keep", "0:7"}
+
+	t.Run("disabled skips listing", func(t *testing.T) {
+		fake := &fakeRunner{}
+		if err := checkRevisionFloor(fake, "NAS", pruneAll, 0, now); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if len(fake.calls) != 0 {
+			t.Errorf("expected no commands, got %v", fake.commands())
+		}
+	})
+
+	t.Run("lists all IDs", func(t *testing.T) {
+		fake := &fakeRunner{output: dailyListOutput(now, 10, "appdata")}
+		err := checkRevisionFloor(fake, "NAS", pruneAll, 8, now)
+		if err == nil || !strings.Contains(err.Error(), "appdata would keep 7 of 10") {
+			t.Errorf("expected floor error, got %v", err)
+		}
+		if cmds := fake.commands(); len(cmds) != 1 || cmds[0] != "list -storage NAS -a" {
+			t.Errorf("unexpected commands: %v", cmds)
+		}
+	})
+
+	t.Run("lists one ID", func(t *testing.T) {
+		fake := &fakeRunner{output: dailyListOutput(now, 10, "appdata")}
+		if err := checkRevisionFloor(fake, "NAS", pruneID, 5, now); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if cmds := fake.commands(); len(cmds) != 1 || cmds[0] != "list -storage NAS -id appdata" {
+			t.Errorf("unexpected commands: %v", cmds)
+		}
+	})
+
+	t.Run("list failure refuses", func(t *testing.T) {
+		fake := &fakeRunner{errs: map[string]error{"list NAS": errors.New("exit status 1")}}
+		if err := checkRevisionFloor(fake, "NAS", pruneAll, 5, now); err == nil {
+			t.Error("expected error when revisions cannot be listed")
+		}
+	})
+}
+
+func TestPruneCmdArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		threads  int
+		expected string
+	}{
+		{"default", 1, "prune -storage NAS -keep 0:180 -keep 7:14 -keep 1:1 -a"},
+		{"threads", 4, "prune -storage NAS -keep 0:180 -keep 7:14 -keep 1:1 -a -threads 4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := strings.Join(pruneCmdArgs("NAS", "-keep 0:180 -keep 7:14 -keep 1:1 -a", tt.threads), " ")
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -1,8 +1,14 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/lioreshai/duplicaci/internal/config"
+	"github.com/lioreshai/duplicaci/internal/executor"
 	"github.com/spf13/cobra"
 )
 
@@ -13,8 +19,17 @@ var (
 
 	// Global flags
 	configFile string
+	configDir  string
 	dryRun     bool
 	verbose    bool
+	verbosity  int
+	strict     bool
+	noNotify   bool
+
+	noRepoCheck bool
+
+	envFile         string
+	envFileOverride bool
 )
 
 // SetVersionInfo sets version information from main
@@ -24,6 +39,18 @@ func SetVersionInfo(version, commit, date string) {
 	dateStr = date
 }
 
+// generatedBy identifies this build in the stats entries it writes, e.g.
+// "duplicaci 1.4.0 (abc1234)"; "" before SetVersionInfo
+func generatedBy() string {
+	if versionStr == "" {
+		return ""
+	}
+	if commitStr == "" || commitStr == "none" {
+		return "duplicaci " + versionStr
+	}
+	return fmt.Sprintf("duplicaci %s (%s)", versionStr, commitStr)
+}
+
 var rootCmd = &cobra.Command{
 	Use:   "duplicaci",
 	Short: "Duplicacy + CI - Run Duplicacy backups from CI/CD pipelines",
@@ -32,6 +59,22 @@ from CI/CD systems like GitHub Actions, Forgejo Actions, or cron.
 
 It supports running Duplicacy commands locally, via SSH, or inside
 Docker containers, with optional failure notifications via issue creation.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		verbose = verbosity > 0
+		colorEnabled = useColor(noColor, os.Stdout)
+
+		switch sshPasswordMode {
+		case "", executor.SSHPasswordArg, executor.SSHPasswordFile:
+		default:
+			return fmt.Errorf("--ssh-password-mode must be %q or %q, got %q", executor.SSHPasswordArg, executor.SSHPasswordFile, sshPasswordMode)
+		}
+
+		// Load the env file before any command reads SSH/storage/Forgejo secrets
+		if envFile != "" {
+			return loadEnvFile(envFile, envFileOverride)
+		}
+		return nil
+	},
 }
 
 var versionCmd = &cobra.Command{
@@ -46,8 +89,15 @@ var versionCmd = &cobra.Command{
 
 func init() {
 	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "", "Config file path")
+	rootCmd.PersistentFlags().StringVar(&configDir, "config-dir", "", "Directory of *.yaml config fragments to merge (alternative to --config)")
 	rootCmd.PersistentFlags().BoolVarP(&dryRun, "dry-run", "n", false, "Print commands without executing")
-	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Verbose output")
+	rootCmd.PersistentFlags().BoolVar(&strict, "strict", false, "Treat config problems that are normally warnings (e.g., deprecated fields) as errors")
+	rootCmd.PersistentFlags().StringVar(&envFile, "env-file", "", "Load KEY=VALUE lines from this file into the environment (existing variables win)")
+	rootCmd.PersistentFlags().BoolVar(&envFileOverride, "env-file-override", false, "Let --env-file values replace variables that are already set")
+	rootCmd.PersistentFlags().BoolVar(&noNotify, "no-notify", false, "Never send failure notifications, even when they are configured")
+	rootCmd.PersistentFlags().BoolVar(&noRepoCheck, "no-repo-check", false, "Skip checking that the working directory contains a .duplicacy folder before running duplicacy")
+	rootCmd.PersistentFlags().CountVarP(&verbosity, "verbose", "v", "Verbose output (-vv also enables duplicacy debug logging)")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Never color OK/ERROR/WARNING labels (also NO_COLOR; off when stdout is not a terminal)")
 
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(backupCmd)
@@ -55,7 +105,123 @@ func init() {
 	rootCmd.AddCommand(pruneCmd)
 }
 
-// Execute runs the root command
+// configSpecified reports whether --config or --config-dir was given
+func configSpecified() bool {
+	return configFile != "" || configDir != ""
+}
+
+// loadConfig loads the config from --config-dir or --config, reports
+// deprecated fields (as an error under --strict) and resolves *_command secrets
+func loadConfig() (*config.Config, error) {
+	cfg, err := loadConfigFiles()
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.ResolveSecrets(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadConfigFiles loads the config like loadConfig but leaves *_command
+// secret sources unresolved
+func loadConfigFiles() (*config.Config, error) {
+	if configFile != "" && configDir != "" {
+		return nil, fmt.Errorf("--config and --config-dir are mutually exclusive")
+	}
+
+	var cfg *config.Config
+	var err error
+	if configDir != "" {
+		cfg, err = config.LoadDir(configDir)
+	} else {
+		cfg, err = config.Load(configFile)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if strict {
+		if err := cfg.CheckStrict(); err != nil {
+			return nil, err
+		}
+	} else if warning := cfg.DeprecationWarning(); warning != "" {
+		printWarning("", "%s", warning)
+	}
+
+	return cfg, nil
+}
+
+// containerResolver looks up the running container for a Compose or Swarm service
+type containerResolver interface {
+	ResolveContainer(service string) (string, error)
+}
+
+// resolveContainer sets connection.container to the running container for
+// connection.compose_service, keeping the literal container when it is unset
+func resolveContainer(cfg *config.Config, resolver containerResolver) error {
+	if cfg.Connection.ComposeService == "" {
+		return nil
+	}
+	name, err := resolver.ResolveContainer(cfg.Connection.ComposeService)
+	if err != nil {
+		return err
+	}
+	cfg.Connection.Container = name
+	return nil
+}
+
+// hostResolver returns an executor that runs docker ps on the Docker host
+func hostResolver(ctx context.Context, cfg *config.Config, sshPassword string) containerResolver {
+	return executor.New(executor.Options{
+		Context:         ctx,
+		DryRun:          dryRun,
+		Verbose:         verbose,
+		SSHHost:         cfg.Connection.Host,
+		SSHPassword:     sshPassword,
+		SSHPasswordMode: cfg.Connection.SSHPasswordMode,
+	})
+}
+
+// duplicacyGlobalOptions returns the duplicacy global options implied by the
+// CLI flags, followed by the configured ones the flags don't already add.
+// Only the flag-implied options are deduplicated, so option values and
+// repeated options such as -suppress are kept.
+func duplicacyGlobalOptions(configured ...string) []string {
+	var opts []string
+	if jsonLogs {
+		opts = append(opts, "-log")
+	}
+	if verbosity >= 2 {
+		opts = append(opts, "-d")
+	}
+	implied := len(opts)
+	for _, opt := range configured {
+		seen := false
+		for _, o := range opts[:implied] {
+			seen = seen || o == opt
+		}
+		if !seen {
+			opts = append(opts, opt)
+		}
+	}
+	return opts
+}
+
+// Execute runs the root command. SIGINT/SIGTERM cancel the command's context,
+// which kills running duplicacy processes and skips remaining work.
 func Execute() error {
-	return rootCmd.Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return interruptedError(ctx, rootCmd.ExecuteContext(ctx))
+}
+
+// interruptedError gives a command error the interrupted exit code when the
+// context was cancelled by a signal
+func interruptedError(ctx context.Context, err error) error {
+	if err == nil || ctx.Err() == nil || ExitCode(err) != exitFailure {
+		return err
+	}
+	return &exitError{code: exitInterrupted, err: fmt.Errorf("interrupted: %w", err)}
 }
@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/lioreshai/duplicaci/internal/config"
+)
+
+func TestDuplicacyGlobalOptions_Verbosity(t *testing.T) {
+	defer func() { verbosity = 0 }()
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected []string
+	}{
+		{name: "no flag", args: []string{}, expected: nil},
+		{name: "-v", args: []string{"-v"}, expected: nil},
+		{name: "-vv", args: []string{"-vv"}, expected: []string{"-d"}},
+		{name: "repeated --verbose", args: []string{"--verbose", "--verbose"}, expected: []string{"-d"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verbosity = 0
+			if err := rootCmd.PersistentFlags().Parse(tt.args); err != nil {
+				t.Fatalf("failed to parse flags: %v", err)
+			}
+
+			got := duplicacyGlobalOptions()
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("duplicacyGlobalOptions() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDuplicacyGlobalOptions_JSONLogs(t *testing.T) {
+	defer func() {
+		jsonLogs = false
+		verbosity = 0
+	}()
+	jsonLogs = true
+
+	verbosity = 0
+	if got := duplicacyGlobalOptions(); !reflect.DeepEqual(got, []string{"-log"}) {
+		t.Errorf("duplicacyGlobalOptions() = %v, want [-log]", got)
+	}
+	verbosity = 2
+	if got := duplicacyGlobalOptions(); !reflect.DeepEqual(got, []string{"-log", "-d"}) {
+		t.Errorf("duplicacyGlobalOptions() = %v, want [-log -d]", got)
+	}
+}
+
+func TestDuplicacyGlobalOptions_Configured(t *testing.T) {
+	defer func() { jsonLogs = false }()
+	jsonLogs = true
+
+	got := duplicacyGlobalOptions("-profile", "localhost:6060", "-log", "-suppress", "A", "-suppress", "B")
+	want := []string{"-log", "-profile", "localhost:6060", "-suppress", "A", "-suppress", "B"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("duplicacyGlobalOptions() = %v, want %v", got, want)
+	}
+}
+
+func TestLoadConfig_MutuallyExclusive(t *testing.T) {
+	defer func() {
+		configFile = ""
+		configDir = ""
+	}()
+	configFile = "duplicaci.yaml"
+	configDir = "conf.d"
+
+	if _, err := loadConfig(); err == nil {
+		t.Error("expected error when both --config and --config-dir are set")
+	}
+}
+
+func TestLoadConfig_Dir(t *testing.T) {
+	defer func() { configDir = "" }()
+
+	dir := t.TempDir()
+	fragment := "backups:\n  - name: appdata\n    destinations: [NAS]\n"
+	if err := os.WriteFile(filepath.Join(dir, "appdata.yaml"), []byte(fragment), 0644); err != nil {
+		t.Fatalf("failed to write fragment: %v", err)
+	}
+	configDir = dir
+
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig() error: %v", err)
+	}
+	if len(cfg.Backups) != 1 || cfg.Backups[0].Name != "appdata" {
+		t.Errorf("expected appdata backup from fragment, got %+v", cfg.Backups)
+	}
+}
+
+func TestLoadConfig_Strict(t *testing.T) {
+	defer func() {
+		configFile = ""
+		strict = false
+	}()
+
+	configFile = filepath.Join(t.TempDir(), "legacy.yaml")
+	legacy := "ssh:\n  host: root@host\nrepositories:\n  - id: repo\n"
+	if err := os.WriteFile(configFile, []byte(legacy), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	strict = false
+	if _, err := loadConfig(); err != nil {
+		t.Errorf("legacy config should only warn without --strict: %v", err)
+	}
+
+	strict = true
+	if _, err := loadConfig(); err == nil {
+		t.Error("expected legacy config to fail under --strict")
+	}
+}
+
+type fakeResolver struct {
+	name     string
+	err      error
+	services []string
+}
+
+func (f *fakeResolver) ResolveContainer(service string) (string, error) {
+	f.services = append(f.services, service)
+	return f.name, f.err
+}
+
+func TestResolveContainer(t *testing.T) {
+	tests := []struct {
+		name      string
+		conn      config.ConnectionConfig
+		resolver  *fakeResolver
+		container string
+		lookups   int
+		wantErr   bool
+	}{
+		{
+			name:      "no compose service keeps the literal container",
+			conn:      config.ConnectionConfig{Container: "Duplicacy"},
+			resolver:  &fakeResolver{name: "ignored"},
+			container: "Duplicacy",
+		},
+		{
+			name:      "compose service replaces the container",
+			conn:      config.ConnectionConfig{Container: "Duplicacy", ComposeService: "duplicacy"},
+			resolver:  &fakeResolver{name: "stack_duplicacy_1"},
+			container: "stack_duplicacy_1",
+			lookups:   1,
+		},
+		{
+			name:      "lookup failure",
+			conn:      config.ConnectionConfig{Container: "Duplicacy", ComposeService: "duplicacy"},
+			resolver:  &fakeResolver{err: errors.New("no running container")},
+			container: "Duplicacy",
+			lookups:   1,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Connection: tt.conn}
+			err := resolveContainer(cfg, tt.resolver)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveContainer() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if cfg.Connection.Container != tt.container {
+				t.Errorf("expected container %q, got %q", tt.container, cfg.Connection.Container)
+			}
+			if len(tt.resolver.services) != tt.lookups {
+				t.Errorf("expected %d lookup(s), got %v", tt.lookups, tt.resolver.services)
+			}
+		})
+	}
+}
+
+func TestGeneratedBy(t *testing.T) {
+	defer SetVersionInfo("", "", "")
+
+	tests := []struct {
+		version  string
+		commit   string
+		expected string
+	}{
+		{"1.4.0", "abc1234", "duplicaci 1.4.0 (abc1234)"},
+		{"dev", "none", "duplicaci dev"},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		SetVersionInfo(tt.version, tt.commit, "")
+		if got := generatedBy(); got != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, got)
+		}
+	}
+}
@@ -1,21 +1,43 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/lioreshai/duplicaci/internal/config"
 	"github.com/lioreshai/duplicaci/internal/executor"
 	"github.com/lioreshai/duplicaci/internal/notifier"
 	"github.com/lioreshai/duplicaci/internal/stats"
+	"github.com/lioreshai/duplicaci/internal/summary"
 	"github.com/spf13/cobra"
 )
 
+var (
+	// Run flags
+	summaryFile         string
+	maxParallelStorages int
+	explain             bool
+	listOperations      bool
+	deadline            time.Duration
+	summaryOnly         bool
+	onlyPhases          []string
+	outputFormat        string
+)
+
+// runPhases are the run phases --only can select, in run order
+var runPhases = []string{"backup", "check", "prune"}
+
 var runCmd = &cobra.Command{
 	Use:   "run",
 	Short: "Run all backups defined in config file",
-	Long: `Run all backup, prune, and check operations defined in the configuration file.
+	Long: `Run all backup, check, and prune operations defined in the configuration file.
 
 This is the recommended way to use duplicaci. Define your backups in a YAML config
 file and let duplicaci handle the orchestration.
@@ -47,17 +69,115 @@ Then run: duplicaci run --config duplicaci.yaml`,
 }
 
 func init() {
+	runCmd.Flags().StringVar(&summaryFile, "summary-file", "", "Write a JSON summary of the run to this local file (written even on failure)")
+	runCmd.Flags().BoolVar(&verifyChunks, "chunks", false, "Download and verify every chunk during check (slow: reads the entire storage)")
+	runCmd.Flags().BoolVar(&persist, "persist", false, "Keep checking past missing/corrupt chunks and report them all")
+	runCmd.Flags().IntVar(&maxParallelStorages, "max-parallel-storages", 1, "Maximum number of storages to prune/check concurrently")
+	runCmd.Flags().BoolVar(&jsonLogs, "json-logs", false, "Run duplicacy with -log: stats come from JSON log events when present, else from the timestamp-prefixed log-style check output")
+	runCmd.Flags().BoolVar(&summaryOnly, "summary-only", false, "Print only the per-storage stats summary instead of the raw check -tabular output (still printed when a check fails)")
+	runCmd.Flags().BoolVar(&noStats, "no-stats", false, "Run checks without writing Web UI stats")
+	runCmd.Flags().StringVar(&checkOutputDir, "output-dir", "", "Save each storage's raw check output to <dir>/<storage>-<date>.txt")
+	runCmd.Flags().BoolVar(&explain, "explain", false, "Print the resolved retention and prune command for each storage/backup, then exit")
+	runCmd.Flags().BoolVar(&listOperations, "list-operations", false, "Print every hook and duplicacy command the run would execute, from the config alone (no SSH or Docker), then exit")
+	runCmd.Flags().StringVar(&outputFormat, "output", "text", "Output format: text, or json to print the run summary as JSON on stdout (progress goes to stderr)")
+	runCmd.Flags().DurationVar(&deadline, "deadline", 0, "Bound the whole run, e.g. 6h: in-flight operations are cancelled and remaining phases skipped (overrides connection.run_deadline)")
+	runCmd.Flags().StringSliceVar(&onlyPhases, "only", nil, "Run only these phases (backup, check, prune), e.g. --only check,prune")
+
 	rootCmd.AddCommand(runCmd)
 }
 
-func runAllBackups(cmd *cobra.Command, args []string) error {
+// duplicacyRunner is the part of the executor used by the run phases
+type duplicacyRunner interface {
+	RunDuplicacyWithStorage(storageName string, args ...string) error
+	RunDuplicacyStreamWithStorage(storageName string, args ...string) (executor.Result, error)
+	RunDuplicacyCaptureWithStorage(storageName string, args ...string) (string, error)
+	RunShell(command string) error
+	DiscoverCacheDir(backupName string) (string, error)
+	ReadPreferences(dir string) ([]executor.Preference, error)
+}
+
+// statsUpdater is the part of the stats writer used by the check phase
+type statsUpdater interface {
+	Update(storage string, dayStats *stats.DayStats) (stats.UpdateResult, error)
+}
+
+// runner holds the state shared by the phases of a run.
+// Result recording is guarded so storages can be processed concurrently.
+type runner struct {
+	ctx              context.Context // cancelled on SIGINT/SIGTERM or at the run deadline; nil never cancels
+	cfg              *config.Config
+	sshPassword      string
+	storagePassword  string
+	storagePasswords map[string]string // per duplicacy storage name, from the keyring
+	summary          *summary.RunSummary
+	statsWriter      statsUpdater
+	lockHeld         bool            // the run holds lock_file, so no other duplicaci run can back up concurrently
+	phases           map[string]bool // phases selected with --only; nil runs all
+
+	// newRunner creates the executor for a set of options (tests substitute a fake)
+	newRunner func(opts executor.Options) duplicacyRunner
+
+	mu            sync.Mutex
+	cacheDirs     map[string]string // resolved cache dir per backup name
+	checkFailed   map[string]bool   // storages whose check failed, so the prune phase leaves them alone
+	repositories  map[string]string // repository path detected per backup name
+	errors        []string
+	warnings      []string
+	failedBackups []string
+	failureLogs   []failureLog // output tails of failed duplicacy commands, for the issue body
+	operations    int          // duplicacy invocations attempted
+	notFound      int          // invocations that failed because duplicacy could not be found
+}
+
+func runAllBackups(cmd *cobra.Command, args []string) (err error) {
+	r := &runner{ctx: cmd.Context(), summary: summary.New(time.Now())}
+
+	if outputFormat != "text" && outputFormat != "json" {
+		return fmt.Errorf("--output must be \"text\" or \"json\", got %q", outputFormat)
+	}
+	jsonOutput := outputFormat == "json"
+
+	// With --output json, stdout carries only the summary document: everything
+	// the run prints (banners, duplicacy output) goes to stderr instead
+	stdout := os.Stdout
+	if jsonOutput {
+		os.Stdout = os.Stderr
+	}
+
+	// Write the summary on every exit path so failing jobs still produce the artifact
+	defer func() {
+		os.Stdout = stdout
+		if summaryFile == "" && !jsonOutput {
+			return
+		}
+		summaryErrors := r.errors
+		if len(summaryErrors) == 0 && err != nil {
+			summaryErrors = []string{err.Error()}
+		}
+		r.summary.Finish(time.Now(), summaryErrors)
+		if summaryFile != "" {
+			if writeErr := r.summary.WriteFile(summaryFile); writeErr != nil {
+				printWarning("", "%v", writeErr)
+			}
+		}
+		if jsonOutput {
+			if writeErr := r.summary.WriteJSON(os.Stdout); writeErr != nil {
+				printWarning("", "%v", writeErr)
+			}
+		}
+	}()
+
 	// Config file is required for run command
-	if configFile == "" {
-		return fmt.Errorf("--config is required for the run command")
+	if !configSpecified() {
+		return fmt.Errorf("--config or --config-dir is required for the run command")
+	}
+
+	if r.phases, err = parsePhases(onlyPhases); err != nil {
+		return err
 	}
 
 	// Load config
-	cfg, err := config.Load(configFile)
+	cfg, err := loadConfig()
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
@@ -67,259 +187,1146 @@ func runAllBackups(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("invalid config: %w", err)
 	}
 
-	// Get credentials from environment
-	sshPassword := os.Getenv("SSH_PASSWORD")
-	storagePassword := os.Getenv("DUPLICACY_PASSWORD")
+	r.cfg = cfg
+
+	if explain {
+		explainRetention(os.Stdout, cfg)
+		return nil
+	}
+
+	if listOperations {
+		printOperations(os.Stdout, planOperations(cfg, r.phases))
+		return nil
+	}
+
+	limit, err := runDeadline(cfg)
+	if err != nil {
+		return err
+	}
+	if limit > 0 {
+		parent := r.ctx
+		if parent == nil {
+			parent = context.Background()
+		}
+		var cancel context.CancelFunc
+		r.ctx, cancel = context.WithTimeout(parent, limit)
+		defer cancel()
+	}
+
+	if cfg.LockFile != "" {
+		release, err := acquireLock(cfg.LockFile)
+		if err != nil {
+			r.addError(err.Error())
+			return err
+		}
+		defer release()
+		r.lockHeld = true
+	}
+
+	// Get credentials from *_password_command or the environment
+	r.sshPassword = cfg.Connection.SSHPassword()
+	r.storagePassword = cfg.Connection.StoragePassword()
 
-	// Track all errors
-	var allErrors []string
-	var failedBackups []string
+	if err := resolveContainer(cfg, hostResolver(r.ctx, cfg, r.sshPassword)); err != nil {
+		r.addError(err.Error())
+		return err
+	}
+
+	// Create stats writer for updating Duplicacy Web UI stats
+	if cfg.Connection.Container != "" {
+		w := stats.NewWriter(cfg.Connection.Host, r.sshPassword, cfg.Connection.Container)
+		w.ContainerUser = cfg.Connection.ContainerUser
+		w.SSHPasswordMode = cfg.Connection.SSHPasswordMode
+		w.Location, _ = cfg.Stats.Location() // validated above
+		w.RefuseStale = cfg.Stats.RefuseStale
+		w.MergeSameDay = cfg.Stats.MergeSameDay
+		w.SizeFormat = cfg.Stats.Format
+		w.GeneratedBy = generatedBy()
+		w.DryRun = dryRun
+		w.Verbose = verbose
+		r.statsWriter = w
+
+		if cfg.Connection.KeyringPath != "" {
+			if r.storagePasswords, err = loadKeyring(w, cfg); err != nil {
+				r.addError(err.Error())
+				return err
+			}
+		}
+	}
+
+	if r.runsPhase("backup") {
+		if err := r.checkCredentials(); err != nil {
+			r.addError(err.Error())
+			return err
+		}
+	}
+
+	r.execute()
+
+	// Summary
+	fmt.Println("\n==========================================")
+	fmt.Println("Summary")
+	fmt.Println("==========================================")
+	printOperationCounts(r.summary)
+
+	if len(r.warnings) > 0 {
+		fmt.Printf("\n%d warning(s):\n", len(r.warnings))
+		for _, w := range r.warnings {
+			fmt.Printf("  - %s\n", w)
+		}
+		if cfg.Stats.GrowthAlertNotify && forgejoConfigured(cfg) {
+			if err := sendGrowthAlertNotification(cfg, r.warnings); err != nil {
+				printWarning("\n", "Failed to create issue: %v", err)
+			}
+		}
+	}
+
+	if len(r.errors) == 0 {
+		fmt.Println("All operations completed successfully")
+		return nil
+	}
+
+	// Report errors
+	if r.duplicacyMissing() {
+		fmt.Fprintf(os.Stderr, "\nduplicacy could not be found for any storage; check connection.container and the duplicacy install\n")
+	}
+	fmt.Printf("\n%d error(s) occurred:\n", len(r.errors))
+	for _, e := range r.errors {
+		fmt.Printf("  - %s\n", e)
+	}
+
+	// Send notification if configured
+	if forgejoConfigured(cfg) {
+		if err := sendRunFailureNotification(cfg, r.errors, r.failedBackups, r.failureLogs); err != nil {
+			printWarning("\n", "Failed to create issue: %v", err)
+		}
+	}
+
+	if r.duplicacyMissing() {
+		return &exitError{
+			code: exitDuplicacyNotFound,
+			err:  fmt.Errorf("%w for any storage", executor.ErrDuplicacyNotFound),
+		}
+	}
+
+	return fmt.Errorf("completed with %d error(s)", len(r.errors))
+}
+
+// printOperationCounts prints how many operations succeeded, were skipped
+// (e.g. a backup with nothing to back up) and failed, listing the skipped ones
+func printOperationCounts(s *summary.RunSummary) {
+	counts := s.CountOperations()
+	if counts.Total() == 0 {
+		return
+	}
+	fmt.Printf("\nOperations: %d succeeded, %d skipped/unchanged, %d failed\n", counts.Succeeded, counts.Skipped, counts.Failed)
+	for _, op := range s.SkippedOperations() {
+		target := op.Storage
+		if op.Backup != "" {
+			target = op.Backup + " -> " + op.Storage
+		}
+		fmt.Printf("  - %s: skipped (%s)\n", target, op.Reason)
+	}
+}
+
+// execute runs the hooks and phases in order: pre-hook, known hosts, backup,
+// check, prune, post-hook. A failing pre-hook aborts the run; a failing
+// post-hook is only recorded. Checking before pruning means a storage whose
+// check fails is not pruned on the strength of data that may be corrupt.
+func (r *runner) execute() {
+	if r.cfg.Hooks.Pre != "" {
+		if err := r.runHook("pre-run", r.cfg.Hooks.Pre); err != nil {
+			r.addError(fmt.Sprintf("pre-run hook: %v", err))
+			printError("    ", "%v", err)
+			fmt.Fprintf(os.Stderr, "Aborting run: pre-run hook failed\n")
+			return
+		}
+	}
+
+	r.addKnownHosts()
 
 	// Phase 1: Run backups
+	if r.runsPhase("backup") {
+		r.runBackupPhase()
+		if r.stopIfInterrupted() {
+			return
+		}
+	}
+
+	allStorages := r.cfg.AllStorages()
+
+	// Check/prune run in maintenance_dir, else the first backup's cache dir
+	maintenanceCacheDir := r.cfg.MaintenanceDir
+	if maintenanceCacheDir == "" && len(r.cfg.Backups) > 0 {
+		maintenanceCacheDir = r.cacheDirFor(r.cfg.Backups[0])
+	}
+
+	maintenanceExec := r.newExecutor(maintenanceCacheDir)
+
+	// Phase 2: Check all storages
+	if r.runsPhase("check") {
+		r.runCheckPhase(maintenanceExec, r.checkStatsWriter(), allStorages)
+		if r.stopIfInterrupted() {
+			return
+		}
+	}
+
+	// Phase 3: Prune all storages that passed their check
+	if r.runsPhase("prune") {
+		r.runPrunePhase(maintenanceExec, allStorages)
+		if r.stopIfInterrupted() {
+			return
+		}
+	}
+
+	if r.cfg.Hooks.Post != "" {
+		if err := r.runHook("post-run", r.cfg.Hooks.Post); err != nil {
+			r.addError(fmt.Sprintf("post-run hook: %v", err))
+			printError("    ", "%v", err)
+		}
+	}
+}
+
+// parsePhases turns --only values into a phase set; an empty list selects
+// every phase (nil)
+func parsePhases(names []string) (map[string]bool, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	phases := make(map[string]bool)
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		known := false
+		for _, p := range runPhases {
+			known = known || p == name
+		}
+		if !known {
+			return nil, fmt.Errorf("--only: unknown phase %q (want %s)", name, strings.Join(runPhases, ", "))
+		}
+		phases[name] = true
+	}
+	return phases, nil
+}
+
+// runsPhase reports whether the run includes phase
+func (r *runner) runsPhase(phase string) bool {
+	return r.phases == nil || r.phases[phase]
+}
+
+// checkStatsWriter returns the stats writer for the check phase, or nil with
+// --no-stats. A nil writer skips only the Web UI stats update: check output is
+// still parsed (and held to stats.strict_parse), summarized and recorded.
+func (r *runner) checkStatsWriter() statsUpdater {
+	if noStats {
+		return nil
+	}
+	return r.statsWriter
+}
+
+// statsDate returns today's date key in the stats.timezone zone
+func (r *runner) statsDate() string {
+	loc, err := r.cfg.Stats.Location()
+	if err != nil {
+		loc = time.Local
+	}
+	return stats.TodayDateIn(loc)
+}
+
+// runDeadline returns the limit on the whole run: --deadline, else
+// connection.run_deadline (zero for none)
+func runDeadline(cfg *config.Config) (time.Duration, error) {
+	if deadline < 0 {
+		return 0, fmt.Errorf("--deadline must be positive, got %s", deadline)
+	}
+	if deadline > 0 {
+		return deadline, nil
+	}
+	return cfg.Connection.Deadline()
+}
+
+// interrupted reports whether the run has been cancelled by a signal or its deadline
+func (r *runner) interrupted() bool {
+	return r.ctx != nil && r.ctx.Err() != nil
+}
+
+// stopIfInterrupted records that the remaining phases were skipped and
+// reports true once the run has been cancelled
+func (r *runner) stopIfInterrupted() bool {
+	if !r.interrupted() {
+		return false
+	}
+	if errors.Is(r.ctx.Err(), context.DeadlineExceeded) {
+		r.addError("run deadline exceeded; remaining phases skipped")
+		fmt.Fprintf(os.Stderr, "\nRun deadline exceeded: skipping remaining phases\n")
+		return true
+	}
+	r.addError("run interrupted; remaining phases skipped")
+	fmt.Fprintf(os.Stderr, "\nInterrupted: skipping remaining phases\n")
+	return true
+}
+
+// checkCredentials reports backup destinations duplicacy would have no
+// credentials for before anything runs: as warnings, or as an error under --strict
+func (r *runner) checkCredentials() error {
+	missing := r.cfg.MissingCredentials(r.storagePasswords)
+
+	// gcd storages without a token in their env share connection.gcd_token,
+	// which must exist where duplicacy runs
+	var needToken []string
+	seen := make(map[string]bool)
+	for _, b := range r.cfg.Backups {
+		for _, dest := range r.cfg.Destinations(b) {
+			sc := r.cfg.GetStorageConfig(dest)
+			tokenEnv := config.CredentialEnvName(r.cfg.StorageName(dest), "GCD_TOKEN")
+			if !seen[dest] && sc.Type == config.StorageTypeGCD && sc.Env[tokenEnv] == "" {
+				needToken = append(needToken, dest)
+			}
+			seen[dest] = true
+		}
+	}
+	if token := r.cfg.Connection.GCDToken; token != "" && len(needToken) > 0 {
+		if err := r.newExecutor("").RunShell("test -f " + executor.ShellQuote(token)); err != nil {
+			missing = append(missing, fmt.Sprintf("%s: Google Drive token %s not found", strings.Join(needToken, ", "), token))
+		}
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+	if strict {
+		return fmt.Errorf("missing credentials: %s", strings.Join(missing, "; "))
+	}
+	for _, msg := range missing {
+		printWarning("", "%s", msg)
+		r.addWarning(msg)
+	}
+	return nil
+}
+
+// runHook runs a hook command locally, or in the container when hooks.in_container is set
+func (r *runner) runHook(name, command string) error {
+	fmt.Println("\n==========================================")
+	fmt.Printf("Hook: %s\n", name)
+	fmt.Println("==========================================")
+
+	var hookExec duplicacyRunner
+	if r.cfg.Hooks.InContainer {
+		hookExec = r.newExecutor("")
+	} else {
+		hookExec = r.executorFor(executor.Options{Context: r.ctx, DryRun: dryRun, Verbose: verbose})
+	}
+
+	if err := hookExec.RunShell(command); err != nil {
+		return err
+	}
+	printOK("    ", "")
+	return nil
+}
+
+// addKnownHosts adds the host keys of SFTP storages with known_host set to the
+// known_hosts duplicacy keeps in each working dir's preferences. A failure is
+// recorded; the run continues.
+func (r *runner) addKnownHosts() {
+	hosts := r.cfg.KnownHosts()
+	if len(hosts) == 0 {
+		return
+	}
+
+	fmt.Println("==========================================")
+	fmt.Println("Setup: SFTP host keys")
+	fmt.Println("==========================================")
+
+	exec := r.newExecutor("")
+	for _, dir := range r.workingDirs() {
+		for _, host := range hosts {
+			fmt.Printf("    -> %s (%s)\n", host, dir)
+			if err := exec.RunShell(knownHostCommand(host, dir)); err != nil {
+				r.addError(fmt.Sprintf("known_host %s: %v", host, err))
+				printError("       ", "%v", err)
+				continue
+			}
+			printOK("       ", "")
+		}
+	}
+}
+
+// workingDirs returns the distinct directories duplicacy runs in: each
+// backup's cache dir and maintenance_dir. Each has its own preferences and so
+// its own known_hosts.
+func (r *runner) workingDirs() []string {
+	seen := make(map[string]bool)
+	var dirs []string
+	add := func(dir string) {
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	for _, backup := range r.cfg.Backups {
+		add(r.cacheDirFor(backup))
+	}
+	if r.cfg.MaintenanceDir != "" {
+		add(r.cfg.MaintenanceDir)
+	}
+	return dirs
+}
+
+// executorFor creates a runner for the given executor options
+func (r *runner) executorFor(opts executor.Options) duplicacyRunner {
+	if r.newRunner != nil {
+		return r.newRunner(opts)
+	}
+	return executor.New(opts)
+}
+
+// newExecutor creates an executor for the configured connection
+func (r *runner) newExecutor(cacheDir string) duplicacyRunner {
+	return r.executorFor(executor.Options{
+		Context:          r.ctx,
+		DryRun:           dryRun,
+		Verbose:          verbose,
+		GlobalOptions:    duplicacyGlobalOptions(r.cfg.Connection.GlobalOptions...),
+		SkipRepoCheck:    noRepoCheck,
+		PrefixOutput:     maxParallelStorages > 1,
+		DockerContainer:  r.cfg.Connection.Container,
+		ContainerUser:    r.cfg.Connection.ContainerUser,
+		DuplicacyVersion: r.cfg.Connection.DuplicacyVersion,
+		SSHHost:          r.cfg.Connection.Host,
+		SSHPassword:      r.sshPassword,
+		SSHPasswordMode:  r.cfg.Connection.SSHPasswordMode,
+		StoragePassword:  r.storagePassword,
+		StoragePasswords: r.storagePasswords,
+		GCDToken:         r.cfg.Connection.GCDToken,
+		StorageEnv:       r.cfg.StorageEnv(),
+		CacheDir:         cacheDir,
+	})
+}
+
+// cacheDirFor returns the working directory for a backup: the configured
+// cache_dir, else the discovered Web UI cache dir, else the backup path.
+// Discovery runs at most once per backup.
+func (r *runner) cacheDirFor(backup config.BackupConfig) string {
+	if backup.CacheDir != "" {
+		return backup.CacheDir
+	}
+
+	r.mu.Lock()
+	dir, ok := r.cacheDirs[backup.Name]
+	r.mu.Unlock()
+	if ok {
+		return dir
+	}
+
+	dir, err := r.newExecutor("").DiscoverCacheDir(backup.Name)
+	if err != nil {
+		printWarning("    ", "%v (using %s)", err, backup.Path)
+	}
+	if dir == "" {
+		dir = backup.Path
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cacheDirs == nil {
+		r.cacheDirs = make(map[string]string)
+	}
+	r.cacheDirs[backup.Name] = dir
+	return dir
+}
+
+// withRepository returns backup with Repository read from its cache dir's
+// .duplicacy/preferences when detect_repository is set and repository is not.
+// Detection runs at most once per backup; without a match the backup is
+// returned unchanged.
+func (r *runner) withRepository(backup config.BackupConfig) config.BackupConfig {
+	if backup.Repository != "" || !backup.DetectRepository {
+		return backup
+	}
+
+	r.mu.Lock()
+	repo, ok := r.repositories[backup.Name]
+	r.mu.Unlock()
+	if !ok {
+		dir := r.cacheDirFor(backup)
+		prefs, err := r.newExecutor("").ReadPreferences(dir)
+		if err != nil {
+			printWarning("    ", "%v (not passing -repository)", err)
+		}
+		repo = executor.RepositoryFor(prefs, backup.Name)
+		if repo != "" && verbose {
+			fmt.Printf("    Detected repository for %s at: %s\n", backup.Name, repo)
+		}
+
+		r.mu.Lock()
+		if r.repositories == nil {
+			r.repositories = make(map[string]string)
+		}
+		r.repositories[backup.Name] = repo
+		r.mu.Unlock()
+	}
+
+	backup.Repository = repo
+	return backup
+}
+
+// fileReader reads a file through the container exec channel
+type fileReader interface {
+	ReadFile(path string) (string, error)
+}
+
+// loadKeyring reads connection.keyring_path from the container and returns the
+// passwords for the configured storages, keyed by duplicacy storage name
+func loadKeyring(reader fileReader, cfg *config.Config) (map[string]string, error) {
+	data, err := reader.ReadFile(cfg.Connection.KeyringPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load keyring: %w", err)
+	}
+	keyring, err := config.ParseKeyring([]byte(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load keyring %s: %w", cfg.Connection.KeyringPath, err)
+	}
+
+	passwords := make(map[string]string)
+	for _, storage := range cfg.AllStorages() {
+		if pw, ok := cfg.KeyringPassword(keyring, storage); ok {
+			passwords[cfg.StorageName(storage)] = pw
+		}
+	}
+	return passwords, nil
+}
+
+// recordOperation adds a finished duplicacy invocation to the phase summary
+func (r *runner) recordOperation(phase *summary.PhaseResult, backupName, storage string, start time.Time, err error) {
+	r.recordResult(phase, newOperation(backupName, storage, start, err), err)
+}
+
+// recordResult adds an operation entry for a finished duplicacy invocation
+// that failed with err, if any, to the phase summary
+func (r *runner) recordResult(phase *summary.PhaseResult, op summary.OperationResult, err error) {
+	phase.AddOperation(op)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.operations++
+	if errors.Is(err, executor.ErrDuplicacyNotFound) {
+		r.notFound++
+	}
+}
+
+// duplicacyMissing reports whether every duplicacy invocation failed because
+// the binary could not be found, which points at the environment rather than a storage
+func (r *runner) duplicacyMissing() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.operations > 0 && r.notFound == r.operations
+}
+
+// checkGrowth warns when a storage grew more than stats.growth_alert_pct since its prior entry
+func (r *runner) checkGrowth(storage string, result stats.UpdateResult, dayStats *stats.DayStats) {
+	threshold := r.cfg.Stats.GrowthAlertPct
+	if threshold <= 0 {
+		return
+	}
+	pct, ok := stats.GrowthPercent(result.Previous, dayStats)
+	if !ok || pct <= threshold {
+		return
+	}
+
+	msg := fmt.Sprintf("%s grew %.1f%% since %s (%s -> %s, alert threshold %.1f%%)",
+		storage, pct, result.PreviousDate,
+		stats.FormatBytes(result.Previous.TotalSize), stats.FormatBytes(dayStats.TotalSize), threshold)
+	printWarning("    ", "%s", msg)
+	r.addWarning(msg)
+}
+
+// reportChunkDelta prints and records how many chunks a storage gained or
+// lost since its prior stats entry; the first run has nothing to compare
+func (r *runner) reportChunkDelta(storage string, result stats.UpdateResult, dayStats *stats.DayStats) {
+	delta, ok := stats.ChunkDeltas(result.PreviousDate, result.Previous, dayStats)
+	if !ok {
+		return
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "      Chunks since %s: %+d\n", delta.Since, delta.Total)
+	names := make([]string, 0, len(delta.Repositories))
+	for name := range delta.Repositories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&b, "        - %s: %+d\n", name, delta.Repositories[name])
+	}
+	fmt.Print(b.String())
+	r.summary.SetChunkDelta(storage, delta)
+}
+
+// addWarning records a run warning
+func (r *runner) addWarning(msg string) {
+	r.mu.Lock()
+	r.warnings = append(r.warnings, msg)
+	r.mu.Unlock()
+	r.summary.AddWarning(msg)
+}
+
+// addError records a run error
+func (r *runner) addError(msg string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errors = append(r.errors, msg)
+}
+
+// addFailedBackup records a backup that failed, for the failure issue
+func (r *runner) addFailedBackup(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failedBackups = append(r.failedBackups, name)
+}
+
+// failureLog is the end of a failed command's output, labelled like its error
+type failureLog struct {
+	Label string
+	Tail  string
+}
+
+// addFailureLog records the last notifications.log_tail_lines lines of the
+// failed command's output behind err, with known secrets redacted
+func (r *runner) addFailureLog(label string, err error) {
+	tail := executor.LogTail(err, r.cfg.Notifications.TailLines())
+	if tail == "" {
+		return
+	}
+	tail = redactSecrets(tail, r.secrets())
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failureLogs = append(r.failureLogs, failureLog{Label: label, Tail: tail})
+}
+
+// secrets returns the secret values the run knows about, for redaction
+func (r *runner) secrets() []string {
+	secrets := []string{r.sshPassword, r.storagePassword, r.cfg.Notifications.Forgejo.GetToken()}
+	for _, pw := range r.storagePasswords {
+		secrets = append(secrets, pw)
+	}
+	for _, sc := range r.cfg.Storages {
+		for _, v := range sc.Env {
+			secrets = append(secrets, v)
+		}
+	}
+	return secrets
+}
+
+// redactSecrets replaces every non-empty secret in s with config.RedactedValue
+func redactSecrets(s string, secrets []string) string {
+	for _, secret := range secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, config.RedactedValue)
+		}
+	}
+	return s
+}
+
+// runBackupPhase backs up every configured backup to each of its destinations
+func (r *runner) runBackupPhase() {
 	fmt.Println("==========================================")
 	fmt.Println("Phase 1: Backups")
 	fmt.Println("==========================================")
 
-	for _, backup := range cfg.Backups {
-		fmt.Printf("\n==> Backing up '%s'\n", backup.Name)
+	phase := r.summary.StartPhase("backup")
+	defer phase.Finish()
 
-		// Determine cache directory
-		cacheDir := backup.CacheDir
-		if cacheDir == "" {
-			// Auto-discover would go here, for now require it or use path
-			cacheDir = backup.Path
+	for _, backup := range r.cfg.Backups {
+		if r.interrupted() {
+			return
 		}
+		fmt.Printf("\n==> Backing up '%s'\n", backup.Name)
 
 		// Update executor with this backup's cache dir
-		backupExec := executor.New(executor.Options{
-			DryRun:          dryRun,
-			Verbose:         verbose,
-			DockerContainer: cfg.Connection.Container,
-			SSHHost:         cfg.Connection.Host,
-			SSHPassword:     sshPassword,
-			StoragePassword: storagePassword,
-			GCDToken:        cfg.Connection.GCDToken,
-			CacheDir:        cacheDir,
-		})
+		backupExec := r.newExecutor(r.cacheDirFor(backup))
 
 		backupFailed := false
 
+		// Quiesce the source before its first destination
+		if backup.PreHook != "" {
+			fmt.Printf("    -> pre-hook\n")
+			if err := backupExec.RunShell(backup.PreHook); err != nil {
+				r.addError(fmt.Sprintf("%s pre-hook: %v (backup skipped)", backup.Name, err))
+				printError("       ", "%v", err)
+				r.addFailedBackup(backup.Name)
+				continue
+			}
+			printOK("       ", "")
+		}
+
 		// Backup to each destination
-		for _, dest := range backup.Destinations {
+		for _, dest := range r.cfg.Destinations(backup) {
+			if r.interrupted() {
+				break
+			}
 			fmt.Printf("    -> %s\n", dest)
 
-			backupArgs := []string{"backup", "-storage", dest}
-			if backup.Threads > 1 {
-				backupArgs = append(backupArgs, "-threads", fmt.Sprintf("%d", backup.Threads))
-			}
+			storageName := r.cfg.StorageName(dest)
+			opStart := time.Now()
+			// Output is streamed as it runs; the revision line is among the last printed
+			result, err := backupExec.RunDuplicacyStreamWithStorage(storageName, backupArgs(storageName, r.withRepository(backup), r.cfg.LimitRate(backup, dest))...)
 
-			err := backupExec.RunDuplicacyWithStorage(dest, backupArgs...)
+			unchanged := nothingToBackup(err)
+			err = acceptExitCode(err, r.cfg.Connection.AcceptedExitCodes())
+			unchanged = unchanged && err == nil
+			op := newOperation(backup.Name, dest, opStart, err)
+			revision, created := backupRevision(result.Tail)
+			if unchanged {
+				op.Status = summary.StatusSkipped
+				op.Reason = "unchanged: nothing to back up"
+			} else if err == nil && created {
+				op.Revision = revision
+			}
+			r.recordResult(phase, op, err)
 			if err != nil {
-				errMsg := fmt.Sprintf("%s -> %s: %v", backup.Name, dest, err)
-				allErrors = append(allErrors, errMsg)
-				fmt.Fprintf(os.Stderr, "       ERROR: %v\n", err)
+				r.addError(fmt.Sprintf("%s -> %s: %v", backup.Name, dest, err))
+				r.addFailureLog(fmt.Sprintf("%s -> %s", backup.Name, dest), err)
+				printError("       ", "%v", err)
 				backupFailed = true
 				continue
 			}
-			fmt.Printf("       OK\n")
+			if unchanged {
+				printOK("       ", "(unchanged: nothing to back up)")
+			} else if created {
+				printOK("       ", fmt.Sprintf("(revision %d)", revision))
+			} else {
+				printOK("       ", "")
+			}
+		}
+
+		if backup.PostHook != "" && !r.interrupted() {
+			fmt.Printf("    -> post-hook\n")
+			if err := backupExec.RunShell(backup.PostHook); err != nil {
+				r.addError(fmt.Sprintf("%s post-hook: %v", backup.Name, err))
+				printError("       ", "%v", err)
+			} else {
+				printOK("       ", "")
+			}
 		}
 
 		if backupFailed {
-			failedBackups = append(failedBackups, backup.Name)
+			r.addFailedBackup(backup.Name)
 		}
 	}
+}
 
-	// Phase 2: Prune all storages
+// runPrunePhase prunes every storage, up to --max-parallel-storages at a time
+func (r *runner) runPrunePhase(exec duplicacyRunner, storages []string) {
 	fmt.Println("\n==========================================")
-	fmt.Println("Phase 2: Prune")
+	fmt.Println("Phase 3: Prune")
 	fmt.Println("==========================================")
 
-	allStorages := cfg.AllStorages()
+	phase := r.summary.StartPhase("prune")
+	defer phase.Finish()
 
-	// Use first backup's cache dir for prune/check, or empty if no backups
-	var maintenanceCacheDir string
-	if len(cfg.Backups) > 0 {
-		maintenanceCacheDir = cfg.Backups[0].CacheDir
-		if maintenanceCacheDir == "" {
-			maintenanceCacheDir = cfg.Backups[0].Path
+	forEachParallel(storages, maxParallelStorages, func(storage string) {
+		if !r.interrupted() {
+			r.pruneStorage(exec, phase, storage)
 		}
+	})
+}
+
+// pruneTarget is a single prune invocation resolved from the retention config
+type pruneTarget struct {
+	backupName string // empty when pruning all repositories with -a
+	source     string // which retention applied, for display
+	retention  config.RetentionConfig
+	args       []string
+}
+
+// planPrune resolves the prune invocations for a storage: storage-level retention
+// prunes all repositories with -a, otherwise each backup's repository is pruned
+// with its own retention
+func planPrune(cfg *config.Config, storage string) []pruneTarget {
+	storageName := cfg.StorageName(storage)
+	// Options appended to every prune of this storage
+	extraArgs := threadsArgs(cfg.GetStorageConfig(storage).Threads)
+	if cfg.GetStorageConfig(storage).ExclusivePrune {
+		extraArgs = append(extraArgs, "-exclusive")
 	}
 
-	maintenanceExec := executor.New(executor.Options{
-		DryRun:          dryRun,
-		Verbose:         verbose,
-		DockerContainer: cfg.Connection.Container,
-		SSHHost:         cfg.Connection.Host,
-		SSHPassword:     sshPassword,
-		StoragePassword: storagePassword,
-		GCDToken:        cfg.Connection.GCDToken,
-		CacheDir:        maintenanceCacheDir,
-	})
+	// Check if storage has retention defined
+	if retention, ok := cfg.GetStorageRetention(storage); ok {
+		// Storage-level retention: prune all repositories with -a
+		args := []string{"prune", "-storage", storageName}
+		args = append(args, strings.Fields(retention.ToPruneOptions())...)
+		args = append(args, extraArgs...)
+		return []pruneTarget{{source: "all repositories", retention: retention, args: args}}
+	}
 
-	for _, storage := range allStorages {
-		// Check if storage has retention defined
-		if retention, ok := cfg.GetStorageRetention(storage); ok {
-			// Storage-level retention: prune all repositories with -a
-			fmt.Printf("\n==> Pruning '%s' (all repositories)\n", storage)
+	// Per-backup retention: prune each repository separately with -id
+	backups := cfg.BackupsForStorage(storage)
+	if len(backups) == 0 {
+		// Maintenance-only storage with no backups targeting it
+		// Use default retention with -a
+		defaultRetention := cfg.DefaultRetention()
+		args := []string{"prune", "-storage", storageName}
+		args = append(args, strings.Fields(defaultRetention.ToPruneOptions())...)
+		args = append(args, extraArgs...)
+		return []pruneTarget{{source: "maintenance, default retention", retention: defaultRetention, args: args}}
+	}
 
-			pruneArgs := []string{"prune", "-storage", storage}
-			pruneArgs = append(pruneArgs, strings.Fields(retention.ToPruneOptions())...)
+	var targets []pruneTarget
+	storageCfg := cfg.GetStorageConfig(storage)
+	for _, backupName := range backups {
+		if storageCfg.ExcludesFromPrune(backupName) {
+			continue
+		}
+		retention := cfg.GetDestinationRetention(backupName, storage)
+		args := []string{"prune", "-storage", storageName, "-id", backupName}
+		// Remove -a from options since we're targeting specific repository
+		args = append(args, strings.Fields(retention.ToPruneOptionsWithoutAll())...)
+		args = append(args, extraArgs...)
+		targets = append(targets, pruneTarget{
+			backupName: backupName,
+			source:     "repository: " + backupName,
+			retention:  retention,
+			args:       args,
+		})
+	}
+	return targets
+}
 
-			err := maintenanceExec.RunDuplicacyWithStorage(storage, pruneArgs...)
-			if err != nil {
-				errMsg := fmt.Sprintf("prune %s: %v", storage, err)
-				allErrors = append(allErrors, errMsg)
-				fmt.Fprintf(os.Stderr, "    ERROR: %v\n", err)
-			} else {
-				fmt.Printf("    OK\n")
-			}
-		} else {
-			// Per-backup retention: prune each repository separately with -id
-			backups := cfg.BackupsForStorage(storage)
-			if len(backups) == 0 {
-				// Maintenance-only storage with no backups targeting it
-				// Use default retention with -a
-				fmt.Printf("\n==> Pruning '%s' (maintenance, default retention)\n", storage)
-
-				defaultRetention := config.RetentionConfig{Daily: 7, Weekly: 4}
-				pruneArgs := []string{"prune", "-storage", storage}
-				pruneArgs = append(pruneArgs, strings.Fields(defaultRetention.ToPruneOptions())...)
-
-				err := maintenanceExec.RunDuplicacyWithStorage(storage, pruneArgs...)
-				if err != nil {
-					errMsg := fmt.Sprintf("prune %s: %v", storage, err)
-					allErrors = append(allErrors, errMsg)
-					fmt.Fprintf(os.Stderr, "    ERROR: %v\n", err)
-				} else {
-					fmt.Printf("    OK\n")
-				}
-			} else {
-				// Prune each backup's repository separately
-				for _, backupName := range backups {
-					fmt.Printf("\n==> Pruning '%s' (repository: %s)\n", storage, backupName)
-
-					retention := cfg.GetBackupRetention(backupName)
-					pruneArgs := []string{"prune", "-storage", storage, "-id", backupName}
-					// Remove -a from options since we're targeting specific repository
-					opts := retention.ToPruneOptionsWithoutAll()
-					pruneArgs = append(pruneArgs, strings.Fields(opts)...)
-
-					err := maintenanceExec.RunDuplicacyWithStorage(storage, pruneArgs...)
-					if err != nil {
-						errMsg := fmt.Sprintf("prune %s/%s: %v", storage, backupName, err)
-						allErrors = append(allErrors, errMsg)
-						fmt.Fprintf(os.Stderr, "    ERROR: %v\n", err)
-						continue
-					}
-					fmt.Printf("    OK\n")
-				}
-			}
+// explainRetention prints which retention applies to each storage/backup and the
+// prune command it resolves to, without running anything
+func explainRetention(w io.Writer, cfg *config.Config) {
+	fmt.Fprintln(w, "Retention plan")
+	for _, storage := range cfg.AllStorages() {
+		mode := "per-backup retention"
+		if _, ok := cfg.GetStorageRetention(storage); ok {
+			mode = "storage-level retention"
+		}
+		fmt.Fprintf(w, "\n==> %s (%s)\n", storage, mode)
+		if !cfg.GetStorageConfig(storage).IsPrunable() {
+			fmt.Fprintf(w, "    not pruned (prunable: false)\n")
+			continue
+		}
+
+		for _, target := range planPrune(cfg, storage) {
+			fmt.Fprintf(w, "    [%s] %s\n", target.source, target.retention.Describe())
+			fmt.Fprintf(w, "      duplicacy %s\n", strings.Join(target.args, " "))
+		}
+		for _, id := range cfg.GetStorageConfig(storage).PruneExclude {
+			fmt.Fprintf(w, "    [repository: %s] not pruned (prune_exclude)\n", id)
 		}
 	}
+}
+
+// pruneStorage applies storage-level or per-backup retention to a single
+// storage. Storages with prunable: false are skipped and recorded as such.
+func (r *runner) pruneStorage(exec duplicacyRunner, phase *summary.PhaseResult, storage string) {
+	if !r.cfg.GetStorageConfig(storage).IsPrunable() {
+		fmt.Printf("\n==> Skipping prune of '%s' (prunable: false)\n", storage)
+		phase.AddOperation(summary.OperationResult{Storage: storage, Status: summary.StatusSkipped, Reason: "prunable: false"})
+		return
+	}
+	if r.failedCheck(storage) {
+		fmt.Printf("\n==> Skipping prune of '%s' (check failed)\n", storage)
+		phase.AddOperation(summary.OperationResult{Storage: storage, Status: summary.StatusSkipped, Reason: "check failed"})
+		return
+	}
+	if err := exclusivePruneAllowed(r.cfg.GetStorageConfig(storage), r.lockHeld); err != nil {
+		r.recordOperation(phase, "", storage, time.Now(), err)
+		r.addError(fmt.Sprintf("prune %s: %v", storage, err))
+		printError("    ", "%v", err)
+		return
+	}
+
+	for _, target := range planPrune(r.cfg, storage) {
+		fmt.Printf("\n==> Pruning '%s' (%s)\n", storage, target.source)
+		r.runPrune(exec, phase, storage, target.backupName, target.args)
+	}
+}
+
+// markCheckFailed records that a check of storage failed this run
+func (r *runner) markCheckFailed(storage string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.checkFailed == nil {
+		r.checkFailed = make(map[string]bool)
+	}
+	r.checkFailed[storage] = true
+}
+
+// failedCheck reports whether a check of storage failed this run
+func (r *runner) failedCheck(storage string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.checkFailed[storage]
+}
 
-	// Phase 3: Check all storages
+// runPrune executes a single prune and records the result under the storage's config name
+func (r *runner) runPrune(exec duplicacyRunner, phase *summary.PhaseResult, storage, backupName string, pruneArgs []string) {
+	opStart := time.Now()
+	storageName := r.cfg.StorageName(storage)
+	err := checkRevisionFloor(exec, storageName, pruneArgs, r.cfg.GetStorageConfig(storage).MinRevisions, opStart)
+	if err != nil {
+		err = fmt.Errorf("refusing to prune: %w", err)
+	} else {
+		err = exec.RunDuplicacyWithStorage(storageName, pruneArgs...)
+	}
+	r.recordOperation(phase, backupName, storage, opStart, err)
+	if err != nil {
+		target := storage
+		if backupName != "" {
+			target = storage + "/" + backupName
+		}
+		r.addError(fmt.Sprintf("prune %s: %v", target, err))
+		r.addFailureLog("prune "+target, err)
+		printError("    ", "%v", err)
+		return
+	}
+	printOK("    ", "")
+}
+
+// runCheckPhase checks every storage, up to --max-parallel-storages at a time
+func (r *runner) runCheckPhase(exec duplicacyRunner, statsWriter statsUpdater, storages []string) {
 	fmt.Println("\n==========================================")
-	fmt.Println("Phase 3: Check")
+	fmt.Println("Phase 2: Check")
 	fmt.Println("==========================================")
 
-	// Create stats writer for updating Duplicacy Web UI stats
-	var statsWriter *stats.Writer
-	if cfg.Connection.Container != "" {
-		statsWriter = stats.NewWriter(cfg.Connection.Host, sshPassword, cfg.Connection.Container)
-		statsWriter.DryRun = dryRun
-		statsWriter.Verbose = verbose
-	}
+	phase := r.summary.StartPhase("check")
+	defer phase.Finish()
+
+	forEachParallel(storages, maxParallelStorages, func(storage string) {
+		if !r.interrupted() {
+			r.checkStorage(exec, phase, statsWriter, storage)
+		}
+	})
+}
 
-	for _, storage := range allStorages {
-		fmt.Printf("\n==> Checking '%s'\n", storage)
+// checkStorage checks a single storage, prints and records its parsed stats,
+// and updates its Web UI stats when statsWriter is set.
+// Commands use the duplicacy storage name; stats and reporting use the config name.
+func (r *runner) checkStorage(exec duplicacyRunner, phase *summary.PhaseResult, statsWriter statsUpdater, storage string) {
+	fmt.Printf("\n==> Checking '%s'\n", storage)
 
-		// Run check with -tabular to get stats output
-		output, err := maintenanceExec.RunDuplicacyCaptureWithStorage(storage, "check", "-tabular", "-storage", storage)
+	storageName := r.cfg.StorageName(storage)
+	checkOpts, ids := planCheck(r.cfg, storage)
 
-		// Print the output (since we captured it)
-		if output != "" {
-			fmt.Print(output)
+	var dayStats *stats.DayStats
+	var outputs []string
+	for _, id := range ids {
+		if r.interrupted() {
+			break
 		}
+		target := storage
+		if id != "" {
+			target = storage + "/" + id
+			fmt.Printf("    -> %s\n", id)
+		}
+
+		checkOpts.ID = id
+		opStart := time.Now()
+		output, err := exec.RunDuplicacyCaptureWithStorage(storageName, checkArgs(storageName, checkOpts)...)
+		r.recordOperation(phase, id, storage, opStart, err)
+		printCheckOutput(output, err)
+		outputs = append(outputs, output)
 
 		if err != nil {
-			errMsg := fmt.Sprintf("check %s: %v", storage, err)
-			allErrors = append(allErrors, errMsg)
-			fmt.Fprintf(os.Stderr, "    ERROR: %v\n", err)
-			continue
+			r.markCheckFailed(storage)
+			r.addError(fmt.Sprintf("check %s: %v", target, err))
+			r.addFailureLog("check "+target, err)
+			printError("    ", "%v", err)
+		} else {
+			printOK("    ", "")
 		}
-		fmt.Printf("    OK\n")
 
-		// Update stats for Duplicacy Web UI
-		if statsWriter != nil && output != "" {
-			dayStats, parseErr := stats.ParseCheckOutput(output)
-			if parseErr != nil {
-				fmt.Fprintf(os.Stderr, "    WARNING: failed to parse check output for stats: %v\n", parseErr)
-			} else {
-				// Print parsed stats summary for CI visibility
-				fmt.Printf("\n    Storage Stats Summary:\n")
-				fmt.Printf("      Total size: %s\n", stats.FormatBytes(dayStats.TotalSize))
-				fmt.Printf("      Total chunks: %d\n", dayStats.TotalChunks)
-				fmt.Printf("      Repositories: %d\n", len(dayStats.Repositories))
-				for repoName, repoStats := range dayStats.Repositories {
-					fmt.Printf("        - %s: %d revisions, %s\n", repoName, repoStats.Revisions, stats.FormatBytes(repoStats.TotalSize))
-				}
-
-				if writeErr := statsWriter.UpdateStorageStats(storage, dayStats); writeErr != nil {
-					fmt.Fprintf(os.Stderr, "    WARNING: failed to update stats: %v\n", writeErr)
-				} else {
-					fmt.Printf("    Updated Duplicacy Web UI stats for '%s'\n", storage)
-				}
+		// Failed checks are recorded too so they show red in the Web UI
+		if output == "" {
+			continue
+		}
+		idStats, parseErr := parseCheckStats(output, id)
+		if parseErr != nil {
+			if err == nil {
+				r.reportParseError(target, parseErr)
 			}
+			continue
 		}
+		idStats.SetCheckResult(err)
+		dayStats = stats.MergeSameDay(dayStats, idStats)
 	}
+	saveCheckOutput(storage, r.statsDate(), strings.Join(outputs, ""))
 
-	// Summary
-	fmt.Println("\n==========================================")
-	fmt.Println("Summary")
-	fmt.Println("==========================================")
+	// Summarize the stats, and update them for the Duplicacy Web UI
+	if dayStats != nil {
+		r.recordCheckStats(statsWriter, storage, dayStats)
+	}
+}
 
-	if len(allErrors) == 0 {
-		fmt.Println("All operations completed successfully")
-		return nil
+// reportParseError reports check output that could not be parsed for stats:
+// as a run error under stats.strict_parse (so format drift fails CI), else as
+// a warning
+func (r *runner) reportParseError(target string, err error) {
+	if r.cfg.Stats.StrictParse {
+		r.addError(fmt.Sprintf("stats %s: failed to parse check output: %v", target, err))
+		printError("    ", "failed to parse check output for stats: %v", err)
+		return
 	}
+	printWarning("    ", "failed to parse check output for stats: %v", err)
+}
 
-	// Report errors
-	fmt.Printf("\n%d error(s) occurred:\n", len(allErrors))
-	for _, e := range allErrors {
-		fmt.Printf("  - %s\n", e)
+// printCheckOutput prints a check's captured output. --summary-only leaves it
+// to the stats summary unless the check failed.
+func printCheckOutput(output string, err error) {
+	if output == "" || (summaryOnly && err == nil) {
+		return
 	}
+	fmt.Print(output)
+}
 
-	// Send notification if configured
-	if cfg.Notifications.Forgejo.URL != "" && cfg.Notifications.Forgejo.Repo != "" {
-		token := cfg.Notifications.Forgejo.GetToken()
-		if token != "" {
-			if err := sendRunFailureNotification(cfg, allErrors, failedBackups); err != nil {
-				fmt.Fprintf(os.Stderr, "\nWARNING: Failed to create issue: %v\n", err)
-			}
+// planCheck returns the check options for a storage and the snapshot IDs to
+// check one at a time ("" checks every ID in one invocation)
+func planCheck(cfg *config.Config, storage string) (checkOptions, []string) {
+	// Run check with -tabular to get stats output
+	storageCfg := cfg.GetStorageConfig(storage)
+	checkOpts := checkOptions{
+		Chunks:  verifyChunks || storageCfg.VerifyChunks,
+		Persist: persist,
+		Threads: storageCfg.Threads,
+	}
+
+	// With check_per_id, check each backup's snapshot ID separately (like
+	// per-backup prune) and merge their stats into one entry
+	ids := []string{""}
+	if storageCfg.CheckPerID {
+		if backups := cfg.BackupsForStorage(storage); len(backups) > 0 {
+			ids = backups
 		}
 	}
+	return checkOpts, ids
+}
+
+// recordCheckStats prints a storage's parsed check stats, adds them to the
+// run summary and, when statsWriter is set, writes them to the Web UI stats file
+func (r *runner) recordCheckStats(statsWriter statsUpdater, storage string, dayStats *stats.DayStats) {
+	// Print parsed stats summary for CI visibility
+	fmt.Printf("\n    Storage Stats Summary:\n")
+	fmt.Printf("      Total size: %s\n", stats.FormatBytes(dayStats.TotalSize))
+	fmt.Printf("      Total chunks: %d\n", dayStats.TotalChunks)
+	if dayStats.MissingChunks > 0 || dayStats.CorruptChunks > 0 {
+		fmt.Printf("      Missing chunks: %d, corrupt chunks: %d\n", dayStats.MissingChunks, dayStats.CorruptChunks)
+	}
+	fmt.Printf("      Repositories: %d\n", len(dayStats.Repositories))
+	for repoName, repoStats := range dayStats.Repositories {
+		fmt.Printf("        - %s: %d revisions, %s\n", repoName, repoStats.Revisions, stats.FormatBytes(repoStats.TotalSize))
+	}
+	r.summary.SetStorageStats(storage, dayStats)
+	if statsWriter == nil {
+		return
+	}
 
-	return fmt.Errorf("completed with %d error(s)", len(allErrors))
+	result, writeErr := statsWriter.Update(storage, dayStats)
+	if writeErr != nil && r.cfg.Stats.Required {
+		r.addError(fmt.Sprintf("stats %s: %v", storage, writeErr))
+		printError("    ", "failed to update stats: %v", writeErr)
+	} else if writeErr != nil {
+		printWarning("    ", "failed to update stats: %v", writeErr)
+	} else {
+		fmt.Printf("    Updated Duplicacy Web UI stats for '%s'\n", storage)
+	}
+	r.checkGrowth(storage, result, dayStats)
+	r.reportChunkDelta(storage, result, dayStats)
 }
 
-func sendRunFailureNotification(cfg *config.Config, errors []string, failedBackups []string) error {
-	n := notifier.NewForgejo(
+// forEachParallel calls fn for every item, running at most limit calls concurrently
+func forEachParallel(items []string, limit int, fn func(string)) {
+	if limit <= 1 {
+		for _, item := range items {
+			fn(item)
+		}
+		return
+	}
+
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for _, item := range items {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(item string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(item)
+		}(item)
+	}
+	wg.Wait()
+}
+
+// newOperation builds the summary entry for a finished duplicacy invocation
+func newOperation(backupName, storage string, start time.Time, err error) summary.OperationResult {
+	op := summary.OperationResult{
+		Backup:   backupName,
+		Storage:  storage,
+		Status:   summary.StatusSuccess,
+		Duration: time.Since(start).Seconds(),
+	}
+	if err != nil {
+		op.Status = summary.StatusFailed
+		op.Error = err.Error()
+	}
+	return op
+}
+
+// forgejoConfigured reports whether Forgejo notifications have a URL, repo and token
+func forgejoConfigured(cfg *config.Config) bool {
+	f := cfg.Notifications.Forgejo
+	return f.URL != "" && f.Repo != "" && f.GetToken() != ""
+}
+
+// newRunNotifier creates the Forgejo notifier from the config, or a null
+// notifier when --no-notify is set
+func newRunNotifier(cfg *config.Config) notifier.Notifier {
+	if noNotify {
+		return &notifier.NullNotifier{}
+	}
+	return notifier.NewForgejo(
 		cfg.Notifications.Forgejo.URL,
 		cfg.Notifications.Forgejo.Repo,
 		cfg.Notifications.Forgejo.GetToken(),
+		notifier.WithAssignees(cfg.Notifications.Forgejo.AllAssignees()...),
+		notifier.WithUpdateMode(notifier.UpdateMode(cfg.Notifications.Forgejo.UpdateMode)),
+		notifier.WithReopenClosed(cfg.Notifications.Forgejo.ReopensClosed()),
 	)
+}
 
-	if cfg.Notifications.Forgejo.Assignee != "" {
-		n.SetAssignee(cfg.Notifications.Forgejo.Assignee)
+// failureTitle renders the failure issue title from tmpl, falling back to the
+// default title (with a warning) if the template cannot be rendered
+func failureTitle(tmpl string, failedBackups []string, host string) string {
+	data := notifier.NewTitleData(failedBackups, notificationHost(host), time.Now())
+	title, err := notifier.RenderTitle(tmpl, data)
+	if err != nil {
+		printWarning("", "%v; using the default title", err)
+		title, _ = notifier.RenderTitle("", data)
 	}
+	return title
+}
 
-	// Build title
-	var title string
-	if len(failedBackups) > 0 {
-		title = fmt.Sprintf("[duplicaci] %s: backup failed", strings.Join(failedBackups, ", "))
-	} else {
-		title = "[duplicaci] maintenance failed"
+// notificationHost returns the host part of the SSH target, or the local
+// hostname when running against a local container
+func notificationHost(sshTarget string) string {
+	if sshTarget != "" {
+		return sshTarget[strings.LastIndex(sshTarget, "@")+1:]
 	}
+	host, _ := os.Hostname()
+	return host
+}
+
+func sendGrowthAlertNotification(cfg *config.Config, alerts []string) error {
+	body := "## Storage Growth Alert\n\n"
+	for _, a := range alerts {
+		body += fmt.Sprintf("- %s\n", a)
+	}
+	return newRunNotifier(cfg).CreateOrUpdateIssue("[duplicaci] storage growth alert", body)
+}
+
+func sendRunFailureNotification(cfg *config.Config, errors []string, failedBackups []string, logs []failureLog) error {
+	n := newRunNotifier(cfg)
+	title := failureTitle(cfg.Notifications.TitleTemplate, failedBackups, cfg.Connection.Host)
 
 	// Build body
 	body := "## Backup Run Failed\n\n"
@@ -333,5 +1340,12 @@ func sendRunFailureNotification(cfg *config.Config, errors []string, failedBacku
 		body += fmt.Sprintf("- %s\n", e)
 	}
 
+	if len(logs) > 0 {
+		body += "\n### Logs\n"
+		for _, l := range logs {
+			body += fmt.Sprintf("\n<details>\n<summary>%s</summary>\n\n```\n%s\n```\n\n</details>\n", l.Label, l.Tail)
+		}
+	}
+
 	return n.CreateOrUpdateIssue(title, body)
 }
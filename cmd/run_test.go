@@ -0,0 +1,2103 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lioreshai/duplicaci/internal/config"
+	"github.com/lioreshai/duplicaci/internal/executor"
+	"github.com/lioreshai/duplicaci/internal/notifier"
+	"github.com/lioreshai/duplicaci/internal/stats"
+	"github.com/lioreshai/duplicaci/internal/summary"
+)
+
+// sampleCheckOutput is minimal check -tabular output that parses into stats
+const sampleCheckOutput = `2025-12-29 01:02:45.064 INFO SNAPSHOT_CHECK Total chunk size is 8,853K in 92 chunks
+ appdata |   1 | @ 2025-10-13 20:36 -hash |     9 |  826K |      4 |   672K |    4 |   672K |   4 |  672K |
+ appdata | all |                          |       |       |     92 | 8,853K |   92 | 8,853K |     |       |
+`
+
+// fakeRunner records duplicacy invocations instead of executing them
+type fakeRunner struct {
+	mu     sync.Mutex
+	calls  []fakeCall
+	output string           // returned by capture calls
+	errs   map[string]error // keyed by "<subcommand> <storage>", "shell <command>" or "discover <backup>"
+
+	cacheDirs   map[string]string // discovered cache dir per backup name
+	preferences map[string]string // .duplicacy/preferences content per dir
+	discoveries []string          // backup names passed to DiscoverCacheDir
+	options     []executor.Options
+}
+
+type fakeCall struct {
+	storage string
+	args    []string
+}
+
+func (f *fakeRunner) record(storage string, args []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, fakeCall{storage: storage, args: args})
+	if len(args) > 0 {
+		return f.errs[args[0]+" "+storage]
+	}
+	return nil
+}
+
+func (f *fakeRunner) RunDuplicacyWithStorage(storage string, args ...string) error {
+	return f.record(storage, args)
+}
+
+func (f *fakeRunner) RunDuplicacyStreamWithStorage(storage string, args ...string) (executor.Result, error) {
+	return executor.Result{Tail: f.output}, f.record(storage, args)
+}
+
+func (f *fakeRunner) RunDuplicacyCaptureWithStorage(storage string, args ...string) (string, error) {
+	return f.output, f.record(storage, args)
+}
+
+func (f *fakeRunner) RunShell(command string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, fakeCall{args: []string{"shell", command}})
+	return f.errs["shell "+command]
+}
+
+func (f *fakeRunner) DiscoverCacheDir(backupName string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.discoveries = append(f.discoveries, backupName)
+	return f.cacheDirs[backupName], f.errs["discover "+backupName]
+}
+
+func (f *fakeRunner) ReadPreferences(dir string) ([]executor.Preference, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, fakeCall{args: []string{"preferences", dir}})
+	if err := f.errs["preferences "+dir]; err != nil {
+		return nil, err
+	}
+	data, ok := f.preferences[dir]
+	if !ok {
+		return nil, nil
+	}
+	return executor.ParsePreferences([]byte(data))
+}
+
+// factory returns a newRunner func that hands out this fake for every executor
+func (f *fakeRunner) factory() func(opts executor.Options) duplicacyRunner {
+	return func(opts executor.Options) duplicacyRunner {
+		f.mu.Lock()
+		f.options = append(f.options, opts)
+		f.mu.Unlock()
+		return f
+	}
+}
+
+// commands returns the recorded invocations as space-joined argument strings
+func (f *fakeRunner) commands() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var cmds []string
+	for _, c := range f.calls {
+		cmds = append(cmds, strings.Join(c.args, " "))
+	}
+	return cmds
+}
+
+// fakeStatsUpdater records which storages had stats written
+type fakeStatsUpdater struct {
+	mu       sync.Mutex
+	storages []string
+	previous stats.UpdateResult // returned as the prior entry for every storage
+	err      error              // returned by every update
+	written  map[string]*stats.DayStats
+}
+
+func (f *fakeStatsUpdater) Update(storage string, dayStats *stats.DayStats) (stats.UpdateResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.storages = append(f.storages, storage)
+	if f.written == nil {
+		f.written = make(map[string]*stats.DayStats)
+	}
+	f.written[storage] = dayStats
+	return f.previous, f.err
+}
+
+// captureStdout runs fn with os.Stdout redirected and returns what was printed
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+
+	orig := os.Stdout
+	os.Stdout = w
+
+	var buf bytes.Buffer
+	done := make(chan struct{})
+	go func() {
+		io.Copy(&buf, r)
+		close(done)
+	}()
+
+	defer func() {
+		os.Stdout = orig
+	}()
+	fn()
+
+	w.Close()
+	<-done
+	return buf.String()
+}
+
+func TestRunner_ParallelMaintenance_DryRun(t *testing.T) {
+	defer func() {
+		dryRun = false
+		maxParallelStorages = 1
+	}()
+	dryRun = true
+	maxParallelStorages = 3
+
+	cfg := &config.Config{
+		Maintenance: []string{"s1", "s2", "s3", "s4", "s5", "s6"},
+	}
+	r := &runner{cfg: cfg, summary: summary.New(time.Now())}
+	exec := r.newExecutor("")
+
+	captureStdout(t, func() {
+		r.runPrunePhase(exec, cfg.AllStorages())
+		r.runCheckPhase(exec, nil, cfg.AllStorages())
+	})
+
+	if len(r.errors) != 0 {
+		t.Errorf("expected no errors, got %v", r.errors)
+	}
+	if len(r.summary.Phases) != 2 {
+		t.Fatalf("expected 2 phases, got %d", len(r.summary.Phases))
+	}
+	for _, phase := range r.summary.Phases {
+		var got []string
+		for _, op := range phase.Operations {
+			got = append(got, op.Storage)
+		}
+		sort.Strings(got)
+		if len(got) != 6 || got[0] != "s1" || got[5] != "s6" {
+			t.Errorf("phase %s: expected one operation per storage, got %v", phase.Name, got)
+		}
+	}
+}
+
+func TestForEachParallel_RespectsLimit(t *testing.T) {
+	items := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+
+	var mu sync.Mutex
+	var running, peak int
+	var seen []string
+
+	forEachParallel(items, 3, func(item string) {
+		mu.Lock()
+		running++
+		if running > peak {
+			peak = running
+		}
+		seen = append(seen, item)
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+	})
+
+	if peak > 3 {
+		t.Errorf("expected at most 3 concurrent calls, got %d", peak)
+	}
+	if len(seen) != len(items) {
+		t.Errorf("expected %d calls, got %d", len(items), len(seen))
+	}
+}
+
+func TestForEachParallel_Serial(t *testing.T) {
+	var order []string
+	forEachParallel([]string{"a", "b", "c"}, 1, func(item string) {
+		order = append(order, item)
+	})
+
+	if len(order) != 3 || order[0] != "a" || order[1] != "b" || order[2] != "c" {
+		t.Errorf("expected serial in-order calls, got %v", order)
+	}
+}
+
+func TestRunner_StorageAlias(t *testing.T) {
+	cfg := &config.Config{
+		Backups: []config.BackupConfig{
+			{Name: "appdata", Destinations: []string{"Cloud"}},
+		},
+		Storages: map[string]config.StorageConfig{
+			"Cloud": {Name: "gdrive-2024", Retention: config.RetentionConfig{Daily: 7, Weekly: 4}},
+		},
+	}
+	r := &runner{cfg: cfg, summary: summary.New(time.Now())}
+	exec := &fakeRunner{output: sampleCheckOutput}
+	writer := &fakeStatsUpdater{}
+
+	captureStdout(t, func() {
+		r.runPrunePhase(exec, cfg.AllStorages())
+		r.runCheckPhase(exec, writer, cfg.AllStorages())
+	})
+
+	for _, call := range exec.calls {
+		if call.storage != "gdrive-2024" {
+			t.Errorf("expected commands to run against real storage name, got %q", call.storage)
+		}
+	}
+	cmds := exec.commands()
+	if len(cmds) != 2 {
+		t.Fatalf("expected prune and check commands, got %v", cmds)
+	}
+	if !strings.HasPrefix(cmds[0], "prune -storage gdrive-2024 ") {
+		t.Errorf("unexpected prune command: %q", cmds[0])
+	}
+	if cmds[1] != "check -tabular -storage gdrive-2024" {
+		t.Errorf("unexpected check command: %q", cmds[1])
+	}
+
+	if len(writer.storages) != 1 || writer.storages[0] != "Cloud" {
+		t.Errorf("expected stats to be written under alias Cloud, got %v", writer.storages)
+	}
+	if _, ok := r.summary.Storages["Cloud"]; !ok {
+		t.Error("expected summary stats to be keyed by alias")
+	}
+}
+
+func TestRunner_CheckSummaryOnly(t *testing.T) {
+	defer func() { summaryOnly = false }()
+
+	cfg := &config.Config{
+		Backups: []config.BackupConfig{{Name: "appdata", Path: "/mnt/appdata", Destinations: []string{"NAS"}}},
+	}
+
+	tests := []struct {
+		name    string
+		only    bool
+		err     error
+		wantRaw bool
+	}{
+		{"full output", false, nil, true},
+		{"summary only", true, nil, false},
+		{"summary only keeps failed output", true, errors.New("exit status 1"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summaryOnly = tt.only
+			r := &runner{cfg: cfg, summary: summary.New(time.Now())}
+			exec := &fakeRunner{output: sampleCheckOutput, errs: map[string]error{"check NAS": tt.err}}
+			writer := &fakeStatsUpdater{}
+
+			out := captureStdout(t, func() {
+				r.runCheckPhase(exec, writer, cfg.AllStorages())
+			})
+
+			if raw := strings.Contains(out, "SNAPSHOT_CHECK"); raw != tt.wantRaw {
+				t.Errorf("expected raw output printed = %v, got:\n%s", tt.wantRaw, out)
+			}
+			if !strings.Contains(out, "Storage Stats Summary:") || !strings.Contains(out, "Total chunks: 92") {
+				t.Errorf("expected the stats summary, got:\n%s", out)
+			}
+			if len(writer.storages) != 1 || writer.storages[0] != "NAS" {
+				t.Errorf("expected stats to be written for NAS, got %v", writer.storages)
+			}
+		})
+	}
+}
+
+func TestRunner_CheckSummaryOnlyWithoutWriter(t *testing.T) {
+	defer func() { summaryOnly = false }()
+	summaryOnly = true
+
+	// No container, so no stats writer: the summary is still printed and recorded
+	cfg := &config.Config{Maintenance: []string{"NAS"}}
+	r := &runner{cfg: cfg, summary: summary.New(time.Now())}
+	exec := &fakeRunner{output: sampleCheckOutput}
+
+	out := captureStdout(t, func() {
+		r.runCheckPhase(exec, nil, cfg.AllStorages())
+	})
+
+	if strings.Contains(out, "SNAPSHOT_CHECK") {
+		t.Errorf("expected the raw output hidden, got:\n%s", out)
+	}
+	if !strings.Contains(out, "Storage Stats Summary:") || !strings.Contains(out, "Total chunks: 92") {
+		t.Errorf("expected the stats summary, got:\n%s", out)
+	}
+	if got, ok := r.summary.Storages["NAS"]; !ok || got.TotalChunks != 92 {
+		t.Errorf("expected NAS stats in the run summary, got %+v", r.summary.Storages)
+	}
+}
+
+func TestRunner_CheckStatsParseError(t *testing.T) {
+	tests := []struct {
+		name   string
+		strict bool
+		errors []string
+	}{
+		{"lenient", false, nil},
+		{"strict", true, []string{"stats NAS: failed to parse check output: "}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Backups: []config.BackupConfig{{Name: "appdata", Path: "/mnt/appdata", Destinations: []string{"NAS"}}},
+				Stats:   config.StatsConfig{StrictParse: tt.strict},
+			}
+			r := &runner{cfg: cfg, summary: summary.New(time.Now())}
+			exec := &fakeRunner{output: "Listing all chunks\nsomething duplicacy changed\n"}
+			writer := &fakeStatsUpdater{}
+
+			captureStdout(t, func() {
+				r.runCheckPhase(exec, writer, cfg.AllStorages())
+			})
+
+			if len(r.errors) != len(tt.errors) {
+				t.Fatalf("expected errors %v, got %v", tt.errors, r.errors)
+			}
+			for i, prefix := range tt.errors {
+				if !strings.HasPrefix(r.errors[i], prefix) {
+					t.Errorf("expected error starting %q, got %q", prefix, r.errors[i])
+				}
+			}
+			if len(writer.storages) != 0 {
+				t.Errorf("expected no stats written, got %v", writer.storages)
+			}
+		})
+	}
+}
+
+// hookConfig is a single backup to one storage with pre and post hooks
+func hookConfig() *config.Config {
+	return &config.Config{
+		Backups: []config.BackupConfig{
+			{Name: "appdata", Path: "/mnt/appdata", Destinations: []string{"NAS"}},
+		},
+		Hooks: config.HooksConfig{
+			Pre:  "pg_dump mydb > /mnt/appdata/db.sql",
+			Post: "rm /mnt/appdata/db.sql",
+		},
+	}
+}
+
+func TestRunner_Hooks_Order(t *testing.T) {
+	fake := &fakeRunner{}
+	r := &runner{cfg: hookConfig(), summary: summary.New(time.Now()), newRunner: fake.factory()}
+
+	captureStdout(t, r.execute)
+
+	cmds := fake.commands()
+	expected := []string{
+		"shell pg_dump mydb > /mnt/appdata/db.sql",
+		"backup -storage NAS",
+		"check -tabular -storage NAS",
+		"prune -storage NAS -id appdata",
+		"shell rm /mnt/appdata/db.sql",
+	}
+	if len(cmds) != len(expected) {
+		t.Fatalf("expected %d commands, got %v", len(expected), cmds)
+	}
+	for i := range expected {
+		if !strings.HasPrefix(cmds[i], expected[i]) {
+			t.Errorf("command %d: expected %q, got %q", i, expected[i], cmds[i])
+		}
+	}
+	if len(r.errors) != 0 {
+		t.Errorf("expected no errors, got %v", r.errors)
+	}
+}
+
+func TestRunner_Hooks_PreFailureAborts(t *testing.T) {
+	cfg := hookConfig()
+	fake := &fakeRunner{errs: map[string]error{"shell " + cfg.Hooks.Pre: errors.New("command exited with code 1")}}
+	r := &runner{cfg: cfg, summary: summary.New(time.Now()), newRunner: fake.factory()}
+
+	captureStdout(t, r.execute)
+
+	cmds := fake.commands()
+	if len(cmds) != 1 {
+		t.Errorf("expected only the pre-run hook to run, got %v", cmds)
+	}
+	if len(r.errors) != 1 || !strings.HasPrefix(r.errors[0], "pre-run hook:") {
+		t.Errorf("expected pre-run hook error, got %v", r.errors)
+	}
+}
+
+func TestRunner_Hooks_PostFailureRecorded(t *testing.T) {
+	cfg := hookConfig()
+	fake := &fakeRunner{errs: map[string]error{"shell " + cfg.Hooks.Post: errors.New("command exited with code 1")}}
+	r := &runner{cfg: cfg, summary: summary.New(time.Now()), newRunner: fake.factory()}
+
+	captureStdout(t, r.execute)
+
+	if len(fake.commands()) != 5 {
+		t.Errorf("expected all phases to run, got %v", fake.commands())
+	}
+	if len(r.errors) != 1 || !strings.HasPrefix(r.errors[0], "post-run hook:") {
+		t.Errorf("expected post-run hook error, got %v", r.errors)
+	}
+	if r.summary.Phases[0].Status != summary.StatusSuccess {
+		t.Errorf("expected completed backup to stay successful, got %q", r.summary.Phases[0].Status)
+	}
+}
+
+func TestRunner_Hooks_DryRun(t *testing.T) {
+	defer func() { dryRun = false }()
+	dryRun = true
+
+	cfg := hookConfig()
+	cfg.Hooks.Pre = "exit 1"
+	r := &runner{cfg: cfg, summary: summary.New(time.Now())}
+
+	out := captureStdout(t, func() {
+		if err := r.runHook("pre-run", cfg.Hooks.Pre); err != nil {
+			t.Errorf("dry run should not execute the hook: %v", err)
+		}
+	})
+	if !strings.Contains(out, "Command: exit 1") {
+		t.Errorf("expected dry run to print the hook command, got %q", out)
+	}
+}
+
+// backupHookConfig has a hooked backup to two storages followed by an unhooked one
+func backupHookConfig() *config.Config {
+	return &config.Config{
+		Backups: []config.BackupConfig{
+			{Name: "db", Destinations: []string{"NAS", "Cloud"}, PreHook: "lock db", PostHook: "unlock db"},
+			{Name: "media", Destinations: []string{"NAS"}},
+		},
+	}
+}
+
+func TestRunner_BackupHooks_WrapDestinations(t *testing.T) {
+	fake := &fakeRunner{}
+	r := &runner{cfg: backupHookConfig(), summary: summary.New(time.Now()), newRunner: fake.factory()}
+
+	captureStdout(t, r.runBackupPhase)
+
+	cmds := fake.commands()
+	expected := []string{
+		"shell lock db",
+		"backup -storage NAS",
+		"backup -storage Cloud",
+		"shell unlock db",
+		"backup -storage NAS",
+	}
+	if len(cmds) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, cmds)
+	}
+	for i := range expected {
+		if cmds[i] != expected[i] {
+			t.Errorf("command %d: expected %q, got %q", i, expected[i], cmds[i])
+		}
+	}
+}
+
+func TestRunner_BackupHooks_PreFailureSkipsBackup(t *testing.T) {
+	fake := &fakeRunner{errs: map[string]error{"shell lock db": errors.New("command exited with code 1")}}
+	r := &runner{cfg: backupHookConfig(), summary: summary.New(time.Now()), newRunner: fake.factory()}
+
+	captureStdout(t, r.runBackupPhase)
+
+	cmds := fake.commands()
+	if len(cmds) != 2 || cmds[0] != "shell lock db" || cmds[1] != "backup -storage NAS" {
+		t.Errorf("expected db destinations and post-hook to be skipped, got %v", cmds)
+	}
+	if len(r.errors) != 1 || !strings.HasPrefix(r.errors[0], "db pre-hook:") {
+		t.Errorf("expected db pre-hook error, got %v", r.errors)
+	}
+	if len(r.failedBackups) != 1 || r.failedBackups[0] != "db" {
+		t.Errorf("expected db to be marked failed, got %v", r.failedBackups)
+	}
+}
+
+func TestRunner_BackupHooks_PostFailureNonFatal(t *testing.T) {
+	fake := &fakeRunner{errs: map[string]error{"shell unlock db": errors.New("command exited with code 1")}}
+	r := &runner{cfg: backupHookConfig(), summary: summary.New(time.Now()), newRunner: fake.factory()}
+
+	captureStdout(t, r.runBackupPhase)
+
+	if len(fake.commands()) != 5 {
+		t.Errorf("expected every command to run, got %v", fake.commands())
+	}
+	if len(r.errors) != 1 || !strings.HasPrefix(r.errors[0], "db post-hook:") {
+		t.Errorf("expected db post-hook error, got %v", r.errors)
+	}
+	if len(r.failedBackups) != 0 {
+		t.Errorf("expected completed backups not to be marked failed, got %v", r.failedBackups)
+	}
+}
+
+func TestRunner_AddFailedBackupConcurrent(t *testing.T) {
+	r := &runner{}
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			r.addFailedBackup(fmt.Sprintf("backup-%d", i))
+		}(i)
+	}
+	wg.Wait()
+
+	if len(r.failedBackups) != 20 {
+		t.Errorf("expected 20 failed backups, got %d", len(r.failedBackups))
+	}
+}
+
+func TestExplainRetention_Mixed(t *testing.T) {
+	cfg := &config.Config{
+		Backups: []config.BackupConfig{
+			{Name: "appdata", Destinations: []string{"NAS", "Cloud"}, Retention: config.RetentionConfig{Daily: 7, Weekly: 4}},
+			{Name: "media", Destinations: []string{"Cloud"}, Retention: config.RetentionConfig{Daily: 3, Weekly: 2, Monthly: 6}},
+		},
+		Storages: map[string]config.StorageConfig{
+			"NAS": {Retention: config.RetentionConfig{Daily: 14, Weekly: 8}},
+		},
+	}
+
+	var buf bytes.Buffer
+	explainRetention(&buf, cfg)
+
+	expected := `Retention plan
+
+==> NAS (storage-level retention)
+    [all repositories] daily until day 14, weekly until day 70, none after
+      duplicacy prune -storage NAS -keep 0:70 -keep 7:14 -keep 1:1 -a
+
+==> Cloud (per-backup retention)
+    [repository: appdata] daily until day 7, weekly until day 35, none after
+      duplicacy prune -storage Cloud -id appdata -keep 0:35 -keep 7:7 -keep 1:1
+    [repository: media] daily until day 3, weekly until day 17, monthly until day 197, none after
+      duplicacy prune -storage Cloud -id media -keep 0:197 -keep 30:17 -keep 7:3 -keep 1:1
+`
+	if buf.String() != expected {
+		t.Errorf("unexpected explain output:\n%s\nwant:\n%s", buf.String(), expected)
+	}
+}
+
+func TestPlanPrune_DefaultsRetention(t *testing.T) {
+	cfg := &config.Config{
+		Backups:     []config.BackupConfig{{Name: "appdata", Destinations: []string{"NAS"}}},
+		Maintenance: []string{"Archive"},
+		Defaults:    config.DefaultsConfig{Retention: config.RetentionConfig{Daily: 14, Weekly: 8}},
+	}
+
+	tests := []struct {
+		storage  string
+		expected string
+	}{
+		{"NAS", "prune -storage NAS -id appdata -keep 0:70 -keep 7:14 -keep 1:1"},
+		{"Archive", "prune -storage Archive -keep 0:70 -keep 7:14 -keep 1:1 -a"},
+	}
+
+	for _, tt := range tests {
+		targets := planPrune(cfg, tt.storage)
+		if len(targets) != 1 {
+			t.Fatalf("%s: expected one prune target, got %d", tt.storage, len(targets))
+		}
+		if got := strings.Join(targets[0].args, " "); got != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.storage, tt.expected, got)
+		}
+	}
+}
+
+func TestPlanPrune_DestinationRetention(t *testing.T) {
+	cfg := &config.Config{
+		Backups: []config.BackupConfig{{
+			Name:                 "db",
+			Destinations:         []string{"Local", "Cloud"},
+			Retention:            config.RetentionConfig{Daily: 30, Weekly: 8},
+			DestinationRetention: map[string]config.RetentionConfig{"Cloud": {Daily: 7, Weekly: 4}},
+		}},
+	}
+
+	tests := []struct {
+		storage  string
+		expected string
+	}{
+		{"Local", "prune -storage Local -id db -keep 0:86 -keep 7:30 -keep 1:1"},
+		{"Cloud", "prune -storage Cloud -id db -keep 0:35 -keep 7:7 -keep 1:1"},
+	}
+
+	for _, tt := range tests {
+		targets := planPrune(cfg, tt.storage)
+		if len(targets) != 1 {
+			t.Fatalf("%s: expected one prune target, got %d", tt.storage, len(targets))
+		}
+		if got := strings.Join(targets[0].args, " "); got != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.storage, tt.expected, got)
+		}
+	}
+}
+
+func TestPlanPrune_StorageThreads(t *testing.T) {
+	cfg := &config.Config{
+		Backups:     []config.BackupConfig{{Name: "appdata", Destinations: []string{"NAS", "Cloud"}}},
+		Maintenance: []string{"Archive"},
+		Storages: map[string]config.StorageConfig{
+			"NAS":     {Threads: 1},
+			"Cloud":   {Threads: 4, Retention: config.RetentionConfig{Daily: 7, Weekly: 4}},
+			"Archive": {Threads: 2},
+		},
+	}
+
+	tests := []struct {
+		storage  string
+		expected string
+	}{
+		{"NAS", "prune -storage NAS -id appdata -keep 0:35 -keep 7:7 -keep 1:1"},
+		{"Cloud", "prune -storage Cloud -keep 0:35 -keep 7:7 -keep 1:1 -a -threads 4"},
+		{"Archive", "prune -storage Archive -keep 0:35 -keep 7:7 -keep 1:1 -a -threads 2"},
+	}
+
+	for _, tt := range tests {
+		targets := planPrune(cfg, tt.storage)
+		if len(targets) != 1 {
+			t.Fatalf("%s: expected one prune target, got %d", tt.storage, len(targets))
+		}
+		if got := strings.Join(targets[0].args, " "); got != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.storage, tt.expected, got)
+		}
+	}
+}
+
+func TestRunner_CheckStorageThreads(t *testing.T) {
+	cfg := &config.Config{
+		Maintenance: []string{"NAS"},
+		Storages:    map[string]config.StorageConfig{"NAS": {Threads: 6}},
+	}
+	fake := &fakeRunner{output: sampleCheckOutput}
+	r := &runner{cfg: cfg, summary: summary.New(time.Now())}
+
+	captureStdout(t, func() {
+		r.runCheckPhase(fake, nil, cfg.AllStorages())
+	})
+
+	cmds := fake.commands()
+	if len(cmds) != 1 || cmds[0] != "check -tabular -storage NAS -threads 6" {
+		t.Errorf("unexpected commands: %v", cmds)
+	}
+}
+
+func TestRunner_PruneExclude(t *testing.T) {
+	cfg := &config.Config{
+		Backups: []config.BackupConfig{
+			{Name: "appdata", Destinations: []string{"NAS"}},
+			{Name: "frozen", Destinations: []string{"NAS"}},
+			{Name: "photos", Destinations: []string{"NAS"}},
+		},
+		Storages: map[string]config.StorageConfig{"NAS": {PruneExclude: []string{"frozen"}}},
+	}
+	fake := &fakeRunner{}
+	r := &runner{cfg: cfg, summary: summary.New(time.Now())}
+
+	captureStdout(t, func() {
+		r.runPrunePhase(fake, []string{"NAS"})
+	})
+
+	var pruned []string
+	for _, c := range fake.commands() {
+		pruned = append(pruned, argValue(strings.Fields(c), "-id"))
+	}
+	if strings.Join(pruned, ",") != "appdata,photos" {
+		t.Errorf("expected appdata and photos pruned, got %v", fake.commands())
+	}
+}
+
+func TestPlanPrune_AllExcluded(t *testing.T) {
+	cfg := &config.Config{
+		Backups:  []config.BackupConfig{{Name: "frozen", Destinations: []string{"NAS"}}},
+		Storages: map[string]config.StorageConfig{"NAS": {PruneExclude: []string{"frozen"}}},
+	}
+
+	// Excluding every backup must not fall back to pruning all IDs with -a
+	if targets := planPrune(cfg, "NAS"); len(targets) != 0 {
+		t.Errorf("expected no prune targets, got %+v", targets)
+	}
+}
+
+func TestRunner_DuplicacyMissing(t *testing.T) {
+	notFound := fmt.Errorf("%w: no such container", executor.ErrDuplicacyNotFound)
+	cfg := &config.Config{
+		Backups: []config.BackupConfig{{Name: "appdata", Destinations: []string{"NAS", "Cloud"}}},
+	}
+
+	t.Run("all storages", func(t *testing.T) {
+		fake := &fakeRunner{errs: map[string]error{
+			"backup NAS": notFound, "backup Cloud": notFound,
+			"prune NAS": notFound, "prune Cloud": notFound,
+			"check NAS": notFound, "check Cloud": notFound,
+		}}
+		r := &runner{cfg: cfg, summary: summary.New(time.Now()), newRunner: fake.factory()}
+		captureStdout(t, r.execute)
+
+		if !r.duplicacyMissing() {
+			t.Error("expected duplicacy to be reported missing when every invocation fails discovery")
+		}
+	})
+
+	t.Run("some storages", func(t *testing.T) {
+		fake := &fakeRunner{errs: map[string]error{
+			"backup NAS": notFound, "prune NAS": notFound, "check NAS": notFound,
+			"backup Cloud": errors.New("command exited with code 1"),
+		}}
+		r := &runner{cfg: cfg, summary: summary.New(time.Now()), newRunner: fake.factory()}
+		captureStdout(t, r.execute)
+
+		if r.duplicacyMissing() {
+			t.Error("duplicacy should not be reported missing when some invocations reached it")
+		}
+		// The failed NAS check keeps NAS from being pruned
+		if len(r.errors) != 3 {
+			t.Errorf("expected 3 errors, got %v", r.errors)
+		}
+	})
+}
+
+func TestRunner_GrowthAlert(t *testing.T) {
+	// sampleCheckOutput parses to a total size of 8,853K
+	current, err := stats.ParseCheckOutput(sampleCheckOutput)
+	if err != nil {
+		t.Fatalf("failed to parse sample output: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		threshold float64
+		prevSize  int64
+		wantAlert bool
+	}{
+		{"above threshold", 20, current.TotalSize / 2, true},
+		{"below threshold", 20, current.TotalSize - 1, false},
+		{"disabled", 0, current.TotalSize / 2, false},
+		{"first run", 20, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Maintenance: []string{"NAS"},
+				Stats:       config.StatsConfig{GrowthAlertPct: tt.threshold},
+			}
+			writer := &fakeStatsUpdater{}
+			if tt.prevSize > 0 {
+				writer.previous = stats.UpdateResult{PreviousDate: "2025-01-01", Previous: &stats.DayStats{TotalSize: tt.prevSize}}
+			}
+			r := &runner{cfg: cfg, summary: summary.New(time.Now())}
+
+			captureStdout(t, func() {
+				r.runCheckPhase(&fakeRunner{output: sampleCheckOutput}, writer, cfg.AllStorages())
+			})
+
+			if tt.wantAlert {
+				if len(r.warnings) != 1 || !strings.Contains(r.warnings[0], "NAS grew 100.0% since 2025-01-01") {
+					t.Errorf("expected growth warning, got %v", r.warnings)
+				}
+				if len(r.summary.Warnings) != 1 {
+					t.Errorf("expected warning in summary, got %v", r.summary.Warnings)
+				}
+			} else if len(r.warnings) != 0 {
+				t.Errorf("expected no warnings, got %v", r.warnings)
+			}
+			if len(r.errors) != 0 {
+				t.Errorf("growth alerts should not be errors, got %v", r.errors)
+			}
+		})
+	}
+}
+
+// fakeFileReader serves container files from memory
+type fakeFileReader map[string]string
+
+func (f fakeFileReader) ReadFile(path string) (string, error) {
+	data, ok := f[path]
+	if !ok {
+		return "", errors.New("no such file")
+	}
+	return data, nil
+}
+
+func TestLoadKeyring(t *testing.T) {
+	cfg := &config.Config{
+		Connection: config.ConnectionConfig{Container: "Duplicacy", KeyringPath: "/config/keyring"},
+		Backups:    []config.BackupConfig{{Name: "appdata", Destinations: []string{"NAS", "GoogleDrive"}}},
+		Storages:   map[string]config.StorageConfig{"GoogleDrive": {Name: "gcd-2024"}},
+	}
+	reader := fakeFileReader{"/config/keyring": `{"NAS": "nas-pw", "gcd-2024": "gd-pw", "Unused": "x"}`}
+
+	passwords, err := loadKeyring(reader, cfg)
+	if err != nil {
+		t.Fatalf("loadKeyring() error: %v", err)
+	}
+	if len(passwords) != 2 || passwords["NAS"] != "nas-pw" || passwords["gcd-2024"] != "gd-pw" {
+		t.Errorf("expected passwords for configured storages by duplicacy name, got %v", passwords)
+	}
+
+	// The passwords reach the executor for the right storage
+	r := &runner{cfg: cfg, storagePasswords: passwords, summary: summary.New(time.Now())}
+	out := captureStdout(t, func() {
+		defer func() { dryRun = false }()
+		dryRun = true
+		r.newExecutor("").RunDuplicacyWithStorage("gcd-2024", "check")
+	})
+	if !strings.Contains(out, "DUPLICACY_GCD_2024_PASSWORD") {
+		t.Errorf("expected keyring password export for gcd-2024, got %q", out)
+	}
+
+	if _, err := loadKeyring(fakeFileReader{}, cfg); err == nil {
+		t.Error("expected error when keyring cannot be read")
+	}
+	if _, err := loadKeyring(fakeFileReader{"/config/keyring": "not json"}, cfg); err == nil {
+		t.Error("expected error for malformed keyring")
+	}
+}
+
+func TestRunner_StatsWriteFailure(t *testing.T) {
+	tests := []struct {
+		name      string
+		required  bool
+		wantError bool
+	}{
+		{"warning only by default", false, false},
+		{"required", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Maintenance: []string{"NAS"},
+				Stats:       config.StatsConfig{Required: tt.required},
+			}
+			writer := &fakeStatsUpdater{err: errors.New("read-only file system")}
+			r := &runner{cfg: cfg, summary: summary.New(time.Now())}
+
+			captureStdout(t, func() {
+				r.runCheckPhase(&fakeRunner{output: sampleCheckOutput}, writer, cfg.AllStorages())
+			})
+
+			if len(writer.storages) != 1 {
+				t.Fatalf("expected stats write to be attempted, got %v", writer.storages)
+			}
+			if tt.wantError {
+				if len(r.errors) != 1 || r.errors[0] != "stats NAS: read-only file system" {
+					t.Errorf("expected stats error, got %v", r.errors)
+				}
+			} else if len(r.errors) != 0 {
+				t.Errorf("expected stats failure to be a warning only, got errors %v", r.errors)
+			}
+
+			// The check itself still succeeded either way
+			if r.summary.Phases[0].Status != summary.StatusSuccess {
+				t.Errorf("expected check operation to succeed, got %q", r.summary.Phases[0].Status)
+			}
+		})
+	}
+}
+
+func TestRunner_CacheDirFor(t *testing.T) {
+	tests := []struct {
+		name        string
+		backup      config.BackupConfig
+		discovered  string
+		discoverErr error
+		expected    string
+		discovers   bool
+	}{
+		{
+			name:       "configured cache_dir wins",
+			backup:     config.BackupConfig{Name: "appdata", Path: "/mnt/appdata", CacheDir: "/cache/localhost/3"},
+			discovered: "/cache/localhost/0/appdata",
+			expected:   "/cache/localhost/3",
+		},
+		{
+			name:       "discovered",
+			backup:     config.BackupConfig{Name: "appdata", Path: "/mnt/appdata"},
+			discovered: "/cache/localhost/0/appdata",
+			expected:   "/cache/localhost/0/appdata",
+			discovers:  true,
+		},
+		{
+			name:      "nothing discovered falls back to path",
+			backup:    config.BackupConfig{Name: "appdata", Path: "/mnt/appdata"},
+			expected:  "/mnt/appdata",
+			discovers: true,
+		},
+		{
+			name:        "discovery error falls back to path",
+			backup:      config.BackupConfig{Name: "appdata", Path: "/mnt/appdata"},
+			discoverErr: errors.New("command exited with code 255"),
+			expected:    "/mnt/appdata",
+			discovers:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeRunner{
+				cacheDirs: map[string]string{"appdata": tt.discovered},
+				errs:      map[string]error{"discover appdata": tt.discoverErr},
+			}
+			r := &runner{cfg: &config.Config{}, newRunner: fake.factory()}
+
+			// Resolve twice: discovery must be cached
+			for i := 0; i < 2; i++ {
+				if got := r.cacheDirFor(tt.backup); got != tt.expected {
+					t.Errorf("expected %q, got %q", tt.expected, got)
+				}
+			}
+
+			wantDiscoveries := 0
+			if tt.discovers {
+				wantDiscoveries = 1
+			}
+			if len(fake.discoveries) != wantDiscoveries {
+				t.Errorf("expected %d discoveries, got %v", wantDiscoveries, fake.discoveries)
+			}
+		})
+	}
+}
+
+func TestRunner_BackupPhaseUsesDiscoveredCacheDir(t *testing.T) {
+	cfg := &config.Config{
+		Backups: []config.BackupConfig{{Name: "appdata", Path: "/mnt/appdata", Destinations: []string{"NAS"}}},
+	}
+	fake := &fakeRunner{cacheDirs: map[string]string{"appdata": "/cache/localhost/0/appdata"}}
+	r := &runner{cfg: cfg, summary: summary.New(time.Now()), newRunner: fake.factory()}
+
+	captureStdout(t, func() { r.runBackupPhase() })
+
+	var cacheDirs []string
+	for _, opts := range fake.options {
+		cacheDirs = append(cacheDirs, opts.CacheDir)
+	}
+	if len(cacheDirs) != 2 || cacheDirs[1] != "/cache/localhost/0/appdata" {
+		t.Errorf("expected backup executor to use discovered cache dir, got %v", cacheDirs)
+	}
+}
+
+func TestRunner_CheckOutputDir(t *testing.T) {
+	defer func() { checkOutputDir = "" }()
+	checkOutputDir = filepath.Join(t.TempDir(), "checks")
+
+	cfg := &config.Config{Maintenance: []string{"NAS", "Cloud"}}
+	fake := &fakeRunner{
+		output: sampleCheckOutput,
+		errs:   map[string]error{"check Cloud": errors.New("command exited with code 1")},
+	}
+	r := &runner{cfg: cfg, summary: summary.New(time.Now())}
+
+	captureStdout(t, func() {
+		r.runCheckPhase(fake, nil, cfg.AllStorages())
+	})
+
+	// Output is archived for failed checks too, since that is when it matters most
+	for _, storage := range []string{"NAS", "Cloud"} {
+		path := filepath.Join(checkOutputDir, storage+"-"+stats.TodayDate()+".txt")
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Errorf("expected archived output for %s: %v", storage, err)
+			continue
+		}
+		if string(data) != sampleCheckOutput {
+			t.Errorf("expected %s output %q, got %q", storage, sampleCheckOutput, string(data))
+		}
+	}
+}
+
+func TestRunner_InterruptedSkipsRemainingPhases(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cfg := &config.Config{
+		Backups: []config.BackupConfig{
+			{Name: "first", Path: "/mnt/first", Destinations: []string{"NAS", "Cloud"}, PostHook: "unlock"},
+			{Name: "second", Path: "/mnt/second", Destinations: []string{"NAS"}},
+		},
+		Hooks: config.HooksConfig{Post: "cleanup"},
+	}
+	fake := &fakeRunner{}
+	r := &runner{ctx: ctx, cfg: cfg, summary: summary.New(time.Now())}
+
+	// Cancel while the first backup is running, as SIGINT would
+	r.newRunner = func(opts executor.Options) duplicacyRunner {
+		return &cancellingRunner{fakeRunner: fake, cancel: cancel}
+	}
+
+	captureStdout(t, func() { r.execute() })
+
+	cmds := fake.commands()
+	if len(cmds) != 1 || !strings.HasPrefix(cmds[0], "backup -storage NAS") {
+		t.Errorf("expected only the interrupted backup to run, got %v", cmds)
+	}
+	if len(r.errors) != 1 || r.errors[0] != "run interrupted; remaining phases skipped" {
+		t.Errorf("expected interrupted error, got %v", r.errors)
+	}
+}
+
+func TestRunner_DeadlineSkipsRemainingPhases(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	cfg := &config.Config{
+		Backups: []config.BackupConfig{
+			{Name: "first", Path: "/mnt/first", Destinations: []string{"NAS", "Cloud"}},
+			{Name: "second", Path: "/mnt/second", Destinations: []string{"NAS"}},
+		},
+		Hooks: config.HooksConfig{Post: "cleanup"},
+	}
+	fake := &fakeRunner{}
+	r := &runner{ctx: ctx, cfg: cfg, summary: summary.New(time.Now())}
+
+	// Each backup outlasts the deadline, as a slow dry run would
+	r.newRunner = func(opts executor.Options) duplicacyRunner {
+		return &slowRunner{fakeRunner: fake, delay: 50 * time.Millisecond}
+	}
+
+	captureStdout(t, func() { r.execute() })
+
+	cmds := fake.commands()
+	if len(cmds) != 1 || !strings.HasPrefix(cmds[0], "backup -storage NAS") {
+		t.Errorf("expected the run to abort after the first backup, got %v", cmds)
+	}
+	if len(r.errors) != 1 || r.errors[0] != "run deadline exceeded; remaining phases skipped" {
+		t.Errorf("expected deadline error, got %v", r.errors)
+	}
+}
+
+func TestRunDeadline(t *testing.T) {
+	defer func() { deadline = 0 }()
+
+	tests := []struct {
+		name     string
+		flag     time.Duration
+		config   string
+		expected time.Duration
+		wantErr  bool
+	}{
+		{"unset", 0, "", 0, false},
+		{"config", 0, "6h", 6 * time.Hour, false},
+		{"flag overrides config", 30 * time.Minute, "6h", 30 * time.Minute, false},
+		{"negative flag", -time.Minute, "", 0, true},
+		{"invalid config", 0, "soon", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deadline = tt.flag
+			cfg := &config.Config{Connection: config.ConnectionConfig{RunDeadline: tt.config}}
+			got, err := runDeadline(cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("runDeadline() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+// slowRunner delays every duplicacy invocation
+type slowRunner struct {
+	*fakeRunner
+	delay time.Duration
+}
+
+func (s *slowRunner) RunDuplicacyStreamWithStorage(storage string, args ...string) (executor.Result, error) {
+	time.Sleep(s.delay)
+	return s.fakeRunner.RunDuplicacyStreamWithStorage(storage, args...)
+}
+
+// cancellingRunner cancels the run context on its first duplicacy invocation
+type cancellingRunner struct {
+	*fakeRunner
+	cancel context.CancelFunc
+}
+
+func (c *cancellingRunner) RunDuplicacyStreamWithStorage(storage string, args ...string) (executor.Result, error) {
+	result, err := c.fakeRunner.RunDuplicacyStreamWithStorage(storage, args...)
+	c.cancel()
+	return result, err
+}
+
+func TestRunner_BackupPhase_StoragePriority(t *testing.T) {
+	cfg := &config.Config{
+		Backups:  []config.BackupConfig{{Name: "appdata", Path: "/mnt/appdata", Destinations: []string{"Cloud", "NAS"}}},
+		Storages: map[string]config.StorageConfig{"NAS": {Priority: 1}, "Cloud": {Priority: 2}},
+	}
+	fake := &fakeRunner{}
+	r := &runner{cfg: cfg, summary: summary.New(time.Now()), newRunner: fake.factory()}
+
+	captureStdout(t, r.runBackupPhase)
+
+	cmds := fake.commands()
+	if len(cmds) != 2 || !strings.HasPrefix(cmds[0], "backup -storage NAS") || !strings.HasPrefix(cmds[1], "backup -storage Cloud") {
+		t.Errorf("expected NAS before Cloud, got %v", cmds)
+	}
+}
+
+func TestRunner_BackupRevision(t *testing.T) {
+	cfg := &config.Config{
+		Backups: []config.BackupConfig{{Name: "appdata", Path: "/mnt/appdata", Destinations: []string{"NAS", "Cloud"}}},
+	}
+	fake := &fakeRunner{
+		output: "Backup for /mnt/appdata at revision 12 completed\n",
+		errs:   map[string]error{"backup Cloud": errors.New("command exited with code 1")},
+	}
+	r := &runner{cfg: cfg, summary: summary.New(time.Now()), newRunner: fake.factory()}
+
+	out := captureStdout(t, r.runBackupPhase)
+
+	if !strings.Contains(out, "OK (revision 12)") {
+		t.Errorf("expected the revision in the output, got %q", out)
+	}
+	ops := r.summary.Phases[0].Operations
+	if len(ops) != 2 {
+		t.Fatalf("expected 2 operations, got %+v", ops)
+	}
+	if ops[0].Storage != "NAS" || ops[0].Revision != 12 {
+		t.Errorf("expected NAS at revision 12, got %+v", ops[0])
+	}
+	// A failed backup created no revision, whatever it printed
+	if ops[1].Storage != "Cloud" || ops[1].Revision != 0 {
+		t.Errorf("expected Cloud without a revision, got %+v", ops[1])
+	}
+}
+
+func TestRunner_CheckCredentials(t *testing.T) {
+	t.Setenv("DUPLICACY_PASSWORD", "")
+	cfg := &config.Config{
+		Backups: []config.BackupConfig{{Name: "appdata", Path: "/mnt/appdata", Destinations: []string{"NAS", "GoogleDrive"}}},
+		Storages: map[string]config.StorageConfig{
+			"GoogleDrive": {Type: config.StorageTypeGCD},
+		},
+		Connection: config.ConnectionConfig{GCDToken: "/config/gcd-token.json"},
+	}
+	fake := &fakeRunner{errs: map[string]error{
+		"shell test -f /config/gcd-token.json": errors.New("command exited with code 1"),
+	}}
+
+	tests := []struct {
+		name      string
+		passwords map[string]string
+		strict    bool
+		warnings  int
+		wantErr   bool
+	}{
+		{"keyring covers passwords, token file missing", map[string]string{"NAS": "a", "GoogleDrive": "b"}, false, 1, false},
+		{"password and token missing", map[string]string{"NAS": "a"}, false, 2, false},
+		{"strict makes them an error", map[string]string{"NAS": "a"}, true, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prevStrict := strict
+			strict = tt.strict
+			defer func() { strict = prevStrict }()
+
+			r := &runner{cfg: cfg, summary: summary.New(time.Now()), newRunner: fake.factory(), storagePasswords: tt.passwords}
+			var err error
+			captureStdout(t, func() { err = r.checkCredentials() })
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if len(r.warnings) != tt.warnings {
+				t.Errorf("expected %d warning(s), got %v", tt.warnings, r.warnings)
+			}
+		})
+	}
+
+	// With the token file present and passwords set there is nothing to report
+	fake = &fakeRunner{}
+	r := &runner{cfg: cfg, summary: summary.New(time.Now()), newRunner: fake.factory(),
+		storagePasswords: map[string]string{"NAS": "a", "GoogleDrive": "b"}}
+	if err := r.checkCredentials(); err != nil || len(r.warnings) != 0 {
+		t.Errorf("expected no problems, got %v %v", err, r.warnings)
+	}
+	if cmds := fake.commands(); len(cmds) != 1 || cmds[0] != "shell test -f /config/gcd-token.json" {
+		t.Errorf("expected one token file check, got %v", cmds)
+	}
+
+	// A token path with a quote is quoted like every other shell word
+	cfg.Connection.GCDToken = "/config/o'brien token.json"
+	fake = &fakeRunner{}
+	r = &runner{cfg: cfg, summary: summary.New(time.Now()), newRunner: fake.factory(),
+		storagePasswords: map[string]string{"NAS": "a", "GoogleDrive": "b"}}
+	r.checkCredentials()
+	if cmds := fake.commands(); len(cmds) != 1 || cmds[0] != `shell test -f '/config/o'"'"'brien token.json'` {
+		t.Errorf("expected the token path quoted, got %v", cmds)
+	}
+}
+
+func TestRunner_CheckStatsStatus(t *testing.T) {
+	cfg := &config.Config{Maintenance: []string{"NAS", "Cloud"}}
+	fake := &fakeRunner{
+		output: sampleCheckOutput,
+		errs:   map[string]error{"check Cloud": errors.New("command exited with code 1")},
+	}
+	writer := &fakeStatsUpdater{}
+	r := &runner{cfg: cfg, summary: summary.New(time.Now())}
+
+	captureStdout(t, func() {
+		r.runCheckPhase(fake, writer, cfg.AllStorages())
+	})
+
+	expected := map[string]string{"NAS": stats.StatusChecked, "Cloud": stats.StatusFailed}
+	for storage, status := range expected {
+		ds := writer.written[storage]
+		if ds == nil {
+			t.Errorf("expected stats written for %s", storage)
+			continue
+		}
+		if ds.Status != status {
+			t.Errorf("%s: expected status %q, got %q", storage, status, ds.Status)
+		}
+	}
+	if len(r.errors) != 1 {
+		t.Errorf("expected the failed check to be an error, got %v", r.errors)
+	}
+}
+
+func TestRunner_PruneMinRevisions(t *testing.T) {
+	cfg := &config.Config{
+		Maintenance: []string{"NAS"},
+		Storages: map[string]config.StorageConfig{
+			"NAS": {Retention: config.RetentionConfig{Daily: 1, Weekly: 1}, MinRevisions: 30},
+		},
+	}
+	fake := &fakeRunner{output: dailyListOutput(time.Now(), 20, "appdata")}
+	r := &runner{cfg: cfg, summary: summary.New(time.Now())}
+
+	captureStdout(t, func() {
+		r.runPrunePhase(fake, cfg.AllStorages())
+	})
+
+	for _, c := range fake.commands() {
+		if strings.HasPrefix(c, "prune ") {
+			t.Errorf("expected prune to be refused, got %q", c)
+		}
+	}
+	if len(r.errors) != 1 || !strings.Contains(r.errors[0], "refusing to prune") {
+		t.Errorf("expected a refusal error, got %v", r.errors)
+	}
+}
+
+func TestRunner_NoStats(t *testing.T) {
+	defer func() { noStats = false }()
+
+	for _, skip := range []bool{false, true} {
+		noStats = skip
+		cfg := &config.Config{Maintenance: []string{"NAS"}}
+		fake := &fakeRunner{output: sampleCheckOutput}
+		writer := &fakeStatsUpdater{}
+		r := &runner{cfg: cfg, summary: summary.New(time.Now()), statsWriter: writer}
+		r.newRunner = fake.factory()
+
+		out := captureStdout(t, func() { r.execute() })
+
+		if skip && len(writer.storages) != 0 {
+			t.Errorf("--no-stats: expected no stats writes, got %v", writer.storages)
+		}
+		if !skip && len(writer.storages) != 1 {
+			t.Errorf("expected stats written for NAS, got %v", writer.storages)
+		}
+		if !strings.Contains(out, "Total chunk size is") {
+			t.Errorf("no-stats=%v: expected check output to be printed", skip)
+		}
+		if !strings.Contains(out, "Storage Stats Summary:") {
+			t.Errorf("no-stats=%v: expected the stats summary", skip)
+		}
+		if _, ok := r.summary.Storages["NAS"]; !ok {
+			t.Errorf("no-stats=%v: expected NAS stats in the run summary", skip)
+		}
+		if len(r.errors) != 0 {
+			t.Errorf("no-stats=%v: unexpected errors %v", skip, r.errors)
+		}
+	}
+}
+
+func TestRunner_NoStatsStrictParse(t *testing.T) {
+	defer func() { noStats = false }()
+	noStats = true
+
+	cfg := &config.Config{Maintenance: []string{"NAS"}, Stats: config.StatsConfig{StrictParse: true}}
+	fake := &fakeRunner{output: "unexpected check output\n"}
+	writer := &fakeStatsUpdater{}
+	r := &runner{cfg: cfg, summary: summary.New(time.Now()), statsWriter: writer, newRunner: fake.factory()}
+
+	captureStdout(t, func() { r.execute() })
+
+	if len(r.errors) != 1 || !strings.Contains(r.errors[0], "failed to parse check output") {
+		t.Errorf("expected strict_parse to fail the run under --no-stats, got %v", r.errors)
+	}
+	if len(writer.storages) != 0 {
+		t.Errorf("--no-stats: expected no stats writes, got %v", writer.storages)
+	}
+}
+
+func TestRunner_BackupOKExitCodes(t *testing.T) {
+	tests := []struct {
+		name    string
+		okCodes []int
+		code    int
+		wantErr bool
+		status  string
+	}{
+		{"default accepts 100", nil, 100, false, summary.StatusSkipped},
+		{"default rejects 1", nil, 1, true, summary.StatusFailed},
+		{"custom set", []int{100, 101}, 101, false, summary.StatusSuccess},
+		{"custom set without 100", []int{101}, 100, true, summary.StatusFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Connection: config.ConnectionConfig{OKExitCodes: tt.okCodes},
+				Backups:    []config.BackupConfig{{Name: "appdata", Path: "/mnt/appdata", Destinations: []string{"NAS"}}},
+			}
+			fake := &fakeRunner{errs: map[string]error{"backup NAS": &executor.ExitError{Code: tt.code}}}
+			r := &runner{cfg: cfg, summary: summary.New(time.Now())}
+			r.newRunner = fake.factory()
+
+			captureStdout(t, func() { r.runBackupPhase() })
+
+			if gotErr := len(r.errors) > 0; gotErr != tt.wantErr {
+				t.Errorf("exit code %d: expected error %v, got %v", tt.code, tt.wantErr, r.errors)
+			}
+			if gotFailed := len(r.failedBackups) > 0; gotFailed != tt.wantErr {
+				t.Errorf("exit code %d: expected failed backup %v, got %v", tt.code, tt.wantErr, r.failedBackups)
+			}
+			// Nothing to back up (100) is reported as skipped, not success
+			if op := r.summary.Phases[0].Operations[0]; op.Status != tt.status {
+				t.Errorf("exit code %d: expected status %q, got %q", tt.code, tt.status, op.Status)
+			}
+		})
+	}
+}
+
+func TestRunner_BackupLockedExit100(t *testing.T) {
+	cfg := &config.Config{
+		Backups: []config.BackupConfig{{Name: "appdata", Path: "/mnt/appdata", Destinations: []string{"NAS"}}},
+	}
+	locked := &lockedExitError{&executor.ExitError{Code: 100, Stderr: "ERROR The storage is locked by another duplicacy operation"}}
+	fake := &fakeRunner{errs: map[string]error{"backup NAS": locked}}
+	r := &runner{cfg: cfg, summary: summary.New(time.Now()), newRunner: fake.factory()}
+
+	captureStdout(t, r.runBackupPhase)
+
+	if len(r.errors) != 1 || !strings.Contains(r.errors[0], "locked by another duplicacy operation") {
+		t.Errorf("expected the locked storage error, got %v", r.errors)
+	}
+	if op := r.summary.Phases[0].Operations[0]; op.Status != summary.StatusFailed {
+		t.Errorf("expected the locked backup to fail despite exit code 100, got %q", op.Status)
+	}
+	if counts := r.summary.CountOperations(); counts.Failed != 1 || counts.Skipped != 0 {
+		t.Errorf("expected 1 failed and 0 skipped, got %+v", counts)
+	}
+}
+
+// lockedExitError is an exit error the executor classified as a locked
+// storage, as executor.ErrStorageLocked errors are
+type lockedExitError struct {
+	*executor.ExitError
+}
+
+func (e *lockedExitError) Error() string {
+	return fmt.Sprintf("storage NAS is locked by another duplicacy operation (exit code %d)", e.Code)
+}
+
+func (e *lockedExitError) Unwrap() error { return e.ExitError }
+
+func (e *lockedExitError) Is(target error) bool { return target == executor.ErrStorageLocked }
+
+func TestPrintOperationCounts(t *testing.T) {
+	s := summary.New(time.Now())
+	phase := s.StartPhase("backup")
+	phase.AddOperation(summary.OperationResult{Backup: "appdata", Storage: "NAS", Status: summary.StatusSuccess})
+	phase.AddOperation(summary.OperationResult{Backup: "photos", Storage: "NAS", Status: summary.StatusSkipped, Reason: "unchanged: nothing to back up"})
+	phase.AddOperation(summary.OperationResult{Backup: "photos", Storage: "Cloud", Status: summary.StatusFailed})
+
+	out := captureStdout(t, func() { printOperationCounts(s) })
+
+	expected := "\nOperations: 1 succeeded, 1 skipped/unchanged, 1 failed\n" +
+		"  - photos -> NAS: skipped (unchanged: nothing to back up)\n"
+	if out != expected {
+		t.Errorf("expected %q, got %q", expected, out)
+	}
+
+	// A run with no operations prints nothing
+	if out := captureStdout(t, func() { printOperationCounts(summary.New(time.Now())) }); out != "" {
+		t.Errorf("expected no output, got %q", out)
+	}
+}
+
+func TestNoNotify_SkipsHTTP(t *testing.T) {
+	var mu sync.Mutex
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Notifications: config.NotificationConfig{
+			Forgejo: config.ForgejoNotificationConfig{
+				URL:      server.URL,
+				Repo:     "user/repo",
+				Token:    "testtoken",
+				Assignee: "admin",
+			},
+		},
+	}
+	if !forgejoConfigured(cfg) {
+		t.Fatal("expected forgejo to be fully configured")
+	}
+
+	defer func(url, repo, token string) {
+		noNotify = false
+		forgejoURL, forgejoRepo, forgejoToken = url, repo, token
+	}(forgejoURL, forgejoRepo, forgejoToken)
+	noNotify = true
+	forgejoURL, forgejoRepo, forgejoToken = server.URL, "user/repo", "testtoken"
+
+	if _, ok := newRunNotifier(cfg).(*notifier.NullNotifier); !ok {
+		t.Error("expected the null notifier with --no-notify")
+	}
+	if err := sendRunFailureNotification(cfg, []string{"backup failed"}, []string{"appdata"}, nil); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := sendGrowthAlertNotification(cfg, []string{"grew 50%"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := sendFailureNotification([]string{"backup failed"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if requests != 0 {
+		t.Errorf("expected no HTTP requests with --no-notify, got %d", requests)
+	}
+}
+
+func TestFailureTitle(t *testing.T) {
+	tests := []struct {
+		name string
+		tmpl string
+		host string
+		want string
+	}{
+		{"default", "", "", "[duplicaci] appdata: backup failed"},
+		{"ssh host", "[{{.Host}}]" + notifier.DefaultTitleTemplate, "root@nas01", "[nas01][duplicaci] appdata: backup failed"},
+		{"render error falls back", "{{.Environment}}", "", "[duplicaci] appdata: backup failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := failureTitle(tt.tmpl, []string{"appdata"}, tt.host)
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRunner_KnownHostsBeforeBackup(t *testing.T) {
+	cfg := &config.Config{
+		Backups: []config.BackupConfig{{Name: "appdata", Destinations: []string{"SFTP", "NAS"}}},
+		Storages: map[string]config.StorageConfig{
+			"SFTP": {KnownHost: "sftp.example.com:2222"},
+			"NAS":  {},
+		},
+	}
+	fake := &fakeRunner{}
+	r := &runner{cfg: cfg, summary: summary.New(time.Now()), newRunner: fake.factory()}
+
+	captureStdout(t, r.execute)
+
+	cmds := fake.commands()
+	if len(cmds) == 0 || cmds[0] != "shell "+knownHostCommand("sftp.example.com:2222", "") {
+		t.Fatalf("expected the known_hosts setup to run first, got %v", cmds)
+	}
+	if !strings.HasPrefix(cmds[1], "backup -storage SFTP") {
+		t.Errorf("expected the backup after setup, got %v", cmds)
+	}
+}
+
+func TestRunner_KnownHostsPerWorkingDir(t *testing.T) {
+	cfg := &config.Config{
+		Backups: []config.BackupConfig{
+			{Name: "appdata", CacheDir: "/cache/localhost/0", Destinations: []string{"SFTP"}},
+			{Name: "photos", CacheDir: "/cache/localhost/1", Destinations: []string{"SFTP"}},
+		},
+		MaintenanceDir: "/cache/localhost/all",
+		Storages:       map[string]config.StorageConfig{"SFTP": {KnownHost: "sftp.example.com"}},
+	}
+	fake := &fakeRunner{}
+	r := &runner{cfg: cfg, summary: summary.New(time.Now()), newRunner: fake.factory()}
+
+	captureStdout(t, r.addKnownHosts)
+
+	expected := []string{
+		"shell " + knownHostCommand("sftp.example.com", "/cache/localhost/0"),
+		"shell " + knownHostCommand("sftp.example.com", "/cache/localhost/1"),
+		"shell " + knownHostCommand("sftp.example.com", "/cache/localhost/all"),
+	}
+	if got := fake.commands(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected a known_hosts entry per working dir:\n%v\ngot\n%v", expected, got)
+	}
+}
+
+func TestRunner_KnownHostsFailureRecorded(t *testing.T) {
+	cfg := &config.Config{
+		Backups:  []config.BackupConfig{{Name: "appdata", Destinations: []string{"SFTP"}}},
+		Storages: map[string]config.StorageConfig{"SFTP": {KnownHost: "sftp.example.com"}},
+	}
+	fake := &fakeRunner{errs: map[string]error{
+		"shell " + knownHostCommand("sftp.example.com", ""): errors.New("command exited with code 1"),
+	}}
+	r := &runner{cfg: cfg, summary: summary.New(time.Now()), newRunner: fake.factory()}
+
+	captureStdout(t, r.execute)
+
+	if len(r.errors) != 1 || !strings.HasPrefix(r.errors[0], "known_host sftp.example.com:") {
+		t.Errorf("expected a known_host error, got %v", r.errors)
+	}
+	if len(fake.commands()) < 2 {
+		t.Errorf("expected the run to continue after a setup failure, got %v", fake.commands())
+	}
+}
+
+func TestRunner_BackupLimitRate(t *testing.T) {
+	cfg := &config.Config{
+		Backups:  []config.BackupConfig{{Name: "appdata", Destinations: []string{"NAS", "Cloud"}}},
+		Storages: map[string]config.StorageConfig{"Cloud": {LimitRate: 2048}},
+	}
+	fake := &fakeRunner{}
+	r := &runner{cfg: cfg, summary: summary.New(time.Now()), newRunner: fake.factory()}
+
+	captureStdout(t, r.runBackupPhase)
+
+	cmds := fake.commands()
+	expected := []string{"backup -storage NAS", "backup -storage Cloud -limit-rate 2048"}
+	if strings.Join(cmds, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected %v, got %v", expected, cmds)
+	}
+}
+
+func TestRunner_BackupOptions(t *testing.T) {
+	cfg := &config.Config{
+		Backups: []config.BackupConfig{
+			{Name: "appdata", Destinations: []string{"NAS", "Cloud"}, BackupOptions: []string{"-hash"}},
+			{Name: "photos", Destinations: []string{"NAS"}},
+		},
+	}
+	fake := &fakeRunner{}
+	r := &runner{cfg: cfg, summary: summary.New(time.Now()), newRunner: fake.factory()}
+
+	captureStdout(t, r.runBackupPhase)
+
+	cmds := fake.commands()
+	expected := []string{"backup -storage NAS -hash", "backup -storage Cloud -hash", "backup -storage NAS"}
+	if strings.Join(cmds, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected %v, got %v", expected, cmds)
+	}
+}
+
+func TestRunner_DetectRepository(t *testing.T) {
+	prefs := `[{"name": "NAS", "id": "appdata", "repository": "/backuproot/appdata"}]`
+
+	tests := []struct {
+		name     string
+		backup   config.BackupConfig
+		expected []string
+	}{
+		{
+			name:   "detected from preferences",
+			backup: config.BackupConfig{Name: "appdata", CacheDir: "/cache/localhost/3", DetectRepository: true, Destinations: []string{"NAS", "Cloud"}},
+			expected: []string{
+				"preferences /cache/localhost/3",
+				"backup -storage NAS -repository /backuproot/appdata",
+				"backup -storage Cloud -repository /backuproot/appdata",
+			},
+		},
+		{
+			name:     "explicit repository wins",
+			backup:   config.BackupConfig{Name: "appdata", CacheDir: "/cache/localhost/3", Repository: "/mnt/user", DetectRepository: true, Destinations: []string{"NAS"}},
+			expected: []string{"backup -storage NAS -repository /mnt/user"},
+		},
+		{
+			name:     "no matching snapshot id",
+			backup:   config.BackupConfig{Name: "photos", CacheDir: "/cache/localhost/3", DetectRepository: true, Destinations: []string{"NAS"}},
+			expected: []string{"preferences /cache/localhost/3", "backup -storage NAS"},
+		},
+		{
+			name:     "detection off",
+			backup:   config.BackupConfig{Name: "appdata", CacheDir: "/cache/localhost/3", Destinations: []string{"NAS"}},
+			expected: []string{"backup -storage NAS"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Backups: []config.BackupConfig{tt.backup}}
+			fake := &fakeRunner{preferences: map[string]string{"/cache/localhost/3": prefs}}
+			r := &runner{cfg: cfg, summary: summary.New(time.Now()), newRunner: fake.factory()}
+
+			captureStdout(t, r.runBackupPhase)
+
+			cmds := fake.commands()
+			if strings.Join(cmds, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("expected %v, got %v", tt.expected, cmds)
+			}
+		})
+	}
+}
+
+func TestRunner_NonPrunableStorage(t *testing.T) {
+	prunable := false
+	cfg := &config.Config{
+		Backups: []config.BackupConfig{{Name: "appdata", Destinations: []string{"NAS", "Glacier"}}},
+		Storages: map[string]config.StorageConfig{
+			"Glacier": {Prunable: &prunable},
+		},
+	}
+	fake := &fakeRunner{output: sampleCheckOutput}
+	r := &runner{cfg: cfg, summary: summary.New(time.Now()), newRunner: fake.factory()}
+
+	captureStdout(t, r.execute)
+
+	var pruned, checked []string
+	for _, c := range fake.calls {
+		switch c.args[0] {
+		case "prune":
+			pruned = append(pruned, c.storage)
+		case "check":
+			checked = append(checked, c.storage)
+		}
+	}
+	if strings.Join(pruned, ",") != "NAS" {
+		t.Errorf("expected only NAS to be pruned, got %v", pruned)
+	}
+	if strings.Join(checked, ",") != "NAS,Glacier" {
+		t.Errorf("expected both storages to be checked, got %v", checked)
+	}
+
+	prunePhase := r.summary.Phases[2]
+	var skipped []string
+	for _, op := range prunePhase.Operations {
+		if op.Status == summary.StatusSkipped {
+			skipped = append(skipped, op.Storage)
+		}
+	}
+	if strings.Join(skipped, ",") != "Glacier" || prunePhase.Status != summary.StatusSuccess {
+		t.Errorf("expected Glacier recorded as skipped in a successful phase, got %+v", prunePhase)
+	}
+}
+
+func TestRunner_FailedCheckSkipsPrune(t *testing.T) {
+	cfg := &config.Config{
+		Backups: []config.BackupConfig{{Name: "appdata", Destinations: []string{"NAS", "Cloud"}}},
+	}
+	fake := &fakeRunner{output: sampleCheckOutput, errs: map[string]error{
+		"check Cloud": errors.New("command exited with code 1"),
+	}}
+	r := &runner{cfg: cfg, summary: summary.New(time.Now()), newRunner: fake.factory()}
+
+	captureStdout(t, r.execute)
+
+	var order []string
+	for _, c := range fake.calls {
+		if c.args[0] == "check" || c.args[0] == "prune" {
+			order = append(order, c.args[0]+" "+c.storage)
+		}
+	}
+	expected := []string{"check NAS", "check Cloud", "prune NAS"}
+	if strings.Join(order, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected %v, got %v", expected, order)
+	}
+
+	prunePhase := r.summary.Phases[2]
+	if prunePhase.Name != "prune" {
+		t.Fatalf("expected prune to be the last phase, got %q", prunePhase.Name)
+	}
+	var skipped []string
+	for _, op := range prunePhase.Operations {
+		if op.Status == summary.StatusSkipped {
+			skipped = append(skipped, op.Storage+": "+op.Reason)
+		}
+	}
+	if strings.Join(skipped, ",") != "Cloud: check failed" {
+		t.Errorf("expected Cloud skipped because its check failed, got %v", skipped)
+	}
+	if len(r.errors) != 1 || !strings.HasPrefix(r.errors[0], "check Cloud: ") {
+		t.Errorf("expected only the check error, got %v", r.errors)
+	}
+}
+
+func TestRunner_ExclusivePrune(t *testing.T) {
+	cfg := &config.Config{
+		LockFile: "/tmp/duplicaci.lock",
+		Backups:  []config.BackupConfig{{Name: "appdata", Destinations: []string{"NAS"}}},
+		Storages: map[string]config.StorageConfig{"NAS": {ExclusivePrune: true}},
+	}
+
+	t.Run("with lock", func(t *testing.T) {
+		fake := &fakeRunner{}
+		r := &runner{cfg: cfg, summary: summary.New(time.Now()), newRunner: fake.factory(), lockHeld: true}
+
+		captureStdout(t, func() { r.runPrunePhase(fake, []string{"NAS"}) })
+
+		cmds := fake.commands()
+		if len(cmds) != 1 || !strings.HasSuffix(cmds[0], " -exclusive") {
+			t.Errorf("expected an -exclusive prune, got %v", cmds)
+		}
+		if len(r.errors) != 0 {
+			t.Errorf("expected no errors, got %v", r.errors)
+		}
+	})
+
+	t.Run("without lock", func(t *testing.T) {
+		fake := &fakeRunner{}
+		r := &runner{cfg: cfg, summary: summary.New(time.Now()), newRunner: fake.factory()}
+
+		captureStdout(t, func() { r.runPrunePhase(fake, []string{"NAS"}) })
+
+		if cmds := fake.commands(); len(cmds) != 0 {
+			t.Errorf("expected no prune without the lock, got %v", cmds)
+		}
+		if len(r.errors) != 1 || !strings.Contains(r.errors[0], "refusing -exclusive prune") {
+			t.Errorf("expected an interlock error, got %v", r.errors)
+		}
+		if r.summary.Phases[0].Status != summary.StatusFailed {
+			t.Errorf("expected the prune phase to fail, got %q", r.summary.Phases[0].Status)
+		}
+	})
+}
+
+func TestRunner_FailureLogTail(t *testing.T) {
+	output := "Storage set to sftp://nas\nuploading chunk\nERROR UPLOAD_CHUNK password s3cret rejected\n"
+	cfg := &config.Config{
+		Backups:       []config.BackupConfig{{Name: "appdata", Path: "/mnt/appdata", Destinations: []string{"NAS"}}},
+		Notifications: config.NotificationConfig{LogTailLines: 2},
+	}
+	fake := &fakeRunner{errs: map[string]error{"backup NAS": &executor.ExitError{Code: 1, Output: output}}}
+	r := &runner{cfg: cfg, summary: summary.New(time.Now()), storagePassword: "s3cret"}
+	r.newRunner = fake.factory()
+
+	captureStdout(t, func() { r.runBackupPhase() })
+
+	if len(r.failureLogs) != 1 {
+		t.Fatalf("expected one failure log, got %v", r.failureLogs)
+	}
+	got := r.failureLogs[0]
+	if got.Label != "appdata -> NAS" {
+		t.Errorf("expected label %q, got %q", "appdata -> NAS", got.Label)
+	}
+	want := "uploading chunk\nERROR UPLOAD_CHUNK password ******** rejected"
+	if got.Tail != want {
+		t.Errorf("expected tail %q, got %q", want, got.Tail)
+	}
+}
+
+func TestSendRunFailureNotification_LogTail(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			w.Write([]byte(`[]`))
+			return
+		}
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		body, _ = payload["body"].(string)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"html_url": "https://git.example.com/user/repo/issues/1"}`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Notifications: config.NotificationConfig{
+			Forgejo: config.ForgejoNotificationConfig{URL: server.URL, Repo: "user/repo", Token: "testtoken"},
+		},
+	}
+	logs := []failureLog{{Label: "appdata -> NAS", Tail: "ERROR UPLOAD_CHUNK failed"}}
+
+	captureStdout(t, func() {
+		if err := sendRunFailureNotification(cfg, []string{"appdata -> NAS: command exited with code 1"}, []string{"appdata"}, logs); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	want := "<details>\n<summary>appdata -> NAS</summary>\n\n```\nERROR UPLOAD_CHUNK failed\n```\n\n</details>"
+	if !strings.Contains(body, want) {
+		t.Errorf("expected issue body to contain the log tail, got:\n%s", body)
+	}
+}
+
+func TestParsePhases(t *testing.T) {
+	phases, err := parsePhases(nil)
+	if err != nil || phases != nil {
+		t.Errorf("expected every phase for no --only, got %v, %v", phases, err)
+	}
+
+	phases, err = parsePhases([]string{"prune", " check"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if phases["backup"] || !phases["prune"] || !phases["check"] {
+		t.Errorf("expected prune and check, got %v", phases)
+	}
+
+	if _, err := parsePhases([]string{"backups"}); err == nil || !strings.Contains(err.Error(), `unknown phase "backups"`) {
+		t.Errorf("expected unknown phase error, got %v", err)
+	}
+}
+
+func TestRunner_MaintenanceOnly(t *testing.T) {
+	cfg := &config.Config{Maintenance: []string{"Archive"}, MaintenanceDir: "/cache/localhost/0"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected maintenance-only config to validate, got %v", err)
+	}
+
+	fake := &fakeRunner{output: sampleCheckOutput}
+	r := &runner{
+		cfg:       cfg,
+		summary:   summary.New(time.Now()),
+		newRunner: fake.factory(),
+		phases:    map[string]bool{"prune": true, "check": true},
+	}
+
+	captureStdout(t, func() { r.execute() })
+
+	cmds := fake.commands()
+	if len(cmds) != 2 || !strings.HasPrefix(cmds[0], "check -tabular -storage Archive") || !strings.HasPrefix(cmds[1], "prune -storage Archive") {
+		t.Errorf("expected check then prune of Archive, got %v", cmds)
+	}
+	if len(r.errors) != 0 {
+		t.Errorf("unexpected errors: %v", r.errors)
+	}
+}
+
+func TestRunner_MaintenanceOnly_Command(t *testing.T) {
+	defer func() { dryRun = false }()
+	dryRun = true
+
+	cfg := &config.Config{Maintenance: []string{"Archive"}, MaintenanceDir: "/cache/localhost/0"}
+	r := &runner{cfg: cfg, summary: summary.New(time.Now())}
+
+	out := captureStdout(t, func() { r.execute() })
+
+	for _, want := range []string{
+		"Command: cd /cache/localhost/0 && duplicacy check -tabular -storage Archive",
+		"Command: cd /cache/localhost/0 && duplicacy prune -storage Archive",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in the output, got %q", want, out)
+		}
+	}
+}
+
+func TestRunner_OnlySkipsPhases(t *testing.T) {
+	cfg := &config.Config{
+		Backups: []config.BackupConfig{{Name: "appdata", Path: "/mnt/appdata", Destinations: []string{"NAS"}}},
+	}
+	fake := &fakeRunner{output: sampleCheckOutput}
+	r := &runner{
+		cfg:       cfg,
+		summary:   summary.New(time.Now()),
+		newRunner: fake.factory(),
+		phases:    map[string]bool{"check": true},
+	}
+
+	captureStdout(t, func() { r.execute() })
+
+	cmds := fake.commands()
+	if len(cmds) != 1 || !strings.HasPrefix(cmds[0], "check -tabular -storage NAS") {
+		t.Errorf("expected only the check, got %v", cmds)
+	}
+}
+
+func TestRunner_ChunkDelta(t *testing.T) {
+	cfg := &config.Config{Maintenance: []string{"NAS"}}
+	writer := &fakeStatsUpdater{previous: stats.UpdateResult{
+		PreviousDate: "2025-01-01",
+		Previous: &stats.DayStats{
+			TotalChunks:  80,
+			Repositories: map[string]stats.RepoStats{"appdata": {TotalChunks: 80}},
+		},
+	}}
+	r := &runner{cfg: cfg, summary: summary.New(time.Now())}
+
+	out := captureStdout(t, func() {
+		r.runCheckPhase(&fakeRunner{output: sampleCheckOutput}, writer, cfg.AllStorages())
+	})
+
+	if !strings.Contains(out, "Chunks since 2025-01-01: +12\n        - appdata: +12\n") {
+		t.Errorf("expected chunk delta in output, got:\n%s", out)
+	}
+	if d := r.summary.Deltas["NAS"]; d.Total != 12 || d.Repositories["appdata"] != 12 {
+		t.Errorf("expected +12 chunk delta in summary, got %+v", d)
+	}
+}
+
+func TestRunner_ChunkDeltaFirstRun(t *testing.T) {
+	cfg := &config.Config{Maintenance: []string{"NAS"}}
+	r := &runner{cfg: cfg, summary: summary.New(time.Now())}
+
+	out := captureStdout(t, func() {
+		r.runCheckPhase(&fakeRunner{output: sampleCheckOutput}, &fakeStatsUpdater{}, cfg.AllStorages())
+	})
+
+	if strings.Contains(out, "Chunks since") {
+		t.Errorf("expected no chunk delta without a prior entry, got:\n%s", out)
+	}
+	if len(r.summary.Deltas) != 0 {
+		t.Errorf("expected no deltas in summary, got %v", r.summary.Deltas)
+	}
+}
+
+// perIDRunner returns a different check output for each -id
+type perIDRunner struct {
+	*fakeRunner
+	outputs map[string]string // keyed by -id value
+}
+
+func (p *perIDRunner) RunDuplicacyCaptureWithStorage(storage string, args ...string) (string, error) {
+	_, err := p.fakeRunner.RunDuplicacyCaptureWithStorage(storage, args...)
+	for i, arg := range args {
+		if arg == "-id" && i+1 < len(args) {
+			return p.outputs[args[i+1]], err
+		}
+	}
+	return p.fakeRunner.output, err
+}
+
+func TestRunner_CheckPerID(t *testing.T) {
+	photosOutput := `2025-12-29 01:05:12.001 INFO SNAPSHOT_CHECK Total chunk size is 8,853K in 92 chunks
+ photos |   1 | @ 2025-10-13 20:40 -hash |     3 |  100K |      2 |    50K |    2 |    50K |   2 |   50K |
+ photos | all |                          |       |       |     10 | 1,000K |   10 | 1,000K |     |       |
+`
+	cfg := &config.Config{
+		Backups: []config.BackupConfig{
+			{Name: "appdata", Path: "/mnt/appdata", Destinations: []string{"NAS"}},
+			{Name: "photos", Path: "/mnt/photos", Destinations: []string{"NAS", "Cloud"}},
+		},
+		Storages: map[string]config.StorageConfig{"NAS": {CheckPerID: true}},
+	}
+	fake := &perIDRunner{
+		fakeRunner: &fakeRunner{output: sampleCheckOutput},
+		outputs:    map[string]string{"appdata": sampleCheckOutput, "photos": photosOutput},
+	}
+	writer := &fakeStatsUpdater{}
+	r := &runner{cfg: cfg, summary: summary.New(time.Now())}
+
+	captureStdout(t, func() {
+		r.runCheckPhase(fake, writer, []string{"NAS", "Cloud"})
+	})
+
+	cmds := fake.commands()
+	want := []string{
+		"check -tabular -storage NAS -id appdata",
+		"check -tabular -storage NAS -id photos",
+		"check -tabular -storage Cloud",
+	}
+	if strings.Join(cmds, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected commands %v, got %v", want, cmds)
+	}
+
+	nas := r.summary.Storages["NAS"]
+	if nas == nil {
+		t.Fatal("expected merged NAS stats")
+	}
+	if len(nas.Repositories) != 2 || nas.Repositories["appdata"].TotalChunks != 92 || nas.Repositories["photos"].TotalChunks != 10 {
+		t.Errorf("expected appdata and photos merged into one entry, got %+v", nas.Repositories)
+	}
+	if nas.TotalChunks != 92 || nas.Status != stats.StatusChecked {
+		t.Errorf("expected storage totals from the checks, got %+v", nas)
+	}
+	if len(writer.storages) != 2 {
+		t.Errorf("expected one stats write per storage, got %v", writer.storages)
+	}
+}
+
+func TestRunAllBackups_OutputJSON(t *testing.T) {
+	defer func() {
+		configFile = ""
+		dryRun = false
+		outputFormat = "text"
+	}()
+	configFile = filepath.Join(t.TempDir(), "duplicaci.yaml")
+	content := "backups:\n  - name: appdata\n    path: /mnt/appdata\n    destinations: [NAS]\n"
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	dryRun = true
+	outputFormat = "json"
+
+	// Progress goes to stderr; keep it out of the test output
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	origStderr := os.Stderr
+	os.Stderr = devNull
+	defer func() { os.Stderr = origStderr }()
+
+	out := captureStdout(t, func() {
+		if err := runAllBackups(runCmd, nil); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	var got summary.RunSummary
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("expected stdout to be one JSON document, got %v:\n%s", err, out)
+	}
+	if got.Status != summary.StatusSuccess {
+		t.Errorf("expected status %q, got %q", summary.StatusSuccess, got.Status)
+	}
+	var phases []string
+	for _, p := range got.Phases {
+		phases = append(phases, p.Name)
+	}
+	if strings.Join(phases, ",") != "backup,check,prune" {
+		t.Errorf("expected backup, check and prune phases, got %v", phases)
+	}
+}
+
+func TestRunAllBackups_InvalidOutput(t *testing.T) {
+	defer func() { outputFormat = "text" }()
+	outputFormat = "yaml"
+
+	err := runAllBackups(runCmd, nil)
+	if err == nil || !strings.Contains(err.Error(), `--output must be "text" or "json"`) {
+		t.Errorf("expected invalid output error, got %v", err)
+	}
+}
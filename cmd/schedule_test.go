@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSchedule_Next(t *testing.T) {
+	// 2025-01-01 is a Wednesday
+	from := time.Date(2025, 1, 1, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		expr     string
+		expected time.Time
+	}{
+		{"0 2 * * *", time.Date(2025, 1, 2, 2, 0, 0, 0, time.UTC)},
+		{"* * * * *", time.Date(2025, 1, 1, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2025, 1, 1, 10, 45, 0, 0, time.UTC)},
+		{"0,30 9-17 * * *", time.Date(2025, 1, 1, 11, 0, 0, 0, time.UTC)},
+		{"0 2 * * 0", time.Date(2025, 1, 5, 2, 0, 0, 0, time.UTC)},
+		{"0 2 * * 7", time.Date(2025, 1, 5, 2, 0, 0, 0, time.UTC)},
+		{"0 2 * * 1-5", time.Date(2025, 1, 2, 2, 0, 0, 0, time.UTC)},
+		{"0 0 1 */3 *", time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"30 10 * * *", time.Date(2025, 1, 2, 10, 30, 0, 0, time.UTC)},
+		{"5/20 * * * *", time.Date(2025, 1, 1, 10, 45, 0, 0, time.UTC)},
+		// Both day fields restricted: either matches (the 15th, or Friday the 3rd)
+		{"0 0 15 * 5", time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			sched, err := parseSchedule(tt.expr)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := sched.Next(from); !got.Equal(tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseSchedule_Invalid(t *testing.T) {
+	tests := []struct {
+		expr   string
+		errMsg string
+	}{
+		{"0 2 * *", "expected 5 fields"},
+		{"0 2 * * * *", "expected 5 fields"},
+		{"60 2 * * *", "minute: value 60 out of range 0-59"},
+		{"0 24 * * *", "hour: value 24 out of range"},
+		{"0 2 0 * *", "day of month: value 0 out of range"},
+		{"0 2 * 13 *", "month: value 13 out of range"},
+		{"0 2 * * 8", "day of week: value 8 out of range"},
+		{"*/0 * * * *", "invalid step"},
+		{"5-1 * * * *", "invalid range"},
+		{"a * * * *", "invalid value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := parseSchedule(tt.expr)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %q", tt.errMsg, err.Error())
+			}
+		})
+	}
+}
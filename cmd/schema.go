@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/lioreshai/duplicaci/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema for the config file",
+	Long: `Print a JSON Schema describing the duplicaci config file.
+
+Save it next to your config for editor autocompletion, e.g. with the YAML
+language server:
+
+  duplicaci schema > duplicaci.schema.json
+  # yaml-language-server: $schema=./duplicaci.schema.json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		schema, err := config.JSONSchema()
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(schema))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(schemaCmd)
+}
@@ -0,0 +1,364 @@
+package cmd
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/lioreshai/duplicaci/internal/stats"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Serve flags
+	serveAddr     string
+	serveSchedule string
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve health, metrics and run endpoints for sidecar use",
+	Long: `Run a small HTTP server for use as a long-lived sidecar container:
+
+  GET  /healthz  200 while the server is up, with the latest run and next scheduled run
+  GET  /metrics  latest stats of each storage in Prometheus text format
+  POST /run      start a run (same as "duplicaci run"); returns the job status
+  GET  /run      status of the latest run
+
+Only one run executes at a time; a POST while one is running returns 409.
+
+The server listens on 127.0.0.1 by default. When DUPLICACI_SERVE_TOKEN is set,
+POST /run requires "Authorization: Bearer <token>"; listening on any
+non-loopback address requires it.
+
+With --schedule, runs also start on a cron schedule (minute hour day-of-month
+month day-of-week, in local time). A tick while a run is in progress is skipped.
+
+Example:
+  DUPLICACI_SERVE_TOKEN=... duplicaci serve --config duplicaci.yaml --addr :8080 --schedule "0 2 * * *"`,
+	RunE: runServe,
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveAddr, "addr", "127.0.0.1:8080", "Address to listen on (non-loopback addresses require DUPLICACI_SERVE_TOKEN)")
+	serveCmd.Flags().StringVar(&serveSchedule, "schedule", "", `Also start runs on this cron schedule, e.g. "0 2 * * *" (local time)`)
+
+	rootCmd.AddCommand(serveCmd)
+}
+
+// Job states reported by /run
+const (
+	jobRunning   = "running"
+	jobSucceeded = "succeeded"
+	jobFailed    = "failed"
+)
+
+// jobStatus describes a run started through /run
+type jobStatus struct {
+	ID       int        `json:"id"`
+	State    string     `json:"state"`
+	Started  time.Time  `json:"started"`
+	Finished *time.Time `json:"finished,omitempty"`
+	Error    string     `json:"error,omitempty"`
+}
+
+// server holds the state behind the serve endpoints
+type server struct {
+	ctx      context.Context // passed to runs; cancelled on SIGINT/SIGTERM
+	reader   statsReader     // nil when no container is configured
+	storages []string
+	run      func(ctx context.Context) error
+	token    string // bearer token POST /run requires; empty accepts any caller
+
+	// now and after tell the time for runs and the schedule (tests substitute a fake clock)
+	now   func() time.Time
+	after func(d time.Duration) <-chan time.Time
+
+	mu      sync.Mutex
+	job     *jobStatus // latest run, nil before the first
+	nextRun time.Time  // next scheduled run, zero without --schedule
+	wg      sync.WaitGroup
+}
+
+// healthStatus is the /healthz response
+type healthStatus struct {
+	Status  string     `json:"status"`
+	LastRun *jobStatus `json:"last_run,omitempty"`
+	NextRun *time.Time `json:"next_run,omitempty"`
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	if !configSpecified() {
+		return fmt.Errorf("--config or --config-dir is required for the serve command")
+	}
+
+	var sched *cronSchedule
+	if serveSchedule != "" {
+		var err error
+		if sched, err = parseSchedule(serveSchedule); err != nil {
+			return err
+		}
+		if sched.Next(time.Now()).IsZero() {
+			return fmt.Errorf("schedule %q never matches", serveSchedule)
+		}
+	}
+
+	// POST /run starts a full run, so only loopback listeners may skip the token
+	token := os.Getenv("DUPLICACI_SERVE_TOKEN")
+	if token == "" && !isLoopbackAddr(serveAddr) {
+		return fmt.Errorf("--addr %s is reachable from other hosts: set DUPLICACI_SERVE_TOKEN so POST /run requires a bearer token", serveAddr)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	s := &server{
+		ctx:      cmd.Context(),
+		storages: cfg.AllStorages(),
+		run:      runFromServer,
+		token:    token,
+		now:      time.Now,
+		after:    time.After,
+	}
+	if cfg.Connection.HasContainer() {
+		sshPassword := cfg.Connection.SSHPassword()
+		if err := resolveContainer(cfg, hostResolver(cmd.Context(), cfg, sshPassword)); err != nil {
+			return err
+		}
+		w := stats.NewWriter(cfg.Connection.Host, sshPassword, cfg.Connection.Container)
+		w.ContainerUser = cfg.Connection.ContainerUser
+		w.SSHPasswordMode = cfg.Connection.SSHPasswordMode
+		s.reader = w
+	}
+
+	srv := &http.Server{Addr: serveAddr, Handler: s.handler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-s.ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	if sched != nil {
+		go s.runSchedule(sched)
+	}
+
+	fmt.Printf("Listening on %s\n", serveAddr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	// A running job sees the cancelled context; let it record its result
+	s.wg.Wait()
+	return nil
+}
+
+// runFromServer performs a full run as "duplicaci run" would with its defaults
+func runFromServer(ctx context.Context) error {
+	c := &cobra.Command{}
+	c.SetContext(ctx)
+	return runAllBackups(c, nil)
+}
+
+// handler returns the routes of the serve command
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", s.handleHealthz)
+	mux.HandleFunc("/metrics", s.handleMetrics)
+	mux.HandleFunc("/run", s.handleRun)
+	return mux
+}
+
+func (s *server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	health := healthStatus{Status: "ok"}
+	s.mu.Lock()
+	if s.job != nil {
+		job := *s.job
+		health.LastRun = &job
+	}
+	if !s.nextRun.IsZero() {
+		next := s.nextRun
+		health.NextRun = &next
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, health)
+}
+
+func (s *server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	if s.reader == nil {
+		http.Error(w, "connection.container is required to read stats", http.StatusServiceUnavailable)
+		return
+	}
+
+	// A storage whose stats cannot be read is left out rather than failing the scrape
+	all := make(map[string]stats.StorageStats, len(s.storages))
+	for _, storage := range s.storages {
+		storageStats, err := s.reader.ReadStorageStats(storage)
+		if err != nil {
+			printWarning("", "metrics: %s: %v", storage, err)
+			continue
+		}
+		all[storage] = storageStats
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := stats.WriteMetrics(w, all); err != nil {
+		printWarning("", "metrics: %v", err)
+	}
+}
+
+func (s *server) handleRun(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		if !s.authorized(r) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		job, started := s.startRun()
+		code := http.StatusAccepted
+		if !started {
+			code = http.StatusConflict
+		}
+		writeJSON(w, code, job)
+	case http.MethodGet:
+		job, ok := s.latestJob()
+		if !ok {
+			http.Error(w, "no run started yet", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, job)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// authorized reports whether r carries the server's bearer token, if it has one
+func (s *server) authorized(r *http.Request) bool {
+	if s.token == "" {
+		return true
+	}
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+s.token)) == 1
+}
+
+// isLoopbackAddr reports whether a listen address only accepts local
+// connections; an empty host (":8080") listens on every interface
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// startRun starts a run in the background unless one is already running. It
+// returns the status of the new job, or of the running one and false.
+func (s *server) startRun() (jobStatus, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.job != nil && s.job.State == jobRunning {
+		return *s.job, false
+	}
+
+	id := 1
+	if s.job != nil {
+		id = s.job.ID + 1
+	}
+	job := &jobStatus{ID: id, State: jobRunning, Started: s.now()}
+	s.job = job
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		err := s.run(s.ctx)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		finished := s.now()
+		job.Finished = &finished
+		job.State = jobSucceeded
+		if err != nil {
+			job.State = jobFailed
+			job.Error = err.Error()
+		}
+	}()
+	return *job, true
+}
+
+// runSchedule starts a run at each tick of sched until the context is
+// cancelled. A tick while a run is still in progress is skipped.
+func (s *server) runSchedule(sched *cronSchedule) {
+	for {
+		now := s.now()
+		next := sched.Next(now)
+		if next.IsZero() {
+			return
+		}
+		s.mu.Lock()
+		s.nextRun = next
+		s.mu.Unlock()
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-s.after(next.Sub(now)):
+		}
+
+		if job, started := s.startRun(); started {
+			fmt.Printf("Scheduled run %d started\n", job.ID)
+		} else {
+			printWarning("", "skipping scheduled run: run %d is still in progress", job.ID)
+		}
+	}
+}
+
+// latestJob returns a copy of the latest job's status
+func (s *server) latestJob() (jobStatus, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.job == nil {
+		return jobStatus{}, false
+	}
+	return *s.job, true
+}
+
+// allowMethod replies 405 and returns false unless r uses method
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	return false
+}
+
+// writeJSON writes v as a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
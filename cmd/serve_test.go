@@ -0,0 +1,319 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lioreshai/duplicaci/internal/stats"
+)
+
+// newTestServer returns a server whose runs call run
+func newTestServer(reader statsReader, run func(ctx context.Context) error) *server {
+	return &server{
+		ctx:      context.Background(),
+		reader:   reader,
+		storages: []string{"NAS"},
+		run:      run,
+		now:      time.Now,
+	}
+}
+
+// serveRequest sends a request to the server's handler and returns the recorded response
+func serveRequest(s *server, method, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	s.handler().ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	return rec
+}
+
+// decodeJob decodes a /run response body
+func decodeJob(t *testing.T, rec *httptest.ResponseRecorder) jobStatus {
+	t.Helper()
+	var job jobStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &job); err != nil {
+		t.Fatalf("invalid job JSON %q: %v", rec.Body.String(), err)
+	}
+	return job
+}
+
+func TestServe_Healthz(t *testing.T) {
+	s := newTestServer(nil, nil)
+
+	rec := serveRequest(s, http.MethodGet, "/healthz")
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", rec.Code)
+	}
+	var health healthStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &health); err != nil {
+		t.Fatalf("invalid health JSON %q: %v", rec.Body.String(), err)
+	}
+	if health.Status != "ok" || health.LastRun != nil || health.NextRun != nil {
+		t.Errorf("expected ok without runs, got %+v", health)
+	}
+
+	if rec := serveRequest(s, http.MethodPost, "/healthz"); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405 for POST, got %d", rec.Code)
+	}
+}
+
+func TestServe_Metrics(t *testing.T) {
+	reader := fakeStatsReader{
+		"NAS": {"2025-01-02": {TotalSize: 2048, TotalChunks: 7, Status: stats.StatusChecked}},
+	}
+	s := newTestServer(reader, nil)
+
+	rec := serveRequest(s, http.MethodGet, "/metrics")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("expected Prometheus content type, got %q", ct)
+	}
+	body := rec.Body.String()
+	for _, line := range []string{
+		"# TYPE duplicaci_storage_size_bytes gauge",
+		`duplicaci_storage_size_bytes{storage="NAS"} 2048`,
+		`duplicaci_storage_chunks{storage="NAS"} 7`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("expected line %q in:\n%s", line, body)
+		}
+	}
+}
+
+func TestServe_MetricsWithoutContainer(t *testing.T) {
+	s := newTestServer(nil, nil)
+
+	if rec := serveRequest(s, http.MethodGet, "/metrics"); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503, got %d", rec.Code)
+	}
+}
+
+func TestServe_RunTrigger(t *testing.T) {
+	release := make(chan struct{})
+	s := newTestServer(nil, func(ctx context.Context) error {
+		<-release
+		return errors.New("completed with 1 error(s)")
+	})
+
+	if rec := serveRequest(s, http.MethodGet, "/run"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 before any run, got %d", rec.Code)
+	}
+
+	rec := serveRequest(s, http.MethodPost, "/run")
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", rec.Code)
+	}
+	job := decodeJob(t, rec)
+	if job.ID != 1 || job.State != jobRunning {
+		t.Errorf("expected job 1 running, got %+v", job)
+	}
+
+	// A second trigger while running reports the running job
+	rec = serveRequest(s, http.MethodPost, "/run")
+	if rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 while running, got %d", rec.Code)
+	}
+	if job := decodeJob(t, rec); job.ID != 1 {
+		t.Errorf("expected running job 1, got %+v", job)
+	}
+
+	close(release)
+	s.wg.Wait()
+
+	rec = serveRequest(s, http.MethodGet, "/run")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	job = decodeJob(t, rec)
+	if job.State != jobFailed || job.Error != "completed with 1 error(s)" || job.Finished == nil {
+		t.Errorf("expected finished failed job, got %+v", job)
+	}
+
+	// The next trigger starts a new job
+	s.run = func(ctx context.Context) error { return nil }
+	if job := decodeJob(t, serveRequest(s, http.MethodPost, "/run")); job.ID != 2 {
+		t.Errorf("expected job 2, got %+v", job)
+	}
+	s.wg.Wait()
+	if job, _ := s.latestJob(); job.State != jobSucceeded {
+		t.Errorf("expected job 2 succeeded, got %+v", job)
+	}
+
+	var health healthStatus
+	if err := json.Unmarshal(serveRequest(s, http.MethodGet, "/healthz").Body.Bytes(), &health); err != nil {
+		t.Fatalf("invalid health JSON: %v", err)
+	}
+	if health.LastRun == nil || health.LastRun.ID != 2 || health.LastRun.State != jobSucceeded {
+		t.Errorf("expected last run 2 succeeded in /healthz, got %+v", health.LastRun)
+	}
+}
+
+func TestServe_RunRequiresToken(t *testing.T) {
+	s := newTestServer(nil, func(ctx context.Context) error { return nil })
+	s.token = "s3cret"
+
+	for _, auth := range []string{"", "Bearer wrong", "s3cret"} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/run", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		s.handler().ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("Authorization %q: expected 401, got %d", auth, rec.Code)
+		}
+	}
+	if _, ok := s.latestJob(); ok {
+		t.Error("expected no run to start without the token")
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/run", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	s.handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("expected 202 with the token, got %d", rec.Code)
+	}
+	s.wg.Wait()
+
+	// Status reads stay open
+	if rec := serveRequest(s, http.MethodGet, "/run"); rec.Code != http.StatusOK {
+		t.Errorf("expected 200 for GET /run, got %d", rec.Code)
+	}
+}
+
+func TestIsLoopbackAddr(t *testing.T) {
+	tests := []struct {
+		addr     string
+		expected bool
+	}{
+		{"127.0.0.1:8080", true},
+		{"localhost:8080", true},
+		{"[::1]:8080", true},
+		{":8080", false},
+		{"0.0.0.0:8080", false},
+		{"192.168.1.10:8080", false},
+		{"8080", false},
+	}
+	for _, tt := range tests {
+		if got := isLoopbackAddr(tt.addr); got != tt.expected {
+			t.Errorf("isLoopbackAddr(%q) = %v, want %v", tt.addr, got, tt.expected)
+		}
+	}
+}
+
+// fakeClock is a settable clock whose timers fire when the test sends on them
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	waits  []time.Duration
+	timers chan chan time.Time
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{now: now, timers: make(chan chan time.Time, 10)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	c.waits = append(c.waits, d)
+	c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	c.timers <- ch
+	return ch
+}
+
+func (c *fakeClock) Waits() []time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]time.Duration(nil), c.waits...)
+}
+
+func TestServe_ScheduleTickRuns(t *testing.T) {
+	sched, err := parseSchedule("0 2 * * *")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	clock := newFakeClock(time.Date(2025, 1, 1, 1, 59, 30, 0, time.Local))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ran := make(chan struct{}, 10)
+	release := make(chan struct{})
+	s := newTestServer(nil, func(ctx context.Context) error {
+		ran <- struct{}{}
+		<-release
+		return nil
+	})
+	s.ctx = ctx
+	s.now = clock.Now
+	s.after = clock.After
+
+	done := make(chan struct{})
+	go func() {
+		s.runSchedule(sched)
+		close(done)
+	}()
+
+	// The first wait is until 02:00, 30s away
+	timer := <-clock.timers
+	var health healthStatus
+	json.Unmarshal(serveRequest(s, http.MethodGet, "/healthz").Body.Bytes(), &health)
+	if health.NextRun == nil || !health.NextRun.Equal(time.Date(2025, 1, 1, 2, 0, 0, 0, time.Local)) {
+		t.Errorf("expected next run at 02:00 in /healthz, got %v", health.NextRun)
+	}
+
+	clock.Set(time.Date(2025, 1, 1, 2, 0, 0, 0, time.Local))
+	timer <- clock.Now()
+	select {
+	case <-ran:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the tick to start a run")
+	}
+
+	// The next tick arrives while the run is still going and is skipped
+	timer = <-clock.timers
+	clock.Set(time.Date(2025, 1, 2, 2, 0, 0, 0, time.Local))
+	timer <- clock.Now()
+	<-clock.timers
+	select {
+	case <-ran:
+		t.Error("expected the overlapping tick to be skipped")
+	default:
+	}
+	if job, _ := s.latestJob(); job.ID != 1 || job.State != jobRunning {
+		t.Errorf("expected job 1 still running, got %+v", job)
+	}
+
+	cancel()
+	<-done
+	close(release)
+	s.wg.Wait()
+
+	waits := clock.Waits()
+	expected := []time.Duration{30 * time.Second, 24 * time.Hour, 24 * time.Hour}
+	if !reflect.DeepEqual(waits, expected) {
+		t.Errorf("expected waits %v, got %v", expected, waits)
+	}
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/lioreshai/duplicaci/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// showJSON prints show output as a JSON array instead of one name per line
+var showJSON bool
+
+var showCmd = &cobra.Command{
+	Use:   "show",
+	Short: "List names defined in the configuration",
+	Long:  `List configured backups or storages, for scripting and shell completion.`,
+}
+
+var showBackupsCmd = &cobra.Command{
+	Use:   "backups",
+	Short: "List configured backup names",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runShow(os.Stdout, backupNames)
+	},
+}
+
+var showStoragesCmd = &cobra.Command{
+	Use:   "storages",
+	Short: "List configured storages in processing order",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runShow(os.Stdout, (*config.Config).AllStorages)
+	},
+}
+
+func init() {
+	showCmd.PersistentFlags().BoolVar(&showJSON, "json", false, "Print a JSON array instead of one name per line")
+	showCmd.AddCommand(showBackupsCmd, showStoragesCmd)
+	rootCmd.AddCommand(showCmd)
+}
+
+// runShow loads and validates the config and prints the names list returns
+func runShow(w io.Writer, list func(*config.Config) []string) error {
+	if !configSpecified() {
+		return fmt.Errorf("--config or --config-dir is required for the show command")
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	return printNames(w, list(cfg), showJSON)
+}
+
+// backupNames returns the configured backup names in config order
+func backupNames(cfg *config.Config) []string {
+	names := make([]string, 0, len(cfg.Backups))
+	for _, b := range cfg.Backups {
+		names = append(names, b.Name)
+	}
+	return names
+}
+
+// printNames writes names one per line, or as a JSON array
+func printNames(w io.Writer, names []string, asJSON bool) error {
+	if asJSON {
+		if names == nil {
+			names = []string{}
+		}
+		data, err := json.Marshal(names)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+
+	for _, name := range names {
+		if _, err := fmt.Fprintln(w, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
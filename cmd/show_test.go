@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lioreshai/duplicaci/internal/config"
+)
+
+const showSampleConfig = `version: 2
+backups:
+  - name: appdata
+    path: /mnt/appdata
+    destinations: [NAS, Cloud]
+  - name: photos
+    path: /mnt/photos
+    destinations: [NAS]
+storages:
+  Cloud: { priority: -1 }
+maintenance: [Archive]
+`
+
+func TestRunShow(t *testing.T) {
+	defer func() {
+		configFile = ""
+		showJSON = false
+	}()
+	configFile = filepath.Join(t.TempDir(), "duplicaci.yaml")
+	if err := os.WriteFile(configFile, []byte(showSampleConfig), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		list     func(*config.Config) []string
+		asJSON   bool
+		expected string
+	}{
+		{"backups", backupNames, false, "appdata\nphotos\n"},
+		{"storages", (*config.Config).AllStorages, false, "Cloud\nNAS\nArchive\n"},
+		{"backups json", backupNames, true, `["appdata","photos"]` + "\n"},
+		{"storages json", (*config.Config).AllStorages, true, `["Cloud","NAS","Archive"]` + "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			showJSON = tt.asJSON
+			var buf bytes.Buffer
+			if err := runShow(&buf, tt.list); err != nil {
+				t.Fatalf("runShow() error: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestRunShow_NoConfig(t *testing.T) {
+	var buf bytes.Buffer
+	err := runShow(&buf, backupNames)
+	if err == nil || !strings.Contains(err.Error(), "--config or --config-dir is required") {
+		t.Errorf("expected missing config error, got %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected no output, got %q", buf.String())
+	}
+}
+
+func TestRunShow_InvalidConfig(t *testing.T) {
+	defer func() { configFile = "" }()
+	configFile = filepath.Join(t.TempDir(), "duplicaci.yaml")
+	if err := os.WriteFile(configFile, []byte("version: 2\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := runShow(&buf, backupNames); err == nil || !strings.Contains(err.Error(), "invalid config") {
+		t.Errorf("expected invalid config error, got %v", err)
+	}
+}
+
+func TestPrintNames_EmptyJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := printNames(&buf, nil, true); err != nil {
+		t.Fatalf("printNames() error: %v", err)
+	}
+	if buf.String() != "[]\n" {
+		t.Errorf("expected empty JSON array, got %q", buf.String())
+	}
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/lioreshai/duplicaci/internal/stats"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Status flags
+	statusSince string
+	statusUntil string
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show recorded storage stats",
+	Long: `Show the stats recorded in the Duplicacy Web UI stats files for every storage
+in the config, optionally limited to a date range.
+
+Example:
+  duplicaci status --config duplicaci.yaml --since 2025-01-01 --until 2025-02-01`,
+	RunE: runStatus,
+}
+
+func init() {
+	statusCmd.Flags().StringVar(&statusSince, "since", "", "Only include stats on or after this date (YYYY-MM-DD)")
+	statusCmd.Flags().StringVar(&statusUntil, "until", "", "Only include stats on or before this date (YYYY-MM-DD)")
+
+	rootCmd.AddCommand(statusCmd)
+}
+
+// statsReader is the part of the stats writer used by the status command
+type statsReader interface {
+	ReadStorageStats(storage string) (stats.StorageStats, error)
+}
+
+func runStatus(cmd *cobra.Command, args []string) error {
+	if !configSpecified() {
+		return fmt.Errorf("--config or --config-dir is required for the status command")
+	}
+
+	since, until, err := parseDateRange(statusSince, statusUntil)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if !cfg.Connection.HasContainer() {
+		return fmt.Errorf("connection.container is required to read stats")
+	}
+
+	sshPassword := cfg.Connection.SSHPassword()
+	if err := resolveContainer(cfg, hostResolver(cmd.Context(), cfg, sshPassword)); err != nil {
+		return err
+	}
+
+	w := stats.NewWriter(cfg.Connection.Host, sshPassword, cfg.Connection.Container)
+	w.ContainerUser = cfg.Connection.ContainerUser
+	w.SSHPasswordMode = cfg.Connection.SSHPasswordMode
+	w.Verbose = verbose
+
+	return printStatus(os.Stdout, w, cfg.AllStorages(), since, until)
+}
+
+// parseDateRange parses the optional --since/--until dates
+func parseDateRange(sinceStr, untilStr string) (since, until time.Time, err error) {
+	if sinceStr != "" {
+		if since, err = stats.ParseDate(sinceStr); err != nil {
+			return since, until, fmt.Errorf("--since: %w", err)
+		}
+	}
+	if untilStr != "" {
+		if until, err = stats.ParseDate(untilStr); err != nil {
+			return since, until, fmt.Errorf("--until: %w", err)
+		}
+	}
+	if !since.IsZero() && !until.IsZero() && until.Before(since) {
+		return since, until, fmt.Errorf("--until is before --since")
+	}
+	return since, until, nil
+}
+
+// printStatus prints the stats of each storage within the date range
+func printStatus(out io.Writer, reader statsReader, storages []string, since, until time.Time) error {
+	var failed int
+	for _, storage := range storages {
+		fmt.Fprintf(out, "==> %s\n", storage)
+
+		all, err := reader.ReadStorageStats(storage)
+		if err != nil {
+			fprintError(out, "    ", "%v", err)
+			failed++
+			continue
+		}
+
+		filtered := all.Between(since, until)
+		if len(filtered) == 0 {
+			fmt.Fprintf(out, "    No stats in range\n")
+			continue
+		}
+
+		for _, date := range filtered.Dates() {
+			day := filtered[date]
+			fmt.Fprintf(out, "    %s  %10s  %d chunks\n", date, stats.FormatBytes(day.TotalSize), day.TotalChunks)
+		}
+
+		sum := filtered.Summarize()
+		growth := stats.FormatBytes(abs64(sum.Growth))
+		if sum.Growth < 0 {
+			growth = "-" + growth
+		}
+		fmt.Fprintf(out, "    %d day(s) %s..%s: min %s, max %s, avg %s, growth %s\n",
+			sum.Days, sum.First, sum.Last,
+			stats.FormatBytes(sum.MinSize), stats.FormatBytes(sum.MaxSize), stats.FormatBytes(sum.AvgSize), growth)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to read stats for %d storage(s)", failed)
+	}
+	return nil
+}
+
+// abs64 returns the absolute value of n
+func abs64(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
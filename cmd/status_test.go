@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lioreshai/duplicaci/internal/stats"
+)
+
+// fakeStatsReader serves stats per storage from memory
+type fakeStatsReader map[string]stats.StorageStats
+
+func (f fakeStatsReader) ReadStorageStats(storage string) (stats.StorageStats, error) {
+	s, ok := f[storage]
+	if !ok {
+		return nil, errors.New("read failed")
+	}
+	return s, nil
+}
+
+func TestPrintStatus_DateRange(t *testing.T) {
+	reader := fakeStatsReader{
+		"NAS": {
+			"2025-01-10": {TotalSize: 1024, TotalChunks: 1},
+			"2025-01-20": {TotalSize: 2048, TotalChunks: 2},
+			"2025-02-05": {TotalSize: 4096, TotalChunks: 4},
+		},
+		"Cloud": {
+			"2024-12-01": {TotalSize: 1024},
+		},
+	}
+
+	since, until, err := parseDateRange("2025-01-15", "2025-02-10")
+	if err != nil {
+		t.Fatalf("parseDateRange() error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := printStatus(&buf, reader, []string{"NAS", "Cloud"}, since, until); err != nil {
+		t.Fatalf("printStatus() error: %v", err)
+	}
+
+	expected := `==> NAS
+    2025-01-20      2.0 KB  2 chunks
+    2025-02-05      4.0 KB  4 chunks
+    2 day(s) 2025-01-20..2025-02-05: min 2.0 KB, max 4.0 KB, avg 3.0 KB, growth 2.0 KB
+==> Cloud
+    No stats in range
+`
+	if buf.String() != expected {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", buf.String(), expected)
+	}
+}
+
+func TestPrintStatus_ReadError(t *testing.T) {
+	var buf bytes.Buffer
+	err := printStatus(&buf, fakeStatsReader{}, []string{"NAS"}, zeroTime(), zeroTime())
+	if err == nil {
+		t.Error("expected error when stats cannot be read")
+	}
+	if !strings.Contains(buf.String(), "ERROR: read failed") {
+		t.Errorf("expected read error in output, got %q", buf.String())
+	}
+}
+
+func TestParseDateRange(t *testing.T) {
+	tests := []struct {
+		name    string
+		since   string
+		until   string
+		wantErr bool
+	}{
+		{"open", "", "", false},
+		{"valid", "2025-01-01", "2025-02-01", false},
+		{"bad since", "2025/01/01", "", true},
+		{"bad until", "", "tomorrow", true},
+		{"reversed", "2025-02-01", "2025-01-01", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := parseDateRange(tt.since, tt.until)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseDateRange(%q, %q) error = %v, wantErr %v", tt.since, tt.until, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// zeroTime is an open end of a date range
+func zeroTime() (t time.Time) {
+	return t
+}
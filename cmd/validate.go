@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/lioreshai/duplicaci/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// resolveSecrets makes validate also check that every referenced secret resolves
+var resolveSecrets bool
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate the config without running anything",
+	Long: `Load and validate the config. With --resolve-secrets, also resolve every
+secret it references (env vars and *_command sources) and print whether each
+is present. Secret values are never printed.
+
+Exits non-zero if the config is invalid or a required secret is missing.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runValidate(os.Stdout)
+	},
+}
+
+func init() {
+	validateCmd.Flags().BoolVar(&resolveSecrets, "resolve-secrets", false, "Also resolve every referenced secret and report which are missing")
+	rootCmd.AddCommand(validateCmd)
+}
+
+// runValidate validates the config and, with --resolve-secrets, writes the
+// secret presence table
+func runValidate(w io.Writer) error {
+	if !configSpecified() {
+		return fmt.Errorf("--config or --config-dir is required for the validate command")
+	}
+
+	// Secrets are resolved below, so a failing command is reported, not fatal
+	cfg, err := loadConfigFiles()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	fmt.Fprintln(w, "config is valid")
+
+	if !resolveSecrets {
+		return nil
+	}
+	fmt.Fprintln(w)
+	missing := printSecretStatuses(w, cfg.CheckSecrets())
+	if missing > 0 {
+		return fmt.Errorf("%d required secret(s) missing", missing)
+	}
+	return nil
+}
+
+// printSecretStatuses writes a secret/source/status table and returns how
+// many required secrets are missing
+func printSecretStatuses(w io.Writer, statuses []config.SecretStatus) int {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SECRET\tSOURCE\tSTATUS")
+	missing := 0
+	for _, s := range statuses {
+		status := "present"
+		switch {
+		case s.Present:
+		case s.Required:
+			status = "MISSING"
+			missing++
+		default:
+			status = "absent (optional)"
+		}
+		if s.Err != nil {
+			status += " (" + s.Err.Error() + ")"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", s.Name, s.Source, status)
+	}
+	tw.Flush()
+	return missing
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const validateSecretsConfig = `backups:
+  - name: appdata
+    path: /mnt/appdata
+    destinations: [NAS]
+connection:
+  host: root@nas
+  storage_password_command: [echo, storage-s3cret]
+notifications:
+  forgejo:
+    url: https://git.example.com
+    repo: ops/backups
+    token_env: VALIDATE_TEST_TOKEN
+`
+
+func writeValidateConfig(t *testing.T, content string) {
+	t.Helper()
+	configFile = filepath.Join(t.TempDir(), "duplicaci.yaml")
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+}
+
+func TestRunValidate_ResolveSecrets(t *testing.T) {
+	defer func() {
+		configFile = ""
+		resolveSecrets = false
+	}()
+	writeValidateConfig(t, validateSecretsConfig)
+	resolveSecrets = true
+	os.Setenv("SSH_PASSWORD", "ssh-s3cret")
+	defer os.Unsetenv("SSH_PASSWORD")
+
+	var buf bytes.Buffer
+	err := runValidate(&buf)
+	out := buf.String()
+
+	if err == nil || !strings.Contains(err.Error(), "1 required secret(s) missing") {
+		t.Errorf("expected the missing token to fail validation, got %v", err)
+	}
+	for _, line := range [][]string{
+		{"forgejo token", "env VALIDATE_TEST_TOKEN", "MISSING"},
+		{"ssh password", "env SSH_PASSWORD", "present"},
+		{"storage password", "storage_password_command", "present"},
+	} {
+		if !containsLine(out, line) {
+			t.Errorf("expected a line with %v, got:\n%s", line, out)
+		}
+	}
+	for _, secret := range []string{"ssh-s3cret", "storage-s3cret"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("secret %q leaked into output:\n%s", secret, out)
+		}
+	}
+
+	os.Setenv("VALIDATE_TEST_TOKEN", "token-s3cret")
+	defer os.Unsetenv("VALIDATE_TEST_TOKEN")
+	buf.Reset()
+	if err := runValidate(&buf); err != nil {
+		t.Errorf("expected validation to pass with every required secret, got %v", err)
+	}
+	if strings.Contains(buf.String(), "token-s3cret") {
+		t.Errorf("token leaked into output:\n%s", buf.String())
+	}
+}
+
+func TestRunValidate_WithoutResolveSecrets(t *testing.T) {
+	defer func() { configFile = "" }()
+	writeValidateConfig(t, validateSecretsConfig)
+
+	var buf bytes.Buffer
+	if err := runValidate(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buf.String() != "config is valid\n" {
+		t.Errorf("expected only the valid message, got %q", buf.String())
+	}
+}
+
+// containsLine reports whether some line of out contains every field
+func containsLine(out string, fields []string) bool {
+	for _, line := range strings.Split(out, "\n") {
+		all := true
+		for _, f := range fields {
+			all = all && strings.Contains(line, f)
+		}
+		if all {
+			return true
+		}
+	}
+	return false
+}
@@ -3,6 +3,7 @@ module github.com/lioreshai/duplicaci
 go 1.19
 
 require (
+	github.com/BurntSushi/toml v1.3.2
 	github.com/spf13/cobra v1.10.2
 	gopkg.in/yaml.v3 v3.0.1
 )
@@ -3,12 +3,25 @@ package config
 import (
 	"fmt"
 	"os"
-
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/BurntSushi/toml"
 	"gopkg.in/yaml.v3"
 )
 
+// CurrentVersion is the config schema version written by current releases
+const CurrentVersion = 2
+
 // Config represents the duplicaci configuration file
 type Config struct {
+	// Schema version (absent means a pre-versioned config)
+	Version int `yaml:"version"`
+
 	// Connection settings
 	Connection ConnectionConfig `yaml:"connection"`
 
@@ -21,35 +34,208 @@ type Config struct {
 	// Storages that only need maintenance (prune/check), not backup
 	Maintenance []string `yaml:"maintenance"`
 
+	// Initialized duplicacy directory (or Web UI cache dir) that check and prune
+	// run in; defaults to the first backup's. Required when there are no backups.
+	MaintenanceDir string `yaml:"maintenance_dir"`
+
 	// Notification settings
 	Notifications NotificationConfig `yaml:"notifications"`
 
+	// Commands to run before and after the run
+	Hooks HooksConfig `yaml:"hooks"`
+
+	// Fallbacks for settings not configured per backup or storage
+	Defaults DefaultsConfig `yaml:"defaults"`
+
+	// Web UI stats settings
+	Stats StatsConfig `yaml:"stats"`
+
+	// Local lock file held for the whole run so two runs never overlap
+	LockFile string `yaml:"lock_file"`
+
 	// Legacy fields for backward compatibility
 	SSH          SSHConfig          `yaml:"ssh"`
 	Docker       DockerConfig       `yaml:"docker"`
 	Repositories []RepositoryConfig `yaml:"repositories"`
+
+	// Deprecations lists the legacy fields found while loading (not read from the file)
+	Deprecations []string `yaml:"-"`
 }
 
 // StorageConfig defines per-storage settings
 type StorageConfig struct {
-	Retention RetentionConfig `yaml:"retention"` // Retention policy for this storage
+	Name           string            `yaml:"name"`            // Duplicacy storage name, if different from the config key
+	Retention      RetentionConfig   `yaml:"retention"`       // Retention policy for this storage
+	VerifyChunks   bool              `yaml:"verify_chunks"`   // Download and verify every chunk during check (slow)
+	Env            map[string]string `yaml:"env"`             // Extra env vars exported for this storage (e.g., DUPLICACY_<NAME>_B2_KEY)
+	Priority       int               `yaml:"priority"`        // Processing order across phases; lower runs first (default: 0, ties keep config order)
+	MinRevisions   int               `yaml:"min_revisions"`   // Refuse to prune a repository below this many revisions (0 = no floor)
+	Threads        int               `yaml:"threads"`         // Threads for prune and check on this storage (default: 1)
+	PruneExclude   []string          `yaml:"prune_exclude"`   // Snapshot IDs never pruned on this storage (per-backup retention only)
+	KnownHost      string            `yaml:"known_host"`      // SFTP host[:port] whose key is added to duplicacy's known_hosts before backups
+	LimitRate      int               `yaml:"limit_rate"`      // Upload limit in KB/s for backups to this storage (0 = unlimited)
+	Prunable       *bool             `yaml:"prunable"`        // Set false for append-only/immutable storages to skip prune (default: true)
+	ExclusivePrune bool              `yaml:"exclusive_prune"` // Prune with -exclusive (faster, unsafe with concurrent backups from any client); requires lock_file
+	CheckPerID     bool              `yaml:"check_per_id"`    // Check each backup targeting this storage with -id instead of one check of everything
+	Type           string            `yaml:"type"`            // Storage backend, as in its URL scheme (e.g. sftp, b2, gcd); gcd storages need a token
+}
+
+// IsPrunable reports whether the run should prune this storage
+func (s StorageConfig) IsPrunable() bool {
+	return s.Prunable == nil || *s.Prunable
+}
+
+// ExcludesFromPrune reports whether snapshot ID id is listed in prune_exclude
+func (s StorageConfig) ExcludesFromPrune(id string) bool {
+	for _, excluded := range s.PruneExclude {
+		if excluded == id {
+			return true
+		}
+	}
+	return false
+}
+
+// envNamePattern matches valid shell environment variable names
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// composeServicePattern matches a Docker container or service name
+var composeServicePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// knownHostPattern matches a hostname or IPv4 address with an optional port
+var knownHostPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9.-]*(:\d{1,5})?$`)
+
+// StatsConfig holds settings for the Web UI stats written after each check
+type StatsConfig struct {
+	GrowthAlertPct    float64 `yaml:"growth_alert_pct"`    // Warn when a storage grows more than this percent since the prior entry (0 = off)
+	GrowthAlertNotify bool    `yaml:"growth_alert_notify"` // Also create a notification issue for growth alerts
+	Required          bool    `yaml:"required"`            // Treat stats write failures as run errors instead of warnings
+	Timezone          string  `yaml:"timezone"`            // IANA zone for the stats date key, e.g. UTC (default: local time)
+	RefuseStale       bool    `yaml:"refuse_stale"`        // Refuse to write when today's date is before the latest entry (clock skew) instead of warning
+	MergeSameDay      bool    `yaml:"merge_same_day"`      // Merge repeat checks on the same day into one entry instead of replacing it
+	Format            string  `yaml:"format"`              // Sizes written as "bytes" (integers, default) or "human" strings like "1.5 GB"
+	StrictParse       bool    `yaml:"strict_parse"`        // Treat check output that cannot be parsed for stats as a run error instead of a warning
+}
+
+// Location returns the timezone for stats date keys (time.Local when unset)
+func (s StatsConfig) Location() (*time.Location, error) {
+	if s.Timezone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(s.Timezone)
+}
+
+// DefaultsConfig holds org-wide fallback settings
+type DefaultsConfig struct {
+	Retention RetentionConfig `yaml:"retention"` // Used when neither storage nor backup retention is set (default: daily 7, weekly 4)
+	Threads   int             `yaml:"threads"`   // Backup threads for backups without threads (default: 1)
+}
+
+// HooksConfig defines shell commands run around the backup, prune and check phases
+type HooksConfig struct {
+	Pre         string `yaml:"pre"`          // Run before backups; a failure aborts the run
+	Post        string `yaml:"post"`         // Run after checks; a failure is recorded as an error
+	InContainer bool   `yaml:"in_container"` // Run hooks in the container (over SSH) instead of locally
 }
 
 // ConnectionConfig holds connection settings
 type ConnectionConfig struct {
-	Host      string `yaml:"host"`      // SSH host (user@host)
-	Container string `yaml:"container"` // Docker container name
-	GCDToken  string `yaml:"gcd_token"` // Google Drive token path (default: /config/gcd-token.json)
+	Host          string `yaml:"host"`           // SSH host (user@host)
+	Container     string `yaml:"container"`      // Docker container name
+	ContainerUser string `yaml:"container_user"` // User to run as inside the container (e.g., abc)
+	GCDToken      string `yaml:"gcd_token"`      // Google Drive token path (default: /config/gcd-token.json)
+	KeyringPath   string `yaml:"keyring_path"`   // Container-side JSON file mapping storage names to passwords
+	OKExitCodes   []int  `yaml:"ok_exit_codes"`  // Backup exit codes treated as success (default: [100])
+
+	// ComposeService is a Compose or Swarm service whose running container
+	// (e.g. project_service_1) is looked up with docker ps and used instead of
+	// Container
+	ComposeService string `yaml:"compose_service"`
+
+	// SSHPasswordMode is how sshpass gets the SSH password: "arg" (sshpass -p,
+	// the default; visible in the local process list) or "file" (a pipe)
+	SSHPasswordMode string `yaml:"ssh_password_mode"`
+
+	// RunDeadline bounds a whole run, e.g. "6h"; when it passes, in-flight
+	// operations are cancelled and the remaining phases skipped
+	RunDeadline string `yaml:"run_deadline"`
+
+	// DuplicacyVersion selects /config/bin/duplicacy_linux_x64_<version> when
+	// the Web UI has downloaded several CLIs (default: the newest)
+	DuplicacyVersion string `yaml:"duplicacy_version"`
+
+	// GlobalOptions are duplicacy global options placed between the binary and
+	// the subcommand on every invocation, e.g. ["-log", "-profile", "localhost:6060"]
+	GlobalOptions []string `yaml:"global_options"`
+
+	// SSHPasswordCommand and StoragePasswordCommand are run locally (argv, no
+	// shell) and their trimmed stdout used in place of SSH_PASSWORD and
+	// DUPLICACY_PASSWORD, e.g. ["pass", "show", "nas/ssh"]
+	SSHPasswordCommand     []string `yaml:"ssh_password_command"`
+	StoragePasswordCommand []string `yaml:"storage_password_command"`
+
+	sshPassword     string // resolved from SSHPasswordCommand
+	storagePassword string // resolved from StoragePasswordCommand
+}
+
+// DefaultOKExitCodes are the backup exit codes treated as success when
+// connection.ok_exit_codes is not set (100: nothing to back up)
+var DefaultOKExitCodes = []int{100}
+
+// AcceptedExitCodes returns ok_exit_codes, or DefaultOKExitCodes when unset.
+// An explicit empty list accepts no non-zero code.
+func (c ConnectionConfig) AcceptedExitCodes() []int {
+	if c.OKExitCodes == nil {
+		return DefaultOKExitCodes
+	}
+	return c.OKExitCodes
+}
+
+// HasContainer reports whether commands run in a container, named directly or
+// through compose_service
+func (c ConnectionConfig) HasContainer() bool {
+	return c.Container != "" || c.ComposeService != ""
+}
+
+// Deadline returns run_deadline as a duration (zero when unset)
+func (c ConnectionConfig) Deadline() (time.Duration, error) {
+	if c.RunDeadline == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(c.RunDeadline)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%q must be positive", c.RunDeadline)
+	}
+	return d, nil
 }
 
 // BackupConfig defines what to backup and where
 type BackupConfig struct {
 	Name         string          `yaml:"name"`         // Duplicacy repository ID
 	Path         string          `yaml:"path"`         // Source path to backup
+	Repository   string          `yaml:"repository"`   // Source path passed as -repository when several IDs share one repository
 	CacheDir     string          `yaml:"cache_dir"`    // Cache directory (auto-discovered if not set)
 	Destinations []string        `yaml:"destinations"` // Storage backends to backup to
 	Retention    RetentionConfig `yaml:"retention"`    // Retention policy
 	Threads      int             `yaml:"threads"`      // Number of backup threads (default: 1)
+	LimitRate    int             `yaml:"limit_rate"`   // Upload limit in KB/s, overriding the storage's limit_rate (0 = unlimited)
+	VSS          bool            `yaml:"vss"`          // Back up from a Volume Shadow Copy (-vss, Windows only)
+	PreHook      string          `yaml:"pre_hook"`     // Command run before the first destination; a failure skips this backup
+	PostHook     string          `yaml:"post_hook"`    // Command run after the last destination
+
+	// DetectRepository reads the repository path for -repository from the
+	// cache dir's .duplicacy/preferences when Repository is not set
+	DetectRepository bool `yaml:"detect_repository,omitempty"`
+
+	// BackupOptions are extra duplicacy backup options appended to every
+	// backup of this repository, e.g. ["-hash"] (values as -option=value)
+	BackupOptions []string `yaml:"backup_options,omitempty"`
+
+	// DestinationRetention overrides Retention per storage; set from the
+	// object form of destinations entries (see DestinationConfig)
+	DestinationRetention map[string]RetentionConfig `yaml:"-"`
 }
 
 // RetentionConfig defines backup retention policy
@@ -64,6 +250,11 @@ type RetentionConfig struct {
 	Weeks int `yaml:"weeks"` // Keep weekly backups for N days
 }
 
+// IsZero reports whether no retention values are set
+func (r RetentionConfig) IsZero() bool {
+	return r == RetentionConfig{}
+}
+
 // ToPruneOptions converts retention config to duplicacy prune options (with -a flag)
 func (r RetentionConfig) ToPruneOptions() string {
 	return r.toPruneOptions(true)
@@ -82,6 +273,21 @@ func (r RetentionConfig) toPruneOptions(includeAll bool) string {
 		allFlag = " -a"
 	}
 
+	dailyEnd, weeklyEnd, monthlyEnd := r.boundaries()
+
+	var opts string
+	if monthlyEnd > 0 {
+		opts = fmt.Sprintf("-keep 0:%d -keep 30:%d -keep 7:%d -keep 1:1%s", monthlyEnd, weeklyEnd, dailyEnd, allFlag)
+	} else {
+		opts = fmt.Sprintf("-keep 0:%d -keep 7:%d -keep 1:1%s", weeklyEnd, dailyEnd, allFlag)
+	}
+
+	return opts
+}
+
+// boundaries returns the age in days at which each retention tier ends.
+// monthlyEnd is 0 when no monthly tier is kept.
+func (r RetentionConfig) boundaries() (dailyEnd, weeklyEnd, monthlyEnd int) {
 	// Handle legacy format
 	if r.Days > 0 || r.Weeks > 0 {
 		days := r.Days
@@ -92,7 +298,7 @@ func (r RetentionConfig) toPruneOptions(includeAll bool) string {
 		if weeks == 0 {
 			weeks = 180
 		}
-		return fmt.Sprintf("-keep 0:%d -keep 7:%d -keep 1:1%s", weeks, days, allFlag)
+		return days, weeks, 0
 	}
 
 	// New format: counts
@@ -110,23 +316,48 @@ func (r RetentionConfig) toPruneOptions(includeAll bool) string {
 	// Daily: days 1 to daily
 	// Weekly: days daily+1 to daily + (weekly * 7)
 	// Monthly: days weekly_end+1 to weekly_end + (monthly * 30)
-	dailyEnd := daily
-	weeklyEnd := dailyEnd + (weekly * 7)
-
-	var opts string
+	dailyEnd = daily
+	weeklyEnd = dailyEnd + (weekly * 7)
 	if monthly > 0 {
-		monthlyEnd := weeklyEnd + (monthly * 30)
-		opts = fmt.Sprintf("-keep 0:%d -keep 30:%d -keep 7:%d -keep 1:1%s", monthlyEnd, weeklyEnd, dailyEnd, allFlag)
-	} else {
-		opts = fmt.Sprintf("-keep 0:%d -keep 7:%d -keep 1:1%s", weeklyEnd, dailyEnd, allFlag)
+		monthlyEnd = weeklyEnd + (monthly * 30)
 	}
+	return dailyEnd, weeklyEnd, monthlyEnd
+}
 
-	return opts
+// Describe explains the resolved retention as day boundaries
+func (r RetentionConfig) Describe() string {
+	dailyEnd, weeklyEnd, monthlyEnd := r.boundaries()
+	if monthlyEnd > 0 {
+		return fmt.Sprintf("daily until day %d, weekly until day %d, monthly until day %d, none after", dailyEnd, weeklyEnd, monthlyEnd)
+	}
+	return fmt.Sprintf("daily until day %d, weekly until day %d, none after", dailyEnd, weeklyEnd)
 }
 
 // NotificationConfig holds notification settings
 type NotificationConfig struct {
 	Forgejo ForgejoNotificationConfig `yaml:"forgejo"`
+
+	// TitleTemplate is a Go template for failure issue titles, with
+	// .FailedBackups, .Host and .Timestamp. Empty uses the built-in title.
+	TitleTemplate string `yaml:"title_template"`
+
+	// LogTailLines is how many trailing output lines of each failed duplicacy
+	// command go into the failure issue (0 = DefaultLogTailLines, negative = none)
+	LogTailLines int `yaml:"log_tail_lines"`
+}
+
+// DefaultLogTailLines is the log tail length used when log_tail_lines is unset
+const DefaultLogTailLines = 50
+
+// TailLines returns the number of log lines to attach to failure issues
+func (n NotificationConfig) TailLines() int {
+	if n.LogTailLines == 0 {
+		return DefaultLogTailLines
+	}
+	if n.LogTailLines < 0 {
+		return 0
+	}
+	return n.LogTailLines
 }
 
 // ForgejoNotificationConfig holds Forgejo-specific notification settings
@@ -136,13 +367,53 @@ type ForgejoNotificationConfig struct {
 	Token    string `yaml:"token"`     // Direct token value
 	TokenEnv string `yaml:"token_env"` // Environment variable name
 	Assignee string `yaml:"assignee"`
+
+	// Assignees are additional users to assign issues to, alongside Assignee
+	Assignees []string `yaml:"assignees"`
+
+	// TokenCommand is run locally (argv, no shell) and its trimmed stdout used
+	// as the token, e.g. ["vault", "read", "-field=token", "secret/forgejo"]
+	TokenCommand []string `yaml:"token_command"`
+
+	// UpdateMode is how repeat failures update an open issue: "comment" adds a
+	// comment per failure (default), "body" rewrites the body with recent failures
+	UpdateMode string `yaml:"update_mode"`
+
+	// ReopenClosed reopens and comments on a matching issue closed in the last
+	// 30 days instead of creating a new one (default: true)
+	ReopenClosed *bool `yaml:"reopen_closed"`
+
+	commandToken string // resolved from TokenCommand
+}
+
+// ReopensClosed reports whether failures should reopen a recently closed issue
+func (f ForgejoNotificationConfig) ReopensClosed() bool {
+	return f.ReopenClosed == nil || *f.ReopenClosed
 }
 
-// GetToken returns the Forgejo token, checking direct value first, then env var
+// AllAssignees returns assignee followed by assignees, skipping repeats
+func (f ForgejoNotificationConfig) AllAssignees() []string {
+	var all []string
+	seen := make(map[string]bool)
+	for _, name := range append([]string{f.Assignee}, f.Assignees...) {
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		all = append(all, name)
+	}
+	return all
+}
+
+// GetToken returns the Forgejo token, checking the direct value first, then
+// token_command output, then the env var
 func (f ForgejoNotificationConfig) GetToken() string {
 	if f.Token != "" {
 		return f.Token
 	}
+	if f.commandToken != "" {
+		return f.commandToken
+	}
 	if f.TokenEnv != "" {
 		return os.Getenv(f.TokenEnv)
 	}
@@ -171,22 +442,52 @@ type RepositoryConfig struct {
 
 // Load reads and parses a config file
 func Load(path string) (*Config, error) {
+	cfg, err := parseFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply defaults
+	cfg.applyDefaults()
+
+	return cfg, nil
+}
+
+// parseFile reads and parses a config file without applying defaults
+func parseFile(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 
 	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
+	if err := unmarshalConfig(path, data, &cfg); err != nil {
 		return nil, err
 	}
-
-	// Apply defaults
-	cfg.applyDefaults()
+	if cfg.Version > CurrentVersion {
+		return nil, fmt.Errorf("unsupported config version %d (this build supports up to %d)", cfg.Version, CurrentVersion)
+	}
 
 	return &cfg, nil
 }
 
+// unmarshalConfig decodes config data by file extension: .toml files as TOML,
+// anything else as YAML
+func unmarshalConfig(path string, data []byte, cfg *Config) error {
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		var doc map[string]interface{}
+		if _, err := toml.Decode(string(data), &doc); err != nil {
+			return err
+		}
+		// Re-encode as YAML so the yaml tags stay the only field mapping
+		var err error
+		if data, err = yaml.Marshal(doc); err != nil {
+			return err
+		}
+	}
+	return yaml.Unmarshal(data, cfg)
+}
+
 // applyDefaults sets default values for optional fields
 func (c *Config) applyDefaults() {
 	// Default GCD token path
@@ -196,6 +497,9 @@ func (c *Config) applyDefaults() {
 
 	// Apply defaults to each backup
 	for i := range c.Backups {
+		if c.Backups[i].Retention.IsZero() {
+			c.Backups[i].Retention = c.DefaultRetention()
+		}
 		// Only set new format defaults if legacy format not used
 		if c.Backups[i].Retention.Days == 0 && c.Backups[i].Retention.Weeks == 0 {
 			if c.Backups[i].Retention.Daily == 0 {
@@ -206,8 +510,19 @@ func (c *Config) applyDefaults() {
 			}
 			// Monthly defaults to 0 (disabled)
 		}
+		for storage, retention := range c.Backups[i].DestinationRetention {
+			if retention.Days == 0 && retention.Weeks == 0 {
+				if retention.Daily == 0 {
+					retention.Daily = 7
+				}
+				if retention.Weekly == 0 {
+					retention.Weekly = 4
+				}
+				c.Backups[i].DestinationRetention[storage] = retention
+			}
+		}
 		if c.Backups[i].Threads == 0 {
-			c.Backups[i].Threads = 1
+			c.Backups[i].Threads = c.DefaultThreads()
 		}
 	}
 
@@ -218,12 +533,62 @@ func (c *Config) applyDefaults() {
 	if c.Connection.Container == "" && c.Docker.Container != "" {
 		c.Connection.Container = c.Docker.Container
 	}
+
+	c.Deprecations = c.legacyFields()
+}
+
+// legacyFields describes each legacy field in use and what replaces it
+func (c *Config) legacyFields() []string {
+	var fields []string
+	if c.SSH.Host != "" {
+		fields = append(fields, "ssh.host (use connection.host)")
+	}
+	if c.SSH.PasswordEnv != "" {
+		fields = append(fields, "ssh.password_env (use the SSH_PASSWORD env var)")
+	}
+	if c.Docker.Container != "" {
+		fields = append(fields, "docker.container (use connection.container)")
+	}
+	if len(c.Repositories) > 0 {
+		fields = append(fields, "repositories (use backups)")
+	}
+	return fields
+}
+
+// DeprecationWarning returns a warning listing the legacy fields in use, or "" if there are none
+func (c *Config) DeprecationWarning() string {
+	if len(c.Deprecations) == 0 {
+		return ""
+	}
+	version := "no version"
+	if c.Version > 0 {
+		version = fmt.Sprintf("version %d", c.Version)
+	}
+	return fmt.Sprintf("config (%s) uses deprecated fields: %s; set version: %d after migrating",
+		version, strings.Join(c.Deprecations, ", "), CurrentVersion)
+}
+
+// CheckStrict returns an error if the config uses legacy fields
+func (c *Config) CheckStrict() error {
+	if len(c.Deprecations) > 0 {
+		return fmt.Errorf("deprecated config fields are not allowed in strict mode: %s", strings.Join(c.Deprecations, ", "))
+	}
+	return nil
 }
 
+// globalOptionsWithValue are the duplicacy global options followed by a
+// separate value (they may also be written -opt=value)
+var globalOptionsWithValue = map[string]bool{"-profile": true, "-comment": true, "-suppress": true}
+
 // Validate checks the config for required fields
 func (c *Config) Validate() error {
-	if len(c.Backups) == 0 && len(c.Repositories) == 0 {
-		return fmt.Errorf("no backups defined")
+	// A maintenance-only config (prune/check, no backups) is valid
+	if len(c.Backups) == 0 && len(c.Repositories) == 0 && len(c.Maintenance) == 0 {
+		return fmt.Errorf("no backups defined (and no maintenance storages)")
+	}
+	// duplicacy check/prune need a directory with .duplicacy/preferences
+	if len(c.Backups) == 0 && len(c.Repositories) == 0 && c.MaintenanceDir == "" {
+		return fmt.Errorf("maintenance_dir is required when no backups are defined (check and prune need an initialized duplicacy directory)")
 	}
 
 	for i, b := range c.Backups {
@@ -233,12 +598,133 @@ func (c *Config) Validate() error {
 		if len(b.Destinations) == 0 {
 			return fmt.Errorf("backup[%d] (%s): at least one destination is required", i, b.Name)
 		}
+		if b.LimitRate < 0 {
+			return fmt.Errorf("backup[%d] (%s): limit_rate must not be negative", i, b.Name)
+		}
+		for _, opt := range b.BackupOptions {
+			if !strings.HasPrefix(opt, "-") {
+				return fmt.Errorf("backup[%d] (%s): backup_options: %q is not an option (must start with -)", i, b.Name, opt)
+			}
+		}
+	}
+
+	if c.Connection.ComposeService != "" && !composeServicePattern.MatchString(c.Connection.ComposeService) {
+		return fmt.Errorf("connection.compose_service %q is not a valid container name", c.Connection.ComposeService)
+	}
+	if c.Connection.KeyringPath != "" && !c.Connection.HasContainer() {
+		return fmt.Errorf("connection.keyring_path requires connection.container")
+	}
+	if c.Connection.ContainerUser != "" && !c.Connection.HasContainer() {
+		return fmt.Errorf("connection.container_user requires connection.container")
+	}
+	switch c.Connection.SSHPasswordMode {
+	case "", "arg", "file":
+	default:
+		return fmt.Errorf("connection.ssh_password_mode must be \"arg\" or \"file\", got %q", c.Connection.SSHPasswordMode)
+	}
+	if _, err := c.Connection.Deadline(); err != nil {
+		return fmt.Errorf("connection.run_deadline: %w", err)
+	}
+	if c.Connection.DuplicacyVersion != "" && !c.Connection.HasContainer() {
+		return fmt.Errorf("connection.duplicacy_version requires connection.container")
+	}
+	globalOpts := c.Connection.GlobalOptions
+	for i := 0; i < len(globalOpts); i++ {
+		opt := globalOpts[i]
+		if !strings.HasPrefix(opt, "-") {
+			return fmt.Errorf("connection.global_options: %q is not an option (must start with -)", opt)
+		}
+		if globalOptionsWithValue[opt] {
+			if i+1 == len(globalOpts) {
+				return fmt.Errorf("connection.global_options: %s needs a value", opt)
+			}
+			i++ // the value, which may not start with -
+		}
+	}
+	for name, argv := range map[string][]string{
+		"notifications.forgejo.token_command": c.Notifications.Forgejo.TokenCommand,
+		"connection.ssh_password_command":     c.Connection.SSHPasswordCommand,
+		"connection.storage_password_command": c.Connection.StoragePasswordCommand,
+	} {
+		if argv != nil && (len(argv) == 0 || argv[0] == "") {
+			return fmt.Errorf("%s must name a program to run", name)
+		}
+	}
+	switch c.Notifications.Forgejo.UpdateMode {
+	case "", "comment", "body":
+	default:
+		return fmt.Errorf("notifications.forgejo.update_mode must be \"comment\" or \"body\", got %q", c.Notifications.Forgejo.UpdateMode)
+	}
+	if _, err := template.New("title").Parse(c.Notifications.TitleTemplate); err != nil {
+		return fmt.Errorf("notifications.title_template: %w", err)
+	}
+	if _, err := c.Stats.Location(); err != nil {
+		return fmt.Errorf("stats.timezone: %w", err)
+	}
+	switch c.Stats.Format {
+	case "", "bytes", "human":
+	default:
+		return fmt.Errorf("stats.format must be \"bytes\" or \"human\", got %q", c.Stats.Format)
+	}
+
+	if err := CheckPruneOptions(c.DefaultRetention().ToPruneOptions()); err != nil {
+		return fmt.Errorf("defaults: %w", err)
+	}
+	if c.Defaults.Threads < 0 {
+		return fmt.Errorf("defaults: threads must not be negative")
+	}
+
+	for i, b := range c.Backups {
+		if err := CheckPruneOptions(b.Retention.ToPruneOptions()); err != nil {
+			return fmt.Errorf("backup[%d] (%s): %w", i, b.Name, err)
+		}
+		for storage, retention := range b.DestinationRetention {
+			if err := CheckPruneOptions(retention.ToPruneOptions()); err != nil {
+				return fmt.Errorf("backup[%d] (%s): destination %s: %w", i, b.Name, storage, err)
+			}
+			if _, ok := c.GetStorageRetention(storage); ok {
+				return fmt.Errorf("backup[%d] (%s): destination %s: retention cannot override storage-level retention, which prunes every ID with -a", i, b.Name, storage)
+			}
+		}
+	}
+
+	for name, sc := range c.Storages {
+		if err := CheckPruneOptions(sc.Retention.ToPruneOptions()); err != nil {
+			return fmt.Errorf("storage %s: %w", name, err)
+		}
+		if len(sc.PruneExclude) > 0 && !sc.Retention.IsZero() {
+			return fmt.Errorf("storage %s: prune_exclude cannot be used with storage-level retention, which prunes every ID with -a", name)
+		}
+		if len(sc.PruneExclude) > 0 && len(c.BackupsForStorage(name)) == 0 {
+			return fmt.Errorf("storage %s: prune_exclude needs backups targeting the storage; a maintenance-only storage is pruned with -a", name)
+		}
+		if sc.MinRevisions < 0 {
+			return fmt.Errorf("storage %s: min_revisions must not be negative", name)
+		}
+		if sc.LimitRate < 0 {
+			return fmt.Errorf("storage %s: limit_rate must not be negative", name)
+		}
+		if sc.ExclusivePrune && c.LockFile == "" {
+			return fmt.Errorf("storage %s: exclusive_prune requires lock_file, so no other duplicaci run can back up during the prune "+
+				"(the lock does not cover other clients: -exclusive is only safe when no other client, including the Web UI scheduler, writes to this storage)", name)
+		}
+		for envName := range sc.Env {
+			if !envNamePattern.MatchString(envName) {
+				return fmt.Errorf("storage %s: invalid env var name %q", name, envName)
+			}
+		}
+		if sc.Type != "" && !storageTypes[sc.Type] {
+			return fmt.Errorf("storage %s: unknown type %q", name, sc.Type)
+		}
+		if sc.KnownHost != "" && !knownHostPattern.MatchString(sc.KnownHost) {
+			return fmt.Errorf("storage %s: known_host must be host or host:port, got %q", name, sc.KnownHost)
+		}
 	}
 
 	return nil
 }
 
-// AllStorages returns a deduplicated list of all storage backends
+// AllStorages returns a deduplicated list of all storage backends, ordered by priority
 func (c *Config) AllStorages() []string {
 	seen := make(map[string]bool)
 	var storages []string
@@ -261,27 +747,109 @@ func (c *Config) AllStorages() []string {
 		}
 	}
 
+	return c.sortByPriority(storages)
+}
+
+// LimitRate returns the upload limit in KB/s for a backup to a destination:
+// the backup's limit_rate if set, else the storage's (0 = unlimited)
+func (c *Config) LimitRate(backup BackupConfig, dest string) int {
+	if backup.LimitRate > 0 {
+		return backup.LimitRate
+	}
+	return c.Storages[dest].LimitRate
+}
+
+// KnownHosts returns the distinct known_host entries of the storages in use, in storage order
+func (c *Config) KnownHosts() []string {
+	seen := make(map[string]bool)
+	var hosts []string
+	for _, storage := range c.AllStorages() {
+		host := c.Storages[storage].KnownHost
+		if host != "" && !seen[host] {
+			seen[host] = true
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// Destinations returns a backup's destinations ordered by storage priority
+func (c *Config) Destinations(b BackupConfig) []string {
+	return c.sortByPriority(append([]string{}, b.Destinations...))
+}
+
+// sortByPriority orders storages by storages.<name>.priority (lower first),
+// keeping config order for equal priorities
+func (c *Config) sortByPriority(storages []string) []string {
+	sort.SliceStable(storages, func(i, j int) bool {
+		return c.Storages[storages[i]].Priority < c.Storages[storages[j]].Priority
+	})
 	return storages
 }
 
 // GetStorageRetention returns the retention config for a storage, if defined
 func (c *Config) GetStorageRetention(storage string) (RetentionConfig, bool) {
 	if c.Storages != nil {
-		if sc, ok := c.Storages[storage]; ok {
+		if sc, ok := c.Storages[storage]; ok && !sc.Retention.IsZero() {
 			return sc.Retention, true
 		}
 	}
 	return RetentionConfig{}, false
 }
 
+// StorageName returns the duplicacy storage name for a configured storage.
+// The config key acts as an alias used for reporting and stats files; the
+// optional name field is what duplicacy itself knows the storage as.
+func (c *Config) StorageName(storage string) string {
+	if name := c.GetStorageConfig(storage).Name; name != "" {
+		return name
+	}
+	return storage
+}
+
+// GetStorageConfig returns the settings for a storage, or zero values if not configured
+func (c *Config) GetStorageConfig(storage string) StorageConfig {
+	if c.Storages != nil {
+		return c.Storages[storage]
+	}
+	return StorageConfig{}
+}
+
+// StorageEnv returns the extra env vars for every storage that defines them,
+// keyed by duplicacy storage name
+func (c *Config) StorageEnv() map[string]map[string]string {
+	env := make(map[string]map[string]string)
+	for storage, sc := range c.Storages {
+		if len(sc.Env) > 0 {
+			env[c.StorageName(storage)] = sc.Env
+		}
+	}
+	return env
+}
+
 // GetBackupRetention returns the retention config for a specific backup
 func (c *Config) GetBackupRetention(backupName string) RetentionConfig {
 	for _, b := range c.Backups {
-		if b.Name == backupName {
+		if b.Name == backupName && !b.Retention.IsZero() {
 			return b.Retention
 		}
 	}
-	// Default retention
+	return c.DefaultRetention()
+}
+
+// DefaultThreads returns defaults.threads, or 1 if it is not set
+func (c *Config) DefaultThreads() int {
+	if c.Defaults.Threads > 0 {
+		return c.Defaults.Threads
+	}
+	return 1
+}
+
+// DefaultRetention returns defaults.retention, or daily 7 / weekly 4 if it is not set
+func (c *Config) DefaultRetention() RetentionConfig {
+	if !c.Defaults.Retention.IsZero() {
+		return c.Defaults.Retention
+	}
 	return RetentionConfig{Daily: 7, Weekly: 4}
 }
 
@@ -290,6 +858,18 @@ func (c *Config) HasStorageLevelRetention() bool {
 	return len(c.Storages) > 0
 }
 
+// ValidateBackupName returns an error if no backup with the given name is configured
+func (c *Config) ValidateBackupName(name string) error {
+	var names []string
+	for _, b := range c.Backups {
+		if b.Name == name {
+			return nil
+		}
+		names = append(names, b.Name)
+	}
+	return fmt.Errorf("unknown backup %q (configured: %s)", name, strings.Join(names, ", "))
+}
+
 // BackupsForStorage returns all backup names that target a specific storage
 func (c *Config) BackupsForStorage(storage string) []string {
 	var backups []string
@@ -3,7 +3,9 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestLoad_ValidConfig(t *testing.T) {
@@ -246,6 +248,37 @@ func TestForgejoNotificationConfig_GetToken(t *testing.T) {
 	})
 }
 
+func TestForgejoNotificationConfig_AllAssignees(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      ForgejoNotificationConfig
+		expected []string
+	}{
+		{"none", ForgejoNotificationConfig{}, nil},
+		{"single", ForgejoNotificationConfig{Assignee: "alice"}, []string{"alice"}},
+		{"list", ForgejoNotificationConfig{Assignees: []string{"alice", "bob"}}, []string{"alice", "bob"}},
+		{"both deduped", ForgejoNotificationConfig{Assignee: "alice", Assignees: []string{"bob", "alice"}}, []string{"alice", "bob"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.cfg.AllAssignees()
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestForgejoNotificationConfig_ReopensClosed(t *testing.T) {
+	off := false
+	if !(ForgejoNotificationConfig{}).ReopensClosed() {
+		t.Error("expected reopen_closed to default to true")
+	}
+	if (ForgejoNotificationConfig{ReopenClosed: &off}).ReopensClosed() {
+		t.Error("expected reopen_closed: false to be honored")
+	}
+}
+
 func TestConfig_Validate(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -259,6 +292,102 @@ func TestConfig_Validate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "no backups defined",
 		},
+		{
+			name: "global option without dash",
+			config: Config{
+				Connection:     ConnectionConfig{GlobalOptions: []string{"-log", "profile"}},
+				Maintenance:    []string{"Archive"},
+				MaintenanceDir: "/cache/localhost/0",
+			},
+			wantErr: true,
+			errMsg:  `connection.global_options: "profile" is not an option`,
+		},
+		{
+			name: "global option with value",
+			config: Config{
+				Connection:     ConnectionConfig{GlobalOptions: []string{"-profile", "localhost:6060", "-comment=nightly", "-log"}},
+				Maintenance:    []string{"Archive"},
+				MaintenanceDir: "/cache/localhost/0",
+			},
+			wantErr: false,
+		},
+		{
+			name: "global option missing its value",
+			config: Config{
+				Connection:     ConnectionConfig{GlobalOptions: []string{"-log", "-profile"}},
+				Maintenance:    []string{"Archive"},
+				MaintenanceDir: "/cache/localhost/0",
+			},
+			wantErr: true,
+			errMsg:  "connection.global_options: -profile needs a value",
+		},
+		{
+			name: "unknown ssh password mode",
+			config: Config{
+				Connection:     ConnectionConfig{SSHPasswordMode: "pipe"},
+				Maintenance:    []string{"Archive"},
+				MaintenanceDir: "/cache/localhost/0",
+			},
+			wantErr: true,
+			errMsg:  `connection.ssh_password_mode must be "arg" or "file", got "pipe"`,
+		},
+		{
+			name: "file ssh password mode",
+			config: Config{
+				Connection:     ConnectionConfig{SSHPasswordMode: "file"},
+				Maintenance:    []string{"Archive"},
+				MaintenanceDir: "/cache/localhost/0",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid compose service",
+			config: Config{
+				Connection:     ConnectionConfig{ComposeService: "stack service"},
+				Maintenance:    []string{"Archive"},
+				MaintenanceDir: "/cache/localhost/0",
+			},
+			wantErr: true,
+			errMsg:  `connection.compose_service "stack service" is not a valid container name`,
+		},
+		{
+			name: "container user with compose service",
+			config: Config{
+				Connection:     ConnectionConfig{ComposeService: "duplicacy", ContainerUser: "abc"},
+				Maintenance:    []string{"Archive"},
+				MaintenanceDir: "/cache/localhost/0",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid run deadline",
+			config: Config{
+				Connection:     ConnectionConfig{RunDeadline: "-1h"},
+				Maintenance:    []string{"Archive"},
+				MaintenanceDir: "/cache/localhost/0",
+			},
+			wantErr: true,
+			errMsg:  `connection.run_deadline: "-1h" must be positive`,
+		},
+		{
+			name: "backup option without dash",
+			config: Config{
+				Backups: []BackupConfig{{Name: "test", Destinations: []string{"NAS"}, BackupOptions: []string{"-hash", "hash"}}},
+			},
+			wantErr: true,
+			errMsg:  `backup[0] (test): backup_options: "hash" is not an option (must start with -)`,
+		},
+		{
+			name:    "maintenance only",
+			config:  Config{Maintenance: []string{"Archive"}, MaintenanceDir: "/cache/localhost/0"},
+			wantErr: false,
+		},
+		{
+			name:    "maintenance only without maintenance_dir",
+			config:  Config{Maintenance: []string{"Archive"}},
+			wantErr: true,
+			errMsg:  "maintenance_dir is required when no backups are defined (check and prune need an initialized duplicacy directory)",
+		},
 		{
 			name: "backup without name",
 			config: Config{
@@ -289,6 +418,149 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "negative legacy retention",
+			config: Config{
+				Backups: []BackupConfig{{Name: "test", Destinations: []string{"NAS"}, Retention: RetentionConfig{Days: 5, Weeks: -1}}},
+			},
+			wantErr: true,
+			errMsg:  "invalid -keep age",
+		},
+		{
+			name: "keyring without container",
+			config: Config{
+				Connection: ConnectionConfig{KeyringPath: "/config/keyring"},
+				Backups:    []BackupConfig{{Name: "test", Destinations: []string{"NAS"}}},
+			},
+			wantErr: true,
+			errMsg:  "keyring_path requires connection.container",
+		},
+		{
+			name: "unknown stats timezone",
+			config: Config{
+				Backups: []BackupConfig{{Name: "test", Destinations: []string{"NAS"}}},
+				Stats:   StatsConfig{Timezone: "Mars/Olympus_Mons"},
+			},
+			wantErr: true,
+			errMsg:  "stats.timezone",
+		},
+		{
+			name: "invalid stats format",
+			config: Config{
+				Backups: []BackupConfig{{Name: "test", Destinations: []string{"NAS"}}},
+				Stats:   StatsConfig{Format: "kilobytes"},
+			},
+			wantErr: true,
+			errMsg:  "stats.format",
+		},
+		{
+			name: "container user without container",
+			config: Config{
+				Connection: ConnectionConfig{ContainerUser: "abc"},
+				Backups:    []BackupConfig{{Name: "test", Destinations: []string{"NAS"}}},
+			},
+			wantErr: true,
+			errMsg:  "container_user requires connection.container",
+		},
+		{
+			name: "duplicacy_version without container",
+			config: Config{
+				Connection: ConnectionConfig{DuplicacyVersion: "3.2.3"},
+				Backups:    []BackupConfig{{Name: "test", Destinations: []string{"NAS"}}},
+			},
+			wantErr: true,
+			errMsg:  "duplicacy_version requires connection.container",
+		},
+		{
+			name: "invalid storage env var name",
+			config: Config{
+				Backups:  []BackupConfig{{Name: "test", Destinations: []string{"b2"}}},
+				Storages: map[string]StorageConfig{"b2": {Env: map[string]string{"BAD;NAME": "x"}}},
+			},
+			wantErr: true,
+			errMsg:  "invalid env var name",
+		},
+		{
+			name: "negative min_revisions",
+			config: Config{
+				Backups:  []BackupConfig{{Name: "test", Destinations: []string{"NAS"}}},
+				Storages: map[string]StorageConfig{"NAS": {MinRevisions: -1}},
+			},
+			wantErr: true,
+			errMsg:  "min_revisions must not be negative",
+		},
+		{
+			name: "exclusive_prune without lock_file",
+			config: Config{
+				Backups:  []BackupConfig{{Name: "test", Destinations: []string{"NAS"}}},
+				Storages: map[string]StorageConfig{"NAS": {ExclusivePrune: true}},
+			},
+			wantErr: true,
+			errMsg:  "exclusive_prune requires lock_file",
+		},
+		{
+			name: "negative backup limit_rate",
+			config: Config{
+				Backups: []BackupConfig{{Name: "test", Destinations: []string{"NAS"}, LimitRate: -1}},
+			},
+			wantErr: true,
+			errMsg:  "limit_rate must not be negative",
+		},
+		{
+			name: "negative defaults threads",
+			config: Config{
+				Backups:  []BackupConfig{{Name: "test", Destinations: []string{"NAS"}}},
+				Defaults: DefaultsConfig{Threads: -1},
+			},
+			wantErr: true,
+			errMsg:  "defaults: threads must not be negative",
+		},
+		{
+			name: "prune_exclude with storage retention",
+			config: Config{
+				Backups:  []BackupConfig{{Name: "test", Destinations: []string{"NAS"}}},
+				Storages: map[string]StorageConfig{"NAS": {PruneExclude: []string{"test"}, Retention: RetentionConfig{Daily: 7}}},
+			},
+			wantErr: true,
+			errMsg:  "prune_exclude cannot be used with storage-level retention",
+		},
+		{
+			name: "prune_exclude on maintenance-only storage",
+			config: Config{
+				Backups:     []BackupConfig{{Name: "test", Destinations: []string{"NAS"}}},
+				Maintenance: []string{"Archive"},
+				Storages:    map[string]StorageConfig{"Archive": {PruneExclude: []string{"legacy"}}},
+			},
+			wantErr: true,
+			errMsg:  "storage Archive: prune_exclude needs backups targeting the storage; a maintenance-only storage is pruned with -a",
+		},
+		{
+			name: "unknown forgejo update_mode",
+			config: Config{
+				Backups:       []BackupConfig{{Name: "test", Destinations: []string{"NAS"}}},
+				Notifications: NotificationConfig{Forgejo: ForgejoNotificationConfig{UpdateMode: "edit"}},
+			},
+			wantErr: true,
+			errMsg:  "update_mode must be",
+		},
+		{
+			name: "invalid known_host",
+			config: Config{
+				Backups:  []BackupConfig{{Name: "test", Destinations: []string{"NAS"}}},
+				Storages: map[string]StorageConfig{"NAS": {KnownHost: "host; rm -rf /"}},
+			},
+			wantErr: true,
+			errMsg:  "known_host must be host or host:port",
+		},
+		{
+			name: "unparsable title_template",
+			config: Config{
+				Backups:       []BackupConfig{{Name: "test", Destinations: []string{"NAS"}}},
+				Notifications: NotificationConfig{TitleTemplate: "{{.FailedBackups"},
+			},
+			wantErr: true,
+			errMsg:  "notifications.title_template",
+		},
 	}
 
 	for _, tt := range tests {
@@ -332,6 +604,67 @@ func TestConfig_AllStorages(t *testing.T) {
 	}
 }
 
+func TestConfig_StoragePriority(t *testing.T) {
+	tests := []struct {
+		name     string
+		storages map[string]StorageConfig
+		expected []string
+	}{
+		{
+			name:     "no priorities keeps config order",
+			expected: []string{"Cloud", "NAS", "USB", "Archive"},
+		},
+		{
+			name: "lower priority first",
+			storages: map[string]StorageConfig{
+				"NAS":   {Priority: -1},
+				"Cloud": {Priority: 10},
+			},
+			expected: []string{"NAS", "USB", "Archive", "Cloud"},
+		},
+		{
+			name: "equal priorities are stable",
+			storages: map[string]StorageConfig{
+				"Cloud":   {Priority: 5},
+				"USB":     {Priority: 1},
+				"Archive": {Priority: 1},
+				"NAS":     {Priority: 5},
+			},
+			expected: []string{"USB", "Archive", "Cloud", "NAS"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				Backups: []BackupConfig{
+					{Name: "appdata", Destinations: []string{"Cloud", "NAS", "USB"}},
+				},
+				Maintenance: []string{"Archive"},
+				Storages:    tt.storages,
+			}
+
+			if got := cfg.AllStorages(); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("AllStorages() = %v, want %v", got, tt.expected)
+			}
+
+			// Destinations follow the same order and leave the config untouched
+			var expectedDests []string
+			for _, s := range tt.expected {
+				if s != "Archive" {
+					expectedDests = append(expectedDests, s)
+				}
+			}
+			if got := cfg.Destinations(cfg.Backups[0]); !reflect.DeepEqual(got, expectedDests) {
+				t.Errorf("Destinations() = %v, want %v", got, expectedDests)
+			}
+			if !reflect.DeepEqual(cfg.Backups[0].Destinations, []string{"Cloud", "NAS", "USB"}) {
+				t.Errorf("Destinations() modified the config: %v", cfg.Backups[0].Destinations)
+			}
+		})
+	}
+}
+
 func TestConfig_GetStorageRetention(t *testing.T) {
 	cfg := Config{
 		Storages: map[string]StorageConfig{
@@ -360,6 +693,37 @@ func TestConfig_GetStorageRetention(t *testing.T) {
 	if ok {
 		t.Error("GetStorageRetention() should return false when Storages is nil")
 	}
+
+	// Storage configured without a retention block
+	cfg3 := Config{
+		Storages: map[string]StorageConfig{
+			"storage1": {VerifyChunks: true},
+		},
+	}
+	_, ok = cfg3.GetStorageRetention("storage1")
+	if ok {
+		t.Error("GetStorageRetention() should return false when retention is not set")
+	}
+}
+
+func TestConfig_GetStorageConfig(t *testing.T) {
+	cfg := Config{
+		Storages: map[string]StorageConfig{
+			"storage1": {VerifyChunks: true},
+		},
+	}
+
+	if !cfg.GetStorageConfig("storage1").VerifyChunks {
+		t.Error("GetStorageConfig() should return configured settings")
+	}
+	if cfg.GetStorageConfig("other").VerifyChunks {
+		t.Error("GetStorageConfig() should return zero values for unknown storage")
+	}
+
+	cfg2 := Config{}
+	if cfg2.GetStorageConfig("any").VerifyChunks {
+		t.Error("GetStorageConfig() should return zero values when Storages is nil")
+	}
 }
 
 func TestConfig_GetBackupRetention(t *testing.T) {
@@ -463,6 +827,33 @@ backups:
 	}
 }
 
+func TestConfig_ApplyDefaults_Threads(t *testing.T) {
+	tests := []struct {
+		name     string
+		defaults int
+		backup   int
+		expected int
+	}{
+		{"no defaults", 0, 0, 1},
+		{"global default", 4, 0, 4},
+		{"backup overrides default", 4, 2, 2},
+		{"backup without default", 0, 8, 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Defaults: DefaultsConfig{Threads: tt.defaults},
+				Backups:  []BackupConfig{{Name: "test", Destinations: []string{"NAS"}, Threads: tt.backup}},
+			}
+			cfg.applyDefaults()
+			if cfg.Backups[0].Threads != tt.expected {
+				t.Errorf("expected %d threads, got %d", tt.expected, cfg.Backups[0].Threads)
+			}
+		})
+	}
+}
+
 func TestConfig_ApplyDefaults_LegacyMigration(t *testing.T) {
 	content := `
 ssh:
@@ -523,3 +914,392 @@ backups:
 		t.Errorf("Legacy Days should be preserved, got %d", cfg.Backups[0].Retention.Days)
 	}
 }
+
+func TestConfig_ValidateBackupName(t *testing.T) {
+	cfg := Config{
+		Backups: []BackupConfig{
+			{Name: "appdata"},
+			{Name: "photos"},
+		},
+	}
+
+	if err := cfg.ValidateBackupName("photos"); err != nil {
+		t.Errorf("ValidateBackupName() unexpected error: %v", err)
+	}
+
+	err := cfg.ValidateBackupName("missing")
+	if err == nil {
+		t.Fatal("ValidateBackupName() should return error for unknown backup")
+	}
+	if !contains(err.Error(), "missing") || !contains(err.Error(), "appdata, photos") {
+		t.Errorf("ValidateBackupName() error = %q, want unknown name and configured list", err.Error())
+	}
+}
+
+func TestConfig_StorageName(t *testing.T) {
+	cfg := Config{
+		Storages: map[string]StorageConfig{
+			"Cloud": {Name: "gdrive-backup-2024"},
+			"NAS":   {Retention: RetentionConfig{Daily: 7}},
+		},
+	}
+
+	if got := cfg.StorageName("Cloud"); got != "gdrive-backup-2024" {
+		t.Errorf("StorageName(Cloud) = %q, want %q", got, "gdrive-backup-2024")
+	}
+	if got := cfg.StorageName("NAS"); got != "NAS" {
+		t.Errorf("StorageName(NAS) = %q, want %q", got, "NAS")
+	}
+	if got := cfg.StorageName("unknown"); got != "unknown" {
+		t.Errorf("StorageName(unknown) = %q, want %q", got, "unknown")
+	}
+}
+
+func TestConfig_StorageEnv(t *testing.T) {
+	cfg := &Config{
+		Storages: map[string]StorageConfig{
+			"B2":    {Name: "b2-main", Env: map[string]string{"DUPLICACY_B2_MAIN_B2_KEY": "key"}},
+			"Local": {Retention: RetentionConfig{Daily: 7}},
+		},
+	}
+
+	env := cfg.StorageEnv()
+	if len(env) != 1 {
+		t.Fatalf("expected env for one storage, got %v", env)
+	}
+	if env["b2-main"]["DUPLICACY_B2_MAIN_B2_KEY"] != "key" {
+		t.Errorf("expected env keyed by duplicacy storage name, got %v", env)
+	}
+}
+
+func TestLoad_Deprecations(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected []string
+	}{
+		{
+			name:     "current format",
+			content:  "version: 2\nconnection:\n  host: root@host\nbackups:\n  - name: a\n    destinations: [NAS]\n",
+			expected: nil,
+		},
+		{
+			name:     "unversioned current format",
+			content:  "connection:\n  host: root@host\n",
+			expected: nil,
+		},
+		{
+			name:    "legacy format",
+			content: "ssh:\n  host: root@host\n  password_env: PW\ndocker:\n  container: Duplicacy\nrepositories:\n  - id: repo\n",
+			expected: []string{
+				"ssh.host (use connection.host)",
+				"ssh.password_env (use the SSH_PASSWORD env var)",
+				"docker.container (use connection.container)",
+				"repositories (use backups)",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write config: %v", err)
+			}
+
+			cfg, err := Load(path)
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+
+			if len(cfg.Deprecations) != len(tt.expected) {
+				t.Fatalf("expected deprecations %v, got %v", tt.expected, cfg.Deprecations)
+			}
+			for i := range tt.expected {
+				if cfg.Deprecations[i] != tt.expected[i] {
+					t.Errorf("deprecation %d: expected %q, got %q", i, tt.expected[i], cfg.Deprecations[i])
+				}
+			}
+
+			warning := cfg.DeprecationWarning()
+			strictErr := cfg.CheckStrict()
+			if len(tt.expected) == 0 {
+				if warning != "" {
+					t.Errorf("expected no warning, got %q", warning)
+				}
+				if strictErr != nil {
+					t.Errorf("expected no strict error, got %v", strictErr)
+				}
+				return
+			}
+			if !containsHelper(warning, "uses deprecated fields") || !containsHelper(warning, "no version") {
+				t.Errorf("unexpected warning: %q", warning)
+			}
+			if strictErr == nil || !containsHelper(strictErr.Error(), "ssh.host") {
+				t.Errorf("expected strict error listing ssh.host, got %v", strictErr)
+			}
+		})
+	}
+}
+
+func TestLoad_UnsupportedVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("version: 99\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	_, err := Load(path)
+	if err == nil || !containsHelper(err.Error(), "unsupported config version 99") {
+		t.Errorf("expected unsupported version error, got %v", err)
+	}
+}
+
+func TestRetentionConfig_Describe(t *testing.T) {
+	tests := []struct {
+		name      string
+		retention RetentionConfig
+		expected  string
+	}{
+		{"defaults", RetentionConfig{}, "daily until day 7, weekly until day 35, none after"},
+		{"monthly", RetentionConfig{Daily: 7, Weekly: 4, Monthly: 3}, "daily until day 7, weekly until day 35, monthly until day 125, none after"},
+		{"legacy", RetentionConfig{Days: 14, Weeks: 180}, "daily until day 14, weekly until day 180, none after"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.retention.Describe(); got != tt.expected {
+				t.Errorf("Describe() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestConfig_DefaultsRetention(t *testing.T) {
+	content := `
+defaults:
+  retention:
+    daily: 14
+    weekly: 8
+    monthly: 6
+backups:
+  - name: plain
+    destinations: [NAS]
+  - name: custom
+    destinations: [NAS]
+    retention: { daily: 3 }
+`
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	// Backup without retention uses the configured default
+	ret := cfg.GetBackupRetention("plain")
+	if ret != (RetentionConfig{Daily: 14, Weekly: 8, Monthly: 6}) {
+		t.Errorf("expected configured default for plain, got %+v", ret)
+	}
+
+	// Explicit backup retention still wins
+	ret = cfg.GetBackupRetention("custom")
+	if ret.Daily != 3 {
+		t.Errorf("expected explicit retention for custom, got %+v", ret)
+	}
+
+	// Unknown backups and maintenance storages fall back to the configured default
+	if got := cfg.DefaultRetention(); got.Daily != 14 {
+		t.Errorf("expected configured default, got %+v", got)
+	}
+
+	// Without a defaults block the built-in default applies
+	if got := (&Config{}).DefaultRetention(); got != (RetentionConfig{Daily: 7, Weekly: 4}) {
+		t.Errorf("expected built-in default, got %+v", got)
+	}
+}
+
+func TestLoad_TOMLMatchesYAML(t *testing.T) {
+	yamlContent := `
+version: 2
+connection:
+  host: root@192.168.1.100
+  container: Duplicacy
+backups:
+  - name: appdata
+    path: /mnt/appdata
+    destinations: [NAS, B2]
+    threads: 4
+storages:
+  NAS:
+    retention: { daily: 7, weekly: 4, monthly: 3 }
+  B2:
+    name: b2-offsite
+    verify_chunks: true
+    env:
+      DUPLICACY_B2_B2_ID: "0012345"
+maintenance: [Archive]
+stats:
+  growth_alert_pct: 25.5
+notifications:
+  forgejo:
+    url: https://git.example.com
+    repo: user/infra
+`
+	tomlContent := `
+version = 2
+maintenance = ["Archive"]
+
+[connection]
+host = "root@192.168.1.100"
+container = "Duplicacy"
+
+[[backups]]
+name = "appdata"
+path = "/mnt/appdata"
+destinations = ["NAS", "B2"]
+threads = 4
+
+[storages.NAS.retention]
+daily = 7
+weekly = 4
+monthly = 3
+
+[storages.B2]
+name = "b2-offsite"
+verify_chunks = true
+
+[storages.B2.env]
+DUPLICACY_B2_B2_ID = "0012345"
+
+[stats]
+growth_alert_pct = 25.5
+
+[notifications.forgejo]
+url = "https://git.example.com"
+repo = "user/infra"
+`
+
+	tmpDir := t.TempDir()
+	yamlPath := filepath.Join(tmpDir, "duplicaci.yaml")
+	tomlPath := filepath.Join(tmpDir, "duplicaci.toml")
+	if err := os.WriteFile(yamlPath, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("failed to write yaml config: %v", err)
+	}
+	if err := os.WriteFile(tomlPath, []byte(tomlContent), 0644); err != nil {
+		t.Fatalf("failed to write toml config: %v", err)
+	}
+
+	fromYAML, err := Load(yamlPath)
+	if err != nil {
+		t.Fatalf("failed to load yaml: %v", err)
+	}
+	fromTOML, err := Load(tomlPath)
+	if err != nil {
+		t.Fatalf("failed to load toml: %v", err)
+	}
+
+	if !reflect.DeepEqual(fromYAML, fromTOML) {
+		t.Errorf("expected identical configs\nyaml: %+v\ntoml: %+v", fromYAML, fromTOML)
+	}
+	if fromTOML.Storages["B2"].Env["DUPLICACY_B2_B2_ID"] != "0012345" {
+		t.Errorf("expected B2 env from toml, got %v", fromTOML.Storages["B2"].Env)
+	}
+}
+
+func TestLoad_InvalidTOML(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "invalid.toml")
+	if err := os.WriteFile(configPath, []byte("[connection\nhost = "), 0644); err != nil {
+		t.Fatalf("failed to write temp config: %v", err)
+	}
+
+	if _, err := Load(configPath); err == nil {
+		t.Error("expected error for invalid TOML")
+	}
+}
+
+func TestStatsConfig_Location(t *testing.T) {
+	loc, err := StatsConfig{}.Location()
+	if err != nil || loc != time.Local {
+		t.Errorf("expected local time by default, got %v, %v", loc, err)
+	}
+
+	loc, err = StatsConfig{Timezone: "UTC"}.Location()
+	if err != nil || loc.String() != "UTC" {
+		t.Errorf("expected UTC, got %v, %v", loc, err)
+	}
+}
+
+func TestConnectionConfig_AcceptedExitCodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		codes    []int
+		expected []int
+	}{
+		{"unset uses default", nil, []int{100}},
+		{"explicit list", []int{100, 101}, []int{100, 101}},
+		{"explicit empty list", []int{}, []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ConnectionConfig{OKExitCodes: tt.codes}.AcceptedExitCodes()
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestConfig_LimitRate(t *testing.T) {
+	cfg := &Config{
+		Storages: map[string]StorageConfig{
+			"Cloud": {LimitRate: 1024},
+			"NAS":   {},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		backup   BackupConfig
+		dest     string
+		expected int
+	}{
+		{"unlimited", BackupConfig{Name: "appdata"}, "NAS", 0},
+		{"storage limit", BackupConfig{Name: "appdata"}, "Cloud", 1024},
+		{"backup overrides storage", BackupConfig{Name: "appdata", LimitRate: 256}, "Cloud", 256},
+		{"backup limit", BackupConfig{Name: "appdata", LimitRate: 256}, "NAS", 256},
+		{"unknown storage", BackupConfig{Name: "appdata"}, "S3", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cfg.LimitRate(tt.backup, tt.dest); got != tt.expected {
+				t.Errorf("expected %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestStorageConfig_IsPrunable(t *testing.T) {
+	yes, no := true, false
+	tests := []struct {
+		name     string
+		prunable *bool
+		expected bool
+	}{
+		{"default", nil, true},
+		{"explicit true", &yes, true},
+		{"append-only", &no, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (StorageConfig{Prunable: tt.prunable}).IsPrunable(); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
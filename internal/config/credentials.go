@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StorageTypeGCD is the storages.<name>.type of Google Drive storages, which
+// need a token file besides the storage password
+const StorageTypeGCD = "gcd"
+
+// storageTypes are the accepted storages.<name>.type values: the duplicacy
+// storage URL schemes, with "local" for plain paths
+var storageTypes = map[string]bool{
+	"local": true, "sftp": true, "s3": true, "s3c": true, "minio": true, "minios": true,
+	"b2": true, "azure": true, "gcs": true, StorageTypeGCD: true, "dropbox": true,
+	"one": true, "odb": true, "hubic": true, "swift": true, "webdav": true, "wasabi": true,
+	"storj": true,
+}
+
+// CredentialEnvName returns the duplicacy env var for a storage credential,
+// e.g. DUPLICACY_GOOGLE_DRIVE_GCD_TOKEN for "google-drive" and "GCD_TOKEN"
+func CredentialEnvName(storageName, credential string) string {
+	return "DUPLICACY_" + strings.ToUpper(strings.ReplaceAll(storageName, "-", "_")) + "_" + credential
+}
+
+// MissingCredentials lists the backup destinations duplicacy would have no
+// credentials for, one message per problem. A destination has a password when
+// one is set for every storage (storage_password_command or DUPLICACY_PASSWORD),
+// in storagePasswords (the keyring, by duplicacy storage name) or in its env.
+// A gcd storage also needs connection.gcd_token or a token in its env; that
+// the token file exists is left to the caller.
+func (c *Config) MissingCredentials(storagePasswords map[string]string) []string {
+	defaultPassword := c.Connection.StoragePassword()
+
+	var missing []string
+	seen := make(map[string]bool)
+	for _, b := range c.Backups {
+		for _, dest := range c.Destinations(b) {
+			if seen[dest] {
+				continue
+			}
+			seen[dest] = true
+
+			sc := c.GetStorageConfig(dest)
+			name := c.StorageName(dest)
+			passwordEnv := CredentialEnvName(name, "PASSWORD")
+			if defaultPassword == "" && storagePasswords[name] == "" &&
+				sc.Env[passwordEnv] == "" && sc.Env["DUPLICACY_PASSWORD"] == "" {
+				missing = append(missing, fmt.Sprintf(
+					"%s: no storage password (set DUPLICACY_PASSWORD, connection.storage_password_command, a keyring entry or storages.%s.env %s)",
+					dest, dest, passwordEnv))
+			}
+
+			tokenEnv := CredentialEnvName(name, "GCD_TOKEN")
+			if sc.Type == StorageTypeGCD && c.Connection.GCDToken == "" && sc.Env[tokenEnv] == "" {
+				missing = append(missing, fmt.Sprintf("%s: no Google Drive token (set connection.gcd_token or storages.%s.env %s)",
+					dest, dest, tokenEnv))
+			}
+		}
+	}
+	return missing
+}
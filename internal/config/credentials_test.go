@@ -0,0 +1,98 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMissingCredentials(t *testing.T) {
+	backups := []BackupConfig{
+		{Name: "appdata", Destinations: []string{"NAS", "GoogleDrive"}},
+		{Name: "photos", Destinations: []string{"NAS"}},
+	}
+
+	tests := []struct {
+		name      string
+		envPass   string // DUPLICACY_PASSWORD
+		storages  map[string]StorageConfig
+		gcdToken  string
+		passwords map[string]string // keyring, by duplicacy storage name
+		expected  []string          // destinations with a problem, "name:password" or "name:token"
+	}{
+		{
+			name:     "default password covers every destination",
+			envPass:  "secret",
+			gcdToken: "/config/gcd-token.json",
+			storages: map[string]StorageConfig{"GoogleDrive": {Type: "gcd"}},
+		},
+		{
+			name:     "nothing set",
+			storages: map[string]StorageConfig{"GoogleDrive": {Type: "gcd"}},
+			expected: []string{"NAS:password", "GoogleDrive:password", "GoogleDrive:token"},
+		},
+		{
+			name:      "keyring by duplicacy storage name",
+			storages:  map[string]StorageConfig{"GoogleDrive": {Name: "gdrive"}},
+			passwords: map[string]string{"NAS": "a", "gdrive": "b"},
+		},
+		{
+			name: "storage env password and token",
+			storages: map[string]StorageConfig{
+				"NAS":         {Env: map[string]string{"DUPLICACY_NAS_PASSWORD": "a"}},
+				"GoogleDrive": {Type: "gcd", Env: map[string]string{"DUPLICACY_PASSWORD": "b", "DUPLICACY_GOOGLEDRIVE_GCD_TOKEN": "/t.json"}},
+			},
+		},
+		{
+			name:      "one destination uncovered",
+			passwords: map[string]string{"NAS": "a"},
+			expected:  []string{"GoogleDrive:password"},
+		},
+		{
+			name:     "token only needed by gcd storages",
+			envPass:  "secret",
+			storages: map[string]StorageConfig{"NAS": {Type: "sftp"}, "GoogleDrive": {Type: "gcd"}},
+			expected: []string{"GoogleDrive:token"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DUPLICACY_PASSWORD", tt.envPass)
+			cfg := &Config{
+				Backups:    backups,
+				Storages:   tt.storages,
+				Connection: ConnectionConfig{GCDToken: tt.gcdToken},
+			}
+
+			var got []string
+			for _, msg := range cfg.MissingCredentials(tt.passwords) {
+				dest := msg[:strings.Index(msg, ":")]
+				kind := "password"
+				if strings.Contains(msg, "token") {
+					kind = "token"
+				}
+				got = append(got, dest+":"+kind)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestValidate_StorageType(t *testing.T) {
+	cfg := &Config{
+		Backups:  []BackupConfig{{Name: "test", Destinations: []string{"GoogleDrive"}}},
+		Storages: map[string]StorageConfig{"GoogleDrive": {Type: "gdrive"}},
+	}
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), `unknown type "gdrive"`) {
+		t.Errorf("expected unknown type error, got %v", err)
+	}
+
+	cfg.Storages["GoogleDrive"] = StorageConfig{Type: "gcd"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
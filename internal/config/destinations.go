@@ -0,0 +1,113 @@
+package config
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DestinationConfig is the object form of a backups[].destinations entry,
+// which overrides the backup's retention on one storage:
+//
+//	destinations:
+//	  - NAS
+//	  - storage: GoogleDrive
+//	    retention: { daily: 7 }
+type DestinationConfig struct {
+	Storage   string          `yaml:"storage"`   // Storage backend to backup to
+	Retention RetentionConfig `yaml:"retention"` // Retention for this backup on this storage
+}
+
+// UnmarshalYAML decodes a backup, accepting destinations as storage names or
+// DestinationConfig objects. Retention from the object form goes to
+// DestinationRetention.
+func (b *BackupConfig) UnmarshalYAML(value *yaml.Node) error {
+	type plain BackupConfig // without methods, so this does not recurse
+
+	// Decode everything but destinations as usual
+	rest := *value
+	var dests *yaml.Node
+	if value.Kind == yaml.MappingNode {
+		rest.Content = nil
+		for i := 0; i+1 < len(value.Content); i += 2 {
+			if value.Content[i].Value == "destinations" {
+				dests = value.Content[i+1]
+				continue
+			}
+			rest.Content = append(rest.Content, value.Content[i], value.Content[i+1])
+		}
+	}
+	if err := rest.Decode((*plain)(b)); err != nil {
+		return err
+	}
+	if dests == nil || dests.Tag == "!!null" {
+		return nil
+	}
+	if dests.Kind != yaml.SequenceNode {
+		return fmt.Errorf("line %d: destinations must be a list", dests.Line)
+	}
+
+	for _, item := range dests.Content {
+		if item.Kind == yaml.ScalarNode {
+			b.Destinations = append(b.Destinations, item.Value)
+			continue
+		}
+		var d DestinationConfig
+		if err := item.Decode(&d); err != nil {
+			return err
+		}
+		if d.Storage == "" {
+			return fmt.Errorf("line %d: destination requires storage", item.Line)
+		}
+		b.Destinations = append(b.Destinations, d.Storage)
+		if !d.Retention.IsZero() {
+			if b.DestinationRetention == nil {
+				b.DestinationRetention = make(map[string]RetentionConfig)
+			}
+			b.DestinationRetention[d.Storage] = d.Retention
+		}
+	}
+	return nil
+}
+
+// MarshalYAML encodes a backup with destinations that override retention in
+// their object form, so the effective config shows the overrides
+func (b BackupConfig) MarshalYAML() (interface{}, error) {
+	type plain BackupConfig
+	if len(b.DestinationRetention) == 0 {
+		return plain(b), nil
+	}
+
+	var node yaml.Node
+	if err := node.Encode(plain(b)); err != nil {
+		return nil, err
+	}
+	dests := make([]interface{}, len(b.Destinations))
+	for i, storage := range b.Destinations {
+		dests[i] = storage
+		if retention, ok := b.DestinationRetention[storage]; ok {
+			dests[i] = DestinationConfig{Storage: storage, Retention: retention}
+		}
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == "destinations" {
+			if err := node.Content[i+1].Encode(dests); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &node, nil
+}
+
+// GetDestinationRetention returns the retention for a backup on one storage:
+// its destination override if set, else the backup's retention
+func (c *Config) GetDestinationRetention(backupName, storage string) RetentionConfig {
+	for _, b := range c.Backups {
+		if b.Name == backupName {
+			if retention, ok := b.DestinationRetention[storage]; ok {
+				return retention
+			}
+		}
+	}
+	return c.GetBackupRetention(backupName)
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestLoad_DestinationRetention(t *testing.T) {
+	content := `
+backups:
+  - name: db
+    path: /mnt/db
+    retention: { daily: 30, weekly: 8 }
+    destinations:
+      - Local
+      - storage: Cloud
+        retention: { daily: 7 }
+`
+	configPath := filepath.Join(t.TempDir(), "duplicaci.yaml")
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write temp config: %v", err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+
+	b := cfg.Backups[0]
+	if !reflect.DeepEqual(b.Destinations, []string{"Local", "Cloud"}) {
+		t.Errorf("expected destinations [Local Cloud], got %v", b.Destinations)
+	}
+	if b.Path != "/mnt/db" {
+		t.Errorf("expected other fields decoded, got path %q", b.Path)
+	}
+
+	tests := []struct {
+		storage  string
+		expected RetentionConfig
+	}{
+		{"Local", RetentionConfig{Daily: 30, Weekly: 8}},
+		{"Cloud", RetentionConfig{Daily: 7, Weekly: 4}}, // weekly defaulted like backups[].retention
+	}
+	for _, tt := range tests {
+		if got := cfg.GetDestinationRetention("db", tt.storage); got != tt.expected {
+			t.Errorf("%s: expected %+v, got %+v", tt.storage, tt.expected, got)
+		}
+	}
+}
+
+func TestBackupConfig_DestinationsRoundTrip(t *testing.T) {
+	b := BackupConfig{
+		Name:                 "db",
+		Destinations:         []string{"Local", "Cloud"},
+		DestinationRetention: map[string]RetentionConfig{"Cloud": {Daily: 7}},
+	}
+
+	data, err := yaml.Marshal(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(data), "- Local\n") || !strings.Contains(string(data), "- storage: Cloud\n") {
+		t.Errorf("expected Local as a name and Cloud as an object, got:\n%s", data)
+	}
+
+	var got BackupConfig
+	if err := yaml.Unmarshal(data, &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, b) {
+		t.Errorf("expected %+v, got %+v", b, got)
+	}
+}
+
+func TestBackupConfig_DestinationErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		yaml     string
+		expected string
+	}{
+		{"missing storage", "name: db\ndestinations:\n  - retention: {daily: 7}\n", "destination requires storage"},
+		{"not a list", "name: db\ndestinations: Cloud\n", "destinations must be a list"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b BackupConfig
+			err := yaml.Unmarshal([]byte(tt.yaml), &b)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestValidate_DestinationRetention(t *testing.T) {
+	tests := []struct {
+		name      string
+		retention RetentionConfig
+		storage   StorageConfig
+		expected  string
+	}{
+		{"valid override", RetentionConfig{Daily: 7}, StorageConfig{}, ""},
+		{"deletes everything", RetentionConfig{Daily: 7, Weekly: -1}, StorageConfig{}, "destination Cloud"},
+		{"storage-level retention", RetentionConfig{Daily: 7}, StorageConfig{Retention: RetentionConfig{Daily: 3}}, "cannot override storage-level retention"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Backups: []BackupConfig{{
+					Name:                 "db",
+					Destinations:         []string{"Cloud"},
+					DestinationRetention: map[string]RetentionConfig{"Cloud": tt.retention},
+				}},
+				Storages: map[string]StorageConfig{"Cloud": tt.storage},
+			}
+			err := cfg.Validate()
+			if tt.expected == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
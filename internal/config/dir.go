@@ -0,0 +1,157 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// LoadDir reads every *.yaml file in a directory, in lexical order, and merges
+// them into a single config.
+//
+// Merge rules:
+//   - connection, notifications, hooks, defaults, stats and legacy ssh/docker: set fields in later files override earlier ones
+//   - backups and legacy repositories: concatenated; a backup name defined in two files is an error
+//   - storages: merged by key; a later definition of the same storage replaces the earlier one
+//   - maintenance: concatenated
+func LoadDir(dir string) (*Config, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no *.yaml files found in %s", dir)
+	}
+
+	merged := &Config{}
+	backupFiles := make(map[string]string) // backup name -> file that defined it
+
+	for _, file := range files {
+		cfg, err := parseFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(file), err)
+		}
+
+		for _, b := range cfg.Backups {
+			if prev, ok := backupFiles[b.Name]; ok {
+				return nil, fmt.Errorf("backup %q defined in both %s and %s", b.Name, prev, filepath.Base(file))
+			}
+			backupFiles[b.Name] = filepath.Base(file)
+		}
+
+		merged.merge(cfg)
+	}
+
+	merged.applyDefaults()
+
+	return merged, nil
+}
+
+// merge folds another config fragment into c
+func (c *Config) merge(other *Config) {
+	if other.Version > c.Version {
+		c.Version = other.Version
+	}
+
+	mergeString(&c.Connection.Host, other.Connection.Host)
+	mergeString(&c.Connection.Container, other.Connection.Container)
+	mergeString(&c.Connection.ComposeService, other.Connection.ComposeService)
+	mergeString(&c.Connection.RunDeadline, other.Connection.RunDeadline)
+	mergeString(&c.Connection.GCDToken, other.Connection.GCDToken)
+	mergeString(&c.Connection.ContainerUser, other.Connection.ContainerUser)
+	mergeString(&c.Connection.KeyringPath, other.Connection.KeyringPath)
+	mergeString(&c.Connection.DuplicacyVersion, other.Connection.DuplicacyVersion)
+	mergeString(&c.Connection.SSHPasswordMode, other.Connection.SSHPasswordMode)
+	if other.Connection.SSHPasswordCommand != nil {
+		c.Connection.SSHPasswordCommand = other.Connection.SSHPasswordCommand
+	}
+	if other.Connection.StoragePasswordCommand != nil {
+		c.Connection.StoragePasswordCommand = other.Connection.StoragePasswordCommand
+	}
+	if other.Connection.GlobalOptions != nil {
+		c.Connection.GlobalOptions = other.Connection.GlobalOptions
+	}
+	if other.Connection.OKExitCodes != nil {
+		c.Connection.OKExitCodes = other.Connection.OKExitCodes
+	}
+
+	c.Backups = append(c.Backups, other.Backups...)
+
+	if len(other.Storages) > 0 && c.Storages == nil {
+		c.Storages = make(map[string]StorageConfig)
+	}
+	for name, sc := range other.Storages {
+		c.Storages[name] = sc
+	}
+
+	c.Maintenance = append(c.Maintenance, other.Maintenance...)
+
+	f := &c.Notifications.Forgejo
+	mergeString(&f.URL, other.Notifications.Forgejo.URL)
+	mergeString(&f.Repo, other.Notifications.Forgejo.Repo)
+	mergeString(&f.Token, other.Notifications.Forgejo.Token)
+	mergeString(&f.TokenEnv, other.Notifications.Forgejo.TokenEnv)
+	mergeString(&f.Assignee, other.Notifications.Forgejo.Assignee)
+	mergeString(&f.UpdateMode, other.Notifications.Forgejo.UpdateMode)
+	if other.Notifications.Forgejo.ReopenClosed != nil {
+		f.ReopenClosed = other.Notifications.Forgejo.ReopenClosed
+	}
+	if other.Notifications.Forgejo.Assignees != nil {
+		f.Assignees = other.Notifications.Forgejo.Assignees
+	}
+	if other.Notifications.Forgejo.TokenCommand != nil {
+		f.TokenCommand = other.Notifications.Forgejo.TokenCommand
+	}
+	mergeString(&c.Notifications.TitleTemplate, other.Notifications.TitleTemplate)
+	if other.Notifications.LogTailLines != 0 {
+		c.Notifications.LogTailLines = other.Notifications.LogTailLines
+	}
+
+	if !other.Defaults.Retention.IsZero() {
+		c.Defaults.Retention = other.Defaults.Retention
+	}
+	if other.Defaults.Threads != 0 {
+		c.Defaults.Threads = other.Defaults.Threads
+	}
+
+	if other.Stats.GrowthAlertPct != 0 {
+		c.Stats.GrowthAlertPct = other.Stats.GrowthAlertPct
+	}
+	if other.Stats.GrowthAlertNotify {
+		c.Stats.GrowthAlertNotify = true
+	}
+	if other.Stats.Required {
+		c.Stats.Required = true
+	}
+	mergeString(&c.Stats.Timezone, other.Stats.Timezone)
+	mergeString(&c.Stats.Format, other.Stats.Format)
+	if other.Stats.RefuseStale {
+		c.Stats.RefuseStale = true
+	}
+	if other.Stats.MergeSameDay {
+		c.Stats.MergeSameDay = true
+	}
+	if other.Stats.StrictParse {
+		c.Stats.StrictParse = true
+	}
+
+	mergeString(&c.LockFile, other.LockFile)
+	mergeString(&c.MaintenanceDir, other.MaintenanceDir)
+
+	mergeString(&c.Hooks.Pre, other.Hooks.Pre)
+	mergeString(&c.Hooks.Post, other.Hooks.Post)
+	if other.Hooks.InContainer {
+		c.Hooks.InContainer = true
+	}
+
+	mergeString(&c.SSH.Host, other.SSH.Host)
+	mergeString(&c.SSH.PasswordEnv, other.SSH.PasswordEnv)
+	mergeString(&c.Docker.Container, other.Docker.Container)
+	c.Repositories = append(c.Repositories, other.Repositories...)
+}
+
+// mergeString overrides dst with src when src is set
+func mergeString(dst *string, src string) {
+	if src != "" {
+		*dst = src
+	}
+}
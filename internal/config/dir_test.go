@@ -0,0 +1,114 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFragments writes each named fragment into a new temp directory
+func writeFragments(t *testing.T, fragments map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range fragments {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+func TestLoadDir_Merge(t *testing.T) {
+	dir := writeFragments(t, map[string]string{
+		"00-base.yaml": `
+connection:
+  host: root@base
+  container: Duplicacy
+storages:
+  NAS:
+    retention: { daily: 7 }
+`,
+		"10-appdata.yaml": `
+backups:
+  - name: appdata
+    path: /mnt/appdata
+    destinations: [NAS]
+`,
+		"20-media.yaml": `
+connection:
+  host: root@override
+backups:
+  - name: media
+    path: /mnt/media
+    destinations: [NAS, Cloud]
+storages:
+  NAS:
+    retention: { daily: 14 }
+maintenance: [Archive]
+`,
+		"notes.txt": "not yaml",
+	})
+
+	cfg, err := LoadDir(dir)
+	if err != nil {
+		t.Fatalf("LoadDir() error: %v", err)
+	}
+
+	// Later files override scalar connection fields; unset fields keep earlier values
+	if cfg.Connection.Host != "root@override" {
+		t.Errorf("expected host from later file, got %q", cfg.Connection.Host)
+	}
+	if cfg.Connection.Container != "Duplicacy" {
+		t.Errorf("expected container from base file, got %q", cfg.Connection.Container)
+	}
+
+	// Backups are concatenated in file order
+	if len(cfg.Backups) != 2 || cfg.Backups[0].Name != "appdata" || cfg.Backups[1].Name != "media" {
+		t.Errorf("expected backups [appdata media], got %+v", cfg.Backups)
+	}
+
+	// Storages merge by key, later wins
+	if cfg.Storages["NAS"].Retention.Daily != 14 {
+		t.Errorf("expected NAS retention from later file, got %+v", cfg.Storages["NAS"])
+	}
+	if len(cfg.Maintenance) != 1 || cfg.Maintenance[0] != "Archive" {
+		t.Errorf("expected maintenance [Archive], got %v", cfg.Maintenance)
+	}
+
+	// Defaults are applied to the merged config
+	if cfg.Backups[0].Threads != 1 {
+		t.Errorf("expected default threads, got %d", cfg.Backups[0].Threads)
+	}
+}
+
+func TestLoadDir_DuplicateBackup(t *testing.T) {
+	dir := writeFragments(t, map[string]string{
+		"a.yaml": "backups:\n  - name: appdata\n    destinations: [NAS]\n",
+		"b.yaml": "backups:\n  - name: appdata\n    destinations: [Cloud]\n",
+	})
+
+	_, err := LoadDir(dir)
+	if err == nil {
+		t.Fatal("expected error for duplicate backup name")
+	}
+	if !containsHelper(err.Error(), `"appdata" defined in both a.yaml and b.yaml`) {
+		t.Errorf("expected error naming both files, got %q", err.Error())
+	}
+}
+
+func TestLoadDir_Empty(t *testing.T) {
+	if _, err := LoadDir(t.TempDir()); err == nil {
+		t.Error("expected error for directory without yaml files")
+	}
+}
+
+func TestLoadDir_InvalidFragment(t *testing.T) {
+	dir := writeFragments(t, map[string]string{
+		"bad.yaml": "backups: [unclosed",
+	})
+
+	_, err := LoadDir(dir)
+	if err == nil || !containsHelper(err.Error(), "bad.yaml") {
+		t.Errorf("expected error naming bad.yaml, got %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// KeepRule is a parsed duplicacy "-keep n:m" option: keep one revision every
+// Interval days for revisions older than MinAge days (Interval 0 deletes them)
+type KeepRule struct {
+	Interval int
+	MinAge   int
+}
+
+// ParseKeepRules extracts the -keep rules from a prune option string
+func ParseKeepRules(opts string) ([]KeepRule, error) {
+	var rules []KeepRule
+	fields := strings.Fields(opts)
+	for i := 0; i < len(fields); i++ {
+		if fields[i] != "-keep" {
+			continue
+		}
+		if i+1 >= len(fields) {
+			return nil, fmt.Errorf("-keep requires a value")
+		}
+		i++
+		rule, err := parseKeepRule(fields[i])
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// parseKeepRule parses a single "n:m" value
+func parseKeepRule(value string) (KeepRule, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 2 {
+		return KeepRule{}, fmt.Errorf("invalid -keep value %q (expected n:m)", value)
+	}
+	interval, err := strconv.Atoi(parts[0])
+	if err != nil || interval < 0 {
+		return KeepRule{}, fmt.Errorf("invalid -keep interval in %q", value)
+	}
+	minAge, err := strconv.Atoi(parts[1])
+	if err != nil || minAge < 0 {
+		return KeepRule{}, fmt.Errorf("invalid -keep age in %q", value)
+	}
+	return KeepRule{Interval: interval, MinAge: minAge}, nil
+}
+
+// CheckPruneOptions returns an error if the prune options are malformed or
+// would delete every revision (a "-keep 0:0" rule)
+func CheckPruneOptions(opts string) error {
+	rules, err := ParseKeepRules(opts)
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if rule.Interval == 0 && rule.MinAge == 0 {
+			return fmt.Errorf("prune options %q would delete all revisions (-keep 0:0)", opts)
+		}
+	}
+	return nil
+}
+
+// KeptRevisions estimates how many of a snapshot ID's revisions survive a prune
+// with these rules, given each revision's creation time. It mirrors duplicacy's
+// selection: rules apply oldest-age first, a rule with Interval 0 deletes, other
+// rules keep one revision per Interval days, and the latest revision is never
+// deleted.
+func KeptRevisions(rules []KeepRule, created []time.Time, now time.Time) int {
+	if len(created) == 0 {
+		return 0
+	}
+
+	sorted := make([]KeepRule, len(rules))
+	copy(sorted, rules)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].MinAge > sorted[j].MinAge })
+
+	times := make([]time.Time, len(created))
+	copy(times, created)
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+
+	const day = 24 * time.Hour
+	kept := 1 // the latest revision
+	i := 0
+	var lastKept time.Time
+	for _, t := range times[:len(times)-1] {
+		// Move to the first rule whose age this revision has reached
+		for i < len(sorted) && now.Sub(t) < time.Duration(sorted[i].MinAge)*day {
+			i++
+			lastKept = time.Time{}
+		}
+		if i >= len(sorted) {
+			kept++
+			continue
+		}
+		rule := sorted[i]
+		if rule.Interval == 0 {
+			continue
+		}
+		if !lastKept.IsZero() && t.Sub(lastKept) < time.Duration(rule.Interval)*day-10*time.Minute {
+			continue
+		}
+		lastKept = t
+		kept++
+	}
+	return kept
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseKeepRules(t *testing.T) {
+	rules, err := ParseKeepRules("-keep 0:180 -keep 7:14 -keep 1:1 -a")
+	if err != nil {
+		t.Fatalf("ParseKeepRules() error: %v", err)
+	}
+
+	expected := []KeepRule{{0, 180}, {7, 14}, {1, 1}}
+	if len(rules) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, rules)
+	}
+	for i := range expected {
+		if rules[i] != expected[i] {
+			t.Errorf("rule %d: expected %v, got %v", i, expected[i], rules[i])
+		}
+	}
+}
+
+func TestCheckPruneOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    string
+		wantErr bool
+	}{
+		{"default legacy", "-keep 0:180 -keep 7:14 -keep 1:1 -a", false},
+		{"generated", RetentionConfig{Daily: 7, Weekly: 4, Monthly: 3}.ToPruneOptions(), false},
+		{"no keep rules", "-a", false},
+		{"delete everything", "-keep 0:0 -a", true},
+		{"delete everything among others", "-keep 7:14 -keep 0:0", true},
+		{"missing value", "-keep", true},
+		{"malformed value", "-keep 7", true},
+		{"non-numeric", "-keep a:b", true},
+		{"negative age", "-keep 7:-1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckPruneOptions(tt.opts)
+			if tt.wantErr && err == nil {
+				t.Errorf("CheckPruneOptions(%q) expected error", tt.opts)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("CheckPruneOptions(%q) unexpected error: %v", tt.opts, err)
+			}
+		})
+	}
+}
+
+func TestKeptRevisions(t *testing.T) {
+	now := time.Date(2025, 3, 1, 6, 0, 0, 0, time.UTC)
+	// daily returns one revision per day, aged 0 to n-1 days
+	daily := func(n int) []time.Time {
+		var created []time.Time
+		for age := n - 1; age >= 0; age-- {
+			created = append(created, now.AddDate(0, 0, -age))
+		}
+		return created
+	}
+
+	tests := []struct {
+		name     string
+		opts     string
+		created  []time.Time
+		expected int
+	}{
+		{"no revisions", "-keep 0:7", nil, 0},
+		{"no rules keeps everything", "-a", daily(10), 10},
+		{"delete older than a week", "-keep 0:7", daily(10), 7},
+		{"weekly after two weeks", "-keep 0:180 -keep 7:14 -keep 1:1", daily(30), 17},
+		{"latest is never deleted", "-keep 0:1", daily(5), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := ParseKeepRules(tt.opts)
+			if err != nil {
+				t.Fatalf("ParseKeepRules() error: %v", err)
+			}
+			if got := KeptRevisions(rules, tt.created, now); got != tt.expected {
+				t.Errorf("expected %d kept, got %d", tt.expected, got)
+			}
+		})
+	}
+}
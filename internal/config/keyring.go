@@ -0,0 +1,28 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ParseKeyring parses a JSON keyring mapping duplicacy storage names to their
+// encryption passwords, e.g. {"NAS": "secret", "gcd-backup": "other"}
+func ParseKeyring(data []byte) (map[string]string, error) {
+	var keyring map[string]string
+	if err := json.Unmarshal(data, &keyring); err != nil {
+		return nil, fmt.Errorf("failed to parse keyring: %w", err)
+	}
+	for storage, password := range keyring {
+		if password == "" {
+			return nil, fmt.Errorf("keyring entry for %q has an empty password", storage)
+		}
+	}
+	return keyring, nil
+}
+
+// KeyringPassword returns the keyring password for a configured storage,
+// looking it up by duplicacy storage name
+func (c *Config) KeyringPassword(keyring map[string]string, storage string) (string, bool) {
+	pw, ok := keyring[c.StorageName(storage)]
+	return pw, ok
+}
@@ -0,0 +1,53 @@
+package config
+
+import "testing"
+
+func TestParseKeyring(t *testing.T) {
+	keyring, err := ParseKeyring([]byte(`{"NAS": "nas-secret", "gcd-backup-2024": "gd\"secret"}`))
+	if err != nil {
+		t.Fatalf("ParseKeyring() error: %v", err)
+	}
+	if keyring["NAS"] != "nas-secret" {
+		t.Errorf("expected NAS password, got %q", keyring["NAS"])
+	}
+	if keyring["gcd-backup-2024"] != `gd"secret` {
+		t.Errorf("expected unescaped gcd password, got %q", keyring["gcd-backup-2024"])
+	}
+}
+
+func TestParseKeyring_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"not json", "NAS=secret"},
+		{"not a string map", `{"NAS": {"password": "x"}}`},
+		{"empty password", `{"NAS": ""}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseKeyring([]byte(tt.data)); err == nil {
+				t.Errorf("expected error for %s", tt.data)
+			}
+		})
+	}
+}
+
+func TestConfig_KeyringPassword(t *testing.T) {
+	cfg := &Config{
+		Storages: map[string]StorageConfig{"GoogleDrive": {Name: "gcd-backup-2024"}},
+	}
+	keyring := map[string]string{"gcd-backup-2024": "gd-secret", "NAS": "nas-secret"}
+
+	// Aliased storages are looked up by their duplicacy name
+	if pw, ok := cfg.KeyringPassword(keyring, "GoogleDrive"); !ok || pw != "gd-secret" {
+		t.Errorf("expected gd-secret for alias, got %q, %v", pw, ok)
+	}
+	if pw, ok := cfg.KeyringPassword(keyring, "NAS"); !ok || pw != "nas-secret" {
+		t.Errorf("expected nas-secret, got %q, %v", pw, ok)
+	}
+	if _, ok := cfg.KeyringPassword(keyring, "Missing"); ok {
+		t.Error("expected no password for storage not in keyring")
+	}
+}
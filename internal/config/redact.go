@@ -0,0 +1,29 @@
+package config
+
+// RedactedValue replaces secret values in Redacted output
+const RedactedValue = "********"
+
+// Redacted returns a copy of the config with secret values masked: the direct
+// Forgejo token and every storage env value (they typically hold cloud keys).
+// Commands and env var names are left as-is since they are not secrets.
+func (c *Config) Redacted() *Config {
+	out := *c
+	if out.Notifications.Forgejo.Token != "" {
+		out.Notifications.Forgejo.Token = RedactedValue
+	}
+
+	if c.Storages != nil {
+		out.Storages = make(map[string]StorageConfig, len(c.Storages))
+		for name, sc := range c.Storages {
+			if len(sc.Env) > 0 {
+				env := make(map[string]string, len(sc.Env))
+				for k := range sc.Env {
+					env[k] = RedactedValue
+				}
+				sc.Env = env
+			}
+			out.Storages[name] = sc
+		}
+	}
+	return &out
+}
@@ -0,0 +1,36 @@
+package config
+
+import "testing"
+
+func TestRedacted(t *testing.T) {
+	cfg := &Config{
+		Storages: map[string]StorageConfig{
+			"B2":  {Env: map[string]string{"DUPLICACY_B2_KEY": "b2-secret"}},
+			"NAS": {Threads: 2},
+		},
+	}
+	cfg.Notifications.Forgejo.Token = "forgejo-secret"
+	cfg.Notifications.Forgejo.TokenEnv = "MY_TOKEN"
+
+	red := cfg.Redacted()
+	if red.Notifications.Forgejo.Token != RedactedValue {
+		t.Errorf("expected token to be redacted, got %q", red.Notifications.Forgejo.Token)
+	}
+	if red.Notifications.Forgejo.TokenEnv != "MY_TOKEN" {
+		t.Errorf("expected token_env name to be kept, got %q", red.Notifications.Forgejo.TokenEnv)
+	}
+	if got := red.Storages["B2"].Env["DUPLICACY_B2_KEY"]; got != RedactedValue {
+		t.Errorf("expected storage env value to be redacted, got %q", got)
+	}
+	if red.Storages["NAS"].Threads != 2 {
+		t.Errorf("expected non-secret storage fields to be kept, got %+v", red.Storages["NAS"])
+	}
+
+	// The original config is untouched
+	if cfg.Notifications.Forgejo.Token != "forgejo-secret" {
+		t.Errorf("expected original token to be kept, got %q", cfg.Notifications.Forgejo.Token)
+	}
+	if got := cfg.Storages["B2"].Env["DUPLICACY_B2_KEY"]; got != "b2-secret" {
+		t.Errorf("expected original env to be kept, got %q", got)
+	}
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// SchemaID is the $id of the generated config JSON Schema
+const SchemaID = "https://github.com/lioreshai/duplicaci/duplicaci.schema.json"
+
+// JSONSchema returns a JSON Schema (draft 2020-12) describing the config file.
+// It is generated from the Config struct's yaml tags, so it always matches
+// what Load accepts; unknown keys are rejected to catch typos in editors and CI.
+func JSONSchema() ([]byte, error) {
+	schema := schemaFor(reflect.TypeOf(Config{}))
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["$id"] = SchemaID
+	schema["title"] = "duplicaCI configuration"
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// schemaFor builds the schema for a Go type
+func schemaFor(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return schemaFor(t.Elem())
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := yamlName(field)
+			if name == "" {
+				continue
+			}
+			properties[name] = schemaFor(field.Type)
+		}
+		if t == reflect.TypeOf(BackupConfig{}) {
+			// Entries are storage names or DestinationConfig objects (see UnmarshalYAML)
+			properties["destinations"] = map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"anyOf": []interface{}{
+						map[string]interface{}{"type": "string"},
+						schemaFor(reflect.TypeOf(DestinationConfig{})),
+					},
+				},
+			}
+		}
+		return map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": schemaFor(t.Elem()),
+		}
+	case reflect.Slice:
+		return map[string]interface{}{
+			"type":  "array",
+			"items": schemaFor(t.Elem()),
+		}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	}
+	return map[string]interface{}{}
+}
+
+// yamlName returns the config key for a struct field, or "" when the field is
+// not read from the file
+func yamlName(field reflect.StructField) string {
+	if field.PkgPath != "" {
+		return ""
+	}
+	name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+	if name == "-" || name == "" {
+		return ""
+	}
+	return name
+}
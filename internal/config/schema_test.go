@@ -0,0 +1,214 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// sampleConfig exercises every section of the config format
+const sampleConfig = `version: 2
+
+connection:
+  host: root@192.168.1.100
+  container: Duplicacy
+  container_user: abc
+
+backups:
+  - name: appdata
+    path: /mnt/appdata
+    destinations:
+      - storage: LocalNAS
+        retention: { daily: 3 }
+      - S3Backup
+    threads: 4
+    pre_hook: sync
+
+storages:
+  LocalNAS:
+    priority: 1
+  S3Backup:
+    name: s3-backup
+    retention: { daily: 7, weekly: 4, monthly: 3 }
+    env:
+      DUPLICACY_S3_S3_ID: abc
+
+maintenance: [LocalArray]
+
+defaults:
+  retention: { daily: 14 }
+
+hooks:
+  pre: echo start
+  in_container: true
+
+stats:
+  growth_alert_pct: 50.5
+
+notifications:
+  forgejo:
+    url: https://git.example.com
+    repo: user/infra
+`
+
+func loadSchema(t *testing.T) map[string]interface{} {
+	t.Helper()
+	data, err := JSONSchema()
+	if err != nil {
+		t.Fatalf("failed to generate schema: %v", err)
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("schema is not valid JSON: %v", err)
+	}
+	return schema
+}
+
+// validateSchema checks a decoded YAML value against the subset of JSON Schema
+// that JSONSchema emits and returns the violations
+func validateSchema(schema map[string]interface{}, value interface{}, path string) []string {
+	if branches, ok := schema["anyOf"].([]interface{}); ok {
+		// Report the last branch's problems (the object form for destinations)
+		var problems []string
+		for _, branch := range branches {
+			if problems = validateSchema(branch.(map[string]interface{}), value, path); len(problems) == 0 {
+				return nil
+			}
+		}
+		return problems
+	}
+
+	var problems []string
+	switch schema["type"] {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected object", path)}
+		}
+		props, _ := schema["properties"].(map[string]interface{})
+		for key, v := range obj {
+			if sub, ok := props[key].(map[string]interface{}); ok {
+				problems = append(problems, validateSchema(sub, v, path+"."+key)...)
+			} else if extra, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+				problems = append(problems, validateSchema(extra, v, path+"."+key)...)
+			} else {
+				problems = append(problems, fmt.Sprintf("%s.%s: unknown key", path, key))
+			}
+		}
+	case "array":
+		arr, ok := value.([]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected array", path)}
+		}
+		items := schema["items"].(map[string]interface{})
+		for i, v := range arr {
+			problems = append(problems, validateSchema(items, v, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			problems = append(problems, fmt.Sprintf("%s: expected string", path))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			problems = append(problems, fmt.Sprintf("%s: expected boolean", path))
+		}
+	case "integer":
+		if _, ok := value.(int); !ok {
+			problems = append(problems, fmt.Sprintf("%s: expected integer", path))
+		}
+	case "number":
+		switch value.(type) {
+		case int, float64:
+		default:
+			problems = append(problems, fmt.Sprintf("%s: expected number", path))
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+func TestJSONSchema_ValidatesSampleConfig(t *testing.T) {
+	schema := loadSchema(t)
+
+	var doc interface{}
+	if err := yaml.Unmarshal([]byte(sampleConfig), &doc); err != nil {
+		t.Fatalf("failed to parse sample: %v", err)
+	}
+	if problems := validateSchema(schema, doc, "$"); len(problems) > 0 {
+		t.Errorf("sample config does not match schema: %v", problems)
+	}
+
+	// The sample must also load, so the schema and the loader agree
+	var cfg Config
+	if err := yaml.Unmarshal([]byte(sampleConfig), &cfg); err != nil {
+		t.Fatalf("sample config does not load: %v", err)
+	}
+}
+
+func TestJSONSchema_RejectsInvalidConfig(t *testing.T) {
+	schema := loadSchema(t)
+
+	tests := []struct {
+		name    string
+		yaml    string
+		problem string
+	}{
+		{"typo in key", "conection:\n  host: x\n", "$.conection: unknown key"},
+		{"wrong type", "backups:\n  - name: a\n    threads: four\n", "$.backups[0].threads: expected integer"},
+		{"nested typo", "storages:\n  NAS:\n    retension: {daily: 1}\n", "$.storages.NAS.retension: unknown key"},
+		{"destination typo", "backups:\n  - name: a\n    destinations: [{storage: NAS, retension: {daily: 1}}]\n", "$.backups[0].destinations[0].retension: unknown key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc interface{}
+			if err := yaml.Unmarshal([]byte(tt.yaml), &doc); err != nil {
+				t.Fatalf("failed to parse: %v", err)
+			}
+			problems := validateSchema(schema, doc, "$")
+			if len(problems) != 1 || problems[0] != tt.problem {
+				t.Errorf("expected [%s], got %v", tt.problem, problems)
+			}
+		})
+	}
+}
+
+func TestJSONSchema_CoversStructTags(t *testing.T) {
+	schema := loadSchema(t)
+
+	// Every yaml-tagged field of every config struct must appear in the schema
+	var walk func(t *testing.T, typ reflect.Type, node map[string]interface{}, path string)
+	walk = func(t *testing.T, typ reflect.Type, node map[string]interface{}, path string) {
+		switch typ.Kind() {
+		case reflect.Struct:
+			props := node["properties"].(map[string]interface{})
+			for i := 0; i < typ.NumField(); i++ {
+				field := typ.Field(i)
+				tag := strings.Split(field.Tag.Get("yaml"), ",")[0]
+				if tag == "" || tag == "-" {
+					continue
+				}
+				sub, ok := props[tag].(map[string]interface{})
+				if !ok {
+					t.Errorf("schema is missing %s.%s", path, tag)
+					continue
+				}
+				walk(t, field.Type, sub, path+"."+tag)
+			}
+		case reflect.Map:
+			walk(t, typ.Elem(), node["additionalProperties"].(map[string]interface{}), path+".*")
+		case reflect.Slice:
+			walk(t, typ.Elem(), node["items"].(map[string]interface{}), path+"[]")
+		}
+	}
+	walk(t, reflect.TypeOf(Config{}), schema, "$")
+
+	if schema["$id"] != SchemaID {
+		t.Errorf("expected $id %q, got %v", SchemaID, schema["$id"])
+	}
+}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// ResolveSecrets runs the configured *_command secret sources and stores
+// their output. token_command is skipped when a direct token is set.
+func (c *Config) ResolveSecrets() error {
+	f := &c.Notifications.Forgejo
+	if len(f.TokenCommand) > 0 && f.Token == "" {
+		token, err := runSecretCommand(f.TokenCommand)
+		if err != nil {
+			return fmt.Errorf("notifications.forgejo.token_command: %w", err)
+		}
+		f.commandToken = token
+	}
+
+	if len(c.Connection.SSHPasswordCommand) > 0 {
+		pw, err := runSecretCommand(c.Connection.SSHPasswordCommand)
+		if err != nil {
+			return fmt.Errorf("connection.ssh_password_command: %w", err)
+		}
+		c.Connection.sshPassword = pw
+	}
+
+	if len(c.Connection.StoragePasswordCommand) > 0 {
+		pw, err := runSecretCommand(c.Connection.StoragePasswordCommand)
+		if err != nil {
+			return fmt.Errorf("connection.storage_password_command: %w", err)
+		}
+		c.Connection.storagePassword = pw
+	}
+
+	return nil
+}
+
+// SSHPassword returns the ssh_password_command output, or SSH_PASSWORD
+func (c ConnectionConfig) SSHPassword() string {
+	if c.sshPassword != "" {
+		return c.sshPassword
+	}
+	return os.Getenv("SSH_PASSWORD")
+}
+
+// StoragePassword returns the storage_password_command output, or
+// DUPLICACY_PASSWORD
+func (c ConnectionConfig) StoragePassword() string {
+	if c.storagePassword != "" {
+		return c.storagePassword
+	}
+	return os.Getenv("DUPLICACY_PASSWORD")
+}
+
+// runSecretCommand executes argv directly (never through a shell, so secret
+// paths can't inject commands) and returns its trimmed stdout
+func runSecretCommand(argv []string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s failed: %w: %s", argv[0], err, msg)
+		}
+		return "", fmt.Errorf("%s failed: %w", argv[0], err)
+	}
+	secret := strings.TrimSpace(string(out))
+	if secret == "" {
+		return "", fmt.Errorf("%s printed nothing", argv[0])
+	}
+	return secret, nil
+}
+
+// SecretStatus reports whether one secret the config relies on resolves,
+// without carrying its value
+type SecretStatus struct {
+	Name     string // What the secret is for, e.g. "forgejo token"
+	Source   string // Where it is read from, e.g. "token_command" or "env FORGEJO_TOKEN"
+	Present  bool
+	Required bool  // The run cannot work without it (notification tokens, configured commands)
+	Err      error // Why a command source failed
+}
+
+// CheckSecrets resolves every secret the config references, running
+// *_command sources, and reports which are present. Values are discarded.
+func (c *Config) CheckSecrets() []SecretStatus {
+	var statuses []SecretStatus
+
+	f := c.Notifications.Forgejo
+	if f.URL != "" || f.Repo != "" {
+		status := SecretStatus{Name: "forgejo token", Required: true}
+		switch {
+		case f.Token != "":
+			status.Source, status.Present = "token", true
+		case len(f.TokenCommand) > 0:
+			status.Source = "token_command"
+			status.Present, status.Err = commandPresent(f.TokenCommand)
+		case f.TokenEnv != "":
+			status.Source, status.Present = envPresent(f.TokenEnv)
+		default:
+			status.Source, status.Present = envPresent("FORGEJO_TOKEN")
+		}
+		statuses = append(statuses, status)
+	}
+
+	// Sources as SSHPassword resolves them; the legacy ssh.password_env is not read by runs
+	if c.Connection.Host != "" {
+		status := SecretStatus{Name: "ssh password"}
+		if len(c.Connection.SSHPasswordCommand) > 0 {
+			status.Source, status.Required = "ssh_password_command", true
+			status.Present, status.Err = commandPresent(c.Connection.SSHPasswordCommand)
+		} else {
+			status.Source, status.Present = envPresent("SSH_PASSWORD")
+		}
+		statuses = append(statuses, status)
+	}
+
+	status := SecretStatus{Name: "storage password"}
+	if len(c.Connection.StoragePasswordCommand) > 0 {
+		status.Source, status.Required = "storage_password_command", true
+		status.Present, status.Err = commandPresent(c.Connection.StoragePasswordCommand)
+	} else {
+		status.Source, status.Present = envPresent("DUPLICACY_PASSWORD")
+	}
+	return append(statuses, status)
+}
+
+// envPresent describes an env var source and whether it is set and non-empty
+func envPresent(name string) (string, bool) {
+	return "env " + name, os.Getenv(name) != ""
+}
+
+// commandPresent runs a secret command and reports whether it printed a value
+func commandPresent(argv []string) (bool, error) {
+	_, err := runSecretCommand(argv)
+	return err == nil, err
+}
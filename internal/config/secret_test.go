@@ -0,0 +1,156 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestResolveSecrets_TokenCommand(t *testing.T) {
+	os.Setenv("FORGEJO_TOKEN", "env-token")
+	defer os.Unsetenv("FORGEJO_TOKEN")
+
+	t.Run("command above env", func(t *testing.T) {
+		cfg := &Config{}
+		cfg.Notifications.Forgejo.TokenCommand = []string{"echo", "mytoken"}
+		if err := cfg.ResolveSecrets(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := cfg.Notifications.Forgejo.GetToken(); got != "mytoken" {
+			t.Errorf("expected %q, got %q", "mytoken", got)
+		}
+	})
+
+	t.Run("direct token above command", func(t *testing.T) {
+		cfg := &Config{}
+		cfg.Notifications.Forgejo.Token = "direct-token"
+		cfg.Notifications.Forgejo.TokenCommand = []string{"false"}
+		if err := cfg.ResolveSecrets(); err != nil {
+			t.Fatalf("token_command should not run when token is set: %v", err)
+		}
+		if got := cfg.Notifications.Forgejo.GetToken(); got != "direct-token" {
+			t.Errorf("expected %q, got %q", "direct-token", got)
+		}
+	})
+
+	t.Run("no shell", func(t *testing.T) {
+		cfg := &Config{}
+		cfg.Notifications.Forgejo.TokenCommand = []string{"echo", "a; echo injected"}
+		if err := cfg.ResolveSecrets(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := cfg.Notifications.Forgejo.GetToken(); got != "a; echo injected" {
+			t.Errorf("expected argument passed verbatim, got %q", got)
+		}
+	})
+}
+
+func TestResolveSecrets_PasswordCommands(t *testing.T) {
+	os.Setenv("SSH_PASSWORD", "env-ssh")
+	defer os.Unsetenv("SSH_PASSWORD")
+	os.Setenv("DUPLICACY_PASSWORD", "env-storage")
+	defer os.Unsetenv("DUPLICACY_PASSWORD")
+
+	cfg := &Config{}
+	if got := cfg.Connection.SSHPassword(); got != "env-ssh" {
+		t.Errorf("expected env fallback %q, got %q", "env-ssh", got)
+	}
+
+	cfg.Connection.SSHPasswordCommand = []string{"echo", "  ssh-secret  "}
+	cfg.Connection.StoragePasswordCommand = []string{"echo", "storage-secret"}
+	if err := cfg.ResolveSecrets(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := cfg.Connection.SSHPassword(); got != "ssh-secret" {
+		t.Errorf("expected %q, got %q", "ssh-secret", got)
+	}
+	if got := cfg.Connection.StoragePassword(); got != "storage-secret" {
+		t.Errorf("expected %q, got %q", "storage-secret", got)
+	}
+}
+
+func TestResolveSecrets_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		argv []string
+		want string
+	}{
+		{"command fails", []string{"false"}, "connection.ssh_password_command: false failed"},
+		{"empty output", []string{"true"}, "printed nothing"},
+		{"missing program", []string{"duplicaci-no-such-program"}, "duplicaci-no-such-program failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{}
+			cfg.Connection.SSHPasswordCommand = tt.argv
+			err := cfg.ResolveSecrets()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestCheckSecrets(t *testing.T) {
+	os.Unsetenv("FORGEJO_TOKEN")
+	os.Setenv("DUPLICACY_PASSWORD", "env-storage")
+	defer os.Unsetenv("DUPLICACY_PASSWORD")
+
+	cfg := &Config{}
+	cfg.Notifications.Forgejo.URL = "https://git.example.com"
+	cfg.Connection.Host = "root@nas"
+	cfg.Connection.SSHPasswordCommand = []string{"false"}
+
+	got := cfg.CheckSecrets()
+	want := []SecretStatus{
+		{Name: "forgejo token", Source: "env FORGEJO_TOKEN", Present: false, Required: true},
+		{Name: "ssh password", Source: "ssh_password_command", Present: false, Required: true},
+		{Name: "storage password", Source: "env DUPLICACY_PASSWORD", Present: true},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d statuses, got %+v", len(want), got)
+	}
+	for i := range want {
+		g := got[i]
+		g.Err = nil
+		if g != want[i] {
+			t.Errorf("status %d: expected %+v, got %+v", i, want[i], g)
+		}
+	}
+	if got[1].Err == nil {
+		t.Error("expected the failing command's error")
+	}
+}
+
+func TestCheckSecrets_SSHPasswordSource(t *testing.T) {
+	os.Setenv("LEGACY_SSH_PASSWORD", "legacy")
+	defer os.Unsetenv("LEGACY_SSH_PASSWORD")
+	os.Unsetenv("SSH_PASSWORD")
+
+	// ssh_password_command wins, as in SSHPassword
+	cfg := &Config{}
+	cfg.Connection.Host = "root@nas"
+	cfg.Connection.SSHPasswordCommand = []string{"echo", "from-command"}
+	cfg.SSH.PasswordEnv = "LEGACY_SSH_PASSWORD"
+
+	got := cfg.CheckSecrets()
+	want := SecretStatus{Name: "ssh password", Source: "ssh_password_command", Present: true, Required: true}
+	if len(got) != 2 || got[0] != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+
+	// Without it runs read SSH_PASSWORD, never the legacy password_env
+	cfg.Connection.SSHPasswordCommand = nil
+	got = cfg.CheckSecrets()
+	want = SecretStatus{Name: "ssh password", Source: "env SSH_PASSWORD", Present: false}
+	if len(got) != 2 || got[0] != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestCheckSecrets_NoNotifications(t *testing.T) {
+	got := (&Config{}).CheckSecrets()
+	if len(got) != 1 || got[0].Name != "storage password" {
+		t.Errorf("expected only the storage password without forgejo or ssh, got %+v", got)
+	}
+}
@@ -0,0 +1,38 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// webCacheRoot is where Duplicacy Web keeps a working directory per repository,
+// laid out as /cache/localhost/<n>/<repository>
+const webCacheRoot = "/cache/localhost"
+
+// DiscoverCacheDir finds the Duplicacy Web cache directory for a backup in the
+// container. It returns "" when no container is configured, in dry-run mode, or
+// when no directory matches, so callers can fall back to a configured path.
+func (e *Executor) DiscoverCacheDir(backupName string) (string, error) {
+	if e.opts.DockerContainer == "" || e.opts.DryRun {
+		return "", nil
+	}
+
+	out, err := e.executeCapture(e.buildCacheDiscoveryCommand(backupName))
+	if err != nil {
+		return "", fmt.Errorf("failed to discover cache dir for %s: %w", backupName, err)
+	}
+
+	dir := strings.TrimSpace(out)
+	if dir != "" && e.opts.Verbose {
+		e.logf("    Discovered cache dir for %s at: %s\n", backupName, dir)
+	}
+	return dir, nil
+}
+
+// buildCacheDiscoveryCommand constructs the command that lists the first
+// /cache/localhost/*/<backup> directory in the container
+func (e *Executor) buildCacheDiscoveryCommand(backupName string) string {
+	listCmd := fmt.Sprintf("ls -d %s/*/%s 2>/dev/null | head -1", webCacheRoot, backupName)
+	escapedCmd := strings.ReplaceAll(listCmd, "'", "'\"'\"'")
+	return e.wrapSSH(fmt.Sprintf("%s sh -c '%s'", e.dockerExec(), escapedCmd))
+}
@@ -0,0 +1,53 @@
+package executor
+
+import "testing"
+
+func TestBuildCacheDiscoveryCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Options
+		expected string
+	}{
+		{
+			name:     "docker",
+			opts:     Options{DockerContainer: "Duplicacy"},
+			expected: `docker exec Duplicacy sh -c 'ls -d /cache/localhost/*/appdata 2>/dev/null | head -1'`,
+		},
+		{
+			name:     "docker over ssh",
+			opts:     Options{DockerContainer: "Duplicacy", SSHHost: "root@host"},
+			expected: `ssh -o StrictHostKeyChecking=no -o LogLevel=ERROR root@host 'docker exec Duplicacy sh -c '"'"'ls -d /cache/localhost/*/appdata 2>/dev/null | head -1'"'"''`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := New(tt.opts).buildCacheDiscoveryCommand("appdata")
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestDiscoverCacheDir_Skipped(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{"no container", Options{}},
+		{"dry run", Options{DockerContainer: "Duplicacy", DryRun: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := New(tt.opts).DiscoverCacheDir("appdata")
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if dir != "" {
+				t.Errorf("expected no discovered dir, got %q", dir)
+			}
+		})
+	}
+}
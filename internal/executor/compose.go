@@ -0,0 +1,53 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// resolvedContainers caches ResolveContainer results by SSH host and service
+var (
+	resolvedMu         sync.Mutex
+	resolvedContainers = map[string]string{}
+)
+
+// composeServiceCommand returns the command printing the first running
+// container whose name matches a Compose or Swarm service
+func composeServiceCommand(service string) string {
+	return fmt.Sprintf("docker ps --filter name=%s --format '{{.Names}}' | head -1", service)
+}
+
+// ResolveContainer returns the running container for a Compose or Swarm
+// service (e.g. project_service_1), looked up with docker ps on the host (over
+// SSH when configured). Names are cached for the life of the process; a dry
+// run prints the lookup and returns the service name unchanged.
+func (e *Executor) ResolveContainer(service string) (string, error) {
+	key := e.opts.SSHHost + "\x00" + service
+
+	resolvedMu.Lock()
+	defer resolvedMu.Unlock()
+	if name, ok := resolvedContainers[key]; ok {
+		return name, nil
+	}
+
+	cmdStr := e.wrapSSH(composeServiceCommand(service))
+	if e.opts.Verbose || e.opts.DryRun {
+		e.logf("    Command: %s\n", e.redacted().wrapSSH(composeServiceCommand(service)))
+	}
+	if e.opts.DryRun {
+		return service, nil
+	}
+
+	out, err := e.executeCapture(cmdStr)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve compose service %q: %w", service, err)
+	}
+	name := strings.TrimSpace(out)
+	if name == "" {
+		return "", fmt.Errorf("no running container matches compose service %q", service)
+	}
+
+	resolvedContainers[key] = name
+	return name, nil
+}
@@ -0,0 +1,77 @@
+package executor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestComposeServiceCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Options
+		expected string
+	}{
+		{
+			name:     "local",
+			opts:     Options{},
+			expected: "docker ps --filter name=duplicacy --format '{{.Names}}' | head -1",
+		},
+		{
+			name:     "ssh",
+			opts:     Options{SSHHost: "root@nas"},
+			expected: `ssh -o StrictHostKeyChecking=no -o LogLevel=ERROR root@nas 'docker ps --filter name=duplicacy --format '"'"'{{.Names}}'"'"' | head -1'`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := New(tt.opts)
+			got := e.wrapSSH(composeServiceCommand("duplicacy"))
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestResolveContainer_DryRun(t *testing.T) {
+	e := New(Options{DryRun: true})
+
+	name, err := e.ResolveContainer("dryrun-service")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if name != "dryrun-service" {
+		t.Errorf("expected the service name in a dry run, got %q", name)
+	}
+	if _, ok := resolvedContainers["\x00dryrun-service"]; ok {
+		t.Error("a dry run must not cache the service name")
+	}
+}
+
+func TestResolveContainer_Cached(t *testing.T) {
+	resolvedMu.Lock()
+	resolvedContainers["root@nas\x00cached-service"] = "stack_cached-service_1"
+	resolvedMu.Unlock()
+	defer func() {
+		resolvedMu.Lock()
+		delete(resolvedContainers, "root@nas\x00cached-service")
+		resolvedMu.Unlock()
+	}()
+
+	// The cached name is returned without running docker ps over SSH
+	e := New(Options{SSHHost: "root@nas"})
+	name, err := e.ResolveContainer("cached-service")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if name != "stack_cached-service_1" {
+		t.Errorf("expected %q, got %q", "stack_cached-service_1", name)
+	}
+
+	// A different host is looked up separately
+	e = New(Options{SSHHost: "root@other", DryRun: true})
+	if name, _ := e.ResolveContainer("cached-service"); strings.HasPrefix(name, "stack_") {
+		t.Errorf("cache must be keyed by host, got %q", name)
+	}
+}
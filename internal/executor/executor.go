@@ -2,34 +2,100 @@ package executor
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"regexp"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 )
 
+// ErrDuplicacyNotFound is returned (wrapped) when the duplicacy binary cannot be discovered
+var ErrDuplicacyNotFound = errors.New("cannot find duplicacy")
+
+// ExitError is returned when a command runs but exits with a non-zero code
+type ExitError struct {
+	Code   int
+	Stderr string // Captured stderr (capture methods only; streamed otherwise)
+	Output string // Last 64 KiB of stdout and stderr interleaved as written, for LogTail (never in Error())
+}
+
+func (e *ExitError) Error() string {
+	if e.Stderr != "" {
+		return fmt.Sprintf("command exited with code %d: %s", e.Code, e.Stderr)
+	}
+	return fmt.Sprintf("command exited with code %d", e.Code)
+}
+
+// Result describes a finished duplicacy invocation
+type Result struct {
+	ExitCode int           // 0 on success, -1 when the command could not be run or was interrupted
+	Duration time.Duration // Time the command ran (zero in dry-run mode)
+	Stdout   string        // Captured stdout; empty for streamed commands
+	Stderr   string        // Captured stderr; empty for streamed commands
+	Tail     string        // Last 64 KiB of stdout and stderr interleaved, as in ExitError.Output
+}
+
+// SSH password modes for Options.SSHPasswordMode
+const (
+	SSHPasswordArg  = "arg"  // sshpass -p '<password>': visible in the local process list
+	SSHPasswordFile = "file" // sshpass -d 3: read from a pipe, never on a command line or disk
+)
+
+// sshPasswordFD is the descriptor the SSHPasswordFile pipe is passed on, the
+// first after stdin, stdout and stderr
+const sshPasswordFD = 3
+
 // Options configures the executor
 type Options struct {
 	DryRun           bool
 	Verbose          bool
 	DockerContainer  string
+	ContainerUser    string // User to run as inside the container (docker exec -u)
 	SSHHost          string
 	SSHPassword      string
+	SSHPasswordMode  string            // How sshpass gets SSHPassword: SSHPasswordArg (default) or SSHPasswordFile
 	DuplicacyPath    string            // Path to duplicacy binary (default: auto-discover)
+	DuplicacyVersion string            // Web UI CLI version to discover in the container (default: the newest)
 	RepoPath         string            // Repository path to cd into before running duplicacy
 	CacheDir         string            // Duplicacy Web GUI cache directory (e.g., /cache/localhost/0)
 	StoragePassword  string            // Default storage encryption password
 	StoragePasswords map[string]string // Per-storage passwords (storage name -> password)
 	GCDToken         string            // Google Drive token file path
+	GlobalOptions    []string          // Duplicacy global options placed before the subcommand (e.g., -d)
+	SkipRepoCheck    bool              // Don't check for a .duplicacy folder in the working directory first
+	PrefixOutput     bool              // Prefix streamed output lines with [storage] (for parallel runs)
+
+	// Extra environment exported before duplicacy runs, per storage (storage name -> var -> value)
+	StorageEnv map[string]map[string]string
+
+	// Context cancels running commands (killing their process group) when done; nil never cancels
+	Context context.Context
 }
 
-// Executor runs duplicacy commands
+// Executor runs duplicacy commands.
+//
+// An Executor is safe for concurrent use by multiple goroutines: Options are
+// never modified after New, duplicacy discovery runs at most once, and the
+// executor's own log lines are written atomically. The capture methods buffer
+// all child output and return it, so they never write into the output of a
+// concurrent streaming call. Streaming calls write child output directly to
+// stdout, so concurrent streaming calls may interleave with each other unless
+// PrefixOutput is set, which keeps each line whole and labelled.
 type Executor struct {
 	opts           Options
 	discoveredPath string
 	discoverOnce   sync.Once
 	discoverErr    error
+	outputMu       sync.Mutex
+
+	repoCheckOnce sync.Once
+	repoCheckErr  error
 }
 
 // New creates a new Executor
@@ -59,113 +125,194 @@ func (e *Executor) discoverDuplicacyPath() (string, error) {
 			return
 		}
 
-		// Search for CLI in Docker container
-		searchCmd := fmt.Sprintf("docker exec %s sh -c 'ls /config/bin/duplicacy_linux_x64_* 2>/dev/null | head -1'",
-			e.opts.DockerContainer)
+		// List every CLI in the Docker container; the newest (or the
+		// configured version) is picked below. ls fails when nothing
+		// matches, so an empty listing succeeds and is reported as not found.
+		searchCmd := fmt.Sprintf("%s sh -c 'ls /config/bin/%s* 2>/dev/null || true'",
+			e.dockerExec(), webBinaryPrefix)
 
 		// Wrap in SSH if needed
-		if e.opts.SSHHost != "" {
-			escapedCmd := strings.ReplaceAll(searchCmd, "'", "'\"'\"'")
-			searchCmd = fmt.Sprintf("ssh -o StrictHostKeyChecking=no -o LogLevel=ERROR %s '%s'", e.opts.SSHHost, escapedCmd)
-			if e.opts.SSHPassword != "" {
-				searchCmd = fmt.Sprintf("sshpass -p '%s' %s",
-					strings.ReplaceAll(e.opts.SSHPassword, "'", "'\"'\"'"),
-					searchCmd)
-			}
-		}
+		searchCmd = e.wrapSSH(searchCmd)
 
 		cmd := exec.Command("bash", "-c", searchCmd)
 		var out bytes.Buffer
 		cmd.Stdout = &out
 		cmd.Stderr = os.Stderr
 
-		if err := cmd.Run(); err != nil {
+		if err := e.runCommand(cmd); err != nil {
 			e.discoverErr = fmt.Errorf("failed to discover duplicacy path: %w", err)
 			return
 		}
 
-		path := strings.TrimSpace(out.String())
-		if path == "" {
-			e.discoverErr = fmt.Errorf("duplicacy CLI not found in /config/bin/")
+		path, err := selectDuplicacyBinary(strings.Split(out.String(), "\n"), e.opts.DuplicacyVersion)
+		if err != nil {
+			e.discoverErr = err
 			return
 		}
 
 		e.discoveredPath = path
 		if e.opts.Verbose {
-			fmt.Printf("    Discovered duplicacy at: %s\n", path)
+			e.logf("    Discovered duplicacy at: %s\n", path)
 		}
 	})
 
 	return e.discoveredPath, e.discoverErr
 }
 
-// RunDuplicacy executes a duplicacy command with the given arguments
-func (e *Executor) RunDuplicacy(args ...string) error {
-	return e.RunDuplicacyWithStorage("", args...)
-}
-
-// RunDuplicacyWithStorage executes a duplicacy command with storage-specific password
-func (e *Executor) RunDuplicacyWithStorage(storageName string, args ...string) error {
-	// Discover duplicacy path first (cached after first call)
-	duplicacyBin, err := e.discoverDuplicacyPath()
+// DuplicacyPath returns the duplicacy binary commands will run, discovering it
+// in the container on first use
+func (e *Executor) DuplicacyPath() (string, error) {
+	path, err := e.discoverDuplicacyPath()
 	if err != nil {
-		return fmt.Errorf("cannot find duplicacy: %w", err)
-	}
-
-	// Build the full command with storage-specific password
-	cmdStr := e.buildCommandWithStorage(duplicacyBin, args, storageName)
-
-	if e.opts.Verbose || e.opts.DryRun {
-		fmt.Printf("    Command: %s\n", cmdStr)
+		return "", fmt.Errorf("%w: %v", ErrDuplicacyNotFound, err)
 	}
+	return path, nil
+}
 
+// Ping checks that commands can reach their target (over SSH and into the
+// container when configured) by running a no-op there
+func (e *Executor) Ping() error {
 	if e.opts.DryRun {
 		return nil
 	}
+	_, err := e.executeCapture(e.buildShellCommand("true"))
+	return err
+}
 
-	// Execute the command
-	return e.execute(cmdStr)
+// RunDuplicacy executes a duplicacy command with the given arguments
+func (e *Executor) RunDuplicacy(args ...string) error {
+	return e.RunDuplicacyWithStorage("", args...)
+}
+
+// RunDuplicacyWithStorage executes a duplicacy command with storage-specific
+// password, streaming its output
+func (e *Executor) RunDuplicacyWithStorage(storageName string, args ...string) error {
+	_, err := e.run(storageName, args, true)
+	return err
+}
+
+// RunDuplicacyStreamWithStorage executes a duplicacy command with
+// storage-specific password, streaming its output like RunDuplicacyWithStorage
+// while also returning its Result; the last lines printed are in Result.Tail
+func (e *Executor) RunDuplicacyStreamWithStorage(storageName string, args ...string) (Result, error) {
+	return e.run(storageName, args, true)
 }
 
 // RunDuplicacyCaptureWithStorage executes a duplicacy command and captures stdout
 // Returns the command output as a string instead of streaming to stdout
 func (e *Executor) RunDuplicacyCaptureWithStorage(storageName string, args ...string) (string, error) {
+	result, err := e.RunResult(storageName, args...)
+	return result.Stdout, err
+}
+
+// RunResult executes a duplicacy command with storage-specific password and
+// returns its exit code, duration and captured output. Output is not streamed.
+// On failure the Result is still filled in as far as the command got.
+func (e *Executor) RunResult(storageName string, args ...string) (Result, error) {
+	return e.run(storageName, args, false)
+}
+
+// run builds and executes a duplicacy command, streaming its output as well
+// as capturing it when stream is set
+func (e *Executor) run(storageName string, args []string, stream bool) (Result, error) {
 	// Discover duplicacy path first (cached after first call)
 	duplicacyBin, err := e.discoverDuplicacyPath()
 	if err != nil {
-		return "", fmt.Errorf("cannot find duplicacy: %w", err)
+		return Result{ExitCode: -1}, fmt.Errorf("%w: %v", ErrDuplicacyNotFound, err)
+	}
+	if err := e.CheckRepository(); err != nil {
+		return Result{ExitCode: -1}, err
 	}
 
 	// Build the full command with storage-specific password
 	cmdStr := e.buildCommandWithStorage(duplicacyBin, args, storageName)
 
 	if e.opts.Verbose || e.opts.DryRun {
-		fmt.Printf("    Command: %s\n", cmdStr)
+		e.logf("    Command: %s\n", e.redacted().buildCommandWithStorage(duplicacyBin, args, storageName))
 	}
 
 	if e.opts.DryRun {
-		return "", nil
+		return Result{}, nil
 	}
 
-	// Execute the command and capture output
-	return e.executeCapture(cmdStr)
+	var result Result
+	if stream {
+		stdout, stderr, flush := e.streamWriters(storageName)
+		result, err = e.runResult(cmdStr, stdout, stderr)
+		flush()
+	} else {
+		result, err = e.runResult(cmdStr, nil, nil)
+	}
+	return result, classifyLocked(storageName, err)
+}
+
+// streamWriters returns where streamed output goes: stdout and stderr, or with
+// PrefixOutput, line-buffered writers that prefix each line with [label]
+func (e *Executor) streamWriters(label string) (stdout, stderr io.Writer, flush func()) {
+	if !e.opts.PrefixOutput || label == "" {
+		return os.Stdout, os.Stderr, func() {}
+	}
+
+	prefix := "[" + label + "] "
+	out := newPrefixWriter(os.Stdout, &e.outputMu, prefix)
+	errOut := newPrefixWriter(os.Stderr, &e.outputMu, prefix)
+	return out, errOut, func() {
+		out.Flush()
+		errOut.Flush()
+	}
+}
+
+// logf writes an executor log line to stdout as a single atomic write
+func (e *Executor) logf(format string, args ...interface{}) {
+	e.outputMu.Lock()
+	defer e.outputMu.Unlock()
+	fmt.Printf(format, args...)
 }
 
 // executeCapture runs the command and captures stdout
 func (e *Executor) executeCapture(cmdStr string) (string, error) {
+	result, err := e.runResult(cmdStr, nil, nil)
+	return result.Stdout, err
+}
+
+// runResult runs the command, capturing its output or, when streamOut and
+// streamErr are set, writing it to them as it arrives. Streamed output is not
+// captured beyond the bounded Result.Tail, so a long backup runs in constant
+// memory. A non-zero exit is returned as an *ExitError, carrying stderr only
+// when it was captured.
+func (e *Executor) runResult(cmdStr string, streamOut, streamErr io.Writer) (Result, error) {
 	cmd := exec.Command("bash", "-c", cmdStr)
 	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	combined := &tailBuffer{}
+	cmd.Stdout = io.MultiWriter(&stdout, combined)
+	cmd.Stderr = io.MultiWriter(&stderr, combined)
+	stream := streamOut != nil
+	if stream {
+		cmd.Stdout = io.MultiWriter(streamOut, combined)
+		cmd.Stderr = io.MultiWriter(streamErr, combined)
+	}
 
-	if err := cmd.Run(); err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			return stdout.String(), fmt.Errorf("command exited with code %d: %s", exitErr.ExitCode(), stderr.String())
-		}
-		return stdout.String(), err
+	start := time.Now()
+	err := e.runCommand(cmd)
+	result := Result{
+		Duration: time.Since(start),
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+		Tail:     combined.String(),
+	}
+	if err == nil {
+		return result, nil
 	}
 
-	return stdout.String(), nil
+	result.ExitCode = -1
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		result.ExitCode = exitErr.ExitCode()
+		if stream {
+			return result, &ExitError{Code: result.ExitCode, Output: combined.String()}
+		}
+		return result, &ExitError{Code: result.ExitCode, Stderr: result.Stderr, Output: combined.String()}
+	}
+	return result, err
 }
 
 // buildCommand constructs the full command string (for backward compatibility)
@@ -175,13 +322,16 @@ func (e *Executor) buildCommand(duplicacyBin string, args []string) string {
 
 // buildCommandWithStorage constructs the full command string with storage-specific password
 func (e *Executor) buildCommandWithStorage(duplicacyBin string, args []string, storageName string) string {
-	duplicacyCmd := duplicacyBin + " " + strings.Join(args, " ")
-
-	// Determine working directory: CacheDir takes precedence over RepoPath
-	workDir := e.opts.CacheDir
-	if workDir == "" {
-		workDir = e.opts.RepoPath
+	// Global options must come between the binary and the subcommand; they
+	// come from the config and may hold values with spaces, so are quoted
+	cmdArgs := make([]string, 0, len(e.opts.GlobalOptions)+len(args))
+	for _, opt := range e.opts.GlobalOptions {
+		cmdArgs = append(cmdArgs, ShellQuote(opt))
 	}
+	cmdArgs = append(cmdArgs, args...)
+	duplicacyCmd := duplicacyBin + " " + strings.Join(cmdArgs, " ")
+
+	workDir := e.workDir()
 
 	// If working directory specified, cd to it first
 	if workDir != "" {
@@ -190,62 +340,171 @@ func (e *Executor) buildCommandWithStorage(duplicacyBin string, args []string, s
 
 	// Build docker exec command
 	if e.opts.DockerContainer != "" {
-		// Get the password for this storage (check per-storage first, then default)
-		password := e.getStoragePassword(storageName)
+		exports := e.credentialExports(storageName)
 
-		if workDir != "" || password != "" {
-			// Need sh -c to handle cd and/or env var
+		if workDir != "" || len(exports) > 0 {
+			// Need sh -c to handle cd and/or env vars; exports go inside the shell
+			// command to avoid escaping issues
 			shellCmd := duplicacyCmd
-
-			// Prepend password export if needed (inside the shell command to avoid escaping issues)
-			if password != "" {
-				// Use double quotes and escape chars that are special inside double quotes
-				escapedPw := password
-				escapedPw = strings.ReplaceAll(escapedPw, "\\", "\\\\")
-				escapedPw = strings.ReplaceAll(escapedPw, "\"", "\\\"")
-				escapedPw = strings.ReplaceAll(escapedPw, "$", "\\$")
-				escapedPw = strings.ReplaceAll(escapedPw, "`", "\\`")
-
-				// Set both generic and storage-specific password env vars
-				// Duplicacy uses DUPLICACY_<STORAGENAME>_PASSWORD for non-default storages
-				exports := fmt.Sprintf("export DUPLICACY_PASSWORD=\"%s\"", escapedPw)
-				if storageName != "" {
-					// Convert storage name to uppercase for env var
-					upperName := strings.ToUpper(strings.ReplaceAll(storageName, "-", "_"))
-					exports += fmt.Sprintf(" && export DUPLICACY_%s_PASSWORD=\"%s\"", upperName, escapedPw)
-				}
-				shellCmd = exports + " && " + shellCmd
-			}
-
-			// Set GCD token path if provided (for Google Drive storages)
-			if e.opts.GCDToken != "" && storageName != "" {
-				upperName := strings.ToUpper(strings.ReplaceAll(storageName, "-", "_"))
-				tokenExport := fmt.Sprintf("export DUPLICACY_%s_GCD_TOKEN=\"%s\"", upperName, e.opts.GCDToken)
-				shellCmd = tokenExport + " && " + shellCmd
+			if len(exports) > 0 {
+				shellCmd = strings.Join(exports, " && ") + " && " + shellCmd
 			}
-
-			duplicacyCmd = fmt.Sprintf("docker exec %s sh -c '%s'", e.opts.DockerContainer, shellCmd)
+			escapedCmd := strings.ReplaceAll(shellCmd, "'", "'\"'\"'")
+			duplicacyCmd = fmt.Sprintf("%s sh -c '%s'", e.dockerExec(), escapedCmd)
 		} else {
 			// Simple command, no shell needed
-			duplicacyCmd = fmt.Sprintf("docker exec %s %s", e.opts.DockerContainer, duplicacyCmd)
+			duplicacyCmd = fmt.Sprintf("%s %s", e.dockerExec(), duplicacyCmd)
 		}
 	}
 
 	// Wrap in SSH if host specified
-	if e.opts.SSHHost != "" {
-		// Escape single quotes in the command
-		escapedCmd := strings.ReplaceAll(duplicacyCmd, "'", "'\"'\"'")
-		duplicacyCmd = fmt.Sprintf("ssh -o StrictHostKeyChecking=no -o LogLevel=ERROR %s '%s'", e.opts.SSHHost, escapedCmd)
-
-		// Add sshpass if password provided
-		if e.opts.SSHPassword != "" {
-			duplicacyCmd = fmt.Sprintf("sshpass -p '%s' %s",
-				strings.ReplaceAll(e.opts.SSHPassword, "'", "'\"'\"'"),
-				duplicacyCmd)
+	return e.wrapSSH(duplicacyCmd)
+}
+
+// shellSafePattern matches words the shell passes through unchanged
+var shellSafePattern = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// ShellQuote returns s as a single shell word, single-quoted unless it is
+// already safe
+func ShellQuote(s string) string {
+	if shellSafePattern.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", "'\"'\"'") + "'"
+}
+
+// workDir returns the directory duplicacy runs in: CacheDir takes precedence over RepoPath
+func (e *Executor) workDir() string {
+	if e.opts.CacheDir != "" {
+		return e.opts.CacheDir
+	}
+	return e.opts.RepoPath
+}
+
+// dockerExec returns the docker exec prefix for the configured container,
+// running as ContainerUser when set
+func (e *Executor) dockerExec() string {
+	if e.opts.ContainerUser != "" {
+		return fmt.Sprintf("docker exec -u %s %s", e.opts.ContainerUser, e.opts.DockerContainer)
+	}
+	return "docker exec " + e.opts.DockerContainer
+}
+
+// wrapSSH wraps a command in ssh (and sshpass) when an SSH host is configured
+func (e *Executor) wrapSSH(cmdStr string) string {
+	if e.opts.SSHHost == "" {
+		return cmdStr
+	}
+
+	// Escape single quotes in the command
+	escapedCmd := strings.ReplaceAll(cmdStr, "'", "'\"'\"'")
+	cmdStr = fmt.Sprintf("ssh -o StrictHostKeyChecking=no -o LogLevel=ERROR %s '%s'", e.opts.SSHHost, escapedCmd)
+
+	// Add sshpass if password provided
+	if e.usesPasswordPipe() {
+		cmdStr = fmt.Sprintf("sshpass -d %d %s", sshPasswordFD, cmdStr)
+	} else if e.opts.SSHPassword != "" {
+		cmdStr = fmt.Sprintf("sshpass -p '%s' %s",
+			strings.ReplaceAll(e.opts.SSHPassword, "'", "'\"'\"'"),
+			cmdStr)
+	}
+
+	return cmdStr
+}
+
+// usesPasswordPipe reports whether commands pass the SSH password to sshpass
+// through a pipe on sshPasswordFD
+func (e *Executor) usesPasswordPipe() bool {
+	return e.opts.SSHHost != "" && e.opts.SSHPassword != "" && e.opts.SSHPasswordMode == SSHPasswordFile
+}
+
+// passwordPipe returns the read end of a pipe holding password and a newline.
+// The password fits in the pipe buffer, so the write end is closed before the
+// reader starts.
+func passwordPipe(password string) (*os.File, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SSH password pipe: %w", err)
+	}
+	_, err = w.WriteString(password + "\n")
+	w.Close()
+	if err != nil {
+		r.Close()
+		return nil, fmt.Errorf("failed to write SSH password pipe: %w", err)
+	}
+	return r, nil
+}
+
+// RunShell executes an arbitrary shell command through the same channel as
+// duplicacy commands (inside the container and/or over SSH when configured)
+func (e *Executor) RunShell(command string) error {
+	cmdStr := e.buildShellCommand(command)
+
+	if e.opts.Verbose || e.opts.DryRun {
+		e.logf("    Command: %s\n", e.redacted().buildShellCommand(command))
+	}
+
+	if e.opts.DryRun {
+		return nil
+	}
+
+	return e.execute(cmdStr)
+}
+
+// buildShellCommand constructs the full command string for RunShell
+func (e *Executor) buildShellCommand(command string) string {
+	cmdStr := command
+	if e.opts.DockerContainer != "" {
+		escapedCmd := strings.ReplaceAll(command, "'", "'\"'\"'")
+		cmdStr = fmt.Sprintf("%s sh -c '%s'", e.dockerExec(), escapedCmd)
+	}
+	return e.wrapSSH(cmdStr)
+}
+
+// credentialExports returns the export statements for a storage's credentials:
+// storage env vars, GCD token and password. Each is emitted whenever it is set.
+func (e *Executor) credentialExports(storageName string) []string {
+	var exports []string
+	upperName := strings.ToUpper(strings.ReplaceAll(storageName, "-", "_"))
+
+	// Export storage credentials (B2/S3/WebDAV keys, etc.) in a stable order
+	env := e.opts.StorageEnv[storageName]
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		exports = append(exports, fmt.Sprintf("export %s=\"%s\"", name, escapeDoubleQuoted(env[name])))
+	}
+
+	// Set GCD token path if provided (for Google Drive storages)
+	if e.opts.GCDToken != "" && storageName != "" {
+		exports = append(exports, fmt.Sprintf("export DUPLICACY_%s_GCD_TOKEN=\"%s\"", upperName, escapeDoubleQuoted(e.opts.GCDToken)))
+	}
+
+	// Get the password for this storage (check per-storage first, then default)
+	if password := e.getStoragePassword(storageName); password != "" {
+		escapedPw := escapeDoubleQuoted(password)
+
+		// Set both generic and storage-specific password env vars
+		// Duplicacy uses DUPLICACY_<STORAGENAME>_PASSWORD for non-default storages
+		exports = append(exports, fmt.Sprintf("export DUPLICACY_PASSWORD=\"%s\"", escapedPw))
+		if storageName != "" {
+			exports = append(exports, fmt.Sprintf("export DUPLICACY_%s_PASSWORD=\"%s\"", upperName, escapedPw))
 		}
 	}
 
-	return duplicacyCmd
+	return exports
+}
+
+// escapeDoubleQuoted escapes the characters that are special inside a double-quoted shell string
+func escapeDoubleQuoted(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+	s = strings.ReplaceAll(s, "\"", "\\\"")
+	s = strings.ReplaceAll(s, "$", "\\$")
+	s = strings.ReplaceAll(s, "`", "\\`")
+	return s
 }
 
 // getStoragePassword returns the password for a storage, checking per-storage first then default
@@ -262,16 +521,48 @@ func (e *Executor) getStoragePassword(storageName string) string {
 
 // execute runs the command and streams output
 func (e *Executor) execute(cmdStr string) error {
-	cmd := exec.Command("bash", "-c", cmdStr)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	_, err := e.runResult(cmdStr, os.Stdout, os.Stderr)
+	return err
+}
 
-	if err := cmd.Run(); err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			return fmt.Errorf("command exited with code %d", exitErr.ExitCode())
+// runCommand runs cmd in its own process group. If the executor's context is
+// cancelled first, the whole group is killed so no duplicacy process is orphaned.
+func (e *Executor) runCommand(cmd *exec.Cmd) error {
+	ctx := e.opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("command interrupted: %w", err)
+	}
+
+	if e.usesPasswordPipe() {
+		pipe, err := passwordPipe(e.opts.SSHPassword)
+		if err != nil {
+			return err
 		}
+		defer pipe.Close()
+		cmd.ExtraFiles = append(cmd.ExtraFiles, pipe)
+	}
+
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
 		return err
 	}
 
-	return nil
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			killProcessGroup(cmd)
+		case <-done:
+		}
+	}()
+
+	err := cmd.Wait()
+	close(done)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("command interrupted: %w", ctxErr)
+	}
+	return err
 }
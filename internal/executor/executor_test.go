@@ -1,7 +1,16 @@
 package executor
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestBuildCommand_Basic(t *testing.T) {
@@ -55,6 +64,53 @@ func TestBuildCommand_WithSSHAndPassword(t *testing.T) {
 	}
 }
 
+func TestBuildCommand_PasswordFileMode(t *testing.T) {
+	exec := New(Options{
+		SSHHost:         "root@192.168.1.100",
+		SSHPassword:     "secret123",
+		SSHPasswordMode: SSHPasswordFile,
+	})
+
+	cmd := exec.buildCommand("duplicacy", []string{"backup"})
+	expected := "sshpass -d 3 ssh -o StrictHostKeyChecking=no -o LogLevel=ERROR root@192.168.1.100 'duplicacy backup'"
+
+	if cmd != expected {
+		t.Errorf("expected %q, got %q", expected, cmd)
+	}
+	if strings.Contains(cmd, "secret123") {
+		t.Errorf("password must not be on the command line: %s", cmd)
+	}
+}
+
+func TestRunCommand_PasswordPipe(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Options
+		expected string
+	}{
+		{"file mode", Options{SSHHost: "root@host", SSHPassword: "it's secret", SSHPasswordMode: SSHPasswordFile}, "it's secret\n"},
+		{"arg mode", Options{SSHHost: "root@host", SSHPassword: "secret", SSHPasswordMode: SSHPasswordArg}, ""},
+		{"no host", Options{SSHPassword: "secret", SSHPasswordMode: SSHPasswordFile}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := New(tt.opts)
+
+			// Read whatever arrives on fd 3, as sshpass -d 3 would
+			cmd := osexec.Command("bash", "-c", "cat <&3 2>/dev/null || true")
+			var out bytes.Buffer
+			cmd.Stdout = &out
+			if err := e.runCommand(cmd); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out.String() != tt.expected {
+				t.Errorf("expected %q on fd 3, got %q", tt.expected, out.String())
+			}
+		})
+	}
+}
+
 func TestBuildCommand_WithDockerAndSSH(t *testing.T) {
 	exec := New(Options{
 		DockerContainer: "Duplicacy",
@@ -331,6 +387,40 @@ func TestDiscoverDuplicacyPath_Cached(t *testing.T) {
 	}
 }
 
+func TestSelectDuplicacyBinary(t *testing.T) {
+	paths := []string{
+		"/config/bin/duplicacy_linux_x64_2.7.2",
+		"/config/bin/duplicacy_linux_x64_3.10.0",
+		"/config/bin/duplicacy_linux_x64_3.2.3",
+		"",
+	}
+	tests := []struct {
+		name    string
+		paths   []string
+		version string
+		want    string
+		wantErr bool
+	}{
+		{"newest", paths, "", "/config/bin/duplicacy_linux_x64_3.10.0", false},
+		{"exact version", paths, "3.2.3", "/config/bin/duplicacy_linux_x64_3.2.3", false},
+		{"version not installed", paths, "3.1.0", "", true},
+		{"unparsable name only", []string{"/config/bin/duplicacy_linux_x64_beta"}, "", "/config/bin/duplicacy_linux_x64_beta", false},
+		{"versioned beats unparsable", []string{"/config/bin/duplicacy_linux_x64_beta", "/config/bin/duplicacy_linux_x64_3.0.1"}, "", "/config/bin/duplicacy_linux_x64_3.0.1", false},
+		{"none", nil, "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectDuplicacyBinary(tt.paths, tt.version)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
 func TestExecuteCapture_Success(t *testing.T) {
 	exec := New(Options{})
 
@@ -568,11 +658,14 @@ func TestRunDuplicacyWithStorage_DiscoverError(t *testing.T) {
 
 	err := exec.RunDuplicacyWithStorage("test", "backup")
 	if err == nil {
-		t.Error("should error when discovery fails")
+		t.Fatal("should error when discovery fails")
 	}
 	if !contains(err.Error(), "cannot find duplicacy") {
 		t.Errorf("error should mention discovery failure: %v", err)
 	}
+	if !errors.Is(err, ErrDuplicacyNotFound) {
+		t.Errorf("error should wrap ErrDuplicacyNotFound: %v", err)
+	}
 }
 
 func TestRunDuplicacyCaptureWithStorage_DiscoverError(t *testing.T) {
@@ -583,11 +676,14 @@ func TestRunDuplicacyCaptureWithStorage_DiscoverError(t *testing.T) {
 
 	_, err := exec.RunDuplicacyCaptureWithStorage("test", "check")
 	if err == nil {
-		t.Error("should error when discovery fails")
+		t.Fatal("should error when discovery fails")
 	}
 	if !contains(err.Error(), "cannot find duplicacy") {
 		t.Errorf("error should mention discovery failure: %v", err)
 	}
+	if !errors.Is(err, ErrDuplicacyNotFound) {
+		t.Errorf("error should wrap ErrDuplicacyNotFound: %v", err)
+	}
 }
 
 func TestDiscoverDuplicacyPath_WithSSH(t *testing.T) {
@@ -638,6 +734,22 @@ func TestDiscoverDuplicacyPath_EmptyPathFromDocker(t *testing.T) {
 	}
 }
 
+func TestDiscoverDuplicacyPath_NoBinaries(t *testing.T) {
+	// A docker that runs the command locally, where /config/bin has no CLI
+	bin := t.TempDir()
+	script := "#!/bin/sh\nshift 2\nexec \"$@\"\n"
+	if err := os.WriteFile(filepath.Join(bin, "docker"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	exec := New(Options{DockerContainer: "Duplicacy"})
+	_, err := exec.discoverDuplicacyPath()
+	if err == nil || err.Error() != "duplicacy CLI not found in /config/bin/" {
+		t.Errorf("expected the not-found error for an empty listing, got %v", err)
+	}
+}
+
 func TestDiscoverDuplicacyPath_Verbose(t *testing.T) {
 	// Can't easily test verbose output without capturing stdout,
 	// but we can at least exercise the code path with explicit path
@@ -654,3 +766,340 @@ func TestDiscoverDuplicacyPath_Verbose(t *testing.T) {
 		t.Errorf("expected '/custom/path', got %q", path)
 	}
 }
+
+func TestBuildCommand_GlobalOptions(t *testing.T) {
+	exec := New(Options{
+		GlobalOptions: []string{"-d"},
+	})
+
+	cmd := exec.buildCommand("duplicacy", []string{"backup", "-storage", "gdrive"})
+	expected := "duplicacy -d backup -storage gdrive"
+
+	if cmd != expected {
+		t.Errorf("expected %q, got %q", expected, cmd)
+	}
+}
+
+func TestBuildCommandWithStorage_GlobalOptionsBeforeSubcommand(t *testing.T) {
+	exec := New(Options{
+		GlobalOptions: []string{"-profile", "localhost:6060", "-comment", "it's nightly", "-log"},
+	})
+
+	cmd := exec.buildCommandWithStorage("duplicacy", []string{"check", "-tabular", "-storage", "NAS"}, "NAS")
+	expected := `duplicacy -profile localhost:6060 -comment 'it'"'"'s nightly' -log check -tabular -storage NAS`
+	if cmd != expected {
+		t.Errorf("expected %q, got %q", expected, cmd)
+	}
+}
+
+func TestRunDuplicacyCaptureWithStorage_Concurrent(t *testing.T) {
+	exec := New(Options{
+		DuplicacyPath: "echo",
+	})
+
+	const workers = 16
+	var wg sync.WaitGroup
+	results := make([]string, workers)
+	errs := make([]error, workers)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			storage := fmt.Sprintf("storage%d", i)
+			results[i], errs[i] = exec.RunDuplicacyCaptureWithStorage(storage, "check", "-storage", storage)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < workers; i++ {
+		if errs[i] != nil {
+			t.Errorf("worker %d: unexpected error: %v", i, errs[i])
+			continue
+		}
+		expected := fmt.Sprintf("check -storage storage%d\n", i)
+		if results[i] != expected {
+			t.Errorf("worker %d: expected %q, got %q", i, expected, results[i])
+		}
+	}
+}
+
+func TestBuildShellCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Options
+		command  string
+		expected string
+	}{
+		{
+			name:     "local",
+			opts:     Options{},
+			command:  "pg_dump mydb > /tmp/db.sql",
+			expected: "pg_dump mydb > /tmp/db.sql",
+		},
+		{
+			name:     "docker",
+			opts:     Options{DockerContainer: "Duplicacy"},
+			command:  "echo 'hi'",
+			expected: `docker exec Duplicacy sh -c 'echo '"'"'hi'"'"''`,
+		},
+		{
+			name:     "docker over ssh",
+			opts:     Options{DockerContainer: "Duplicacy", SSHHost: "root@host"},
+			command:  "sync",
+			expected: `ssh -o StrictHostKeyChecking=no -o LogLevel=ERROR root@host 'docker exec Duplicacy sh -c '"'"'sync'"'"''`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := New(tt.opts).buildShellCommand(tt.command)
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRunShell(t *testing.T) {
+	if err := New(Options{}).RunShell("true"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := New(Options{}).RunShell("exit 3"); err == nil {
+		t.Error("expected error for failing command")
+	}
+	// Dry run never executes the command
+	if err := New(Options{DryRun: true}).RunShell("exit 3"); err != nil {
+		t.Errorf("dry run should not execute: %v", err)
+	}
+}
+
+func TestBuildCommandWithStorage_StorageEnv(t *testing.T) {
+	exec := New(Options{
+		DockerContainer: "Duplicacy",
+		StorageEnv: map[string]map[string]string{
+			"b2": {
+				"DUPLICACY_B2_B2_KEY": "k3y",
+				"DUPLICACY_B2_B2_ID":  "id",
+			},
+		},
+	})
+
+	cmd := exec.buildCommandWithStorage("duplicacy", []string{"backup"}, "b2")
+	expected := `docker exec Duplicacy sh -c 'export DUPLICACY_B2_B2_ID="id" && export DUPLICACY_B2_B2_KEY="k3y" && duplicacy backup'`
+	if cmd != expected {
+		t.Errorf("expected %q, got %q", expected, cmd)
+	}
+
+	// Other storages do not get the exports
+	other := exec.buildCommandWithStorage("duplicacy", []string{"backup"}, "nas")
+	if contains(other, "B2_KEY") {
+		t.Errorf("env should only be exported for its storage: %s", other)
+	}
+}
+
+func TestBuildCommandWithStorage_StorageEnvEscaping(t *testing.T) {
+	exec := New(Options{
+		DockerContainer: "Duplicacy",
+		StorageEnv: map[string]map[string]string{
+			"s3": {"DUPLICACY_S3_S3_SECRET": "se$cr`et\"\\x"},
+		},
+	})
+
+	cmd := exec.buildCommandWithStorage("duplicacy", []string{"backup"}, "s3")
+	expected := `export DUPLICACY_S3_S3_SECRET="se\$cr\` + "`" + `et\"\\x"`
+	if !contains(cmd, expected) {
+		t.Errorf("expected escaped export %q in %s", expected, cmd)
+	}
+}
+
+func TestBuildCommandWithStorage_SingleQuotes(t *testing.T) {
+	e := New(Options{
+		DockerContainer: "Duplicacy",
+		StoragePassword: "it's a 'secret'",
+		StorageEnv: map[string]map[string]string{
+			"s3": {"DUPLICACY_S3_S3_SECRET": "o'brien$x"},
+		},
+	})
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"DUPLICACY_PASSWORD", "it's a 'secret'"},
+		{"DUPLICACY_S3_S3_SECRET", "o'brien$x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Run the command with docker exec <container> replaced by running
+			// its arguments, so the value must survive both quoting levels
+			cmd := e.buildCommandWithStorage("printenv", []string{tt.name}, "s3")
+			out, err := osexec.Command("bash", "-c", `docker() { shift 2; "$@"; }; `+cmd).Output()
+			if err != nil {
+				t.Fatalf("command %s failed: %v", cmd, err)
+			}
+			if got := strings.TrimSuffix(string(out), "\n"); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestBuildCommandWithStorage_GCDTokenWithoutPasswordOrWorkDir(t *testing.T) {
+	exec := New(Options{
+		DockerContainer: "Duplicacy",
+		GCDToken:        "/config/gcd-token.json",
+	})
+
+	cmd := exec.buildCommandWithStorage("duplicacy", []string{"backup"}, "gdrive")
+
+	expected := `docker exec Duplicacy sh -c 'export DUPLICACY_GDRIVE_GCD_TOKEN="/config/gcd-token.json" && duplicacy backup'`
+	if cmd != expected {
+		t.Errorf("expected %q, got %q", expected, cmd)
+	}
+}
+
+func TestContainerUser(t *testing.T) {
+	exec := New(Options{DockerContainer: "Duplicacy", ContainerUser: "abc", CacheDir: "/cache/localhost/0"})
+
+	tests := []struct {
+		name     string
+		cmd      string
+		expected string
+	}{
+		{
+			name:     "duplicacy",
+			cmd:      exec.buildCommandWithStorage("duplicacy", []string{"check"}, ""),
+			expected: "docker exec -u abc Duplicacy sh -c 'cd /cache/localhost/0 && duplicacy check'",
+		},
+		{
+			name:     "shell",
+			cmd:      exec.buildShellCommand("sync"),
+			expected: "docker exec -u abc Duplicacy sh -c 'sync'",
+		},
+		{
+			name:     "cache discovery",
+			cmd:      exec.buildCacheDiscoveryCommand("appdata"),
+			expected: "docker exec -u abc Duplicacy sh -c 'ls -d /cache/localhost/*/appdata 2>/dev/null | head -1'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.cmd != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, tt.cmd)
+			}
+		})
+	}
+
+	// Without a work dir or credentials the command runs without a shell
+	plain := New(Options{DockerContainer: "Duplicacy", ContainerUser: "abc"})
+	got := plain.buildCommand("duplicacy", []string{"list"})
+	if got != "docker exec -u abc Duplicacy duplicacy list" {
+		t.Errorf("expected -u abc in plain docker exec, got %q", got)
+	}
+}
+
+func TestDuplicacyPath(t *testing.T) {
+	path, err := New(Options{DuplicacyPath: "/custom/path/duplicacy"}).DuplicacyPath()
+	if err != nil {
+		t.Errorf("DuplicacyPath should not error: %v", err)
+	}
+	if path != "/custom/path/duplicacy" {
+		t.Errorf("expected /custom/path/duplicacy, got %q", path)
+	}
+
+	_, err = New(Options{DockerContainer: "NonExistentContainer12345"}).DuplicacyPath()
+	if !errors.Is(err, ErrDuplicacyNotFound) {
+		t.Errorf("error should wrap ErrDuplicacyNotFound: %v", err)
+	}
+}
+
+func TestPing(t *testing.T) {
+	if err := New(Options{}).Ping(); err != nil {
+		t.Errorf("local ping should succeed: %v", err)
+	}
+	if err := New(Options{DockerContainer: "NonExistentContainer12345", DryRun: true}).Ping(); err != nil {
+		t.Errorf("dry-run ping should succeed: %v", err)
+	}
+	if err := New(Options{DockerContainer: "NonExistentContainer12345"}).Ping(); err == nil {
+		t.Error("ping should fail for a missing container")
+	}
+}
+
+func TestExitError(t *testing.T) {
+	exec := New(Options{})
+
+	var exitErr *ExitError
+	if err := exec.execute("exit 100"); !errors.As(err, &exitErr) || exitErr.Code != 100 {
+		t.Errorf("expected ExitError with code 100, got %v", err)
+	}
+	if exitErr.Error() != "command exited with code 100" {
+		t.Errorf("unexpected message: %q", exitErr.Error())
+	}
+
+	_, err := exec.executeCapture("echo oops >&2 && exit 3")
+	if !errors.As(err, &exitErr) || exitErr.Code != 3 || exitErr.Stderr != "oops\n" {
+		t.Errorf("expected ExitError with code 3 and stderr, got %#v", err)
+	}
+}
+
+func TestRunResult(t *testing.T) {
+	exec := New(Options{DuplicacyPath: "sh"})
+
+	result, err := exec.RunResult("NAS", "-c", `'echo out; echo err >&2; sleep 0.01'`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.ExitCode != 0 || result.Stdout != "out\n" || result.Stderr != "err\n" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if result.Duration < 10*time.Millisecond {
+		t.Errorf("expected the duration to be measured, got %v", result.Duration)
+	}
+}
+
+func TestRunResult_Failure(t *testing.T) {
+	exec := New(Options{DuplicacyPath: "sh"})
+
+	result, err := exec.RunResult("NAS", "-c", `'echo partial; echo broken >&2; exit 3'`)
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 3 {
+		t.Fatalf("expected ExitError with code 3, got %v", err)
+	}
+	if result.ExitCode != 3 || result.Stdout != "partial\n" || result.Stderr != "broken\n" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if exitErr.Stderr != "broken\n" {
+		t.Errorf("expected captured stderr in the error, got %q", exitErr.Stderr)
+	}
+}
+
+func TestRunDuplicacyStreamWithStorage(t *testing.T) {
+	exec := New(Options{DuplicacyPath: "sh"})
+
+	// The last lines of streamed output are kept in the result
+	result, err := exec.RunDuplicacyStreamWithStorage("NAS", "-c", `'echo revision 7; exit 0'`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Tail != "revision 7\n" {
+		t.Errorf("expected the streamed output in the result tail, got %q", result.Tail)
+	}
+	// Streamed output is not captured in full
+	if result.Stdout != "" || result.Stderr != "" {
+		t.Errorf("expected no captured output for a streamed command, got %+v", result)
+	}
+}
+
+func TestRunResult_NotRun(t *testing.T) {
+	result, err := New(Options{RepoPath: t.TempDir(), DuplicacyPath: "sh"}).RunResult("NAS", "-c", "true")
+	if !errors.Is(err, ErrRepoNotInitialized) || result.ExitCode != -1 {
+		t.Errorf("expected exit code -1 for a command that never ran, got %+v, %v", result, err)
+	}
+
+	result, err = New(Options{DryRun: true}).RunResult("NAS", "check")
+	if err != nil || result != (Result{}) {
+		t.Errorf("expected an empty result in dry-run mode, got %+v, %v", result, err)
+	}
+}
@@ -0,0 +1,49 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// ErrStorageLocked is wrapped by errors from duplicacy commands that refused
+// to run because another duplicacy operation holds the storage's lock
+var ErrStorageLocked = errors.New("locked by another duplicacy operation")
+
+// lockPattern matches duplicacy's messages for a storage held by another
+// operation (as opposed to a failing or corrupt storage)
+var lockPattern = regexp.MustCompile(`(?i)(is locked by|locked by another|failed to (acquire|obtain) (the )?lock|another( duplicacy)?( backup| prune| check)?( operation| instance| process)? is (already )?(running|in progress))`)
+
+// lockedByAnother reports whether a failed command's stderr or output shows
+// duplicacy stopped at another operation's lock
+func lockedByAnother(exitErr *ExitError) bool {
+	return lockPattern.MatchString(exitErr.Stderr) || lockPattern.MatchString(exitErr.Output)
+}
+
+// classifyLocked wraps err as ErrStorageLocked, naming the storage, when it is
+// an exit error caused by another operation's lock; other errors are returned
+// as is. The *ExitError stays reachable with errors.As.
+func classifyLocked(storageName string, err error) error {
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || !lockedByAnother(exitErr) {
+		return err
+	}
+	return &lockedError{storage: storageName, exitErr: exitErr}
+}
+
+// lockedError is an exit error classified as ErrStorageLocked
+type lockedError struct {
+	storage string
+	exitErr *ExitError
+}
+
+func (e *lockedError) Error() string {
+	if e.storage == "" {
+		return fmt.Sprintf("storage is %s (exit code %d)", ErrStorageLocked, e.exitErr.Code)
+	}
+	return fmt.Sprintf("storage %s is %s (exit code %d)", e.storage, ErrStorageLocked, e.exitErr.Code)
+}
+
+func (e *lockedError) Unwrap() error { return e.exitErr }
+
+func (e *lockedError) Is(target error) bool { return target == ErrStorageLocked }
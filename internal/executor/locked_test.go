@@ -0,0 +1,71 @@
+package executor
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestClassifyLocked(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		locked   bool
+		expected string
+	}{
+		{
+			name:     "lock message on stderr",
+			err:      &ExitError{Code: 100, Stderr: "2025-12-29 01:02:45.064 ERROR STORAGE_LOCK The storage is locked by another duplicacy operation"},
+			locked:   true,
+			expected: "storage NAS is locked by another duplicacy operation (exit code 100)",
+		},
+		{
+			name:     "streamed lock message in output",
+			err:      &ExitError{Code: 1, Output: "Storage set to sftp://nas\nERROR PRUNE_LOCK Another prune operation is in progress\n"},
+			locked:   true,
+			expected: "storage NAS is locked by another duplicacy operation (exit code 1)",
+		},
+		{
+			name:     "corrupt chunk",
+			err:      &ExitError{Code: 1, Stderr: "ERROR SNAPSHOT_CHUNK Chunk 4a3b is corrupted"},
+			expected: "command exited with code 1: ERROR SNAPSHOT_CHUNK Chunk 4a3b is corrupted",
+		},
+		{
+			name:     "not an exit error",
+			err:      errors.New("cannot find duplicacy"),
+			expected: "cannot find duplicacy",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classifyLocked("NAS", tt.err)
+			if errors.Is(err, ErrStorageLocked) != tt.locked {
+				t.Errorf("expected locked = %v, got %v", tt.locked, err)
+			}
+			if err.Error() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, err.Error())
+			}
+		})
+	}
+}
+
+func TestClassifyLocked_KeepsExitError(t *testing.T) {
+	err := classifyLocked("NAS", &ExitError{Code: 100, Output: "line 1\nThe storage is locked by another process\n"})
+
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 100 {
+		t.Errorf("expected the exit error to stay reachable, got %v", err)
+	}
+	if tail := LogTail(err, 1); tail != "The storage is locked by another process" {
+		t.Errorf("expected the log tail of the locked command, got %q", tail)
+	}
+}
+
+func TestRun_LockedStorage(t *testing.T) {
+	e := New(Options{DuplicacyPath: "sh -c 'echo \"ERROR Another backup is already running\" >&2; exit 1' --", SkipRepoCheck: true})
+
+	_, err := e.RunDuplicacyCaptureWithStorage("NAS", "backup")
+	if !errors.Is(err, ErrStorageLocked) {
+		t.Fatalf("expected ErrStorageLocked, got %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Preference is the part of a .duplicacy/preferences entry duplicaci uses.
+// Duplicacy Web writes one entry per storage of a repository.
+type Preference struct {
+	Name       string `json:"name"`       // Storage name
+	SnapshotID string `json:"id"`         // Snapshot (repository) ID
+	Repository string `json:"repository"` // Source path, when it differs from the directory holding .duplicacy
+}
+
+// ParsePreferences decodes the JSON list in a .duplicacy/preferences file
+func ParsePreferences(data []byte) ([]Preference, error) {
+	var prefs []Preference
+	if err := json.Unmarshal(data, &prefs); err != nil {
+		return nil, fmt.Errorf("failed to parse preferences: %w", err)
+	}
+	return prefs, nil
+}
+
+// ReadPreferences reads <dir>/.duplicacy/preferences through the same channel
+// as duplicacy commands. It returns nil in dry-run mode.
+func (e *Executor) ReadPreferences(dir string) ([]Preference, error) {
+	if e.opts.DryRun {
+		return nil, nil
+	}
+
+	out, err := e.executeCapture(e.buildPreferencesCommand(dir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read preferences in %s: %w", dir, err)
+	}
+	return ParsePreferences([]byte(out))
+}
+
+// buildPreferencesCommand constructs the command that prints dir's preferences file
+func (e *Executor) buildPreferencesCommand(dir string) string {
+	return e.buildShellCommand(fmt.Sprintf("cat %s/.duplicacy/preferences", dir))
+}
+
+// RepositoryFor returns the repository path of the snapshot ID in prefs, or ""
+// when no entry for it sets one
+func RepositoryFor(prefs []Preference, snapshotID string) string {
+	for _, p := range prefs {
+		if p.SnapshotID == snapshotID && p.Repository != "" {
+			return p.Repository
+		}
+	}
+	return ""
+}
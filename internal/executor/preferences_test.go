@@ -0,0 +1,86 @@
+package executor
+
+import "testing"
+
+// samplePreferences is a .duplicacy/preferences file as Duplicacy Web writes it
+const samplePreferences = `[
+    {
+        "name": "NAS",
+        "id": "appdata",
+        "repository": "/backuproot/appdata",
+        "storage": "sftp://backup@nas//backups",
+        "encrypted": true,
+        "no_backup": false,
+        "no_restore": false,
+        "no_save_password": false,
+        "nobackup_file": "",
+        "keys": null,
+        "filters": "/cache/localhost/3/.duplicacy/filters",
+        "exclude_by_attribute": false
+    },
+    {
+        "name": "GoogleDrive",
+        "id": "appdata",
+        "repository": "/backuproot/appdata",
+        "storage": "gcd://backups",
+        "encrypted": true
+    }
+]`
+
+func TestParsePreferences(t *testing.T) {
+	prefs, err := ParsePreferences([]byte(samplePreferences))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(prefs) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(prefs))
+	}
+	expected := Preference{Name: "NAS", SnapshotID: "appdata", Repository: "/backuproot/appdata"}
+	if prefs[0] != expected {
+		t.Errorf("expected %+v, got %+v", expected, prefs[0])
+	}
+
+	if _, err := ParsePreferences([]byte("not json")); err == nil {
+		t.Error("expected an error for malformed preferences")
+	}
+}
+
+func TestRepositoryFor(t *testing.T) {
+	prefs := []Preference{
+		{Name: "NAS", SnapshotID: "photos"},
+		{Name: "NAS", SnapshotID: "appdata", Repository: "/backuproot/appdata"},
+	}
+
+	tests := []struct {
+		name       string
+		snapshotID string
+		expected   string
+	}{
+		{"matching id", "appdata", "/backuproot/appdata"},
+		{"id without repository", "photos", ""},
+		{"unknown id", "documents", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RepositoryFor(prefs, tt.snapshotID); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestBuildPreferencesCommand(t *testing.T) {
+	e := New(Options{DockerContainer: "Duplicacy", SSHHost: "root@nas"})
+	cmd := e.buildPreferencesCommand("/cache/localhost/3")
+	expected := `ssh -o StrictHostKeyChecking=no -o LogLevel=ERROR root@nas 'docker exec Duplicacy sh -c '"'"'cat /cache/localhost/3/.duplicacy/preferences'"'"''`
+	if cmd != expected {
+		t.Errorf("expected %q, got %q", expected, cmd)
+	}
+}
+
+func TestReadPreferences_DryRun(t *testing.T) {
+	prefs, err := New(Options{DryRun: true}).ReadPreferences("/cache/localhost/3")
+	if err != nil || prefs != nil {
+		t.Errorf("expected nothing read in a dry run, got %v, %v", prefs, err)
+	}
+}
@@ -0,0 +1,70 @@
+package executor
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// prefixWriter buffers output into lines and writes each complete line to out
+// with a prefix, holding a lock shared with other writers to the same
+// destination so concurrent commands never tear each other's lines
+type prefixWriter struct {
+	out    io.Writer
+	outMu  *sync.Mutex
+	prefix []byte
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+// newPrefixWriter creates a writer that prefixes every line written to out,
+// serializing writes with outMu
+func newPrefixWriter(out io.Writer, outMu *sync.Mutex, prefix string) *prefixWriter {
+	return &prefixWriter{out: out, outMu: outMu, prefix: []byte(prefix)}
+}
+
+// Write buffers p and writes out every line it completes
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	end := bytes.LastIndexByte(w.buf, '\n')
+	if end < 0 {
+		return len(p), nil
+	}
+
+	err := w.writeLines(w.buf[:end+1])
+	w.buf = append(w.buf[:0], w.buf[end+1:]...)
+	return len(p), err
+}
+
+// Flush writes any buffered partial line, terminated with a newline
+func (w *prefixWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) == 0 {
+		return nil
+	}
+	err := w.writeLines(append(w.buf, '\n'))
+	w.buf = w.buf[:0]
+	return err
+}
+
+// writeLines writes newline-terminated lines, each prefixed, in a single write
+func (w *prefixWriter) writeLines(lines []byte) error {
+	var b bytes.Buffer
+	for len(lines) > 0 {
+		i := bytes.IndexByte(lines, '\n')
+		b.Write(w.prefix)
+		b.Write(lines[:i+1])
+		lines = lines[i+1:]
+	}
+
+	w.outMu.Lock()
+	defer w.outMu.Unlock()
+	_, err := w.out.Write(b.Bytes())
+	return err
+}
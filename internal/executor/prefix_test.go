@@ -0,0 +1,93 @@
+package executor
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestPrefixWriter_Lines(t *testing.T) {
+	var out bytes.Buffer
+	var mu sync.Mutex
+	w := newPrefixWriter(&out, &mu, "[NAS] ")
+
+	w.Write([]byte("first line\nsecond "))
+	if out.String() != "[NAS] first line\n" {
+		t.Errorf("expected only the complete line, got %q", out.String())
+	}
+
+	w.Write([]byte("line\nthird"))
+	w.Flush()
+	expected := "[NAS] first line\n[NAS] second line\n[NAS] third\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+
+	w.Flush()
+	if out.String() != expected {
+		t.Errorf("flushing with nothing buffered should write nothing, got %q", out.String())
+	}
+}
+
+func TestPrefixWriter_ConcurrentWritersDoNotTearLines(t *testing.T) {
+	var out bytes.Buffer
+	var mu sync.Mutex
+
+	const writers, lines = 8, 50
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w := newPrefixWriter(&out, &mu, fmt.Sprintf("[storage%d] ", i))
+			for j := 0; j < lines; j++ {
+				// Write a byte at a time so lines would tear without buffering
+				for _, b := range []byte(fmt.Sprintf("storage%d line %d\n", i, j)) {
+					w.Write([]byte{b})
+				}
+			}
+			w.Flush()
+		}(i)
+	}
+	wg.Wait()
+
+	got := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(got) != writers*lines {
+		t.Fatalf("expected %d lines, got %d", writers*lines, len(got))
+	}
+	next := make(map[string]int)
+	for _, line := range got {
+		var prefix, name string
+		var n int
+		if _, err := fmt.Sscanf(line, "%s %s line %d", &prefix, &name, &n); err != nil || prefix != "["+name+"]" {
+			t.Fatalf("torn or unprefixed line: %q", line)
+		}
+		if n != next[name] {
+			t.Errorf("%s: expected line %d, got %d", name, next[name], n)
+		}
+		next[name]++
+	}
+}
+
+func TestStreamWriters(t *testing.T) {
+	if out, _, _ := New(Options{}).streamWriters("NAS"); out != os.Stdout {
+		t.Errorf("expected plain stdout without PrefixOutput, got %#v", out)
+	}
+
+	out, errOut, flush := New(Options{PrefixOutput: true}).streamWriters("NAS")
+	defer flush()
+	pw, ok := out.(*prefixWriter)
+	if !ok || string(pw.prefix) != "[NAS] " {
+		t.Errorf("expected a [NAS] prefix writer, got %#v", out)
+	}
+	if _, ok := errOut.(*prefixWriter); !ok {
+		t.Errorf("expected stderr to be prefixed too, got %#v", errOut)
+	}
+
+	if out, _, _ := New(Options{PrefixOutput: true}).streamWriters(""); out != os.Stdout {
+		t.Errorf("expected plain stdout without a label, got %#v", out)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package executor
+
+import "os/exec"
+
+// setProcessGroup is a no-op where process groups are unavailable
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup kills the command's process
+func killProcessGroup(cmd *exec.Cmd) {
+	if cmd.Process != nil {
+		_ = cmd.Process.Kill()
+	}
+}
@@ -0,0 +1,39 @@
+//go:build unix
+
+package executor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestExecute_CancelledContextKillsProcessGroup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	exec := New(Options{Context: ctx})
+
+	// The background sleep holds the capture pipe open, so Wait only returns
+	// quickly if the whole process group is killed, not just bash
+	time.AfterFunc(100*time.Millisecond, cancel)
+	start := time.Now()
+	_, err := exec.executeCapture("sleep 30 & wait")
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("expected cancelled command to stop promptly, took %v", elapsed)
+	}
+}
+
+func TestExecute_AlreadyCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Nothing is started once the context is done
+	err := New(Options{Context: ctx}).execute("exit 0")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+package executor
+
+// redactedValue replaces secrets in printed commands
+const redactedValue = "***"
+
+// redacted returns an executor that builds the same commands with every
+// secret replaced by redactedValue, for printing only. Masking the values
+// before they are quoted holds at any depth of sh -c and ssh quoting, which
+// pattern matching over the finished command cannot.
+func (e *Executor) redacted() *Executor {
+	opts := e.opts
+	opts.SSHPassword = maskSecret(opts.SSHPassword)
+	opts.StoragePassword = maskSecret(opts.StoragePassword)
+	opts.GCDToken = maskSecret(opts.GCDToken)
+
+	if opts.StoragePasswords != nil {
+		opts.StoragePasswords = make(map[string]string, len(e.opts.StoragePasswords))
+		for storage, pw := range e.opts.StoragePasswords {
+			opts.StoragePasswords[storage] = maskSecret(pw)
+		}
+	}
+	if opts.StorageEnv != nil {
+		opts.StorageEnv = make(map[string]map[string]string, len(e.opts.StorageEnv))
+		for storage, env := range e.opts.StorageEnv {
+			masked := make(map[string]string, len(env))
+			for name, value := range env {
+				masked[name] = maskSecret(value)
+			}
+			opts.StorageEnv[storage] = masked
+		}
+	}
+	return &Executor{opts: opts}
+}
+
+// maskSecret returns redactedValue for a set secret, keeping unset ones empty
+// so the command has the same shape
+func maskSecret(s string) string {
+	if s == "" {
+		return ""
+	}
+	return redactedValue
+}
@@ -0,0 +1,98 @@
+package executor
+
+import (
+	"strings"
+	"testing"
+)
+
+// quotingSecret holds every character the shell layers escape
+const quotingSecret = `ZQX1'ZQX2"ZQX3\ZQX4$ZQX5`
+
+func TestRedacted(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Options
+		storage  string
+		expected string
+	}{
+		{
+			name:     "no secrets",
+			opts:     Options{DockerContainer: "Duplicacy"},
+			expected: "docker exec Duplicacy duplicacy backup",
+		},
+		{
+			name:     "sshpass",
+			opts:     Options{SSHHost: "root@host", SSHPassword: "hunter2"},
+			expected: "sshpass -p '***' ssh -o StrictHostKeyChecking=no -o LogLevel=ERROR root@host 'duplicacy backup'",
+		},
+		{
+			name:     "password exports",
+			opts:     Options{DockerContainer: "D", StoragePassword: `a"b$c`},
+			storage:  "NAS",
+			expected: `docker exec D sh -c 'export DUPLICACY_PASSWORD="***" && export DUPLICACY_NAS_PASSWORD="***" && duplicacy backup'`,
+		},
+		{
+			name: "storage env and token",
+			opts: Options{
+				DockerContainer: "D",
+				GCDToken:        "/config/gcd-token.json",
+				StorageEnv:      map[string]map[string]string{"NAS": {"DUPLICACY_NAS_S3_SECRET": "q'w", "DUPLICACY_NAS_S3_ID": "id"}},
+			},
+			storage:  "NAS",
+			expected: `docker exec D sh -c 'export DUPLICACY_NAS_S3_ID="***" && export DUPLICACY_NAS_S3_SECRET="***" && export DUPLICACY_NAS_GCD_TOKEN="***" && duplicacy backup'`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := New(tt.opts).redacted().buildCommandWithStorage("duplicacy", []string{"backup"}, tt.storage)
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRedacted_FullCommand(t *testing.T) {
+	// connection.host and a container: the exports are quoted for sh -c and
+	// then again for ssh
+	exec := New(Options{
+		DockerContainer:  "Duplicacy",
+		SSHHost:          "root@host",
+		SSHPassword:      quotingSecret,
+		StoragePassword:  quotingSecret,
+		StoragePasswords: map[string]string{"nas": quotingSecret},
+		StorageEnv:       map[string]map[string]string{"nas": {"DUPLICACY_NAS_S3_SECRET": quotingSecret}},
+		CacheDir:         "/cache/localhost/0",
+	})
+
+	cmd := exec.buildCommandWithStorage("duplicacy", []string{"backup"}, "nas")
+	printed := exec.redacted().buildCommandWithStorage("duplicacy", []string{"backup"}, "nas")
+
+	// The executed command keeps the secrets
+	if !strings.Contains(cmd, "ZQX4") {
+		t.Errorf("real command should keep secrets: %s", cmd)
+	}
+	// No part of any secret survives in the printed command
+	if strings.Contains(printed, "ZQX") {
+		t.Errorf("printed command leaks a secret: %s", printed)
+	}
+	for _, want := range []string{"sshpass -p '***'", `DUPLICACY_NAS_PASSWORD="***"`, `DUPLICACY_NAS_S3_SECRET="***"`, "cd /cache/localhost/0 && duplicacy backup"} {
+		if !strings.Contains(printed, want) {
+			t.Errorf("printed command should keep its structure (%s): %s", want, printed)
+		}
+	}
+
+	shell := exec.redacted().buildShellCommand("echo hi")
+	if strings.Contains(shell, "ZQX") || !strings.Contains(shell, "sshpass -p '***'") {
+		t.Errorf("printed shell command should mask the SSH password: %s", shell)
+	}
+}
+
+func TestRedacted_LeavesExecutorUnchanged(t *testing.T) {
+	exec := New(Options{StoragePasswords: map[string]string{"nas": "secret"}, StorageEnv: map[string]map[string]string{"nas": {"KEY": "v"}}})
+	exec.redacted()
+	if exec.opts.StoragePasswords["nas"] != "secret" || exec.opts.StorageEnv["nas"]["KEY"] != "v" {
+		t.Errorf("redacted copy must not modify the executor's options: %+v", exec.opts)
+	}
+}
@@ -0,0 +1,48 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrRepoNotInitialized is returned (wrapped) when the working directory has no .duplicacy folder
+var ErrRepoNotInitialized = errors.New("repository not initialized")
+
+// repoMissingExitCode is the exit code of the repository check command when
+// the .duplicacy folder is missing, chosen to differ from docker/ssh failures
+const repoMissingExitCode = 3
+
+// CheckRepository checks that the working directory (CacheDir, else RepoPath)
+// exists and contains a .duplicacy folder, so a wrong path fails with a clear
+// error instead of deep inside duplicacy. The check runs at most once per
+// executor and is skipped without a working directory, in dry-run mode, or
+// with SkipRepoCheck.
+func (e *Executor) CheckRepository() error {
+	e.repoCheckOnce.Do(func() {
+		e.repoCheckErr = e.checkRepository()
+	})
+	return e.repoCheckErr
+}
+
+func (e *Executor) checkRepository() error {
+	dir := e.workDir()
+	if dir == "" || e.opts.DryRun || e.opts.SkipRepoCheck {
+		return nil
+	}
+
+	_, err := e.executeCapture(e.buildRepoCheckCommand(dir))
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) && exitErr.Code == repoMissingExitCode {
+		return fmt.Errorf("%w at %s (no .duplicacy folder)", ErrRepoNotInitialized, dir)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check repository at %s: %w", dir, err)
+	}
+	return nil
+}
+
+// buildRepoCheckCommand constructs the command that exits with
+// repoMissingExitCode when dir has no .duplicacy folder
+func (e *Executor) buildRepoCheckCommand(dir string) string {
+	return e.buildShellCommand(fmt.Sprintf("test -d %s/.duplicacy || exit %d", dir, repoMissingExitCode))
+}
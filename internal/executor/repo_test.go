@@ -0,0 +1,96 @@
+package executor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildRepoCheckCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Options
+		expected string
+	}{
+		{
+			name:     "local",
+			opts:     Options{},
+			expected: `test -d /backups/appdata/.duplicacy || exit 3`,
+		},
+		{
+			name:     "docker",
+			opts:     Options{DockerContainer: "Duplicacy"},
+			expected: `docker exec Duplicacy sh -c 'test -d /backups/appdata/.duplicacy || exit 3'`,
+		},
+		{
+			name:     "docker over ssh",
+			opts:     Options{DockerContainer: "Duplicacy", SSHHost: "root@host"},
+			expected: `ssh -o StrictHostKeyChecking=no -o LogLevel=ERROR root@host 'docker exec Duplicacy sh -c '"'"'test -d /backups/appdata/.duplicacy || exit 3'"'"''`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := New(tt.opts).buildRepoCheckCommand("/backups/appdata")
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCheckRepository_NotInitialized(t *testing.T) {
+	dir := t.TempDir()
+	exec := New(Options{RepoPath: dir, DuplicacyPath: "echo"})
+
+	err := exec.CheckRepository()
+	if !errors.Is(err, ErrRepoNotInitialized) {
+		t.Fatalf("expected ErrRepoNotInitialized, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "repository not initialized at "+dir) {
+		t.Errorf("expected the path in the error, got %q", err.Error())
+	}
+
+	// The duplicacy command is not run
+	if out, err := exec.RunDuplicacyCaptureWithStorage("NAS", "check"); err == nil || out != "" {
+		t.Errorf("expected the repository error before running duplicacy, got %q, %v", out, err)
+	}
+}
+
+func TestCheckRepository_Initialized(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, ".duplicacy"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	exec := New(Options{CacheDir: dir, DuplicacyPath: "echo"})
+
+	if err := exec.CheckRepository(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	out, err := exec.RunDuplicacyCaptureWithStorage("NAS", "check")
+	if err != nil || out != "check\n" {
+		t.Errorf("expected duplicacy to run, got %q, %v", out, err)
+	}
+}
+
+func TestCheckRepository_Skipped(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{"no working directory", Options{}},
+		{"dry run", Options{RepoPath: missing, DryRun: true}},
+		{"skip flag", Options{RepoPath: missing, SkipRepoCheck: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := New(tt.opts).CheckRepository(); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
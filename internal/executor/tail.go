@@ -0,0 +1,69 @@
+package executor
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+)
+
+// maxOutputTail bounds the combined output kept for ExitError.Output; LogTail
+// only ever needs the last lines, and a long backup can print gigabytes
+const maxOutputTail = 64 << 10
+
+// tailBuffer keeps the last maxOutputTail bytes written to it. It is safe for
+// the concurrent stdout and stderr copies exec.Cmd makes when both go to the
+// same writer.
+type tailBuffer struct {
+	mu        sync.Mutex
+	buf       []byte
+	truncated bool
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, p...)
+	// Trim only once twice the limit is held, so trimming stays amortized
+	if len(b.buf) > 2*maxOutputTail {
+		b.buf = append(b.buf[:0], b.buf[len(b.buf)-maxOutputTail:]...)
+		b.truncated = true
+	}
+	return len(p), nil
+}
+
+// String returns the kept output. Once earlier output has been dropped it
+// starts at the first complete line.
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out, truncated := b.buf, b.truncated
+	if len(out) > maxOutputTail {
+		out = out[len(out)-maxOutputTail:]
+		truncated = true
+	}
+	if truncated {
+		if i := bytes.IndexByte(out, '\n'); i >= 0 {
+			out = out[i+1:]
+		}
+	}
+	return string(out)
+}
+
+// LogTail returns the last n lines of output from the failed command behind
+// err, or "" when err carries no output (e.g. duplicacy was never run)
+func LogTail(err error, n int) string {
+	var exitErr *ExitError
+	if n <= 0 || !errors.As(err, &exitErr) {
+		return ""
+	}
+	output := strings.TrimRight(exitErr.Output, "\n")
+	if output == "" {
+		return ""
+	}
+	lines := strings.Split(output, "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
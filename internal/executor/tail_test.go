@@ -0,0 +1,72 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLogTail(t *testing.T) {
+	output := "line 1\nline 2\nline 3\nline 4\n"
+	tests := []struct {
+		name     string
+		err      error
+		n        int
+		expected string
+	}{
+		{"last lines", &ExitError{Code: 1, Output: output}, 2, "line 3\nline 4"},
+		{"fewer lines than n", &ExitError{Code: 1, Output: output}, 10, "line 1\nline 2\nline 3\nline 4"},
+		{"wrapped", fmt.Errorf("backup: %w", &ExitError{Code: 1, Output: output}), 1, "line 4"},
+		{"disabled", &ExitError{Code: 1, Output: output}, 0, ""},
+		{"no output", &ExitError{Code: 1}, 5, ""},
+		{"not an exit error", errors.New("cannot find duplicacy"), 5, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LogTail(tt.err, tt.n); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestTailBuffer(t *testing.T) {
+	var b tailBuffer
+	line := strings.Repeat("x", 99) + "\n"
+	for i := 0; i < 3*maxOutputTail/len(line); i++ {
+		fmt.Fprint(&b, line)
+	}
+	fmt.Fprint(&b, "last line\n")
+
+	out := b.String()
+	if len(out) > maxOutputTail {
+		t.Errorf("expected at most %d bytes, got %d", maxOutputTail, len(out))
+	}
+	if !strings.HasPrefix(out, line) || !strings.HasSuffix(out, line+"last line\n") {
+		t.Errorf("expected whole lines ending with the last one, got %q...%q", out[:10], out[len(out)-20:])
+	}
+
+	var short tailBuffer
+	fmt.Fprint(&short, "only\nlines\n")
+	if got := short.String(); got != "only\nlines\n" {
+		t.Errorf("expected short output unchanged, got %q", got)
+	}
+}
+
+func TestRunResult_FailureOutput(t *testing.T) {
+	e := New(Options{})
+	_, err := e.runResult("echo out; echo err >&2; exit 2", nil, nil)
+
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("expected *ExitError, got %v", err)
+	}
+	// stdout and stderr are copied concurrently, so only their lines are fixed
+	if exitErr.Output != "out\nerr\n" && exitErr.Output != "err\nout\n" {
+		t.Errorf("expected combined stdout and stderr, got %q", exitErr.Output)
+	}
+	if got := exitErr.Error(); got != "command exited with code 2: err\n" {
+		t.Errorf("expected output kept out of Error(), got %q", got)
+	}
+}
@@ -0,0 +1,91 @@
+package executor
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// webBinaryPrefix is the file name prefix of the CLI binaries the Web UI
+// downloads into /config/bin, followed by the version
+const webBinaryPrefix = "duplicacy_linux_x64_"
+
+// selectDuplicacyBinary picks the binary for version among the listed paths,
+// or the highest version when version is empty. Paths whose names carry no
+// parsable version are only used if nothing else matches.
+func selectDuplicacyBinary(paths []string, version string) (string, error) {
+	var best string
+	var bestVersion []int
+	var fallback string
+	for _, p := range paths {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		name := strings.TrimPrefix(path.Base(p), webBinaryPrefix)
+		if version != "" {
+			if name == version {
+				return p, nil
+			}
+			continue
+		}
+
+		v, ok := parseVersion(name)
+		if !ok {
+			if fallback == "" {
+				fallback = p
+			}
+			continue
+		}
+		if best == "" || compareVersions(v, bestVersion) > 0 {
+			best, bestVersion = p, v
+		}
+	}
+
+	if version != "" {
+		return "", fmt.Errorf("duplicacy CLI version %s not found in /config/bin/", version)
+	}
+	if best == "" {
+		best = fallback
+	}
+	if best == "" {
+		return "", fmt.Errorf("duplicacy CLI not found in /config/bin/")
+	}
+	return best, nil
+}
+
+// parseVersion parses a dotted numeric version such as 3.2.3
+func parseVersion(s string) ([]int, bool) {
+	parts := strings.Split(s, ".")
+	v := make([]int, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, false
+		}
+		v[i] = n
+	}
+	return v, true
+}
+
+// compareVersions returns -1, 0 or 1 as a is lower than, equal to or higher
+// than b; missing trailing parts count as 0
+func compareVersions(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,145 @@
+package notifier
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultRequestInterval is the minimum spacing between API requests made with
+// the shared limiter, so a run that opens several issues does not hammer a
+// small self-hosted instance
+const defaultRequestInterval = 100 * time.Millisecond
+
+const (
+	// defaultMaxRetries is how many times a rate-limited (429) or failed (5xx)
+	// request is retried before its response is returned to the caller; see
+	// retryableStatus for POST
+	defaultMaxRetries = 3
+	// defaultRetryBackoff is the delay before the first retry; it doubles for each
+	// further attempt unless the server sends Retry-After
+	defaultRetryBackoff = time.Second
+	// maxRetryDelay caps any single wait, including one asked for by Retry-After
+	maxRetryDelay = 30 * time.Second
+)
+
+// sharedClient is reused by every notifier so connections are pooled across them
+var sharedClient = &http.Client{
+	Timeout: 30 * time.Second,
+	Transport: &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		MaxIdleConns:        10,
+		MaxIdleConnsPerHost: 4,
+		IdleConnTimeout:     90 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+	},
+}
+
+// sharedLimiter spaces out requests from every notifier using the default rate
+var sharedLimiter = newRateLimiter(defaultRequestInterval)
+
+// rateLimiter lets at most one request start per interval. It is safe for
+// concurrent use; callers are released in the order they arrive.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// newRateLimiter creates a limiter; an interval of 0 never waits
+func newRateLimiter(interval time.Duration) *rateLimiter {
+	return &rateLimiter{interval: interval}
+}
+
+// wait blocks until the caller's turn to send a request
+func (l *rateLimiter) wait() {
+	if l == nil || l.interval <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	start := l.next
+	if start.Before(now) {
+		start = now
+	}
+	l.next = start.Add(l.interval)
+	l.mu.Unlock()
+
+	time.Sleep(time.Until(start))
+}
+
+// do sends a request once the rate limiter allows it, retrying retryable
+// responses with backoff. The last response is returned when retries run out;
+// cancelling the request's context ends the wait between attempts.
+func (f *ForgejoNotifier) do(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		f.limiter.wait()
+		resp, err := f.client.Do(req)
+		if err != nil || !retryableStatus(req.Method, resp.StatusCode) || attempt >= f.maxRetries {
+			return resp, err
+		}
+
+		delay := retryDelay(resp.Header.Get("Retry-After"), f.retryBackoff, attempt, time.Now())
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+
+		// The body was consumed by the previous attempt
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+	}
+}
+
+// retryableStatus reports whether a response status is worth retrying: rate
+// limiting and server errors are usually transient, other 4xx are not. A POST
+// is not idempotent, so it is only retried on 429 and 503, which mean the
+// server did not act on it; after another 5xx the issue or comment may exist.
+func retryableStatus(method string, code int) bool {
+	if code == http.StatusTooManyRequests {
+		return true
+	}
+	if method == http.MethodPost {
+		return code == http.StatusServiceUnavailable
+	}
+	return code >= 500
+}
+
+// retryDelay returns how long to wait before retry number attempt (0-based).
+// A Retry-After header, in seconds or as an HTTP date, takes precedence over
+// exponential backoff from base. The result never exceeds maxRetryDelay.
+func retryDelay(retryAfter string, base time.Duration, attempt int, now time.Time) time.Duration {
+	delay := base
+	for i := 0; i < attempt && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if retryAfter != "" {
+		if secs, err := strconv.Atoi(retryAfter); err == nil && secs >= 0 {
+			delay = time.Duration(secs) * time.Second
+		} else if at, err := http.ParseTime(retryAfter); err == nil {
+			delay = at.Sub(now)
+		}
+	}
+
+	if delay < 0 {
+		return 0
+	}
+	if delay > maxRetryDelay {
+		return maxRetryDelay
+	}
+	return delay
+}
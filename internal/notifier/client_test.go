@@ -0,0 +1,278 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingTransport records requests before passing them to the default transport
+type countingTransport struct {
+	mu       sync.Mutex
+	requests int
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.mu.Lock()
+	c.requests++
+	c.mu.Unlock()
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestNewForgejo_SharedClient(t *testing.T) {
+	a := NewForgejo("https://git.example.com", "user/repo", "token")
+	b := NewForgejo("https://git.example.com", "user/other", "token")
+
+	if a.client != sharedClient || b.client != sharedClient {
+		t.Error("expected notifiers to share the pooled client")
+	}
+	if a.limiter != sharedLimiter || b.limiter != sharedLimiter {
+		t.Error("expected notifiers to share the rate limiter")
+	}
+}
+
+func TestWithHTTPClient_IsUsed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			w.Write([]byte(`[]`))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	transport := &countingTransport{}
+	client := &http.Client{Transport: transport}
+	n := NewForgejo(server.URL, "user/repo", "testtoken", WithHTTPClient(client), WithRateLimit(0))
+
+	if n.client != client {
+		t.Fatal("expected the injected client to be set")
+	}
+	if err := n.CreateOrUpdateIssue("Test", "Body"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Open and closed issue searches, then one create
+	if transport.requests != 3 {
+		t.Errorf("expected 3 requests through the injected client, got %d", transport.requests)
+	}
+}
+
+func TestRateLimiter_SpacesRequests(t *testing.T) {
+	l := newRateLimiter(20 * time.Millisecond)
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.wait()
+		}()
+	}
+	wg.Wait()
+
+	// The first request starts immediately, the other three wait one interval each
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("expected at least 60ms for 4 requests, got %v", elapsed)
+	}
+}
+
+func TestRateLimiter_Disabled(t *testing.T) {
+	for _, l := range []*rateLimiter{nil, newRateLimiter(0)} {
+		start := time.Now()
+		for i := 0; i < 100; i++ {
+			l.wait()
+		}
+		if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+			t.Errorf("expected no waiting, took %v", elapsed)
+		}
+	}
+}
+
+func TestDo_RetriesServerErrors(t *testing.T) {
+	var mu sync.Mutex
+	creates := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			w.Write([]byte(`[]`))
+			return
+		}
+		mu.Lock()
+		creates++
+		n := creates
+		mu.Unlock()
+
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), `"title":"Test"`) {
+			t.Errorf("attempt %d: expected the request body to be resent, got %q", n, body)
+		}
+		if n <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	n := NewForgejo(server.URL, "user/repo", "testtoken", WithRateLimit(0), WithRetry(3, time.Millisecond))
+	if err := n.CreateOrUpdateIssue("Test", "Body"); err != nil {
+		t.Fatalf("expected success after retries, got %v", err)
+	}
+	if creates != 3 {
+		t.Errorf("expected 3 create attempts, got %d", creates)
+	}
+}
+
+func TestDo_DoesNotRetryClientErrors(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	n := NewForgejo(server.URL, "user/repo", "testtoken", WithRateLimit(0), WithRetry(3, time.Millisecond))
+	if err := n.createIssue("Test", "Body"); err == nil {
+		t.Fatal("expected error for 403")
+	}
+	if requests != 1 {
+		t.Errorf("expected 1 request, got %d", requests)
+	}
+}
+
+func TestDo_GivesUpAfterMaxRetries(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	n := NewForgejo(server.URL, "user/repo", "testtoken", WithRateLimit(0), WithRetry(2, time.Millisecond))
+	if err := n.createIssue("Test", "Body"); err == nil {
+		t.Fatal("expected error once retries are exhausted")
+	}
+	if requests != 3 {
+		t.Errorf("expected 3 requests, got %d", requests)
+	}
+}
+
+func TestDo_DoesNotRetryPostServerErrors(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	// The issue may have been created before the proxy failed, so a retry
+	// could create a duplicate
+	n := NewForgejo(server.URL, "user/repo", "testtoken", WithRateLimit(0), WithRetry(3, time.Millisecond))
+	if err := n.createIssue("Test", "Body"); err == nil {
+		t.Fatal("expected error for 502")
+	}
+	if requests != 1 {
+		t.Errorf("expected 1 request, got %d", requests)
+	}
+}
+
+func TestDo_RetriesGetServerErrors(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	n := NewForgejo(server.URL, "user/repo", "testtoken", WithRateLimit(0), WithRetry(3, time.Millisecond))
+	if _, err := n.findIssue("Test"); err != nil {
+		t.Fatalf("expected success after a retry, got %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("expected 2 requests, got %d", requests)
+	}
+}
+
+func TestDo_CancelStopsRetryWait(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	n := NewForgejo(server.URL, "user/repo", "testtoken", WithRateLimit(0), WithContext(ctx))
+	start := time.Now()
+	_, err := n.findIssue("Test")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the wait to end on cancel, took %v", elapsed)
+	}
+}
+
+func TestRetryableStatus(t *testing.T) {
+	tests := []struct {
+		method   string
+		code     int
+		expected bool
+	}{
+		{http.MethodGet, http.StatusTooManyRequests, true},
+		{http.MethodGet, http.StatusInternalServerError, true},
+		{http.MethodPatch, http.StatusBadGateway, true},
+		{http.MethodGet, http.StatusNotFound, false},
+		{http.MethodPost, http.StatusTooManyRequests, true},
+		{http.MethodPost, http.StatusServiceUnavailable, true},
+		{http.MethodPost, http.StatusInternalServerError, false},
+		{http.MethodPost, http.StatusGatewayTimeout, false},
+	}
+	for _, tt := range tests {
+		if got := retryableStatus(tt.method, tt.code); got != tt.expected {
+			t.Errorf("%s %d: expected %v, got %v", tt.method, tt.code, tt.expected, got)
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		retryAfter string
+		attempt    int
+		want       time.Duration
+	}{
+		{"first backoff", "", 0, time.Second},
+		{"doubles", "", 2, 4 * time.Second},
+		{"capped backoff", "", 40, maxRetryDelay},
+		{"retry-after seconds", "5", 0, 5 * time.Second},
+		{"retry-after zero", "0", 2, 0},
+		{"retry-after capped", "3600", 0, maxRetryDelay},
+		{"retry-after date", now.Add(7 * time.Second).Format(http.TimeFormat), 0, 7 * time.Second},
+		{"retry-after past date", now.Add(-time.Minute).Format(http.TimeFormat), 0, 0},
+		{"retry-after invalid", "soon", 1, 2 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := retryDelay(tt.retryAfter, time.Second, tt.attempt, now)
+			if got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
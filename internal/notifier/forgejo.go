@@ -2,91 +2,412 @@ package notifier
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"regexp"
 	"strings"
 	"time"
 )
 
+// urlCredentialsPattern matches the userinfo part of a URL (scheme://user:pass@)
+var urlCredentialsPattern = regexp.MustCompile(`([a-zA-Z][a-zA-Z0-9+.-]*://)[^/@\s]+@`)
+
 // ForgejoNotifier sends notifications via Forgejo issues
 type ForgejoNotifier struct {
-	baseURL  string
-	repo     string
-	token    string
-	assignee string
-	client   *http.Client
+	baseURL   string
+	repo      string
+	token     string
+	assignees []string
+	labels    []int64
+	client    *http.Client
+	limiter   *rateLimiter
+	timeout   time.Duration
+	mode      UpdateMode
+
+	// reopenClosed makes a failure with no open issue reopen the matching
+	// issue closed within reopenWindow instead of creating a new one
+	reopenClosed bool
+
+	maxRetries   int
+	retryBackoff time.Duration
+
+	// ctx cancels in-flight requests and retry waits
+	ctx context.Context
+}
+
+// UpdateMode controls how a repeat failure updates an existing open issue
+type UpdateMode string
+
+const (
+	// UpdateComment adds a timestamped comment per failure (default)
+	UpdateComment UpdateMode = "comment"
+	// UpdateBody rewrites the issue body with the last maxBodyFailures failures
+	UpdateBody UpdateMode = "body"
+)
+
+// maxBodyFailures is how many failures UpdateBody keeps in the issue body
+const maxBodyFailures = 10
+
+// reopenWindow is how recently a matching issue must have been closed to be
+// reopened rather than replaced by a new issue
+const reopenWindow = 30 * 24 * time.Hour
+
+// issuesPageSize is how many issues listIssues requests per page
+const issuesPageSize = 50
+
+// failureSeparator separates failure entries in an issue body written with UpdateBody
+const failureSeparator = "\n\n<!-- duplicaci:failure -->\n\n"
+
+// Option customizes a ForgejoNotifier created by NewForgejo
+type Option func(*ForgejoNotifier)
+
+// WithAssignee assigns created issues to username
+func WithAssignee(username string) Option {
+	return WithAssignees(username)
+}
+
+// WithAssignees assigns created issues to every user in usernames; empty and
+// repeated names are ignored
+func WithAssignees(usernames ...string) Option {
+	return func(f *ForgejoNotifier) {
+		f.SetAssignees(usernames...)
+	}
 }
 
-// NewForgejo creates a new Forgejo notifier
-func NewForgejo(baseURL, repo, token string) *ForgejoNotifier {
-	return &ForgejoNotifier{
+// WithLabels adds the labels with these IDs to created issues
+func WithLabels(ids ...int64) Option {
+	return func(f *ForgejoNotifier) {
+		f.labels = append(f.labels, ids...)
+	}
+}
+
+// WithHTTPClient makes the notifier send requests with client instead of the
+// shared pooled client (e.g., for tests or custom proxy/TLS settings)
+func WithHTTPClient(client *http.Client) Option {
+	return func(f *ForgejoNotifier) {
+		f.client = client
+	}
+}
+
+// WithTimeout sets the timeout for each API request (default: 30s)
+func WithTimeout(d time.Duration) Option {
+	return func(f *ForgejoNotifier) {
+		f.timeout = d
+	}
+}
+
+// WithUpdateMode sets how repeat failures update an existing issue
+func WithUpdateMode(mode UpdateMode) Option {
+	return func(f *ForgejoNotifier) {
+		f.mode = mode
+	}
+}
+
+// WithReopenClosed sets whether a failure reopens a recently closed issue with
+// the same title (the default) instead of creating a new one
+func WithReopenClosed(reopen bool) Option {
+	return func(f *ForgejoNotifier) {
+		f.reopenClosed = reopen
+	}
+}
+
+// WithRateLimit gives the notifier its own limiter allowing one request per
+// interval instead of the shared one (0 disables rate limiting)
+func WithRateLimit(interval time.Duration) Option {
+	return func(f *ForgejoNotifier) {
+		f.limiter = newRateLimiter(interval)
+	}
+}
+
+// WithRetry sets how many times a 429 or 5xx response (only 429 or 503 for a
+// POST) is retried and the delay before the first retry, which doubles per
+// attempt (0 retries disables retrying)
+func WithRetry(maxRetries int, backoff time.Duration) Option {
+	return func(f *ForgejoNotifier) {
+		f.maxRetries = maxRetries
+		f.retryBackoff = backoff
+	}
+}
+
+// WithContext makes requests and the waits between retries stop when ctx is
+// cancelled (default: never)
+func WithContext(ctx context.Context) Option {
+	return func(f *ForgejoNotifier) {
+		f.ctx = ctx
+	}
+}
+
+// NewForgejo creates a new Forgejo notifier. By default it uses a client and
+// rate limiter shared by all notifiers; opts can override either.
+func NewForgejo(baseURL, repo, token string, opts ...Option) *ForgejoNotifier {
+	f := &ForgejoNotifier{
 		baseURL: strings.TrimSuffix(baseURL, "/"),
 		repo:    repo,
 		token:   token,
-		client:  &http.Client{Timeout: 30 * time.Second},
+		client:  sharedClient,
+		limiter: sharedLimiter,
+
+		reopenClosed: true,
+		maxRetries:   defaultMaxRetries,
+		retryBackoff: defaultRetryBackoff,
+		ctx:          context.Background(),
 	}
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	// Apply the timeout to a copy so the shared or injected client is left untouched
+	if f.timeout > 0 {
+		client := *f.client
+		client.Timeout = f.timeout
+		f.client = &client
+	}
+	return f
 }
 
 // SetAssignee sets the user to assign issues to
 func (f *ForgejoNotifier) SetAssignee(username string) {
-	f.assignee = username
+	f.SetAssignees(username)
+}
+
+// SetAssignees sets the users to assign issues to, replacing any set before
+func (f *ForgejoNotifier) SetAssignees(usernames ...string) {
+	f.assignees = nil
+	seen := make(map[string]bool)
+	for _, name := range usernames {
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		f.assignees = append(f.assignees, name)
+	}
 }
 
-// CreateOrUpdateIssue creates a new issue or adds a comment to an existing one
+// CreateOrUpdateIssue creates a new issue or updates an existing one, by adding
+// a comment or, with UpdateBody, by rolling the failure into the issue body.
+// Returned errors never contain the API token or URL credentials.
 func (f *ForgejoNotifier) CreateOrUpdateIssue(title, body string) error {
 	// Check for existing open issue with same title
-	existingID, err := f.findExistingIssue(title)
+	existing, err := f.findIssue(title)
 	if err != nil {
-		return fmt.Errorf("failed to search for existing issues: %w", err)
+		return f.redactError(fmt.Errorf("failed to search for existing issues: %w", err))
 	}
 
-	if existingID > 0 {
+	// Keep the history in one place by reopening a recently closed issue
+	if existing.ID == 0 && f.reopenClosed {
+		closed, err := f.findClosedIssue(title, time.Now())
+		if err != nil {
+			return f.redactError(fmt.Errorf("failed to search for closed issues: %w", err))
+		}
+		if closed.ID > 0 {
+			if err := f.reopenIssue(closed.ID); err != nil {
+				return f.redactError(err)
+			}
+			existing = closed
+		}
+	}
+
+	if f.mode == UpdateBody {
+		entry := failureEntry(time.Now(), body)
+		if existing.ID > 0 {
+			return f.redactError(f.editIssueBody(existing.ID, rollUpFailures(existing.Body, entry)))
+		}
+		return f.redactError(f.createIssue(title, entry))
+	}
+
+	if existing.ID > 0 {
 		// Add comment to existing issue
-		return f.addComment(existingID, body)
+		return f.redactError(f.addComment(existing.ID, body))
 	}
 
 	// Create new issue
-	return f.createIssue(title, body)
+	return f.redactError(f.createIssue(title, body))
 }
 
-func (f *ForgejoNotifier) findExistingIssue(title string) (int, error) {
-	url := fmt.Sprintf("%s/api/v1/repos/%s/issues?state=open&type=issues", f.baseURL, f.repo)
+// failureEntry formats one failure for an issue body written with UpdateBody
+func failureEntry(at time.Time, body string) string {
+	return fmt.Sprintf("**Failure %s**\n\n%s", at.Format("2006-01-02 15:04:05 MST"), body)
+}
 
-	req, err := http.NewRequest("GET", url, nil)
+// rollUpFailures puts entry in front of the failures already in an issue body
+// and drops all but the newest maxBodyFailures. A body not written with
+// UpdateBody is kept as a single older entry.
+func rollUpFailures(existing, entry string) string {
+	entries := []string{entry}
+	if strings.TrimSpace(existing) != "" {
+		entries = append(entries, strings.Split(existing, failureSeparator)...)
+	}
+	if len(entries) > maxBodyFailures {
+		entries = entries[:maxBodyFailures]
+	}
+	return strings.Join(entries, failureSeparator)
+}
+
+// redactError masks the token and URL credentials in an error message
+func (f *ForgejoNotifier) redactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := redactToken(err.Error(), f.token)
+	if msg == err.Error() {
+		return err
+	}
+	return errors.New(msg)
+}
+
+// redactToken masks every occurrence of token, and any credentials embedded in URLs, in s
+func redactToken(s, token string) string {
+	if token != "" {
+		s = strings.ReplaceAll(s, token, "***")
+	}
+	return urlCredentialsPattern.ReplaceAllString(s, "${1}***@")
+}
+
+// issue is the part of an API issue the notifier uses
+type issue struct {
+	ID       int        `json:"number"`
+	Title    string     `json:"title"`
+	Body     string     `json:"body"`
+	ClosedAt *time.Time `json:"closed_at"`
+}
+
+// findIssue returns the open issue with this title (ID 0 if there is none)
+func (f *ForgejoNotifier) findIssue(title string) (issue, error) {
+	issues, err := f.listIssues("open", title)
+	if err != nil {
+		return issue{}, err
+	}
+
+	for _, i := range issues {
+		if i.Title == title {
+			return i, nil
+		}
+	}
+
+	return issue{}, nil
+}
+
+// findClosedIssue returns the most recently closed issue with this title,
+// if it was closed within reopenWindow of now (ID 0 otherwise)
+func (f *ForgejoNotifier) findClosedIssue(title string, now time.Time) (issue, error) {
+	issues, err := f.listIssues("closed", title)
+	if err != nil {
+		return issue{}, err
+	}
+
+	var latest issue
+	for _, i := range issues {
+		if i.Title != title || i.ClosedAt == nil || now.Sub(*i.ClosedAt) > reopenWindow {
+			continue
+		}
+		if latest.ID == 0 || i.ClosedAt.After(*latest.ClosedAt) {
+			latest = i
+		}
+	}
+	return latest, nil
+}
+
+// listIssues returns the repository's issues (not pull requests) in state
+// that match the keyword search for title, following pages until a short one
+func (f *ForgejoNotifier) listIssues(state, title string) ([]issue, error) {
+	var issues []issue
+	for page := 1; ; page++ {
+		batch, err := f.listIssuesPage(state, title, page)
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, batch...)
+		if len(batch) < issuesPageSize {
+			return issues, nil
+		}
+	}
+}
+
+// listIssuesPage returns one page of listIssues
+func (f *ForgejoNotifier) listIssuesPage(state, title string, page int) ([]issue, error) {
+	query := url.Values{
+		"state": {state},
+		"type":  {"issues"},
+		"q":     {title},
+		"limit": {fmt.Sprint(issuesPageSize)},
+		"page":  {fmt.Sprint(page)},
+	}
+	endpoint := fmt.Sprintf("%s/api/v1/repos/%s/issues?%s", f.baseURL, f.repo, query.Encode())
+
+	req, err := http.NewRequestWithContext(f.ctx, "GET", endpoint, nil)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 	req.Header.Set("Authorization", "token "+f.token)
 
-	resp, err := f.client.Do(req)
+	resp, err := f.do(req)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return 0, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
 	}
 
-	var issues []struct {
-		ID    int    `json:"number"`
-		Title string `json:"title"`
+	var issues []issue
+	if err := json.NewDecoder(resp.Body).Decode(&issues); err != nil {
+		return nil, err
 	}
+	return issues, nil
+}
 
-	if err := json.NewDecoder(resp.Body).Decode(&issues); err != nil {
-		return 0, err
+// editIssueBody replaces the body of an existing issue
+func (f *ForgejoNotifier) editIssueBody(issueID int, body string) error {
+	if err := f.patchIssue(issueID, map[string]string{"body": body}); err != nil {
+		return err
 	}
+	fmt.Printf("    Updated issue #%d\n", issueID)
+	return nil
+}
 
-	for _, issue := range issues {
-		if issue.Title == title {
-			return issue.ID, nil
-		}
+// reopenIssue sets a closed issue's state back to open
+func (f *ForgejoNotifier) reopenIssue(issueID int) error {
+	if err := f.patchIssue(issueID, map[string]string{"state": "open"}); err != nil {
+		return fmt.Errorf("failed to reopen issue #%d: %w", issueID, err)
+	}
+	fmt.Printf("    Reopened issue #%d\n", issueID)
+	return nil
+}
+
+// patchIssue edits the given fields of an existing issue
+func (f *ForgejoNotifier) patchIssue(issueID int, fields map[string]string) error {
+	url := fmt.Sprintf("%s/api/v1/repos/%s/issues/%d", f.baseURL, f.repo, issueID)
+
+	jsonData, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(f.ctx, "PATCH", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return err
 	}
+	req.Header.Set("Authorization", "token "+f.token)
+	req.Header.Set("Content-Type", "application/json")
 
-	return 0, nil
+	resp, err := f.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+	}
+	return nil
 }
 
 func (f *ForgejoNotifier) createIssue(title, body string) error {
@@ -97,8 +418,11 @@ func (f *ForgejoNotifier) createIssue(title, body string) error {
 		"body":  body,
 	}
 
-	if f.assignee != "" {
-		payload["assignees"] = []string{f.assignee}
+	if len(f.assignees) > 0 {
+		payload["assignees"] = f.assignees
+	}
+	if len(f.labels) > 0 {
+		payload["labels"] = f.labels
 	}
 
 	jsonData, err := json.Marshal(payload)
@@ -106,14 +430,14 @@ func (f *ForgejoNotifier) createIssue(title, body string) error {
 		return err
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(f.ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return err
 	}
 	req.Header.Set("Authorization", "token "+f.token)
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := f.client.Do(req)
+	resp, err := f.do(req)
 	if err != nil {
 		return err
 	}
@@ -128,7 +452,7 @@ func (f *ForgejoNotifier) createIssue(title, body string) error {
 		HTMLURL string `json:"html_url"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err == nil && result.HTMLURL != "" {
-		fmt.Printf("    Created issue: %s\n", result.HTMLURL)
+		fmt.Printf("    Created issue: %s\n", redactToken(result.HTMLURL, f.token))
 	}
 
 	return nil
@@ -149,14 +473,14 @@ func (f *ForgejoNotifier) addComment(issueID int, body string) error {
 		return err
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(f.ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return err
 	}
 	req.Header.Set("Authorization", "token "+f.token)
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := f.client.Do(req)
+	resp, err := f.do(req)
 	if err != nil {
 		return err
 	}
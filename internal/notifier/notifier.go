@@ -0,0 +1,36 @@
+package notifier
+
+import "sync"
+
+// Notifier reports failures, deduplicating by title where the backend supports it
+type Notifier interface {
+	CreateOrUpdateIssue(title, body string) error
+}
+
+// Notification is one call recorded by NullNotifier
+type Notification struct {
+	Title string
+	Body  string
+}
+
+// NullNotifier records notifications without sending them anywhere. It is used
+// when notifications are disabled and in tests.
+type NullNotifier struct {
+	mu    sync.Mutex
+	calls []Notification
+}
+
+// CreateOrUpdateIssue records the notification and always succeeds
+func (n *NullNotifier) CreateOrUpdateIssue(title, body string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.calls = append(n.calls, Notification{Title: title, Body: body})
+	return nil
+}
+
+// Calls returns the notifications recorded so far
+func (n *NullNotifier) Calls() []Notification {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]Notification(nil), n.calls...)
+}
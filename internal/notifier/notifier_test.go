@@ -0,0 +1,22 @@
+package notifier
+
+import "testing"
+
+func TestNullNotifier_RecordsCalls(t *testing.T) {
+	n := &NullNotifier{}
+
+	if err := n.CreateOrUpdateIssue("first", "body 1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := n.CreateOrUpdateIssue("second", "body 2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	calls := n.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 calls, got %d", len(calls))
+	}
+	if calls[0].Title != "first" || calls[1].Body != "body 2" {
+		t.Errorf("unexpected calls: %+v", calls)
+	}
+}
@@ -0,0 +1,46 @@
+package notifier
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultTitleTemplate renders the built-in failure issue title
+const DefaultTitleTemplate = `[duplicaci] {{if .FailedBackups}}{{.FailedBackups}}: backup failed{{else}}maintenance failed{{end}}`
+
+// TitleData is the data available to a title template
+type TitleData struct {
+	FailedBackups string    // comma-separated names of the failed backups; empty for maintenance-only failures
+	Host          string    // host being backed up
+	Timestamp     time.Time // time of the failure
+}
+
+// NewTitleData builds title data for the given failed backups
+func NewTitleData(failedBackups []string, host string, at time.Time) TitleData {
+	return TitleData{
+		FailedBackups: strings.Join(failedBackups, ", "),
+		Host:          host,
+		Timestamp:     at,
+	}
+}
+
+// RenderTitle executes a Go template for an issue title; an empty template uses
+// DefaultTitleTemplate. Issues are deduplicated by exact title, so a template
+// that includes the timestamp opens a new issue for every failure.
+func RenderTitle(tmpl string, data TitleData) (string, error) {
+	if tmpl == "" {
+		tmpl = DefaultTitleTemplate
+	}
+	t, err := template.New("title").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid title template: %w", err)
+	}
+
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("invalid title template: %w", err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
@@ -0,0 +1,49 @@
+package notifier
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderTitle(t *testing.T) {
+	at := time.Date(2024, 1, 15, 3, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		tmpl   string
+		failed []string
+		want   string
+	}{
+		{"default", "", []string{"appdata", "photos"}, "[duplicaci] appdata, photos: backup failed"},
+		{"default maintenance", "", nil, "[duplicaci] maintenance failed"},
+		{"environment prefix", "[prod]" + DefaultTitleTemplate, []string{"appdata"}, "[prod][duplicaci] appdata: backup failed"},
+		{"host", "[{{.Host}}] {{.FailedBackups}} failed", []string{"appdata"}, "[nas01] appdata failed"},
+		{"timestamp", `{{.FailedBackups}} failed on {{.Timestamp.Format "2006-01-02"}}`, []string{"appdata"}, "appdata failed on 2024-01-15"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderTitle(tt.tmpl, NewTitleData(tt.failed, "nas01", at))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRenderTitle_Invalid(t *testing.T) {
+	tests := []string{
+		"{{.FailedBackups",
+		"{{.Environment}}",
+	}
+
+	for _, tmpl := range tests {
+		_, err := RenderTitle(tmpl, TitleData{})
+		if err == nil || !strings.Contains(err.Error(), "invalid title template") {
+			t.Errorf("%q: expected invalid template error, got %v", tmpl, err)
+		}
+	}
+}
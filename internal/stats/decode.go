@@ -0,0 +1,242 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Stats files written by other Web UI versions can use other number types
+// (floats, numeric or size strings) or carry fields DayStats does not model.
+// Entries are decoded leniently so such files are read rather than reset, and
+// unknown fields are kept in Extra and written back unchanged.
+
+// Size formats for the sizes written to a stats file
+const (
+	SizeFormatBytes = "bytes" // Integer bytes (default)
+	SizeFormatHuman = "human" // Strings like "1.5 GB", as some Web UI versions store them; rounded to 0.1 of the unit
+)
+
+// UnmarshalJSON decodes a stats entry, accepting numbers in any form and
+// keeping unknown fields in Extra
+func (d *DayStats) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	*d = DayStats{}
+	for key, raw := range fields {
+		var err error
+		switch key {
+		case "total-size":
+			d.TotalSize, err = decodeInt64(raw)
+		case "total-chunks":
+			d.TotalChunks, err = decodeInt(raw)
+		case "pruned-chunks":
+			d.PrunedChunks, err = decodeInt(raw)
+		case "pruned-revisions":
+			d.PrunedRevisions, err = decodeInt(raw)
+		case "missing-chunks":
+			d.MissingChunks, err = decodeInt(raw)
+		case "corrupt-chunks":
+			d.CorruptChunks, err = decodeInt(raw)
+		case "status":
+			err = json.Unmarshal(raw, &d.Status)
+		case "generated-by":
+			err = json.Unmarshal(raw, &d.GeneratedBy)
+		case "repositories":
+			err = json.Unmarshal(raw, &d.Repositories)
+		default:
+			if d.Extra == nil {
+				d.Extra = make(map[string]json.RawMessage)
+			}
+			d.Extra[key] = raw
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// MarshalJSON encodes a stats entry with sizes in bytes and its Extra fields
+// after the known ones
+func (d DayStats) MarshalJSON() ([]byte, error) {
+	return d.marshal(SizeFormatBytes)
+}
+
+// marshal encodes a stats entry with sizes in the given format
+func (d DayStats) marshal(format string) ([]byte, error) {
+	if format != SizeFormatHuman {
+		type plain DayStats // without methods, so this does not recurse
+		return marshalWithExtra(plain(d), d.Extra)
+	}
+
+	var repos map[string]json.RawMessage
+	if d.Repositories != nil {
+		repos = make(map[string]json.RawMessage, len(d.Repositories))
+		for id, repo := range d.Repositories {
+			data, err := repo.marshal(format)
+			if err != nil {
+				return nil, err
+			}
+			repos[id] = data
+		}
+	}
+	return marshalWithExtra(struct {
+		TotalSize       string                     `json:"total-size"`
+		TotalChunks     int                        `json:"total-chunks"`
+		PrunedChunks    int                        `json:"pruned-chunks"`
+		PrunedRevisions int                        `json:"pruned-revisions"`
+		Status          string                     `json:"status"`
+		Repositories    map[string]json.RawMessage `json:"repositories"`
+		MissingChunks   int                        `json:"missing-chunks,omitempty"`
+		CorruptChunks   int                        `json:"corrupt-chunks,omitempty"`
+		GeneratedBy     string                     `json:"generated-by,omitempty"`
+	}{
+		TotalSize:       FormatBytes(d.TotalSize),
+		TotalChunks:     d.TotalChunks,
+		PrunedChunks:    d.PrunedChunks,
+		PrunedRevisions: d.PrunedRevisions,
+		Status:          d.Status,
+		Repositories:    repos,
+		MissingChunks:   d.MissingChunks,
+		CorruptChunks:   d.CorruptChunks,
+		GeneratedBy:     d.GeneratedBy,
+	}, d.Extra)
+}
+
+// UnmarshalJSON decodes a repository's stats like DayStats.UnmarshalJSON
+func (r *RepoStats) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	*r = RepoStats{}
+	for key, raw := range fields {
+		var err error
+		switch key {
+		case "revisions":
+			r.Revisions, err = decodeInt(raw)
+		case "total-size":
+			r.TotalSize, err = decodeInt64(raw)
+		case "unique-size":
+			r.UniqueSize, err = decodeInt64(raw)
+		case "total-chunks":
+			r.TotalChunks, err = decodeInt(raw)
+		default:
+			if r.Extra == nil {
+				r.Extra = make(map[string]json.RawMessage)
+			}
+			r.Extra[key] = raw
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// MarshalJSON encodes a repository's stats like DayStats.MarshalJSON
+func (r RepoStats) MarshalJSON() ([]byte, error) {
+	return r.marshal(SizeFormatBytes)
+}
+
+// marshal encodes a repository's stats with sizes in the given format
+func (r RepoStats) marshal(format string) ([]byte, error) {
+	if format != SizeFormatHuman {
+		type plain RepoStats
+		return marshalWithExtra(plain(r), r.Extra)
+	}
+	return marshalWithExtra(struct {
+		Revisions   int    `json:"revisions"`
+		TotalSize   string `json:"total-size"`
+		UniqueSize  string `json:"unique-size"`
+		TotalChunks int    `json:"total-chunks"`
+	}{
+		Revisions:   r.Revisions,
+		TotalSize:   FormatBytes(r.TotalSize),
+		UniqueSize:  FormatBytes(r.UniqueSize),
+		TotalChunks: r.TotalChunks,
+	}, r.Extra)
+}
+
+// formattedDayStats marshals a stats entry with sizes in a given format
+type formattedDayStats struct {
+	day    *DayStats
+	format string
+}
+
+func (f formattedDayStats) MarshalJSON() ([]byte, error) {
+	if f.day == nil {
+		return []byte("null"), nil
+	}
+	return f.day.marshal(f.format)
+}
+
+// marshalWithExtra encodes v, a struct, and appends the extra fields in key order
+func marshalWithExtra(v interface{}, extra map[string]json.RawMessage) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || len(extra) == 0 {
+		return data, err
+	}
+
+	keys := make([]string, 0, len(extra))
+	for key := range extra {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	buf.Write(data[:len(data)-1]) // drop the closing brace
+	for i, key := range keys {
+		if i > 0 || len(data) > 2 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(extra[key])
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// decodeInt64 decodes a JSON number, a float (rounded), null (0), or a string
+// holding a number or a size such as "4,617M" or "1.5 GB"
+func decodeInt64(raw json.RawMessage) (int64, error) {
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return 0, err
+	}
+
+	switch v := v.(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		if v < math.MinInt64 || v >= math.MaxInt64 {
+			return 0, fmt.Errorf("number %v overflows int64", v)
+		}
+		return int64(math.Round(v)), nil
+	case string:
+		// FormatBytes-style "1.5 GB" is parseSize's "1.5G"
+		s := strings.ReplaceAll(v, " ", "")
+		if n := len(s); n >= 2 && strings.ToUpper(s[n-1:]) == "B" && strings.ContainsAny(strings.ToUpper(s[n-2:n-1]), "KMGTPE") {
+			s = s[:n-1]
+		}
+		return parseSize(s)
+	default:
+		return 0, fmt.Errorf("expected a number, got %s", string(raw))
+	}
+}
+
+// decodeInt is decodeInt64 for int fields
+func decodeInt(raw json.RawMessage) (int, error) {
+	n, err := decodeInt64(raw)
+	return int(n), err
+}
@@ -189,8 +189,7 @@ func TestIntegration_StatsWriterFullWorkflow(t *testing.T) {
 	writer.Verbose = true
 
 	// Ensure stats directory exists
-	mkdirCmd := writer.buildDockerCommand("mkdir -p /config/stats/storages")
-	if err := writer.execute(mkdirCmd); err != nil {
+	if err := writer.EnsureStatsDir(); err != nil {
 		t.Fatalf("failed to create stats directory: %v", err)
 	}
 
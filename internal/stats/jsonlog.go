@@ -0,0 +1,46 @@
+package stats
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// LogEvent is one entry of a structured duplicacy log stream (one JSON object per line)
+type LogEvent struct {
+	Time    string `json:"time"`
+	Level   string `json:"level"`
+	ID      string `json:"id"`      // Log ID, e.g. SNAPSHOT_CHECK
+	Message string `json:"message"` // May span several lines (e.g., the tabular rows)
+}
+
+// ParseJSONCheckLog parses the JSON log stream of a check -tabular run and
+// returns DayStats. Event messages carry the same text as the plain output, so
+// they are parsed with ParseCheckOutput. Lines that are not JSON objects (e.g.,
+// stray stderr) are skipped; a stream with no events is an error.
+func ParseJSONCheckLog(r io.Reader) (*DayStats, error) {
+	var messages []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+		var event LogEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			continue
+		}
+		messages = append(messages, event.Message)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read JSON log: %w", err)
+	}
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("no JSON log events found in check output")
+	}
+
+	return ParseCheckOutput(strings.Join(messages, "\n"))
+}
@@ -0,0 +1,57 @@
+package stats
+
+import (
+	"strings"
+	"testing"
+)
+
+// sampleJSONCheckLog is a check -tabular run as a JSON event stream, with a stray stderr line
+const sampleJSONCheckLog = `{"time":"2025-12-29 01:02:40.001","level":"INFO","id":"STORAGE_SET","message":"Storage set to /mnt/backups"}
+{"time":"2025-12-29 01:02:45.064","level":"INFO","id":"SNAPSHOT_CHECK","message":"Total chunk size is 4,617M in 975 chunks"}
+ssh: warning: something on stderr
+{"time":"2025-12-29 01:02:45.065","level":"INFO","id":"SNAPSHOT_CHECK","message":"    snap | rev |                          | files |    bytes | chunks |    bytes | uniq |    bytes | new |    bytes |\n appdata |   1 | @ 2025-10-13 20:36 -hash |     9 |     826K |      4 |     672K |    4 |     672K |   4 |     672K |\n appdata |   2 | @ 2025-10-14 06:00 -hash |     9 |     826K |      4 |     672K |    0 |        0 |   0 |        0 |\n appdata | all |                          |       |          |    975 |   4,617M |  975 |   4,617M |     |          |"}
+{"time":"2025-12-29 01:02:45.066","level":"WARN","id":"SNAPSHOT_CHECK","message":"Chunk 1a2b3c referenced by snapshot appdata at revision 2 does not exist"}
+`
+
+func TestParseJSONCheckLog(t *testing.T) {
+	stats, err := ParseJSONCheckLog(strings.NewReader(sampleJSONCheckLog))
+	if err != nil {
+		t.Fatalf("ParseJSONCheckLog() error: %v", err)
+	}
+
+	if stats.TotalChunks != 975 {
+		t.Errorf("expected 975 total chunks, got %d", stats.TotalChunks)
+	}
+	if stats.TotalSize != 4617*1024*1024 {
+		t.Errorf("expected total size %d, got %d", int64(4617*1024*1024), stats.TotalSize)
+	}
+	repo, ok := stats.Repositories["appdata"]
+	if !ok {
+		t.Fatalf("expected appdata repository, got %v", stats.Repositories)
+	}
+	if repo.Revisions != 2 {
+		t.Errorf("expected 2 revisions, got %d", repo.Revisions)
+	}
+	if stats.MissingChunks != 1 || stats.Status != StatusErrors {
+		t.Errorf("expected 1 missing chunk with status %q, got %d, %q", StatusErrors, stats.MissingChunks, stats.Status)
+	}
+}
+
+func TestParseJSONCheckLog_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"empty", ""},
+		{"tabular text", "Total chunk size is 4,617M in 975 chunks\n"},
+		{"events without stats", `{"level":"INFO","id":"STORAGE_SET","message":"Storage set to /mnt/backups"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseJSONCheckLog(strings.NewReader(tt.input)); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package stats
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// metric is one gauge in the Prometheus exposition
+type metric struct {
+	name string
+	help string
+}
+
+// Storage-level gauges, from the latest entry of each storage
+var storageMetrics = []struct {
+	metric
+	value func(date string, day *DayStats) float64
+}{
+	{metric{"duplicaci_storage_size_bytes", "Total storage size on the latest check."},
+		func(_ string, d *DayStats) float64 { return float64(d.TotalSize) }},
+	{metric{"duplicaci_storage_chunks", "Total chunks on the latest check."},
+		func(_ string, d *DayStats) float64 { return float64(d.TotalChunks) }},
+	{metric{"duplicaci_storage_missing_chunks", "Missing chunks reported by the latest check."},
+		func(_ string, d *DayStats) float64 { return float64(d.MissingChunks) }},
+	{metric{"duplicaci_storage_corrupt_chunks", "Corrupt chunks reported by the latest check."},
+		func(_ string, d *DayStats) float64 { return float64(d.CorruptChunks) }},
+	{metric{"duplicaci_storage_check_ok", "1 if the latest check passed, else 0."},
+		func(_ string, d *DayStats) float64 {
+			if d.Status == StatusChecked {
+				return 1
+			}
+			return 0
+		}},
+	{metric{"duplicaci_storage_last_check_timestamp_seconds", "Date of the latest check (midnight UTC) as a Unix timestamp."},
+		func(date string, _ *DayStats) float64 {
+			t, _ := ParseDate(date) // Latest only returns parsable dates
+			return float64(t.Unix())
+		}},
+}
+
+// Repository-level gauges, from the latest entry of each storage
+var repositoryMetrics = []struct {
+	metric
+	value func(r RepoStats) float64
+}{
+	{metric{"duplicaci_repository_revisions", "Revisions of the snapshot ID on the latest check."},
+		func(r RepoStats) float64 { return float64(r.Revisions) }},
+	{metric{"duplicaci_repository_size_bytes", "Total size of the snapshot ID on the latest check."},
+		func(r RepoStats) float64 { return float64(r.TotalSize) }},
+	{metric{"duplicaci_repository_unique_size_bytes", "Size of chunks only the snapshot ID references."},
+		func(r RepoStats) float64 { return float64(r.UniqueSize) }},
+	{metric{"duplicaci_repository_chunks", "Chunks referenced by the snapshot ID on the latest check."},
+		func(r RepoStats) float64 { return float64(r.TotalChunks) }},
+}
+
+// WriteMetrics writes the latest entry of each storage's stats as gauges in
+// the Prometheus text exposition format. Storages without entries are omitted.
+func WriteMetrics(w io.Writer, all map[string]StorageStats) error {
+	type latest struct {
+		storage string
+		date    string
+		day     *DayStats
+	}
+	var entries []latest
+	for storage, s := range all {
+		date := s.Latest()
+		if date == "" || s[date] == nil {
+			continue
+		}
+		entries = append(entries, latest{storage, date, s[date]})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].storage < entries[j].storage })
+
+	bw := bufio.NewWriter(w)
+	for _, m := range storageMetrics {
+		writeHeader(bw, m.metric)
+		for _, e := range entries {
+			fmt.Fprintf(bw, "%s{storage=%s} %s\n", m.name, quoteLabel(e.storage), formatValue(m.value(e.date, e.day)))
+		}
+	}
+	for _, m := range repositoryMetrics {
+		writeHeader(bw, m.metric)
+		for _, e := range entries {
+			ids := make([]string, 0, len(e.day.Repositories))
+			for id := range e.day.Repositories {
+				ids = append(ids, id)
+			}
+			sort.Strings(ids)
+			for _, id := range ids {
+				fmt.Fprintf(bw, "%s{storage=%s,repository=%s} %s\n",
+					m.name, quoteLabel(e.storage), quoteLabel(id), formatValue(m.value(e.day.Repositories[id])))
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// writeHeader writes the HELP and TYPE lines of a gauge
+func writeHeader(w io.Writer, m metric) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", m.name, m.help, m.name)
+}
+
+// formatValue formats a sample value without an exponent
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// labelEscaper escapes a label value for the text exposition format
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// quoteLabel returns a quoted, escaped label value
+func quoteLabel(v string) string {
+	return `"` + labelEscaper.Replace(v) + `"`
+}
@@ -0,0 +1,52 @@
+package stats
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	all := map[string]StorageStats{
+		"NAS": {
+			"2025-01-01": {TotalSize: 1, Status: StatusChecked},
+			"2025-01-02": {
+				TotalSize:   2147483648,
+				TotalChunks: 92,
+				Status:      StatusChecked,
+				Repositories: map[string]RepoStats{
+					"appdata": {Revisions: 3, TotalSize: 1024, UniqueSize: 512, TotalChunks: 40},
+				},
+			},
+		},
+		`B"2`:   {"2025-01-02": {TotalSize: 5, Status: StatusErrors, MissingChunks: 2}},
+		"empty": {},
+	}
+
+	var out strings.Builder
+	if err := WriteMetrics(&out, all); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := out.String()
+
+	for _, line := range []string{
+		"# TYPE duplicaci_storage_size_bytes gauge",
+		`duplicaci_storage_size_bytes{storage="NAS"} 2147483648`,
+		`duplicaci_storage_chunks{storage="NAS"} 92`,
+		`duplicaci_storage_check_ok{storage="NAS"} 1`,
+		`duplicaci_storage_check_ok{storage="B\"2"} 0`,
+		`duplicaci_storage_missing_chunks{storage="B\"2"} 2`,
+		`duplicaci_storage_last_check_timestamp_seconds{storage="NAS"} 1735776000`,
+		`duplicaci_repository_revisions{storage="NAS",repository="appdata"} 3`,
+		`duplicaci_repository_unique_size_bytes{storage="NAS",repository="appdata"} 512`,
+	} {
+		if !strings.Contains(got, line+"\n") {
+			t.Errorf("expected line %q in:\n%s", line, got)
+		}
+	}
+	if strings.Contains(got, `storage="empty"`) {
+		t.Errorf("expected storage without entries to be omitted, got:\n%s", got)
+	}
+	if strings.Count(got, "# HELP duplicaci_storage_size_bytes") != 1 {
+		t.Errorf("expected one HELP line per metric, got:\n%s", got)
+	}
+}
@@ -0,0 +1,149 @@
+package stats
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DateLayout is the format of the date keys in a stats file
+const DateLayout = "2006-01-02"
+
+// Summary aggregates the entries of a StorageStats over a date range
+type Summary struct {
+	Days    int    // Number of dated entries
+	First   string // Earliest date
+	Last    string // Latest date
+	MinSize int64
+	MaxSize int64
+	AvgSize int64
+	Growth  int64 // Total size on the last date minus the first
+}
+
+// ParseDate parses a YYYY-MM-DD date
+func ParseDate(s string) (time.Time, error) {
+	t, err := time.Parse(DateLayout, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q (expected YYYY-MM-DD)", s)
+	}
+	return t, nil
+}
+
+// Dates returns the entry dates in ascending order, skipping keys that are not dates
+func (s StorageStats) Dates() []string {
+	var dates []string
+	for date := range s {
+		if _, err := ParseDate(date); err == nil {
+			dates = append(dates, date)
+		}
+	}
+	sort.Strings(dates)
+	return dates
+}
+
+// Latest returns the most recent entry date, or "" if there are none
+func (s StorageStats) Latest() string {
+	dates := s.Dates()
+	if len(dates) == 0 {
+		return ""
+	}
+	return dates[len(dates)-1]
+}
+
+// Between returns the entries dated within [since, until], inclusive.
+// A zero since or until leaves that side of the range open.
+func (s StorageStats) Between(since, until time.Time) StorageStats {
+	filtered := make(StorageStats)
+	for _, date := range s.Dates() {
+		t, _ := ParseDate(date)
+		if !since.IsZero() && t.Before(since) {
+			continue
+		}
+		if !until.IsZero() && t.After(until) {
+			continue
+		}
+		filtered[date] = s[date]
+	}
+	return filtered
+}
+
+// Before returns the latest entry dated strictly before date, or "" and nil if there is none
+func (s StorageStats) Before(date string) (string, *DayStats) {
+	dates := s.Dates()
+	for i := len(dates) - 1; i >= 0; i-- {
+		if dates[i] < date && s[dates[i]] != nil {
+			return dates[i], s[dates[i]]
+		}
+	}
+	return "", nil
+}
+
+// GrowthPercent returns the total size change from prev to cur as a percentage
+// of prev. ok is false when there is no usable prior size to compare against.
+func GrowthPercent(prev, cur *DayStats) (pct float64, ok bool) {
+	if prev == nil || cur == nil || prev.TotalSize <= 0 {
+		return 0, false
+	}
+	return float64(cur.TotalSize-prev.TotalSize) / float64(prev.TotalSize) * 100, true
+}
+
+// ChunkDelta is the change in chunk counts between two entries of a storage
+type ChunkDelta struct {
+	Since        string         `json:"since"`        // Date of the prior entry
+	Total        int            `json:"total"`        // Storage total chunks, current minus prior
+	Repositories map[string]int `json:"repositories"` // Per repository (snapshot ID), including ones new or gone since
+}
+
+// ChunkDeltas returns the chunk count changes from prev (dated since) to cur.
+// ok is false when there is no prior entry, as on the first run.
+func ChunkDeltas(since string, prev, cur *DayStats) (delta ChunkDelta, ok bool) {
+	if prev == nil || cur == nil {
+		return ChunkDelta{}, false
+	}
+	delta = ChunkDelta{
+		Since:        since,
+		Total:        cur.TotalChunks - prev.TotalChunks,
+		Repositories: make(map[string]int),
+	}
+	for name, repo := range cur.Repositories {
+		delta.Repositories[name] = repo.TotalChunks - prev.Repositories[name].TotalChunks
+	}
+	for name, repo := range prev.Repositories {
+		if _, ok := cur.Repositories[name]; !ok {
+			delta.Repositories[name] = -repo.TotalChunks
+		}
+	}
+	return delta, true
+}
+
+// Summarize computes size statistics over all dated entries
+func (s StorageStats) Summarize() Summary {
+	dates := s.Dates()
+	var sum Summary
+	var total int64
+	for _, date := range dates {
+		day := s[date]
+		if day == nil {
+			continue
+		}
+		if sum.Days == 0 {
+			sum.First = date
+			sum.MinSize = day.TotalSize
+			sum.MaxSize = day.TotalSize
+		}
+		sum.Days++
+		sum.Last = date
+		total += day.TotalSize
+		if day.TotalSize < sum.MinSize {
+			sum.MinSize = day.TotalSize
+		}
+		if day.TotalSize > sum.MaxSize {
+			sum.MaxSize = day.TotalSize
+		}
+	}
+	if sum.Days > 0 {
+		sum.AvgSize = total / int64(sum.Days)
+		sum.Growth = s[sum.Last].TotalSize - s[sum.First].TotalSize
+	}
+	return sum
+}
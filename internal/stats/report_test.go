@@ -0,0 +1,170 @@
+package stats
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+var zeroTime time.Time
+
+// sampleStorageStats has entries across two months plus a non-date key
+func sampleStorageStats() StorageStats {
+	return StorageStats{
+		"2025-01-10": {TotalSize: 1000},
+		"2025-01-20": {TotalSize: 1500},
+		"2025-02-01": {TotalSize: 1200},
+		"2025-02-15": {TotalSize: 3000},
+		"latest":     {TotalSize: 99999},
+	}
+}
+
+func TestStorageStats_Dates(t *testing.T) {
+	dates := sampleStorageStats().Dates()
+	expected := []string{"2025-01-10", "2025-01-20", "2025-02-01", "2025-02-15"}
+	if len(dates) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, dates)
+	}
+	for i := range expected {
+		if dates[i] != expected[i] {
+			t.Errorf("date %d: expected %q, got %q", i, expected[i], dates[i])
+		}
+	}
+}
+
+func TestStorageStats_Between(t *testing.T) {
+	since, _ := ParseDate("2025-01-15")
+	until, _ := ParseDate("2025-02-01")
+
+	tests := []struct {
+		name     string
+		filtered StorageStats
+		expected int
+	}{
+		{"closed range is inclusive", sampleStorageStats().Between(since, until), 2},
+		{"open until", sampleStorageStats().Between(since, until.AddDate(1, 0, 0)), 3},
+		{"open both", sampleStorageStats().Between(zeroTime, zeroTime), 4},
+		{"open since", sampleStorageStats().Between(zeroTime, since), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if len(tt.filtered) != tt.expected {
+				t.Errorf("expected %d entries, got %d", tt.expected, len(tt.filtered))
+			}
+		})
+	}
+}
+
+func TestStorageStats_Summarize(t *testing.T) {
+	since, _ := ParseDate("2025-01-15")
+	until, _ := ParseDate("2025-02-01")
+
+	sum := sampleStorageStats().Between(since, until).Summarize()
+	expected := Summary{
+		Days:    2,
+		First:   "2025-01-20",
+		Last:    "2025-02-01",
+		MinSize: 1200,
+		MaxSize: 1500,
+		AvgSize: 1350,
+		Growth:  -300,
+	}
+	if sum != expected {
+		t.Errorf("Summarize() = %+v, want %+v", sum, expected)
+	}
+
+	all := sampleStorageStats().Summarize()
+	if all.Days != 4 || all.Growth != 2000 || all.MaxSize != 3000 {
+		t.Errorf("unexpected summary for all dates: %+v", all)
+	}
+
+	if empty := (StorageStats{}).Summarize(); empty != (Summary{}) {
+		t.Errorf("expected zero summary for empty stats, got %+v", empty)
+	}
+}
+
+func TestParseDate_Invalid(t *testing.T) {
+	if _, err := ParseDate("01/02/2025"); err == nil {
+		t.Error("expected error for non YYYY-MM-DD date")
+	}
+}
+
+func TestStorageStats_Before(t *testing.T) {
+	date, prev := sampleStorageStats().Before("2025-02-01")
+	if date != "2025-01-20" || prev == nil || prev.TotalSize != 1500 {
+		t.Errorf("expected 2025-01-20 entry, got %q %+v", date, prev)
+	}
+
+	if date, prev := sampleStorageStats().Before("2025-01-10"); date != "" || prev != nil {
+		t.Errorf("expected no entry before the first date, got %q %+v", date, prev)
+	}
+}
+
+func TestStorageStats_Latest(t *testing.T) {
+	if latest := sampleStorageStats().Latest(); latest != "2025-02-15" {
+		t.Errorf("expected 2025-02-15, got %q", latest)
+	}
+	if latest := (StorageStats{}).Latest(); latest != "" {
+		t.Errorf("expected no date for empty stats, got %q", latest)
+	}
+}
+
+func TestGrowthPercent(t *testing.T) {
+	tests := []struct {
+		name   string
+		prev   *DayStats
+		cur    *DayStats
+		pct    float64
+		wantOK bool
+	}{
+		{"first run", nil, &DayStats{TotalSize: 100}, 0, false},
+		{"empty prior", &DayStats{TotalSize: 0}, &DayStats{TotalSize: 100}, 0, false},
+		{"growth", &DayStats{TotalSize: 100}, &DayStats{TotalSize: 150}, 50, true},
+		{"shrink", &DayStats{TotalSize: 200}, &DayStats{TotalSize: 100}, -50, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pct, ok := GrowthPercent(tt.prev, tt.cur)
+			if ok != tt.wantOK || pct != tt.pct {
+				t.Errorf("GrowthPercent() = %v, %v; want %v, %v", pct, ok, tt.pct, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestChunkDeltas(t *testing.T) {
+	prev := &DayStats{
+		TotalChunks: 1000,
+		Repositories: map[string]RepoStats{
+			"appdata": {TotalChunks: 600},
+			"photos":  {TotalChunks: 300},
+			"old":     {TotalChunks: 100},
+		},
+	}
+	cur := &DayStats{
+		TotalChunks: 1150,
+		Repositories: map[string]RepoStats{
+			"appdata": {TotalChunks: 720},
+			"photos":  {TotalChunks: 300},
+			"music":   {TotalChunks: 130},
+		},
+	}
+
+	delta, ok := ChunkDeltas("2025-02-14", prev, cur)
+	if !ok {
+		t.Fatal("expected a delta with a prior entry")
+	}
+	if delta.Since != "2025-02-14" || delta.Total != 150 {
+		t.Errorf("expected +150 since 2025-02-14, got %+d since %s", delta.Total, delta.Since)
+	}
+	want := map[string]int{"appdata": 120, "photos": 0, "music": 130, "old": -100}
+	if !reflect.DeepEqual(delta.Repositories, want) {
+		t.Errorf("expected repository deltas %v, got %v", want, delta.Repositories)
+	}
+
+	if _, ok := ChunkDeltas("", nil, cur); ok {
+		t.Error("expected no delta on the first run")
+	}
+}
@@ -1,7 +1,9 @@
 package stats
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
 	"regexp"
 	"strconv"
 	"strings"
@@ -19,6 +21,80 @@ type DayStats struct {
 	PrunedRevisions int                  `json:"pruned-revisions"`
 	Status          string               `json:"status"`
 	Repositories    map[string]RepoStats `json:"repositories"`
+
+	// Problems reported by check -persist (omitted when none, as in Web UI files)
+	MissingChunks int `json:"missing-chunks,omitempty"`
+	CorruptChunks int `json:"corrupt-chunks,omitempty"`
+
+	// GeneratedBy names the duplicaci build that wrote the entry, e.g.
+	// "duplicaci 1.4.0 (abc1234)", for tracing parser regressions
+	GeneratedBy string `json:"generated-by,omitempty"`
+
+	// Extra holds fields of an existing entry that DayStats does not model,
+	// written back unchanged
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// Status values recorded in DayStats
+const (
+	StatusChecked = "Checked" // Check passed
+	StatusErrors  = "Errors"  // Check reported missing or corrupt chunks
+	StatusFailed  = "Failed"  // The check command itself failed
+)
+
+// SetCheckResult sets Status from the check command's error and the parsed
+// chunk problems, so failed checks are stored as failed rather than "Checked"
+func (d *DayStats) SetCheckResult(checkErr error) {
+	switch {
+	case checkErr != nil:
+		d.Status = StatusFailed
+	case d.MissingChunks > 0 || d.CorruptChunks > 0:
+		d.Status = StatusErrors
+	default:
+		d.Status = StatusChecked
+	}
+}
+
+// statusRank orders statuses from best to worst for merging
+var statusRank = map[string]int{StatusChecked: 1, StatusErrors: 2, StatusFailed: 3}
+
+// MergeSameDay combines an earlier entry for the same date with a newer one.
+// Repositories from both are kept, with the newer stats winning for snapshot
+// IDs in both. Storage totals are the larger of the two, since a check of one
+// snapshot ID only counts that ID's chunks; prune counts are summed, and the
+// worse status and chunk problem counts are kept.
+func MergeSameDay(earlier, newer *DayStats) *DayStats {
+	if earlier == nil {
+		return newer
+	}
+
+	merged := *newer
+	merged.Repositories = make(map[string]RepoStats, len(earlier.Repositories)+len(newer.Repositories))
+	for id, repo := range earlier.Repositories {
+		merged.Repositories[id] = repo
+	}
+	for id, repo := range newer.Repositories {
+		merged.Repositories[id] = repo
+	}
+
+	if earlier.TotalSize > merged.TotalSize {
+		merged.TotalSize = earlier.TotalSize
+	}
+	if earlier.TotalChunks > merged.TotalChunks {
+		merged.TotalChunks = earlier.TotalChunks
+	}
+	merged.PrunedChunks += earlier.PrunedChunks
+	merged.PrunedRevisions += earlier.PrunedRevisions
+	if earlier.MissingChunks > merged.MissingChunks {
+		merged.MissingChunks = earlier.MissingChunks
+	}
+	if earlier.CorruptChunks > merged.CorruptChunks {
+		merged.CorruptChunks = earlier.CorruptChunks
+	}
+	if statusRank[earlier.Status] > statusRank[merged.Status] {
+		merged.Status = earlier.Status
+	}
+	return &merged
 }
 
 // RepoStats represents statistics for a single repository
@@ -27,33 +103,54 @@ type RepoStats struct {
 	TotalSize   int64 `json:"total-size"`
 	UniqueSize  int64 `json:"unique-size"`
 	TotalChunks int   `json:"total-chunks"`
+
+	Extra map[string]json.RawMessage `json:"-"` // Unmodelled fields, as in DayStats
 }
 
 // ParseCheckOutput parses duplicacy check -tabular output and returns DayStats
 func ParseCheckOutput(output string) (*DayStats, error) {
 	stats := &DayStats{
-		Status:       "Checked",
+		Status:       StatusChecked,
 		Repositories: make(map[string]RepoStats),
 	}
 
 	lines := strings.Split(output, "\n")
 
 	// Parse total chunks line: "INFO SNAPSHOT_CHECK Total chunk size is 4,617M in 975 chunks"
-	totalChunksRe := regexp.MustCompile(`Total chunk size is ([\d,]+[KMGT]?) in ([\d,]+) chunks`)
+	totalChunksRe := regexp.MustCompile(`Total chunk size is (` + sizePattern + `) in ([\d,]+) chunks`)
 
 	// Parse tabular "all" rows for each repository
 	// Format: " repo_name | all |    |     |      | chunks |    bytes | uniq |    bytes | new | bytes |"
 	// Columns: snap | rev | date | files | bytes | chunks | bytes | uniq | bytes | new | bytes
 	// The "all" row has empty files/bytes columns, we need to capture chunks and uniq columns
-	allRowRe := regexp.MustCompile(`^\s*(\S+)\s*\|\s*all\s*\|[^|]*\|[^|]*\|[^|]*\|\s*([\d,]+)\s*\|\s*([\d,]+[KMGT]?)\s*\|\s*([\d,]+)\s*\|\s*([\d,]+[KMGT]?)\s*\|`)
+	allRowRe := regexp.MustCompile(`^\s*(\S+)\s*\|\s*all\s*\|[^|]*\|[^|]*\|[^|]*\|\s*([\d,]+)\s*\|\s*(` + sizePattern + `)\s*\|\s*([\d,]+)\s*\|\s*(` + sizePattern + `)\s*\|`)
 
 	// Count revisions per repository from individual revision lines
 	// Format: " repo_name | rev_num | @ date ... |"
 	revisionRe := regexp.MustCompile(`^\s*(\S+)\s*\|\s*(\d+)\s*\|\s*@`)
 
+	// Problems reported when check runs with -persist, e.g.
+	// "Chunk 1a2b... referenced by snapshot appdata at revision 3 does not exist"
+	missingChunkRe := regexp.MustCompile(`Chunk \S+ referenced by snapshot \S+ at revision \d+ does not exist`)
+	// "Chunk 1a2b... is corrupted" (with -chunks)
+	corruptChunkRe := regexp.MustCompile(`Chunk \S+ is corrupted`)
+
 	revisionCounts := make(map[string]int)
 
 	for _, line := range lines {
+		// With -log (--json-logs falling back to text) every line, table rows
+		// included, carries the log prefix; the row patterns are anchored
+		line = StripLogPrefix(line)
+
+		if missingChunkRe.MatchString(line) {
+			stats.MissingChunks++
+			continue
+		}
+		if corruptChunkRe.MatchString(line) {
+			stats.CorruptChunks++
+			continue
+		}
+
 		// Check for total chunks summary
 		if matches := totalChunksRe.FindStringSubmatch(line); matches != nil {
 			size, err := parseSize(matches[1])
@@ -98,15 +195,73 @@ func ParseCheckOutput(output string) (*DayStats, error) {
 		return nil, fmt.Errorf("no repository statistics found in check output")
 	}
 
+	if stats.MissingChunks > 0 || stats.CorruptChunks > 0 {
+		stats.Status = StatusErrors
+	}
+
+	return stats, nil
+}
+
+// ParseCheckOutputForID parses check output and keeps only the statistics for one snapshot ID
+func ParseCheckOutputForID(output, id string) (*DayStats, error) {
+	stats, err := ParseCheckOutput(output)
+	if err != nil {
+		return nil, err
+	}
+	if err := stats.KeepRepository(id); err != nil {
+		return nil, err
+	}
 	return stats, nil
 }
 
-// TodayDate returns today's date in YYYY-MM-DD format
+// KeepRepository drops the statistics of every snapshot ID except id
+func (d *DayStats) KeepRepository(id string) error {
+	repoStats, ok := d.Repositories[id]
+	if !ok {
+		return fmt.Errorf("no statistics found for snapshot %q in check output", id)
+	}
+	d.Repositories = map[string]RepoStats{id: repoStats}
+	return nil
+}
+
+// TodayDate returns today's local date in YYYY-MM-DD format
 func TodayDate() string {
-	return time.Now().Format("2006-01-02")
+	return TodayDateIn(time.Local)
+}
+
+// TodayDateIn returns today's date in loc in YYYY-MM-DD format
+func TodayDateIn(loc *time.Location) string {
+	return DateIn(time.Now(), loc)
 }
 
-// parseSize converts size strings like "4,617M", "8,853K", "123G", "456" to bytes
+// DateIn returns the YYYY-MM-DD date of t in loc (local time when loc is nil)
+func DateIn(t time.Time, loc *time.Location) string {
+	if loc == nil {
+		loc = time.Local
+	}
+	return t.In(loc).Format(DateLayout)
+}
+
+// sizePattern matches a size as printed by duplicacy, e.g. "4,617M", "1.5G" or "12k"
+const sizePattern = `[\d,]+(?:\.\d+)?[KMGTPEBkmgtpeb]?`
+
+// logPrefixRe matches the prefix duplicacy -log puts on every line, e.g.
+// "2025-12-29 01:02:45.064 INFO SNAPSHOT_CHECK "
+var logPrefixRe = regexp.MustCompile(`^\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}\.\d{3} [A-Z]+ [A-Z0-9_]+ `)
+
+// StripLogPrefix removes the duplicacy -log prefix from an output line, so
+// parsers of plain output also read -log output
+func StripLogPrefix(line string) string {
+	return logPrefixRe.ReplaceAllString(line, "")
+}
+
+// sizeNumberPattern is the numeric part of a size once commas and the suffix are removed
+var sizeNumberPattern = regexp.MustCompile(`^\d+(\.\d+)?$`)
+
+// parseSize converts size strings like "4,617M", "8,853K", "1.5G", "456" to bytes.
+// Suffixes (K through E, matching FormatBytes) are case-insensitive and "B"
+// means bytes. Negative or malformed numbers (e.g. "1.2.3G") and sizes that do
+// not fit in an int64 are an error.
 func parseSize(s string) (int64, error) {
 	s = strings.TrimSpace(s)
 	s = strings.ReplaceAll(s, ",", "")
@@ -116,30 +271,45 @@ func parseSize(s string) (int64, error) {
 	}
 
 	var multiplier int64 = 1
-	lastChar := s[len(s)-1]
+	suffix := strings.ToUpper(s[len(s)-1:])
 
-	switch lastChar {
-	case 'K':
+	switch suffix {
+	case "B":
+		s = s[:len(s)-1]
+	case "K":
 		multiplier = 1024
 		s = s[:len(s)-1]
-	case 'M':
+	case "M":
 		multiplier = 1024 * 1024
 		s = s[:len(s)-1]
-	case 'G':
+	case "G":
 		multiplier = 1024 * 1024 * 1024
 		s = s[:len(s)-1]
-	case 'T':
+	case "T":
 		multiplier = 1024 * 1024 * 1024 * 1024
 		s = s[:len(s)-1]
+	case "P":
+		multiplier = 1024 * 1024 * 1024 * 1024 * 1024
+		s = s[:len(s)-1]
+	case "E":
+		multiplier = 1024 * 1024 * 1024 * 1024 * 1024 * 1024
+		s = s[:len(s)-1]
 	}
 
 	// Parse the numeric part
+	if !sizeNumberPattern.MatchString(s) {
+		return 0, fmt.Errorf("failed to parse size %q: not a non-negative number", s)
+	}
 	val, err := strconv.ParseFloat(s, 64)
 	if err != nil {
 		return 0, fmt.Errorf("failed to parse size %q: %w", s, err)
 	}
 
-	return int64(val * float64(multiplier)), nil
+	bytes := val * float64(multiplier)
+	if bytes >= math.MaxInt64 {
+		return 0, fmt.Errorf("size %q overflows int64", s)
+	}
+	return int64(bytes), nil
 }
 
 // parseNumber removes commas and parses an integer
@@ -1,7 +1,13 @@
 package stats
 
 import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestParseCheckOutput(t *testing.T) {
@@ -107,6 +113,148 @@ func TestParseSize(t *testing.T) {
 	}
 }
 
+func TestParseSize_Fractional(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"1.5G", 1610612736},
+		{"2.75M", 2883584},
+		{"0.5K", 512},
+		{"1,024.5K", 1049088},
+	}
+
+	for _, tt := range tests {
+		got, err := parseSize(tt.input)
+		if err != nil {
+			t.Errorf("parseSize(%q) error: %v", tt.input, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("parseSize(%q) = %d, want %d", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestParseSize_SuffixCase(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"1k", 1024},
+		{"2m", 2 * 1024 * 1024},
+		{"1.5g", 1610612736},
+		{"1t", 1024 * 1024 * 1024 * 1024},
+		{"512B", 512},
+		{"512b", 512},
+		{"1,024B", 1024},
+	}
+
+	for _, tt := range tests {
+		got, err := parseSize(tt.input)
+		if err != nil {
+			t.Errorf("parseSize(%q) error: %v", tt.input, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("parseSize(%q) = %d, want %d", tt.input, got, tt.expected)
+		}
+	}
+
+	// A bare suffix has no number
+	if _, err := parseSize("B"); err == nil {
+		t.Error("parseSize(\"B\") should error")
+	}
+}
+
+func TestParseCheckOutput_LowercaseSuffixes(t *testing.T) {
+	output := `INFO SNAPSHOT_CHECK Total chunk size is 8,853k in 92 chunks
+ appdata | all |  |  |  |     92 | 8,853k |   92 | 512b |     |       |
+`
+	stats, err := ParseCheckOutput(output)
+	if err != nil {
+		t.Fatalf("ParseCheckOutput() error: %v", err)
+	}
+	if stats.TotalSize != 8853*1024 {
+		t.Errorf("expected total size %d, got %d", 8853*1024, stats.TotalSize)
+	}
+	if repo := stats.Repositories["appdata"]; repo.UniqueSize != 512 {
+		t.Errorf("expected unique size 512, got %d", repo.UniqueSize)
+	}
+}
+
+func TestParseSize_PetaExa(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"1P", 1 << 50},
+		{"1.5p", 3 << 49},
+		{"1E", 1 << 60},
+		{"7E", 7 << 60},
+	}
+
+	for _, tt := range tests {
+		got, err := parseSize(tt.input)
+		if err != nil {
+			t.Errorf("parseSize(%q) error: %v", tt.input, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("parseSize(%q) = %d, want %d", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestParseSize_Overflow(t *testing.T) {
+	for _, input := range []string{"8E", "16E", "9,000,000P", "99999999999999999999"} {
+		got, err := parseSize(input)
+		if err == nil {
+			t.Errorf("parseSize(%q) = %d, expected overflow error", input, got)
+		} else if !strings.Contains(err.Error(), "overflows") {
+			t.Errorf("parseSize(%q) error = %v, want overflow", input, err)
+		}
+	}
+}
+
+func TestFormatBytes_RoundTrip(t *testing.T) {
+	for _, size := range []int64{1 << 50, 3 << 59} {
+		formatted := strings.ReplaceAll(strings.TrimSuffix(FormatBytes(size), "B"), " ", "")
+		got, err := parseSize(formatted)
+		if err != nil {
+			t.Errorf("parseSize(%q) error: %v", formatted, err)
+			continue
+		}
+		if got != size {
+			t.Errorf("parseSize(FormatBytes(%d)) = %d", size, got)
+		}
+	}
+}
+
+func TestParseSize_MalformedFraction(t *testing.T) {
+	for _, input := range []string{"1.2.3G", "-1.5G", ".5M", "1.G", "1e3K", "NaN", "Inf"} {
+		if got, err := parseSize(input); err == nil {
+			t.Errorf("parseSize(%q) = %d, expected error", input, got)
+		}
+	}
+}
+
+func TestParseCheckOutput_FractionalSizes(t *testing.T) {
+	output := `INFO SNAPSHOT_CHECK Total chunk size is 1.5G in 975 chunks
+ appdata | all |  |  |  |    975 | 1.5G |  975 | 2.75M |     |       |
+`
+	stats, err := ParseCheckOutput(output)
+	if err != nil {
+		t.Fatalf("ParseCheckOutput() error: %v", err)
+	}
+	if stats.TotalSize != 1610612736 {
+		t.Errorf("expected total size 1610612736, got %d", stats.TotalSize)
+	}
+	if repo := stats.Repositories["appdata"]; repo.TotalSize != 1610612736 || repo.UniqueSize != 2883584 {
+		t.Errorf("unexpected repository sizes: %+v", repo)
+	}
+}
+
 func TestParseNumber(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -164,6 +312,152 @@ Storage set to /test
 	}
 }
 
+func TestParseCheckOutput_PersistMissingChunks(t *testing.T) {
+	output := `2025-12-29 01:00:19.894 INFO SNAPSHOT_CHECK Listing all chunks
+2025-12-29 01:00:25.120 WARN SNAPSHOT_VALIDATE Chunk 1f3e9a0b referenced by snapshot appdata at revision 2 does not exist
+2025-12-29 01:00:25.121 WARN SNAPSHOT_VALIDATE Chunk 77c0d2e4 referenced by snapshot appdata at revision 2 does not exist
+2025-12-29 01:00:25.130 WARN SNAPSHOT_CHECK Some chunks referenced by snapshot appdata at revision 2 are missing
+2025-12-29 01:00:30.002 WARN VERIFY_PROGRESS Chunk 9a8b7c6d is corrupted
+2025-12-29 01:02:45.064 INFO SNAPSHOT_CHECK Total chunk size is 8,853K in 92 chunks
+ appdata |   1 | @ 2025-10-13 20:36 -hash |     9 |  826K |      4 |   672K |    4 |   672K |   4 |  672K |
+ appdata | all |                          |       |       |     92 | 8,853K |   92 | 8,853K |     |       |
+`
+
+	stats, err := ParseCheckOutput(output)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.MissingChunks != 2 {
+		t.Errorf("MissingChunks = %d, want 2", stats.MissingChunks)
+	}
+	if stats.CorruptChunks != 1 {
+		t.Errorf("CorruptChunks = %d, want 1", stats.CorruptChunks)
+	}
+	if stats.Status != StatusErrors {
+		t.Errorf("Status = %q, want %q", stats.Status, StatusErrors)
+	}
+	if stats.TotalChunks != 92 {
+		t.Errorf("TotalChunks = %d, want 92", stats.TotalChunks)
+	}
+}
+
+func TestParseCheckOutput_LogPrefixedRows(t *testing.T) {
+	// duplicacy -log prefixes the table rows like every other line
+	output := `2025-12-29 01:02:45.064 INFO SNAPSHOT_CHECK Total chunk size is 8,853K in 92 chunks
+2025-12-29 01:02:45.065 INFO SNAPSHOT_CHECK  appdata |   1 | @ 2025-10-13 20:36 -hash |     9 |  826K |      4 |   672K |    4 |   672K |   4 |  672K |
+2025-12-29 01:02:45.065 INFO SNAPSHOT_CHECK  appdata |   2 | @ 2025-10-14 20:36       |     9 |  830K |      5 |   700K |    1 |    28K |   1 |   28K |
+2025-12-29 01:02:45.066 INFO SNAPSHOT_CHECK  appdata | all |                          |       |       |     92 | 8,853K |   92 | 8,853K |     |       |
+`
+
+	stats, err := ParseCheckOutput(output)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	repo, ok := stats.Repositories["appdata"]
+	if !ok {
+		t.Fatalf("expected appdata stats, got %v", stats.Repositories)
+	}
+	if repo.Revisions != 2 || repo.TotalChunks != 92 || repo.TotalSize != 8853*1024 {
+		t.Errorf("unexpected appdata stats: %+v", repo)
+	}
+}
+
+func TestParseCheckOutput_CleanStatus(t *testing.T) {
+	output := `2025-12-29 01:02:45.064 INFO SNAPSHOT_CHECK Total chunk size is 8,853K in 92 chunks
+ appdata | all |                          |       |       |     92 | 8,853K |   92 | 8,853K |     |       |
+`
+
+	stats, err := ParseCheckOutput(output)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.Status != StatusChecked || stats.MissingChunks != 0 || stats.CorruptChunks != 0 {
+		t.Errorf("expected clean %q stats, got %+v", StatusChecked, stats)
+	}
+}
+
+func TestDayStats_SetCheckResult(t *testing.T) {
+	tests := []struct {
+		name     string
+		stats    DayStats
+		err      error
+		expected string
+	}{
+		{"success", DayStats{}, nil, StatusChecked},
+		{"missing chunks", DayStats{MissingChunks: 3}, nil, StatusErrors},
+		{"corrupt chunks", DayStats{CorruptChunks: 1}, nil, StatusErrors},
+		{"command failed", DayStats{}, fmt.Errorf("command exited with code 1"), StatusFailed},
+		{"failed with missing chunks", DayStats{MissingChunks: 3}, fmt.Errorf("command exited with code 1"), StatusFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ds := tt.stats
+			ds.SetCheckResult(tt.err)
+			if ds.Status != tt.expected {
+				t.Errorf("Status = %q, want %q", ds.Status, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseCheckOutput_WithChunkVerification(t *testing.T) {
+	// check -chunks adds verification progress lines around the usual output
+	output := `2025-12-29 01:00:19.894 INFO SNAPSHOT_CHECK Listing all chunks
+2025-12-29 01:02:45.064 INFO SNAPSHOT_CHECK 1 snapshots and 2 revisions
+2025-12-29 01:02:45.064 INFO SNAPSHOT_CHECK Total chunk size is 8,853K in 92 chunks
+2025-12-29 01:02:45.068 INFO SNAPSHOT_VERIFY Verifying 92 chunks
+2025-12-29 01:02:46.001 INFO SNAPSHOT_VERIFY Verified chunk 4f2a9c (1/92), 1.20MB/s 00:00:05 1.1%
+2025-12-29 01:02:51.502 INFO SNAPSHOT_VERIFY All 92 chunks have been successfully verified
+2025-12-29 01:02:51.503 INFO SNAPSHOT_CHECK
+                   snap | rev |                          | files | bytes | chunks |  bytes | uniq |  bytes | new | bytes |
+ mikrotik_config_backup |   1 | @ 2025-10-13 20:36 -hash |     9 |  826K |      4 |   672K |    4 |   672K |   4 |  672K |
+ mikrotik_config_backup |   8 | @ 2025-10-20 01:01       |     8 |  532K |      4 |   377K |    4 |   377K |   4 |  377K |
+ mikrotik_config_backup | all |                          |       |       |     92 | 8,853K |   92 | 8,853K |     |       |`
+
+	stats, err := ParseCheckOutput(output)
+	if err != nil {
+		t.Fatalf("ParseCheckOutput failed: %v", err)
+	}
+
+	if stats.TotalChunks != 92 {
+		t.Errorf("TotalChunks = %d, want 92", stats.TotalChunks)
+	}
+	repo, ok := stats.Repositories["mikrotik_config_backup"]
+	if !ok {
+		t.Fatal("mikrotik_config_backup not found in repositories")
+	}
+	if repo.Revisions != 2 {
+		t.Errorf("Revisions = %d, want 2", repo.Revisions)
+	}
+}
+
+func TestParseCheckOutputForID(t *testing.T) {
+	output := `2025-12-29 01:02:45.064 INFO SNAPSHOT_CHECK Total chunk size is 4,617M in 975 chunks
+ unraid_appdata_backup |   1 | @ 2025-10-13 20:34 -hash |    28 | 3,384M |    195 | 991,477K |   32 | 164,900K | 195 | 991,477K |
+ unraid_appdata_backup | all |                          |       |        |    883 |   4,608M |  883 |   4,608M |     |          |
+ mikrotik_config_backup |   1 | @ 2025-10-13 20:36 -hash |     9 |  826K |      4 |   672K |    4 |   672K |   4 |  672K |
+ mikrotik_config_backup | all |                          |       |       |     92 | 8,853K |   92 | 8,853K |     |       |`
+
+	stats, err := ParseCheckOutputForID(output, "mikrotik_config_backup")
+	if err != nil {
+		t.Fatalf("ParseCheckOutputForID failed: %v", err)
+	}
+	if len(stats.Repositories) != 1 {
+		t.Fatalf("len(Repositories) = %d, want 1", len(stats.Repositories))
+	}
+	if stats.Repositories["mikrotik_config_backup"].TotalChunks != 92 {
+		t.Errorf("TotalChunks = %d, want 92", stats.Repositories["mikrotik_config_backup"].TotalChunks)
+	}
+
+	if _, err := ParseCheckOutputForID(output, "missing"); err == nil {
+		t.Error("ParseCheckOutputForID should return error when the ID is not in the output")
+	}
+	if _, err := ParseCheckOutputForID("no table here", "missing"); err == nil {
+		t.Error("ParseCheckOutputForID should return error when output has no repositories")
+	}
+}
+
 func TestTodayDate(t *testing.T) {
 	date := TodayDate()
 	// Should be in YYYY-MM-DD format
@@ -175,6 +469,43 @@ func TestTodayDate(t *testing.T) {
 	}
 }
 
+func TestDateIn(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+
+	// 23:30 UTC on Jan 15 is still the 15th in New York but already the 16th in Tokyo
+	clock := time.Date(2025, 1, 15, 23, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		loc      *time.Location
+		expected string
+	}{
+		{"UTC", time.UTC, "2025-01-15"},
+		{"New York", newYork, "2025-01-15"},
+		{"Tokyo", tokyo, "2025-01-16"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DateIn(clock, tt.loc); got != tt.expected {
+				t.Errorf("DateIn() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+
+	// nil means local time, matching TodayDate
+	if got := DateIn(clock, nil); got != clock.Local().Format(DateLayout) {
+		t.Errorf("DateIn(nil) = %q, want local date %q", got, clock.Local().Format(DateLayout))
+	}
+}
+
 func TestNewWriter(t *testing.T) {
 	w := NewWriter("root@host", "password", "Duplicacy")
 
@@ -240,6 +571,130 @@ func TestBuildDockerCommand_WithSSHAndPassword(t *testing.T) {
 	}
 }
 
+func TestBuildDockerCommand_PasswordFileMode(t *testing.T) {
+	w := &Writer{
+		DockerContainer: "Duplicacy",
+		SSHHost:         "root@192.168.1.100",
+		SSHPassword:     "secret123",
+		SSHPasswordMode: "file",
+	}
+
+	cmd := w.buildDockerCommand("cat /config/test.txt")
+	if !strings.HasPrefix(cmd, "sshpass -d 3 ssh ") {
+		t.Errorf("buildDockerCommand() should read the password from fd 3: %s", cmd)
+	}
+	if contains(cmd, "secret123") {
+		t.Errorf("buildDockerCommand() must not contain the password: %s", cmd)
+	}
+
+	// The command gets the password on fd 3
+	c, done, err := w.command("cat <&3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer done()
+	out, err := c.Output()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(out) != "secret123\n" {
+		t.Errorf("expected the password on fd 3, got %q", out)
+	}
+}
+
+func TestBuildDockerCommand_ContainerUser(t *testing.T) {
+	w := &Writer{
+		DockerContainer: "Duplicacy",
+		ContainerUser:   "abc",
+	}
+
+	cmd := w.buildDockerCommand("cat /config/test.txt")
+	expected := "docker exec -u abc Duplicacy sh -c 'cat /config/test.txt'"
+	if cmd != expected {
+		t.Errorf("buildDockerCommand() = %q, want %q", cmd, expected)
+	}
+}
+
+func TestEnsureStatsDir_Command(t *testing.T) {
+	var cmds []string
+	w := NewWriter("", "", "Duplicacy")
+	w.ContainerUser = "abc"
+	w.run = func(cmdStr string) (string, error) {
+		cmds = append(cmds, cmdStr)
+		return "", nil
+	}
+
+	if err := w.EnsureStatsDir(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "docker exec -u abc Duplicacy sh -c 'mkdir -p /config/stats/storages'"
+	if len(cmds) != 1 || cmds[0] != expected {
+		t.Errorf("expected [%s], got %v", expected, cmds)
+	}
+}
+
+func TestEnsureStatsDir_DryRun(t *testing.T) {
+	w := NewWriter("", "", "Duplicacy")
+	w.DryRun = true
+	w.run = func(cmdStr string) (string, error) {
+		t.Errorf("dry run should not execute %q", cmdStr)
+		return "", nil
+	}
+
+	if err := w.EnsureStatsDir(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestUpdate_EnsuresStatsDirOnceBeforeWriting(t *testing.T) {
+	var cmds []string
+	w := NewWriter("", "", "Duplicacy")
+	w.run = func(cmdStr string) (string, error) {
+		cmds = append(cmds, cmdStr)
+		return "{}", nil
+	}
+
+	for _, storage := range []string{"NAS", "Cloud"} {
+		if _, err := w.Update(storage, &DayStats{TotalSize: 1}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	var kinds []string
+	for _, c := range cmds {
+		switch {
+		case strings.Contains(c, "mkdir -p"):
+			kinds = append(kinds, "mkdir")
+		case strings.Contains(c, "cat > "):
+			kinds = append(kinds, "write")
+		case strings.Contains(c, "cat "):
+			kinds = append(kinds, "read")
+		}
+	}
+	expected := []string{"read", "mkdir", "write", "read", "write"}
+	if strings.Join(kinds, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected commands %v, got %v", expected, kinds)
+	}
+}
+
+func TestUpdate_EnsureStatsDirFailure(t *testing.T) {
+	w := NewWriter("", "", "Duplicacy")
+	w.run = func(cmdStr string) (string, error) {
+		if strings.Contains(cmdStr, "mkdir") {
+			return "", fmt.Errorf("read-only file system")
+		}
+		if strings.Contains(cmdStr, "cat > ") {
+			t.Error("should not write when the stats dir cannot be created")
+		}
+		return "{}", nil
+	}
+
+	if _, err := w.Update("NAS", &DayStats{}); err == nil {
+		t.Error("expected error when the stats dir cannot be created")
+	}
+}
+
 func TestWriteStatsFile_DryRun(t *testing.T) {
 	w := &Writer{
 		DockerContainer: "Duplicacy",
@@ -261,6 +716,61 @@ func TestWriteStatsFile_DryRun(t *testing.T) {
 	}
 }
 
+func TestEncodeStatsFile_Deterministic(t *testing.T) {
+	stats := StorageStats{}
+	for _, date := range []string{"2025-03-01", "2025-01-15", "2025-02-10", "2024-12-31"} {
+		stats[date] = &DayStats{
+			TotalSize: 1000,
+			Status:    StatusChecked,
+			Repositories: map[string]RepoStats{
+				"photos":  {Revisions: 2},
+				"appdata": {Revisions: 5},
+				"media":   {Revisions: 1},
+			},
+		}
+	}
+	unparsed := map[string]json.RawMessage{"2025-01-01": json.RawMessage(`"broken"`)}
+
+	for _, format := range []string{SizeFormatBytes, SizeFormatHuman} {
+		first, err := encodeStatsFile(stats, unparsed, format)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for i := 0; i < 10; i++ {
+			again, err := encodeStatsFile(stats, unparsed, format)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(again) != string(first) {
+				t.Fatalf("%s: expected byte-identical output, got:\n%s\nthen:\n%s", format, first, again)
+			}
+		}
+
+		out := string(first)
+		assertOrder(t, out, []string{`"2024-12-31"`, `"2025-01-01"`, `"2025-01-15"`, `"2025-02-10"`, `"2025-03-01"`})
+		day := out[strings.Index(out, `"2025-01-15"`):strings.Index(out, `"2025-02-10"`)]
+		assertOrder(t, day, []string{`"appdata"`, `"media"`, `"photos"`})
+	}
+}
+
+// assertOrder fails unless each of keys appears in s, in order
+func assertOrder(t *testing.T, s string, keys []string) {
+	t.Helper()
+	last := -1
+	for _, key := range keys {
+		i := strings.Index(s, key)
+		if i < 0 {
+			t.Errorf("expected %s in:\n%s", key, s)
+			return
+		}
+		if i < last {
+			t.Errorf("expected keys in order %v in:\n%s", keys, s)
+			return
+		}
+		last = i
+	}
+}
+
 func TestParseSize_InvalidNumber(t *testing.T) {
 	// Test with non-numeric input (X is not a recognized suffix, so it tries to parse "100X" as a number)
 	_, err := parseSize("abc")
@@ -288,3 +798,389 @@ func findSubstring(s, substr string) bool {
 	}
 	return false
 }
+
+func TestReadFile_CommandFailure(t *testing.T) {
+	w := &Writer{DockerContainer: "NonExistentContainer12345"}
+
+	if _, err := w.ReadFile("/config/keyring"); err == nil {
+		t.Error("expected error when the file cannot be read")
+	}
+}
+
+func TestUpdate_StaleDate(t *testing.T) {
+	tomorrow := time.Now().AddDate(0, 0, 1).Format(DateLayout)
+	existing := fmt.Sprintf(`{%q: {"total-size": 100}}`, tomorrow)
+
+	tests := []struct {
+		name        string
+		refuseStale bool
+		wantErr     bool
+		wantWrite   bool
+	}{
+		{"warns and writes by default", false, false, true},
+		{"refuses when configured", true, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wrote := false
+			w := NewWriter("", "", "Duplicacy")
+			w.RefuseStale = tt.refuseStale
+			w.run = func(cmdStr string) (string, error) {
+				if strings.Contains(cmdStr, "cat > ") {
+					wrote = true
+					if !strings.Contains(cmdStr, tomorrow) {
+						t.Error("expected the newer entry to be kept")
+					}
+				}
+				return existing, nil
+			}
+
+			_, err := w.Update("NAS", &DayStats{TotalSize: 50})
+			if tt.wantErr && (err == nil || !strings.Contains(err.Error(), "before the latest NAS entry "+tomorrow)) {
+				t.Errorf("expected stale date error, got %v", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if wrote != tt.wantWrite {
+				t.Errorf("expected write=%v, got %v", tt.wantWrite, wrote)
+			}
+		})
+	}
+}
+
+func TestUpdate_SameDayIsNotStale(t *testing.T) {
+	today := TodayDate()
+	w := NewWriter("", "", "Duplicacy")
+	w.RefuseStale = true
+	w.run = func(cmdStr string) (string, error) {
+		return fmt.Sprintf(`{%q: {"total-size": 100}}`, today), nil
+	}
+
+	if _, err := w.Update("NAS", &DayStats{TotalSize: 50}); err != nil {
+		t.Errorf("re-running on the same day should not be refused: %v", err)
+	}
+}
+
+func TestMergeSameDay(t *testing.T) {
+	earlier := &DayStats{
+		TotalSize:       1000,
+		PrunedRevisions: 2,
+		Status:          StatusErrors,
+		MissingChunks:   3,
+		Repositories: map[string]RepoStats{
+			"appdata": {Revisions: 5, TotalSize: 400},
+			"photos":  {Revisions: 2, TotalSize: 600},
+		},
+	}
+	newer := &DayStats{
+		TotalSize:       1100,
+		PrunedRevisions: 1,
+		Status:          StatusChecked,
+		Repositories: map[string]RepoStats{
+			"photos": {Revisions: 3, TotalSize: 700},
+			"docs":   {Revisions: 1, TotalSize: 50},
+		},
+	}
+
+	merged := MergeSameDay(earlier, newer)
+
+	expected := map[string]RepoStats{
+		"appdata": {Revisions: 5, TotalSize: 400},
+		"photos":  {Revisions: 3, TotalSize: 700},
+		"docs":    {Revisions: 1, TotalSize: 50},
+	}
+	if len(merged.Repositories) != len(expected) {
+		t.Fatalf("expected %d repositories, got %v", len(expected), merged.Repositories)
+	}
+	for id, want := range expected {
+		if got := merged.Repositories[id]; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: expected %+v, got %+v", id, want, got)
+		}
+	}
+	if merged.TotalSize != 1100 {
+		t.Errorf("expected the larger total size, got %d", merged.TotalSize)
+	}
+	if merged.PrunedRevisions != 3 {
+		t.Errorf("expected summed pruned revisions, got %d", merged.PrunedRevisions)
+	}
+	if merged.Status != StatusErrors || merged.MissingChunks != 3 {
+		t.Errorf("expected the earlier problems to be kept, got %s with %d missing", merged.Status, merged.MissingChunks)
+	}
+	if len(newer.Repositories) != 2 {
+		t.Error("merging should not modify the newer entry")
+	}
+
+	if MergeSameDay(nil, newer) != newer {
+		t.Error("expected the newer entry when there is nothing to merge")
+	}
+}
+
+func TestMergeSameDay_PerIDTotals(t *testing.T) {
+	// check -id counts only that ID's chunks, so the last ID checked may be the smallest
+	appdata := &DayStats{TotalSize: 9000, TotalChunks: 90, Repositories: map[string]RepoStats{"appdata": {Revisions: 4}}}
+	docs := &DayStats{TotalSize: 500, TotalChunks: 5, Repositories: map[string]RepoStats{"docs": {Revisions: 2}}}
+
+	merged := MergeSameDay(appdata, docs)
+
+	if merged.TotalSize != 9000 || merged.TotalChunks != 90 {
+		t.Errorf("expected the storage totals of the larger ID, got %d in %d chunks", merged.TotalSize, merged.TotalChunks)
+	}
+	if len(merged.Repositories) != 2 {
+		t.Errorf("expected both IDs, got %v", merged.Repositories)
+	}
+}
+
+func TestUpdate_MergeSameDay(t *testing.T) {
+	tests := []struct {
+		name     string
+		merge    bool
+		expected []string
+	}{
+		{"replace by default", false, []string{"photos"}},
+		{"merge partial runs", true, []string{"appdata", "photos"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := "{}"
+			w := NewWriter("", "", "Duplicacy")
+			w.MergeSameDay = tt.merge
+			w.run = func(cmdStr string) (string, error) {
+				if strings.HasPrefix(cmdStr, "docker exec Duplicacy sh -c 'cat > ") {
+					start := strings.Index(cmdStr, "\n") + 1
+					end := strings.LastIndex(cmdStr, "\nSTATSEOF")
+					file = cmdStr[start:end]
+				}
+				return file, nil
+			}
+
+			runs := []string{"appdata", "photos"}
+			for _, id := range runs {
+				day := &DayStats{Repositories: map[string]RepoStats{id: {Revisions: 1}}}
+				if _, err := w.Update("NAS", day); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			written, err := w.ReadStorageStats("NAS")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			entry := written[TodayDate()]
+			if entry == nil {
+				t.Fatalf("expected an entry for today, got %v", written)
+			}
+			var ids []string
+			for id := range entry.Repositories {
+				ids = append(ids, id)
+			}
+			sort.Strings(ids)
+			if strings.Join(ids, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("expected repositories %v, got %v", tt.expected, ids)
+			}
+		})
+	}
+}
+
+func TestUpdate_GeneratedBy(t *testing.T) {
+	tests := []struct {
+		name        string
+		generatedBy string
+	}{
+		{"version set", "duplicaci 1.4.0 (abc1234)"},
+		{"version unset", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := "{}"
+			w := NewWriter("", "", "Duplicacy")
+			w.GeneratedBy = tt.generatedBy
+			w.run = func(cmdStr string) (string, error) {
+				if strings.HasPrefix(cmdStr, "docker exec Duplicacy sh -c 'cat > ") {
+					start := strings.Index(cmdStr, "\n") + 1
+					end := strings.LastIndex(cmdStr, "\nSTATSEOF")
+					file = cmdStr[start:end]
+				}
+				return file, nil
+			}
+
+			day := &DayStats{TotalSize: 1000, Status: StatusChecked}
+			if _, err := w.Update("NAS", day); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if day.GeneratedBy != "" {
+				t.Errorf("Update() must not modify the caller's entry, got %q", day.GeneratedBy)
+			}
+
+			written, err := w.ReadStorageStats("NAS")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := written[TodayDate()].GeneratedBy; got != tt.generatedBy {
+				t.Errorf("expected generated-by %q, got %q", tt.generatedBy, got)
+			}
+			if tt.generatedBy == "" && strings.Contains(file, "generated-by") {
+				t.Errorf("expected no generated-by field, got %s", file)
+			}
+		})
+	}
+}
+
+func TestUpdate_KeepsHistoryWithUnexpectedFields(t *testing.T) {
+	existing := `{
+    "2025-01-01": {"total-size": 1000, "total-chunks": 10, "status": "Checked", "duration": 12.5,
+        "repositories": {"appdata": {"revisions": 2, "total-size": 900, "unique-size": 100, "total-chunks": 9, "files": 42}}},
+    "2025-01-02": {"total-size": 1.5e3, "total-chunks": "11", "status": "Checked", "repositories": {}},
+    "2025-01-03": {"total-size": true},
+    "version": 2
+}`
+	var written string
+	w := NewWriter("", "", "Duplicacy")
+	w.run = func(cmdStr string) (string, error) {
+		if strings.Contains(cmdStr, "cat > ") {
+			written = cmdStr
+		}
+		return existing, nil
+	}
+
+	result, err := w.Update("NAS", &DayStats{TotalSize: 2000, Status: StatusChecked})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.PreviousDate != "2025-01-02" || result.Previous.TotalSize != 1500 || result.Previous.TotalChunks != 11 {
+		t.Errorf("expected the 2025-01-02 entry as previous, got %s %+v", result.PreviousDate, result.Previous)
+	}
+
+	// The written JSON is the heredoc body of the write command
+	start := strings.Index(written, "\n")
+	end := strings.LastIndex(written, "\nSTATSEOF")
+	if start < 0 || end < start {
+		t.Fatalf("unexpected write command %q", written)
+	}
+	var file map[string]interface{}
+	if err := json.Unmarshal([]byte(written[start+1:end]), &file); err != nil {
+		t.Fatalf("invalid written JSON: %v", err)
+	}
+
+	for _, key := range []string{"2025-01-01", "2025-01-02", "2025-01-03", "version", TodayDate()} {
+		if _, ok := file[key]; !ok {
+			t.Errorf("expected %q to be kept, got %v", key, file)
+		}
+	}
+	first, _ := file["2025-01-01"].(map[string]interface{})
+	if first["duration"] != 12.5 {
+		t.Errorf("expected the unknown entry field to be kept, got %v", first)
+	}
+	repo, _ := first["repositories"].(map[string]interface{})["appdata"].(map[string]interface{})
+	if repo["files"] != float64(42) || repo["total-size"] != float64(900) {
+		t.Errorf("expected the repository's unknown field to be kept, got %v", repo)
+	}
+	if broken, _ := file["2025-01-03"].(map[string]interface{}); broken["total-size"] != true {
+		t.Errorf("expected the undecodable entry unchanged, got %v", file["2025-01-03"])
+	}
+	if file["version"] != float64(2) {
+		t.Errorf("expected the non-entry key unchanged, got %v", file["version"])
+	}
+}
+
+func TestDayStats_UnmarshalNumberForms(t *testing.T) {
+	tests := []struct {
+		json     string
+		expected int64
+	}{
+		{`{"total-size": 4096}`, 4096},
+		{`{"total-size": 4.096e3}`, 4096},
+		{`{"total-size": "4096"}`, 4096},
+		{`{"total-size": "4,617M"}`, 4617 * 1024 * 1024},
+		{`{"total-size": "1.5 GB"}`, 1536 * 1024 * 1024},
+		{`{"total-size": null}`, 0},
+	}
+	for _, tt := range tests {
+		var day DayStats
+		if err := json.Unmarshal([]byte(tt.json), &day); err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.json, err)
+			continue
+		}
+		if day.TotalSize != tt.expected {
+			t.Errorf("%s: expected %d, got %d", tt.json, tt.expected, day.TotalSize)
+		}
+	}
+
+	var day DayStats
+	if err := json.Unmarshal([]byte(`{"total-size": "lots"}`), &day); err == nil {
+		t.Error("expected an error for a non-numeric size")
+	}
+}
+
+func TestDayStats_MarshalFormatsRoundTrip(t *testing.T) {
+	// Sizes that are exact at the human format's precision
+	day := &DayStats{
+		TotalSize:       3 * 1024 * 1024 * 1024 / 2,
+		TotalChunks:     92,
+		PrunedChunks:    4,
+		PrunedRevisions: 1,
+		Status:          StatusChecked,
+		Repositories: map[string]RepoStats{
+			"appdata": {Revisions: 3, TotalSize: 512, UniqueSize: 5 * 1024 * 1024 / 2, TotalChunks: 40},
+		},
+	}
+
+	tests := []struct {
+		format string
+		size   string // expected "total-size" in the JSON
+	}{
+		{SizeFormatBytes, `"total-size":1610612736`},
+		{SizeFormatHuman, `"total-size":"1.5 GB"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			data, err := day.marshal(tt.format)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !strings.Contains(string(data), tt.size) {
+				t.Errorf("expected %s in %s", tt.size, data)
+			}
+
+			var got DayStats
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(&got, day) {
+				t.Errorf("expected %+v, got %+v", day, got)
+			}
+		})
+	}
+
+	// MarshalJSON keeps writing bytes
+	data, err := json.Marshal(day)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(data), `"unique-size":2621440`) {
+		t.Errorf("expected integer bytes by default, got %s", data)
+	}
+}
+
+func TestWriteStatsFile_HumanSizes(t *testing.T) {
+	var written string
+	w := NewWriter("", "", "Duplicacy")
+	w.SizeFormat = SizeFormatHuman
+	w.run = func(cmdStr string) (string, error) {
+		written = cmdStr
+		return "", nil
+	}
+
+	stats := StorageStats{"2025-01-01": {TotalSize: 2048, Repositories: map[string]RepoStats{"a": {TotalSize: 512}}}}
+	if err := w.writeStatsFile("/config/stats/storages/NAS.stats", stats); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, expected := range []string{`"total-size": "2.0 KB"`, `"total-size": "512 B"`} {
+		if !strings.Contains(written, expected) {
+			t.Errorf("expected %s in %s", expected, written)
+		}
+	}
+}
@@ -7,16 +7,36 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+	"sync"
+	"time"
 )
 
 // Writer handles updating stats files via SSH/Docker
 type Writer struct {
 	SSHHost         string
 	SSHPassword     string
+	SSHPasswordMode string // "file" passes SSHPassword to sshpass through a pipe (-d 3) instead of -p
 	DockerContainer string
+	ContainerUser   string // User to run as inside the container (docker exec -u)
 	StatsPath       string // default: /config/stats/storages
 	DryRun          bool
 	Verbose         bool
+	Location        *time.Location // Timezone for the date key (default: local time)
+	RefuseStale     bool           // Refuse to write when today's date is before the latest existing entry
+	MergeSameDay    bool           // Merge into an existing entry for today instead of replacing it
+	SizeFormat      string         // SizeFormatBytes (default) or SizeFormatHuman for sizes written
+	GeneratedBy     string         // Recorded in each entry written, e.g. "duplicaci 1.4.0 (abc1234)"
+
+	ensureDirOnce sync.Once
+	ensureDirErr  error
+
+	// unparsed holds, per stats file, entries read that could not be decoded;
+	// they are written back as they were
+	unparsedMu sync.Mutex
+	unparsed   map[string]map[string]json.RawMessage
+
+	// run executes a shell command and returns its stdout (tests substitute a recorder)
+	run func(cmdStr string) (string, error)
 }
 
 // NewWriter creates a new stats writer
@@ -29,9 +49,43 @@ func NewWriter(sshHost, sshPassword, dockerContainer string) *Writer {
 	}
 }
 
+// ReadStorageStats reads the stats file for a storage
+func (w *Writer) ReadStorageStats(storage string) (StorageStats, error) {
+	return w.readStatsFile(w.statsFile(storage))
+}
+
+// ReadFile returns the contents of a file in the container
+func (w *Writer) ReadFile(path string) (string, error) {
+	if w.Verbose {
+		fmt.Printf("    Reading: %s\n", path)
+	}
+	output, err := w.executeCapture(w.buildDockerCommand(fmt.Sprintf("cat %s", path)))
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return output, nil
+}
+
+// statsFile returns the path of a storage's stats file in the container
+func (w *Writer) statsFile(storage string) string {
+	return fmt.Sprintf("%s/%s.stats", w.StatsPath, storage)
+}
+
+// UpdateResult describes the existing stats seen while updating a storage
+type UpdateResult struct {
+	PreviousDate string    // Date of the latest entry before today ("" if none)
+	Previous     *DayStats // That entry, or nil on the first run
+}
+
 // UpdateStorageStats reads existing stats, adds today's entry, writes back
 func (w *Writer) UpdateStorageStats(storage string, dayStats *DayStats) error {
-	statsFile := fmt.Sprintf("%s/%s.stats", w.StatsPath, storage)
+	_, err := w.Update(storage, dayStats)
+	return err
+}
+
+// Update is UpdateStorageStats, also returning the prior entry read from the file
+func (w *Writer) Update(storage string, dayStats *DayStats) (UpdateResult, error) {
+	statsFile := w.statsFile(storage)
 
 	// Read existing stats
 	existingStats, err := w.readStatsFile(statsFile)
@@ -40,12 +94,53 @@ func (w *Writer) UpdateStorageStats(storage string, dayStats *DayStats) error {
 		existingStats = make(StorageStats)
 	}
 
+	// A date earlier than the newest entry means the clock is probably wrong;
+	// writing would put older data in front of a newer entry
+	today := TodayDateIn(w.Location)
+	if latest := existingStats.Latest(); latest > today {
+		if w.RefuseStale {
+			return UpdateResult{}, fmt.Errorf("today's date %s is before the latest %s entry %s; refusing to write stats (check the container clock)", today, storage, latest)
+		}
+		fmt.Fprintf(os.Stderr, "    WARNING: today's date %s is before the latest %s stats entry %s; check the container clock\n", today, storage, latest)
+	}
+
+	// Make sure the stats dir exists before the first write
+	w.ensureDirOnce.Do(func() {
+		w.ensureDirErr = w.EnsureStatsDir()
+	})
+	if w.ensureDirErr != nil {
+		return UpdateResult{}, w.ensureDirErr
+	}
+
 	// Add/update today's entry
-	today := TodayDate()
+	var result UpdateResult
+	result.PreviousDate, result.Previous = existingStats.Before(today)
+	if w.GeneratedBy != "" {
+		entry := *dayStats
+		entry.GeneratedBy = w.GeneratedBy
+		dayStats = &entry
+	}
+	if w.MergeSameDay {
+		dayStats = MergeSameDay(existingStats[today], dayStats)
+	}
 	existingStats[today] = dayStats
 
 	// Write back
-	return w.writeStatsFile(statsFile, existingStats)
+	return result, w.writeStatsFile(statsFile, existingStats)
+}
+
+// EnsureStatsDir creates StatsPath in the container if it does not exist yet.
+// It is safe to call repeatedly; Update calls it once before its first write.
+func (w *Writer) EnsureStatsDir() error {
+	if w.DryRun {
+		fmt.Printf("    [DRY-RUN] Would create %s\n", w.StatsPath)
+		return nil
+	}
+
+	if err := w.execute(w.buildDockerCommand(fmt.Sprintf("mkdir -p %s", w.StatsPath))); err != nil {
+		return fmt.Errorf("failed to create stats dir %s: %w", w.StatsPath, err)
+	}
+	return nil
 }
 
 // readStatsFile reads and parses a stats file from the Docker container
@@ -61,21 +156,52 @@ func (w *Writer) readStatsFile(path string) (StorageStats, error) {
 		return nil, fmt.Errorf("failed to read stats file: %w", err)
 	}
 
-	var stats StorageStats
-	if err := json.Unmarshal([]byte(output), &stats); err != nil {
-		// If parsing fails, return empty stats
+	var entries map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(output), &entries); err != nil {
+		// Not a JSON object at all: there is no history to keep
+		w.warnf("%s is not a stats file (%v); starting it afresh", path, err)
 		return make(StorageStats), nil
 	}
 
+	// An entry that cannot be decoded is kept as is rather than dropping the file
+	stats := make(StorageStats, len(entries))
+	unparsed := make(map[string]json.RawMessage)
+	for key, raw := range entries {
+		var day *DayStats
+		if err := json.Unmarshal(raw, &day); err != nil {
+			w.warnf("%s: keeping entry %q unchanged: %v", path, key, err)
+			unparsed[key] = raw
+			continue
+		}
+		stats[key] = day
+	}
+
+	w.unparsedMu.Lock()
+	if w.unparsed == nil {
+		w.unparsed = make(map[string]map[string]json.RawMessage)
+	}
+	w.unparsed[path] = unparsed
+	w.unparsedMu.Unlock()
+
 	return stats, nil
 }
 
+// warnf prints a warning about the stats file contents in verbose mode
+func (w *Writer) warnf(format string, args ...interface{}) {
+	if w.Verbose {
+		fmt.Fprintf(os.Stderr, "    WARNING: "+format+"\n", args...)
+	}
+}
+
 // writeStatsFile writes stats to a file in the Docker container
 func (w *Writer) writeStatsFile(path string, stats StorageStats) error {
-	// Marshal with indentation to match Duplicacy Web format
-	data, err := json.MarshalIndent(stats, "", "    ")
+	w.unparsedMu.Lock()
+	unparsed := w.unparsed[path]
+	w.unparsedMu.Unlock()
+
+	data, err := encodeStatsFile(stats, unparsed, w.SizeFormat)
 	if err != nil {
-		return fmt.Errorf("failed to marshal stats: %w", err)
+		return err
 	}
 
 	if w.DryRun {
@@ -86,8 +212,9 @@ func (w *Writer) writeStatsFile(path string, stats StorageStats) error {
 	// Escape the JSON for shell
 	escapedJSON := strings.ReplaceAll(string(data), "'", "'\"'\"'")
 
-	// Write via cat with heredoc-style input
-	cmd := w.buildDockerCommand(fmt.Sprintf("cat > %s << 'STATSEOF'\n%s\nSTATSEOF", path, escapedJSON))
+	// Write via cat with heredoc-style input to a temp file, then rename it into
+	// place so an interrupted write never leaves a truncated stats file
+	cmd := w.buildDockerCommand(fmt.Sprintf("cat > %s.tmp << 'STATSEOF' && mv %s.tmp %s\n%s\nSTATSEOF", path, path, path, escapedJSON))
 
 	if w.Verbose {
 		fmt.Printf("    Writing stats: %s\n", path)
@@ -100,10 +227,36 @@ func (w *Writer) writeStatsFile(path string, stats StorageStats) error {
 	return nil
 }
 
+// encodeStatsFile returns the contents of a stats file: the entries and the
+// undecodable entries kept from reading it, with sizes in the given format.
+// encoding/json writes map keys sorted, so dates and repository IDs are in
+// order and unchanged data encodes to the same bytes (diff-friendly files).
+func encodeStatsFile(stats StorageStats, unparsed map[string]json.RawMessage, format string) ([]byte, error) {
+	// Entries that could not be decoded when the file was read go back as they were
+	entries := make(map[string]interface{}, len(stats)+len(unparsed))
+	for key, raw := range unparsed {
+		entries[key] = raw
+	}
+	for key, day := range stats {
+		entries[key] = formattedDayStats{day: day, format: format}
+	}
+
+	// Marshal with indentation to match Duplicacy Web format
+	data, err := json.MarshalIndent(entries, "", "    ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal stats: %w", err)
+	}
+	return data, nil
+}
+
 // buildDockerCommand constructs a command to run inside the Docker container
 func (w *Writer) buildDockerCommand(shellCmd string) string {
 	// Escape the shell command for docker exec
-	dockerCmd := fmt.Sprintf("docker exec %s sh -c '%s'", w.DockerContainer, shellCmd)
+	execPrefix := "docker exec " + w.DockerContainer
+	if w.ContainerUser != "" {
+		execPrefix = fmt.Sprintf("docker exec -u %s %s", w.ContainerUser, w.DockerContainer)
+	}
+	dockerCmd := fmt.Sprintf("%s sh -c '%s'", execPrefix, shellCmd)
 
 	// Wrap in SSH if host specified
 	if w.SSHHost != "" {
@@ -112,7 +265,9 @@ func (w *Writer) buildDockerCommand(shellCmd string) string {
 		dockerCmd = fmt.Sprintf("ssh -o StrictHostKeyChecking=no -o LogLevel=ERROR %s '%s'", w.SSHHost, escapedCmd)
 
 		// Add sshpass if password provided
-		if w.SSHPassword != "" {
+		if w.usesPasswordPipe() {
+			dockerCmd = "sshpass -d 3 " + dockerCmd
+		} else if w.SSHPassword != "" {
 			dockerCmd = fmt.Sprintf("sshpass -p '%s' %s",
 				strings.ReplaceAll(w.SSHPassword, "'", "'\"'\"'"),
 				dockerCmd)
@@ -122,9 +277,44 @@ func (w *Writer) buildDockerCommand(shellCmd string) string {
 	return dockerCmd
 }
 
+// usesPasswordPipe reports whether commands read the SSH password from a pipe on fd 3
+func (w *Writer) usesPasswordPipe() bool {
+	return w.SSHHost != "" && w.SSHPassword != "" && w.SSHPasswordMode == "file"
+}
+
+// command returns cmdStr as a bash command, with the SSH password pipe
+// attached when used; done closes it once the command has finished
+func (w *Writer) command(cmdStr string) (cmd *exec.Cmd, done func(), err error) {
+	cmd = exec.Command("bash", "-c", cmdStr)
+	if !w.usesPasswordPipe() {
+		return cmd, func() {}, nil
+	}
+
+	r, pw, err := os.Pipe()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create SSH password pipe: %w", err)
+	}
+	_, err = pw.WriteString(w.SSHPassword + "\n")
+	pw.Close()
+	if err != nil {
+		r.Close()
+		return nil, nil, fmt.Errorf("failed to write SSH password pipe: %w", err)
+	}
+	cmd.ExtraFiles = []*os.File{r}
+	return cmd, func() { r.Close() }, nil
+}
+
 // executeCapture runs a command and returns stdout
 func (w *Writer) executeCapture(cmdStr string) (string, error) {
-	cmd := exec.Command("bash", "-c", cmdStr)
+	if w.run != nil {
+		return w.run(cmdStr)
+	}
+
+	cmd, done, err := w.command(cmdStr)
+	if err != nil {
+		return "", err
+	}
+	defer done()
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
@@ -138,7 +328,16 @@ func (w *Writer) executeCapture(cmdStr string) (string, error) {
 
 // execute runs a command and streams output
 func (w *Writer) execute(cmdStr string) error {
-	cmd := exec.Command("bash", "-c", cmdStr)
+	if w.run != nil {
+		_, err := w.run(cmdStr)
+		return err
+	}
+
+	cmd, done, err := w.command(cmdStr)
+	if err != nil {
+		return err
+	}
+	defer done()
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
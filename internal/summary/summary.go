@@ -0,0 +1,237 @@
+package summary
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/lioreshai/duplicaci/internal/stats"
+)
+
+// Result status values used for runs, phases and operations
+const (
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped" // Operations only: ran with nothing to do (unchanged backup) or not run (non-prunable storage)
+)
+
+// RunSummary is a machine-readable record of a run, suitable for publishing as a CI artifact.
+// Recording methods may be called from multiple goroutines.
+type RunSummary struct {
+	Status     string                      `json:"status"`
+	StartedAt  time.Time                   `json:"started_at"`
+	FinishedAt time.Time                   `json:"finished_at"`
+	Duration   float64                     `json:"duration_seconds"`
+	Phases     []*PhaseResult              `json:"phases"`
+	Counts     OperationCounts             `json:"counts"` // Operations per status, set by Finish
+	Storages   map[string]*stats.DayStats  `json:"storages,omitempty"`
+	Deltas     map[string]stats.ChunkDelta `json:"chunk_deltas,omitempty"` // Chunk changes since each storage's prior stats entry
+	Errors     []string                    `json:"errors"`
+	Warnings   []string                    `json:"warnings,omitempty"`
+
+	mu sync.Mutex
+}
+
+// PhaseResult records the outcome of a single phase (backup, prune, check)
+type PhaseResult struct {
+	Name       string            `json:"name"`
+	Status     string            `json:"status"`
+	Duration   float64           `json:"duration_seconds"`
+	Operations []OperationResult `json:"operations"`
+
+	started time.Time
+	mu      sync.Mutex
+}
+
+// OperationResult records the outcome of a single duplicacy invocation
+type OperationResult struct {
+	Backup   string  `json:"backup,omitempty"`
+	Storage  string  `json:"storage"`
+	Status   string  `json:"status"`
+	Error    string  `json:"error,omitempty"`
+	Reason   string  `json:"reason,omitempty"`   // Why a skipped operation was skipped
+	Revision int     `json:"revision,omitempty"` // Revision a successful backup created
+	Duration float64 `json:"duration_seconds"`
+}
+
+// OperationCounts counts operations by status
+type OperationCounts struct {
+	Succeeded int `json:"succeeded"`
+	Skipped   int `json:"skipped"`
+	Failed    int `json:"failed"`
+}
+
+// Total returns the number of operations counted
+func (c OperationCounts) Total() int {
+	return c.Succeeded + c.Skipped + c.Failed
+}
+
+// New creates a summary for a run that started at the given time
+func New(startedAt time.Time) *RunSummary {
+	return &RunSummary{
+		StartedAt: startedAt,
+		Phases:    []*PhaseResult{},
+		Storages:  make(map[string]*stats.DayStats),
+		Errors:    []string{},
+	}
+}
+
+// StartPhase begins timing a new phase and appends it to the summary
+func (s *RunSummary) StartPhase(name string) *PhaseResult {
+	phase := &PhaseResult{
+		Name:       name,
+		Status:     StatusSuccess,
+		Operations: []OperationResult{},
+		started:    time.Now(),
+	}
+	s.mu.Lock()
+	s.Phases = append(s.Phases, phase)
+	s.mu.Unlock()
+	return phase
+}
+
+// SetStorageStats records the parsed check statistics for a storage
+func (s *RunSummary) SetStorageStats(storage string, dayStats *stats.DayStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Storages[storage] = dayStats
+}
+
+// SetChunkDelta records a storage's chunk count change since its prior stats entry
+func (s *RunSummary) SetChunkDelta(storage string, delta stats.ChunkDelta) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Deltas == nil {
+		s.Deltas = make(map[string]stats.ChunkDelta)
+	}
+	s.Deltas[storage] = delta
+}
+
+// AddWarning records a non-fatal problem that does not fail the run
+func (s *RunSummary) AddWarning(msg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Warnings = append(s.Warnings, msg)
+}
+
+// AddOperation records an operation result; any failed operation fails the phase
+func (p *PhaseResult) AddOperation(op OperationResult) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if op.Status == StatusFailed {
+		p.Status = StatusFailed
+	}
+	p.Operations = append(p.Operations, op)
+}
+
+// CountOperations returns the operations of every phase by status
+func (s *RunSummary) CountOperations() OperationCounts {
+	s.mu.Lock()
+	phases := append([]*PhaseResult{}, s.Phases...)
+	s.mu.Unlock()
+
+	var counts OperationCounts
+	for _, p := range phases {
+		p.mu.Lock()
+		for _, op := range p.Operations {
+			switch op.Status {
+			case StatusSkipped:
+				counts.Skipped++
+			case StatusFailed:
+				counts.Failed++
+			default:
+				counts.Succeeded++
+			}
+		}
+		p.mu.Unlock()
+	}
+	return counts
+}
+
+// SkippedOperations returns the skipped operations of every phase, in order
+func (s *RunSummary) SkippedOperations() []OperationResult {
+	s.mu.Lock()
+	phases := append([]*PhaseResult{}, s.Phases...)
+	s.mu.Unlock()
+
+	var skipped []OperationResult
+	for _, p := range phases {
+		p.mu.Lock()
+		for _, op := range p.Operations {
+			if op.Status == StatusSkipped {
+				skipped = append(skipped, op)
+			}
+		}
+		p.mu.Unlock()
+	}
+	return skipped
+}
+
+// Finish records the phase duration
+func (p *PhaseResult) Finish() {
+	p.Duration = time.Since(p.started).Seconds()
+}
+
+// Finish sets the overall status and timing from the collected errors
+func (s *RunSummary) Finish(finishedAt time.Time, errors []string) {
+	s.FinishedAt = finishedAt
+	s.Duration = finishedAt.Sub(s.StartedAt).Seconds()
+	s.Errors = append([]string{}, errors...)
+	s.Counts = s.CountOperations()
+
+	s.Status = StatusSuccess
+	if len(errors) > 0 {
+		s.Status = StatusFailed
+	}
+}
+
+// WriteFile writes the summary as indented JSON to a local file
+func (s *RunSummary) WriteFile(path string) error {
+	data, err := s.marshal()
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write summary file: %w", err)
+	}
+
+	return nil
+}
+
+// WriteJSON writes the summary as indented JSON to w
+func (s *RunSummary) WriteJSON(w io.Writer) error {
+	data, err := s.marshal()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// marshal encodes the summary as indented JSON with a trailing newline
+func (s *RunSummary) marshal() ([]byte, error) {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal summary: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// ReadFile loads a summary previously written with WriteFile
+func ReadFile(path string) (*RunSummary, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var s RunSummary
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse summary file: %w", err)
+	}
+
+	return &s, nil
+}
@@ -0,0 +1,169 @@
+package summary
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lioreshai/duplicaci/internal/stats"
+)
+
+func TestRunSummary_WriteFile_Success(t *testing.T) {
+	start := time.Date(2025, 1, 15, 6, 0, 0, 0, time.UTC)
+	s := New(start)
+
+	phase := s.StartPhase("backup")
+	phase.AddOperation(OperationResult{Backup: "appdata", Storage: "NAS", Status: StatusSuccess})
+	phase.Finish()
+
+	s.SetStorageStats("NAS", &stats.DayStats{TotalSize: 1024, TotalChunks: 10, Status: "Checked"})
+	s.SetChunkDelta("NAS", stats.ChunkDelta{Since: "2025-01-01", Total: 4, Repositories: map[string]int{"appdata": 4}})
+	s.Finish(start.Add(90*time.Second), nil)
+
+	path := filepath.Join(t.TempDir(), "summary.json")
+	if err := s.WriteFile(path); err != nil {
+		t.Fatalf("failed to write summary: %v", err)
+	}
+
+	got, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read summary: %v", err)
+	}
+
+	if got.Status != StatusSuccess {
+		t.Errorf("expected status %q, got %q", StatusSuccess, got.Status)
+	}
+	if got.Duration != 90 {
+		t.Errorf("expected duration 90, got %v", got.Duration)
+	}
+	if len(got.Errors) != 0 {
+		t.Errorf("expected no errors, got %v", got.Errors)
+	}
+	if len(got.Phases) != 1 || got.Phases[0].Name != "backup" {
+		t.Fatalf("expected single backup phase, got %+v", got.Phases)
+	}
+	if len(got.Phases[0].Operations) != 1 || got.Phases[0].Operations[0].Storage != "NAS" {
+		t.Errorf("unexpected operations: %+v", got.Phases[0].Operations)
+	}
+	if got.Storages["NAS"] == nil || got.Storages["NAS"].TotalChunks != 10 {
+		t.Errorf("expected NAS stats with 10 chunks, got %+v", got.Storages["NAS"])
+	}
+	if d := got.Deltas["NAS"]; d.Total != 4 || d.Since != "2025-01-01" || d.Repositories["appdata"] != 4 {
+		t.Errorf("expected NAS chunk delta +4 since 2025-01-01, got %+v", d)
+	}
+}
+
+func TestRunSummary_WriteFile_Failure(t *testing.T) {
+	start := time.Now()
+	s := New(start)
+
+	phase := s.StartPhase("prune")
+	phase.AddOperation(OperationResult{Storage: "NAS", Status: StatusSuccess})
+	phase.AddOperation(OperationResult{Storage: "Cloud", Status: StatusFailed, Error: "command exited with code 1"})
+	phase.Finish()
+
+	s.Finish(start.Add(time.Second), []string{"prune Cloud: command exited with code 1"})
+
+	path := filepath.Join(t.TempDir(), "summary.json")
+	if err := s.WriteFile(path); err != nil {
+		t.Fatalf("failed to write summary: %v", err)
+	}
+
+	// Verify the raw JSON field names consumers rely on
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatalf("summary is not valid JSON: %v", err)
+	}
+	for _, key := range []string{"status", "started_at", "finished_at", "duration_seconds", "phases", "errors"} {
+		if _, ok := raw[key]; !ok {
+			t.Errorf("expected key %q in summary JSON", key)
+		}
+	}
+
+	got, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read summary: %v", err)
+	}
+
+	if got.Status != StatusFailed {
+		t.Errorf("expected status %q, got %q", StatusFailed, got.Status)
+	}
+	if len(got.Errors) != 1 {
+		t.Errorf("expected 1 error, got %d", len(got.Errors))
+	}
+	if got.Phases[0].Status != StatusFailed {
+		t.Errorf("expected failed phase, got %q", got.Phases[0].Status)
+	}
+	if got.Phases[0].Operations[1].Error == "" {
+		t.Error("expected error message on failed operation")
+	}
+}
+
+func TestRunSummary_CountOperations(t *testing.T) {
+	start := time.Date(2025, 1, 15, 6, 0, 0, 0, time.UTC)
+	s := New(start)
+
+	backup := s.StartPhase("backup")
+	backup.AddOperation(OperationResult{Backup: "appdata", Storage: "NAS", Status: StatusSuccess, Revision: 12})
+	backup.AddOperation(OperationResult{Backup: "photos", Storage: "NAS", Status: StatusSkipped, Reason: "unchanged: nothing to back up"})
+	backup.AddOperation(OperationResult{Backup: "photos", Storage: "Cloud", Status: StatusFailed, Error: "exit status 1"})
+	prune := s.StartPhase("prune")
+	prune.AddOperation(OperationResult{Storage: "Archive", Status: StatusSkipped, Reason: "prunable: false"})
+	prune.AddOperation(OperationResult{Storage: "NAS", Status: StatusSuccess})
+
+	expected := OperationCounts{Succeeded: 2, Skipped: 2, Failed: 1}
+	if got := s.CountOperations(); got != expected {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+	if backup.Status != StatusFailed || prune.Status != StatusSuccess {
+		t.Errorf("expected only a failure to fail a phase, got backup %q, prune %q", backup.Status, prune.Status)
+	}
+
+	skipped := s.SkippedOperations()
+	if len(skipped) != 2 || skipped[0].Backup != "photos" || skipped[1].Storage != "Archive" {
+		t.Errorf("expected photos -> NAS and Archive skipped, got %+v", skipped)
+	}
+
+	// The counts are part of the JSON summary
+	s.Finish(start.Add(time.Minute), []string{"photos -> Cloud: exit status 1"})
+	path := filepath.Join(t.TempDir(), "summary.json")
+	if err := s.WriteFile(path); err != nil {
+		t.Fatalf("failed to write summary: %v", err)
+	}
+	got, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read summary: %v", err)
+	}
+	if got.Counts != expected {
+		t.Errorf("expected counts %+v in JSON, got %+v", expected, got.Counts)
+	}
+	if op := got.Phases[0].Operations[1]; op.Reason != "unchanged: nothing to back up" {
+		t.Errorf("expected skip reason in JSON, got %+v", op)
+	}
+}
+
+func TestRunSummary_WriteFile_InvalidPath(t *testing.T) {
+	s := New(time.Now())
+	s.Finish(time.Now(), nil)
+
+	if err := s.WriteFile("/nonexistent/dir/summary.json"); err == nil {
+		t.Error("expected error writing to nonexistent directory")
+	}
+}
+
+func TestReadFile_InvalidJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.json")
+	if err := os.WriteFile(path, []byte("not json"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	if _, err := ReadFile(path); err == nil {
+		t.Error("expected error for invalid JSON")
+	}
+}